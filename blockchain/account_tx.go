@@ -0,0 +1,207 @@
+package blockchain
+
+import (
+	"encoding/binary"
+
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/store"
+)
+
+// MaxAccountTxQueryLimit is the maximum number of transactions a single account history query can return.
+const MaxAccountTxQueryLimit = uint64(100)
+
+// accountTxCountKey constructs the DB key for the number of transactions indexed for the given address.
+func accountTxCountKey(addr common.Address) common.Bytes {
+	return append(common.Bytes("atxc/"), addr[:]...)
+}
+
+// accountTxKey constructs the DB key for the seq-th transaction indexed for the given address.
+func accountTxKey(addr common.Address, seq uint64) common.Bytes {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, seq)
+	key := append(common.Bytes("atx/"), addr[:]...)
+	key = append(key, '/')
+	return append(key, buf...)
+}
+
+// accountTxMarkerKey constructs the DB key recording that the given transaction has been indexed
+// for the given address. It maps to the sequence number of the index entry.
+func accountTxMarkerKey(addr common.Address, txHash common.Hash) common.Bytes {
+	key := append(common.Bytes("atxm/"), addr[:]...)
+	key = append(key, '/')
+	return append(key, txHash[:]...)
+}
+
+// AccountTxIndexEntry points to a finalized transaction that touched an address.
+type AccountTxIndexEntry struct {
+	BlockHeight uint64
+	TxHash      common.Hash
+}
+
+// SetAccountTxIndexEnabled sets whether finalized transactions should be indexed by the addresses they touch.
+func (ch *Chain) SetAccountTxIndexEnabled(enabled bool) {
+	ch.accountTxIndexEnabled = enabled
+}
+
+// AccountTxIndexEnabled returns whether finalized transactions are indexed by the addresses they touch.
+func (ch *Chain) AccountTxIndexEnabled() bool {
+	return ch.accountTxIndexEnabled
+}
+
+// addTxsToAccountIndex indexes the transactions of the given finalized block by the addresses they touch.
+func (ch *Chain) addTxsToAccountIndex(block *core.ExtendedBlock) {
+	if !ch.accountTxIndexEnabled {
+		return
+	}
+
+	ch.accountTxMu.Lock()
+	defer ch.accountTxMu.Unlock()
+
+	for _, rawTx := range block.Txs {
+		tx, err := types.TxFromBytes(rawTx)
+		if err != nil {
+			logger.Warnf("Failed to decode tx for the account index: %v", err)
+			continue
+		}
+		txHash := crypto.Keccak256Hash(rawTx)
+		for _, addr := range TxAddresses(tx) {
+			ch.addAccountTx(addr, block.Height, txHash)
+		}
+	}
+}
+
+func (ch *Chain) addAccountTx(addr common.Address, height uint64, txHash common.Hash) {
+	entry := AccountTxIndexEntry{
+		BlockHeight: height,
+		TxHash:      txHash,
+	}
+
+	// A block can be finalized more than once (e.g. FinalizePreviousBlocks followed by
+	// the consensus engine), only refresh the existing entry in that case.
+	var seq uint64
+	markerKey := accountTxMarkerKey(addr, txHash)
+	err := ch.store.Get(markerKey, &seq)
+	if err == nil {
+		if err := ch.store.Put(accountTxKey(addr, seq), entry); err != nil {
+			logger.Panic(err)
+		}
+		return
+	}
+	if err != store.ErrKeyNotFound {
+		logger.Panic(err)
+	}
+
+	seq = ch.accountTxCount(addr)
+	if err := ch.store.Put(accountTxKey(addr, seq), entry); err != nil {
+		logger.Panic(err)
+	}
+	if err := ch.store.Put(markerKey, seq); err != nil {
+		logger.Panic(err)
+	}
+	if err := ch.store.Put(accountTxCountKey(addr), seq+1); err != nil {
+		logger.Panic(err)
+	}
+}
+
+func (ch *Chain) accountTxCount(addr common.Address) uint64 {
+	var count uint64
+	err := ch.store.Get(accountTxCountKey(addr), &count)
+	if err != nil && err != store.ErrKeyNotFound {
+		logger.Panic(err)
+	}
+	return count
+}
+
+// FindAccountTxs returns the transactions that touched the given address, most recent first,
+// skipping the first offset entries. It also returns the total number of indexed transactions.
+func (ch *Chain) FindAccountTxs(addr common.Address, offset, limit uint64) ([]*AccountTxIndexEntry, uint64) {
+	if limit > MaxAccountTxQueryLimit {
+		limit = MaxAccountTxQueryLimit
+	}
+
+	ch.accountTxMu.Lock()
+	defer ch.accountTxMu.Unlock()
+
+	total := ch.accountTxCount(addr)
+	ret := []*AccountTxIndexEntry{}
+	for i := offset; i < total && uint64(len(ret)) < limit; i++ {
+		entry := &AccountTxIndexEntry{}
+		err := ch.store.Get(accountTxKey(addr, total-1-i), entry)
+		if err != nil {
+			logger.Errorf("Failed to load account tx index entry for %v: %v", addr.Hex(), err)
+			continue
+		}
+		ret = append(ret, entry)
+	}
+	return ret, total
+}
+
+// TxAddresses returns the addresses touched by the given transaction, i.e. its inputs, outputs,
+// stake sources and holders, contract callers and contracts.
+func TxAddresses(tx types.Tx) []common.Address {
+	addrs := []common.Address{}
+	switch tx := tx.(type) {
+	case *types.CoinbaseTx:
+		addrs = append(addrs, tx.Proposer.Address)
+		for _, output := range tx.Outputs {
+			addrs = append(addrs, output.Address)
+		}
+	case *types.SlashTx:
+		addrs = append(addrs, tx.Proposer.Address, tx.SlashedAddress)
+	case *types.SendTx:
+		addrs = appendInputOutputAddresses(addrs, tx.Inputs, tx.Outputs)
+	case *types.RametronStakeTx:
+		addrs = appendInputOutputAddresses(addrs, tx.Inputs, tx.Outputs)
+	case *types.WithdrawRametronStakeTx:
+		addrs = appendInputOutputAddresses(addrs, tx.Inputs, tx.Outputs)
+	case *types.ReserveFundTx:
+		addrs = append(addrs, tx.Source.Address)
+	case *types.ReleaseFundTx:
+		addrs = append(addrs, tx.Source.Address)
+	case *types.ServicePaymentTx:
+		addrs = append(addrs, tx.Source.Address, tx.Target.Address)
+	case *types.SplitRuleTx:
+		addrs = append(addrs, tx.Initiator.Address)
+		for _, split := range tx.Splits {
+			addrs = append(addrs, split.Address)
+		}
+	case *types.SmartContractTx:
+		addrs = append(addrs, tx.From.Address)
+		if (tx.To.Address != common.Address{}) {
+			addrs = append(addrs, tx.To.Address)
+		}
+	case *types.DepositStakeTx:
+		addrs = append(addrs, tx.Source.Address, tx.Holder.Address)
+	case *types.DepositStakeTxV2:
+		addrs = append(addrs, tx.Source.Address, tx.Holder.Address)
+	case *types.WithdrawStakeTx:
+		addrs = append(addrs, tx.Source.Address, tx.Holder.Address)
+	case *types.StakeRewardDistributionTx:
+		addrs = append(addrs, tx.Holder.Address, tx.Beneficiary.Address)
+	}
+
+	// Remove duplicates, e.g. a SendTx returning the change to the sender.
+	ret := []common.Address{}
+	seen := make(map[common.Address]bool)
+	for _, addr := range addrs {
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		ret = append(ret, addr)
+	}
+	return ret
+}
+
+func appendInputOutputAddresses(addrs []common.Address, inputs []types.TxInput, outputs []types.TxOutput) []common.Address {
+	for _, input := range inputs {
+		addrs = append(addrs, input.Address)
+	}
+	for _, output := range outputs {
+		addrs = append(addrs, output.Address)
+	}
+	return addrs
+}
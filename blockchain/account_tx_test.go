@@ -0,0 +1,84 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/types"
+)
+
+func TestAccountTxIndex(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	core.ResetTestBlocks()
+	chain := CreateTestChain()
+	chain.SetAccountTxIndexEnabled(true)
+
+	alice := common.HexToAddress("0xa1")
+	bob := common.HexToAddress("0xb1")
+	carol := common.HexToAddress("0xc1")
+
+	tx1 := &types.SendTx{
+		Inputs:  []types.TxInput{{Address: alice, Sequence: 1}},
+		Outputs: []types.TxOutput{{Address: bob}, {Address: alice}},
+	}
+	tx2 := &types.DepositStakeTx{
+		Source: types.TxInput{Address: alice, Sequence: 2},
+		Holder: types.TxOutput{Address: carol},
+	}
+	tx3 := &types.SendTx{
+		Inputs:  []types.TxInput{{Address: bob, Sequence: 1}},
+		Outputs: []types.TxOutput{{Address: carol}},
+	}
+
+	assert.Equal([]common.Address{alice, bob}, TxAddresses(tx1))
+	assert.Equal([]common.Address{alice, carol}, TxAddresses(tx2))
+
+	block1 := core.CreateTestBlock("b1", "a0")
+	block1.Height = 1
+	block1.Txs = []common.Bytes{encodeTestTx(tx1), encodeTestTx(tx2)}
+	block1.UpdateHash()
+
+	block2 := core.CreateTestBlock("b2", "b1")
+	block2.Height = 2
+	block2.Txs = []common.Bytes{encodeTestTx(tx3)}
+	block2.UpdateHash()
+
+	_, err := chain.AddBlock(block1)
+	require.Nil(err)
+	eb2, err := chain.AddBlock(block2)
+	require.Nil(err)
+
+	// Blocks are not indexed by address until finalized.
+	txs, total := chain.FindAccountTxs(alice, 0, 10)
+	assert.Equal(uint64(0), total)
+	assert.Equal(0, len(txs))
+
+	require.Nil(chain.FinalizePreviousBlocks(eb2.Hash()))
+	chain.AddTxsToIndex(eb2, true) // finalizing again should not duplicate entries
+
+	txs, total = chain.FindAccountTxs(alice, 0, 10)
+	require.Equal(uint64(2), total)
+	require.Equal(2, len(txs))
+	assert.Equal(crypto.Keccak256Hash(encodeTestTx(tx2)), txs[0].TxHash)
+	assert.Equal(crypto.Keccak256Hash(encodeTestTx(tx1)), txs[1].TxHash)
+	assert.Equal(uint64(1), txs[0].BlockHeight)
+
+	txs, total = chain.FindAccountTxs(bob, 0, 10)
+	assert.Equal(uint64(2), total)
+	assert.Equal(crypto.Keccak256Hash(encodeTestTx(tx3)), txs[0].TxHash)
+	assert.Equal(uint64(2), txs[0].BlockHeight)
+
+	txs, total = chain.FindAccountTxs(carol, 1, 1)
+	assert.Equal(uint64(2), total)
+	require.Equal(1, len(txs))
+	assert.Equal(crypto.Keccak256Hash(encodeTestTx(tx2)), txs[0].TxHash)
+
+	txs, _ = chain.FindAccountTxs(carol, 2, 1)
+	assert.Equal(0, len(txs))
+}
@@ -25,6 +25,9 @@ type Chain struct {
 	root    common.Hash
 
 	mu *sync.RWMutex
+
+	accountTxIndexEnabled bool
+	accountTxMu           *sync.Mutex
 }
 
 // NewChain creates a new Chain instance.
@@ -33,6 +36,8 @@ func NewChain(chainID string, store store.Store, root *core.Block) *Chain {
 		ChainID: chainID,
 		store:   store,
 		mu:      &sync.RWMutex{},
+
+		accountTxMu: &sync.Mutex{},
 	}
 	rootBlock, err := chain.FindBlock(root.Hash())
 	if err != nil {
@@ -47,6 +52,23 @@ func NewChain(chainID string, store store.Store, root *core.Block) *Chain {
 	return chain
 }
 
+// LoadChain opens the chain already in the store, with the given root block. Unlike NewChain, it
+// never writes to the store, and fails if the root block is not found.
+func LoadChain(chainID string, store store.Store, rootHash common.Hash) (*Chain, error) {
+	chain := &Chain{
+		ChainID: chainID,
+		store:   store,
+		mu:      &sync.RWMutex{},
+
+		accountTxMu: &sync.Mutex{},
+	}
+	if _, err := chain.FindBlock(rootHash); err != nil {
+		return nil, errors.Wrapf(err, "Root block %v is not found in chain", rootHash.Hex())
+	}
+	chain.root = rootHash
+	return chain, nil
+}
+
 // Root returns the root block
 func (ch *Chain) Root() *core.ExtendedBlock {
 	ret, _ := ch.FindBlock(ch.root)
@@ -132,7 +154,7 @@ func (ch *Chain) FixMissingChildren(block *core.ExtendedBlock) {
 	for _, hash := range block.Children {
 		_, err := ch.findBlock(hash)
 		if err != nil {
-			logger.Warningf("Removing dead link from block $v to block %v", block.Hash().Hex(), hash.Hex())
+			logger.Warningf("Removing dead link from block %v to block %v", block.Hash().Hex(), hash.Hex())
 		} else {
 			newChildren = append(newChildren, hash)
 		}
@@ -274,6 +296,15 @@ func (ch *Chain) FinalizePreviousBlocks(hash common.Hash) error {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
 
+	// Blocks are finalized from the newest to the oldest, while the account tx index
+	// expects them in chain order.
+	finalized := []*core.ExtendedBlock{}
+	defer func() {
+		for i := len(finalized) - 1; i >= 0; i-- {
+			ch.addTxsToAccountIndex(finalized[i])
+		}
+	}()
+
 	status := core.BlockStatusDirectlyFinalized
 	for !hash.IsEmpty() {
 		block, err := ch.findBlock(hash)
@@ -292,7 +323,8 @@ func (ch *Chain) FinalizePreviousBlocks(hash common.Hash) error {
 
 		// Force update TX index on block finalization so that the index doesn't point to
 		// duplicate TX in fork.
-		ch.AddTxsToIndex(block, true)
+		ch.addTxsToIndex(block, true)
+		finalized = append(finalized, block)
 
 		hash = block.Parent
 	}
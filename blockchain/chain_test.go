@@ -6,6 +6,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/store/database/backend"
+	"github.com/pandotoken/pando/store/kvstore"
 )
 
 func TestBlockchain(t *testing.T) {
@@ -167,3 +169,29 @@ func TestBlockIndex(t *testing.T) {
 	assert.Equal(core.GetTestBlock("a2").Hash(), blocks[0].Hash())
 	assert.Equal(core.GetTestBlock("b2").Hash(), blocks[1].Hash())
 }
+
+func TestLoadChain(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	core.ResetTestBlocks()
+
+	db := backend.NewMemDatabase()
+	store := kvstore.NewKVStore(db)
+	root := core.CreateTestBlock("a0", "")
+
+	// The root block is not in the store yet, and nothing is written
+	_, err := LoadChain("testchain", store, root.Hash())
+	assert.NotNil(err)
+	assert.Equal(0, db.Len())
+
+	chain := NewChain("testchain", store, root)
+	_, err = chain.AddBlock(core.CreateTestBlock("a1", "a0"))
+	require.Nil(err)
+
+	loaded, err := LoadChain("testchain", store, root.Hash())
+	require.Nil(err)
+	assert.Equal(root.Hash(), loaded.Root().Hash())
+	block, err := loaded.FindBlock(core.GetTestBlock("a1").Hash())
+	require.Nil(err)
+	assert.Equal(uint64(1), block.Height)
+}
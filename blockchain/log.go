@@ -0,0 +1,262 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/store"
+)
+
+// MaxLogQueryBlockRange is the maximum number of blocks a single log query can scan.
+const MaxLogQueryBlockRange = uint64(5000)
+
+// MaxLogQueryTopics is the maximum number of topic positions a log filter can specify.
+const MaxLogQueryTopics = 4
+
+// logIndexKey constructs the DB key for the log index entry of the finalized block at the given height.
+func logIndexKey(height uint64) common.Bytes {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, height)
+	return append(common.Bytes("logidx/"), buf...)
+}
+
+// LogIndexEntry summarizes the logs emitted by the transactions of a finalized block. The bloom
+// filter allows a log query to skip the block without loading any receipt.
+type LogIndexEntry struct {
+	BlockHash common.Hash
+	Bloom     core.Bloom
+	Txs       []LogIndexTx // transactions that emitted logs, in block order
+}
+
+// LogIndexTx points to a transaction that emitted logs.
+type LogIndexTx struct {
+	TxHash common.Hash
+	Index  uint64
+}
+
+// IndexedLog is a log together with its position on the chain.
+type IndexedLog struct {
+	*types.Log
+	BlockHash   common.Hash
+	BlockHeight uint64
+	TxHash      common.Hash
+	TxIndex     uint64
+	LogIndex    uint64 // index of the log in the block
+}
+
+// LogFilter selects logs by contract address and topics. An empty address list matches any
+// address. Topics[i] lists the alternatives for topic position i, an empty list matches any topic.
+type LogFilter struct {
+	Addresses []common.Address
+	Topics    [][]common.Hash
+}
+
+// Validate checks whether the filter is well formed.
+func (f *LogFilter) Validate() error {
+	if len(f.Topics) > MaxLogQueryTopics {
+		return fmt.Errorf("at most %v topic positions can be specified", MaxLogQueryTopics)
+	}
+	return nil
+}
+
+// Matches returns whether the log satisfies the filter.
+func (f *LogFilter) Matches(log *types.Log) bool {
+	if len(f.Addresses) > 0 {
+		found := false
+		for _, addr := range f.Addresses {
+			if addr == log.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, options := range f.Topics {
+		if len(options) == 0 {
+			continue // wildcard
+		}
+		found := false
+		for _, topic := range options {
+			if topic == log.Topics[i] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// MayMatch returns false if the bloom filter rules out any log in the block satisfying the filter.
+func (f *LogFilter) MayMatch(bloom core.Bloom) bool {
+	if len(f.Addresses) > 0 {
+		found := false
+		for _, addr := range f.Addresses {
+			if core.BloomLookup(bloom, addr) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, options := range f.Topics {
+		if len(options) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range options {
+			if core.BloomLookup(bloom, topic) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateLogsBloom creates the bloom filter for the given logs.
+func CreateLogsBloom(logs []*types.Log) core.Bloom {
+	bin := new(big.Int)
+	for _, log := range logs {
+		bin.Or(bin, core.Bloom9(log.Address.Bytes()))
+		for _, topic := range log.Topics {
+			bin.Or(bin, core.Bloom9(topic.Bytes()))
+		}
+	}
+	return core.BytesToBloom(bin.Bytes())
+}
+
+// AddLogsToIndex adds the logs emitted by the transactions of the given finalized block to the
+// log index. It should be called after the block has been executed, i.e. the tx receipts are available.
+func (ch *Chain) AddLogsToIndex(block *core.ExtendedBlock) {
+	blockHash := block.Hash()
+	entry := LogIndexEntry{
+		BlockHash: blockHash,
+		Txs:       []LogIndexTx{},
+	}
+
+	allLogs := []*types.Log{}
+	for idx, tx := range block.Txs {
+		txHash := crypto.Keccak256Hash(tx)
+		receipt, found := ch.FindTxReceiptByHash(blockHash, txHash)
+		if !found || len(receipt.Logs) == 0 {
+			continue
+		}
+		entry.Txs = append(entry.Txs, LogIndexTx{
+			TxHash: txHash,
+			Index:  uint64(idx),
+		})
+		allLogs = append(allLogs, receipt.Logs...)
+	}
+	entry.Bloom = CreateLogsBloom(allLogs)
+
+	err := ch.store.Put(logIndexKey(block.Height), entry)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+// FindLogs returns the logs that satisfy the filter, emitted by the finalized blocks within
+// [startHeight, endHeight]. Blocks finalized before the log index was introduced are scanned directly.
+func (ch *Chain) FindLogs(startHeight, endHeight uint64, filter *LogFilter) ([]*IndexedLog, error) {
+	if startHeight > endHeight {
+		return nil, fmt.Errorf("start height %v is greater than end height %v", startHeight, endHeight)
+	}
+	if endHeight-startHeight >= MaxLogQueryBlockRange {
+		return nil, fmt.Errorf("can't query logs for more than %v blocks at a time", MaxLogQueryBlockRange)
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	ret := []*IndexedLog{}
+	for height := startHeight; height <= endHeight; height++ {
+		entry := &LogIndexEntry{}
+		err := ch.store.Get(logIndexKey(height), entry)
+		if err == store.ErrKeyNotFound {
+			block := ch.findFinalizedBlockByHeight(height)
+			if block == nil {
+				continue
+			}
+			ret = append(ret, ch.FindLogsInBlock(block, filter)...)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(entry.Txs) == 0 || !filter.MayMatch(entry.Bloom) {
+			continue
+		}
+		ret = append(ret, ch.findIndexedLogs(height, entry, filter)...)
+	}
+	return ret, nil
+}
+
+// FindLogsInBlock returns the logs in the given block that satisfy the filter, by scanning its tx receipts.
+func (ch *Chain) FindLogsInBlock(block *core.ExtendedBlock, filter *LogFilter) []*IndexedLog {
+	blockHash := block.Hash()
+	txs := []LogIndexTx{}
+	for idx, tx := range block.Txs {
+		txs = append(txs, LogIndexTx{
+			TxHash: crypto.Keccak256Hash(tx),
+			Index:  uint64(idx),
+		})
+	}
+	entry := &LogIndexEntry{
+		BlockHash: blockHash,
+		Txs:       txs,
+	}
+	return ch.findIndexedLogs(block.Height, entry, filter)
+}
+
+func (ch *Chain) findIndexedLogs(height uint64, entry *LogIndexEntry, filter *LogFilter) []*IndexedLog {
+	ret := []*IndexedLog{}
+	logIndex := uint64(0)
+	for _, tx := range entry.Txs {
+		receipt, found := ch.FindTxReceiptByHash(entry.BlockHash, tx.TxHash)
+		if !found {
+			continue
+		}
+		for _, log := range receipt.Logs {
+			if filter.Matches(log) {
+				ret = append(ret, &IndexedLog{
+					Log:         log,
+					BlockHash:   entry.BlockHash,
+					BlockHeight: height,
+					TxHash:      tx.TxHash,
+					TxIndex:     tx.Index,
+					LogIndex:    logIndex,
+				})
+			}
+			logIndex++
+		}
+	}
+	return ret
+}
+
+func (ch *Chain) findFinalizedBlockByHeight(height uint64) *core.ExtendedBlock {
+	for _, block := range ch.FindBlocksByHeight(height) {
+		if block.Status.IsFinalized() {
+			return block
+		}
+	}
+	return nil
+}
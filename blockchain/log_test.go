@@ -0,0 +1,158 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/types"
+)
+
+func TestLogFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	addr1 := common.HexToAddress("0x01")
+	addr2 := common.HexToAddress("0x02")
+	topic1 := common.HexToHash("0xa1")
+	topic2 := common.HexToHash("0xa2")
+	topic3 := common.HexToHash("0xa3")
+
+	log := &types.Log{
+		Address: addr1,
+		Topics:  []common.Hash{topic1, topic2},
+	}
+	bloom := CreateLogsBloom([]*types.Log{log})
+
+	filter := &LogFilter{}
+	assert.True(filter.Matches(log))
+	assert.True(filter.MayMatch(bloom))
+
+	filter = &LogFilter{Addresses: []common.Address{addr2}}
+	assert.False(filter.Matches(log))
+	assert.False(filter.MayMatch(bloom))
+
+	filter = &LogFilter{
+		Addresses: []common.Address{addr1, addr2},
+		Topics:    [][]common.Hash{nil, {topic3, topic2}},
+	}
+	assert.True(filter.Matches(log))
+	assert.True(filter.MayMatch(bloom))
+
+	filter = &LogFilter{Topics: [][]common.Hash{{topic2}}}
+	assert.False(filter.Matches(log))
+
+	filter = &LogFilter{Topics: [][]common.Hash{{topic3}}}
+	assert.False(filter.Matches(log))
+	assert.False(filter.MayMatch(bloom))
+
+	filter = &LogFilter{Topics: [][]common.Hash{nil, nil, nil}}
+	assert.False(filter.Matches(log))
+
+	filter = &LogFilter{Topics: [][]common.Hash{nil, nil, nil, nil, nil}}
+	assert.NotNil(filter.Validate())
+}
+
+func TestLogIndex(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	core.ResetTestBlocks()
+	chain := CreateTestChain()
+
+	contract1 := common.HexToAddress("0xc1")
+	contract2 := common.HexToAddress("0xc2")
+	transferTopic := common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	otherTopic := common.HexToHash("0xbeef")
+
+	tx1 := newTestSmartContractTx(1)
+	tx2 := newTestSmartContractTx(2)
+	tx3 := newTestSmartContractTx(3)
+
+	block1 := core.CreateTestBlock("b1", "a0")
+	block1.Height = 1
+	block1.Txs = []common.Bytes{encodeTestTx(tx1), encodeTestTx(tx2)}
+	block1.UpdateHash()
+
+	block2 := core.CreateTestBlock("b2", "b1")
+	block2.Height = 2
+	block2.Txs = []common.Bytes{encodeTestTx(tx3)}
+	block2.UpdateHash()
+
+	eb1, err := chain.AddBlock(block1)
+	require.Nil(err)
+	eb2, err := chain.AddBlock(block2)
+	require.Nil(err)
+
+	chain.AddTxReceipt(block1, tx1, []*types.Log{
+		{Address: contract1, Topics: []common.Hash{transferTopic}},
+		{Address: contract2, Topics: []common.Hash{otherTopic}},
+	}, nil, nil, common.Address{}, 0, nil)
+	chain.AddTxReceipt(block1, tx2, nil, nil, nil, common.Address{}, 0, nil)
+	chain.AddTxReceipt(block2, tx3, []*types.Log{
+		{Address: contract1, Topics: []common.Hash{transferTopic}},
+	}, nil, nil, common.Address{}, 0, nil)
+
+	require.Nil(chain.FinalizePreviousBlocks(eb2.Hash()))
+	chain.AddLogsToIndex(eb1)
+	chain.AddLogsToIndex(eb2)
+
+	logs, err := chain.FindLogs(1, 2, &LogFilter{Addresses: []common.Address{contract1}})
+	require.Nil(err)
+	require.Equal(2, len(logs))
+	assert.Equal(eb1.Hash(), logs[0].BlockHash)
+	assert.Equal(uint64(0), logs[0].TxIndex)
+	assert.Equal(uint64(0), logs[0].LogIndex)
+	assert.Equal(eb2.Hash(), logs[1].BlockHash)
+
+	logs, err = chain.FindLogs(1, 2, &LogFilter{Topics: [][]common.Hash{{otherTopic}}})
+	require.Nil(err)
+	require.Equal(1, len(logs))
+	assert.Equal(contract2, logs[0].Address)
+	assert.Equal(uint64(1), logs[0].LogIndex)
+
+	logs, err = chain.FindLogs(2, 2, &LogFilter{Topics: [][]common.Hash{{otherTopic}}})
+	require.Nil(err)
+	assert.Equal(0, len(logs))
+
+	logs = chain.FindLogsInBlock(eb1, &LogFilter{})
+	assert.Equal(2, len(logs))
+
+	_, err = chain.FindLogs(2, 1, &LogFilter{})
+	assert.NotNil(err)
+	_, err = chain.FindLogs(1, 1+MaxLogQueryBlockRange, &LogFilter{})
+	assert.NotNil(err)
+}
+
+func newTestSmartContractTx(sequence uint64) *types.SmartContractTx {
+	privKey, _, _ := crypto.GenerateKeyPair()
+	tx := &types.SmartContractTx{
+		From: types.TxInput{
+			Address:  privKey.PublicKey().Address(),
+			Coins:    types.NewCoins(0, 0),
+			Sequence: sequence,
+		},
+		To: types.TxOutput{
+			Address: common.HexToAddress("0xc1"),
+		},
+		GasLimit: 100000,
+		GasPrice: big.NewInt(1),
+	}
+	sig, err := privKey.Sign(tx.SignBytes("testchain"))
+	if err != nil {
+		panic(err)
+	}
+	tx.SetSignature(tx.From.Address, sig)
+	return tx
+}
+
+func encodeTestTx(tx types.Tx) common.Bytes {
+	raw, err := types.TxToBytes(tx)
+	if err != nil {
+		panic(err)
+	}
+	return raw
+}
@@ -23,8 +23,18 @@ type TxIndexEntry struct {
 	Index       uint64
 }
 
-// AddTxsToIndex adds transactions in given block to index.
+// AddTxsToIndex adds transactions in given block to index. When force is set the block is
+// considered finalized, and its transactions are also added to the account tx index if enabled.
 func (ch *Chain) AddTxsToIndex(block *core.ExtendedBlock, force bool) {
+	ch.addTxsToIndex(block, force)
+
+	// Only index the finalized transactions by address, so the history doesn't include txs in forks.
+	if force {
+		ch.addTxsToAccountIndex(block)
+	}
+}
+
+func (ch *Chain) addTxsToIndex(block *core.ExtendedBlock, force bool) {
 	for idx, tx := range block.Txs {
 		txIndexEntry := TxIndexEntry{
 			BlockHash:   block.Hash(),
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/consensus"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/store/database"
+	"github.com/pandotoken/pando/store/database/backend"
+	"github.com/pandotoken/pando/store/dbcheck"
+	"github.com/pandotoken/pando/store/kvstore"
+	"github.com/pandotoken/pando/store/rollingdb"
+	"github.com/pandotoken/pando/store/trie"
+)
+
+var migrateFrom string
+var migrateTo string
+var checkBlocks uint64
+var checkRepair bool
+
+// dbCmd represents the db command
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the node database. The node must be stopped.",
+}
+
+// dbMigrateCmd represents the db migrate command
+var dbMigrateCmd = &cobra.Command{
+	Use:     "migrate",
+	Short:   "Copy the main database to another storage backend.",
+	Long:    `Copy all the keys of the main database, with their reference counts, to another storage backend. The copy is verified by recomputing the latest state root from it. Set storage.backend in the config to start the node on the new backend afterwards.`,
+	Example: `pando db migrate --from leveldb --to badger`,
+	Run:     runDBMigrate,
+}
+
+// dbCheckCmd represents the db check command
+var dbCheckCmd = &cobra.Command{
+	Use:     "check",
+	Short:   "Check the integrity of the node database.",
+	Long:    `Walk the chain back from the last finalized block, and check the block index, the tx index, the tx receipts and the state tries of each block are in the db. The reference counts of the trie nodes are checked as well when the rolling db is disabled. With --repair, the block index, the tx index, the dead child links and the reference counts are fixed. The missing trie nodes and receipts can only be recovered by resyncing the node.`,
+	Example: `pando db check --repair`,
+	Run:     runDBCheck,
+}
+
+func init() {
+	dbMigrateCmd.Flags().StringVar(&migrateFrom, "from", backend.LevelDBBackend, "storage backend to copy from")
+	dbMigrateCmd.Flags().StringVar(&migrateTo, "to", backend.BadgerDBBackend, "storage backend to copy to")
+
+	dbCheckCmd.Flags().Uint64Var(&checkBlocks, "blocks", 0, "number of finalized blocks to check, defaults to the number of blocks retained by the state pruning")
+	dbCheckCmd.Flags().BoolVar(&checkRepair, "repair", false, "repair the inconsistencies which can be repaired")
+
+	dbCmd.AddCommand(dbMigrateCmd)
+	dbCmd.AddCommand(dbCheckCmd)
+	RootCmd.AddCommand(dbCmd)
+}
+
+// getDataDir returns the data directory of the node.
+func getDataDir() string {
+	dataPath := viper.GetString(common.CfgDataPath)
+	if dataPath == "" {
+		dataPath = cfgPath
+	}
+	return dataPath
+}
+
+// getDBDir returns the directory of the main database.
+func getDBDir() string {
+	return path.Join(getDataDir(), "db")
+}
+
+func openDatabase(backendName string) database.Database {
+	dbDir := getDBDir()
+	db, err := backend.NewDatabase(backendName, dbDir,
+		viper.GetInt(common.CfgStorageLevelDBCacheSize),
+		viper.GetInt(common.CfgStorageLevelDBHandles))
+	if err != nil {
+		log.Fatalf("Failed to open the db. backend: %v, path: %v, err: %v", backendName, dbDir, err)
+	}
+	return db
+}
+
+func runDBMigrate(cmd *cobra.Command, args []string) {
+	if strings.ToLower(migrateFrom) == strings.ToLower(migrateTo) {
+		log.Fatalf("The source and destination backends are the same: %v", migrateFrom)
+	}
+	if strings.ToLower(migrateFrom) == backend.MemDBBackend || strings.ToLower(migrateTo) == backend.MemDBBackend {
+		log.Fatalf("The memdb backend is not persistent")
+	}
+
+	src := openDatabase(migrateFrom)
+	defer src.Close()
+	dst := openDatabase(migrateTo)
+	defer dst.Close()
+
+	// Copying into a non-empty database would add up the reference counts
+	if !isEmptyDatabase(dst) {
+		log.Fatalf("The %v database is not empty", migrateTo)
+	}
+
+	log.Infof("Migrating the db from %v to %v", migrateFrom, migrateTo)
+	logged := uint64(0)
+	count, err := backend.MigrateDatabase(src, dst, func(count uint64) {
+		if count-logged >= 100000 {
+			log.Infof("Copied %v keys", count)
+			logged = count
+		}
+	})
+	if err != nil {
+		log.Fatalf("Failed to migrate the db after %v keys, err: %v", count, err)
+	}
+	log.Infof("Copied %v keys", count)
+
+	height, stateRoot, err := findLatestStateRoot(dst)
+	if err != nil {
+		log.Fatalf("Failed to find the latest state root, err: %v", err)
+	}
+	recomputed, err := recomputeStateRoot(stateRoot, dst)
+	if err != nil {
+		log.Fatalf("Failed to recompute the state root %v at height %v, err: %v", stateRoot.Hex(), height, err)
+	}
+	if recomputed != stateRoot {
+		log.Fatalf("State root mismatch at height %v: %v vs %v", height, recomputed.Hex(), stateRoot.Hex())
+	}
+
+	log.Infof("Verified the state root %v at height %v", stateRoot.Hex(), height)
+	log.Infof("Migration done. Set %v to %v to use the new db.", common.CfgStorageBackend, migrateTo)
+}
+
+func runDBCheck(cmd *cobra.Command, args []string) {
+	db := openDatabase(viper.GetString(common.CfgStorageBackend))
+	rdb := rollingdb.NewRollingDB(getDataDir(), db)
+
+	store := kvstore.NewKVStore(db)
+	rootHeader := &core.BlockHeader{}
+	if err := store.Get([]byte("/snapshot_blockheader"), rootHeader); err != nil {
+		log.Fatalf("Failed to load the snapshot block header, err: %v", err)
+	}
+	stub, err := loadStateStub(db)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	// Without --repair the check must not write to the db, NewChain would add and finalize the
+	// root block if it is missing
+	var chain *blockchain.Chain
+	if checkRepair {
+		chain = blockchain.NewChain(rootHeader.ChainID, store, &core.Block{BlockHeader: rootHeader})
+	} else {
+		chain, err = blockchain.LoadChain(rootHeader.ChainID, store, rootHeader.Hash())
+		if err != nil {
+			log.Fatalf("Failed to load the chain, err: %v", err)
+		}
+	}
+	chain.SetAccountTxIndexEnabled(viper.GetBool(common.CfgStorageAccountTxIndexEnabled))
+	rdb.SetChain(chain)
+
+	numBlocks := checkBlocks
+	if numBlocks == 0 {
+		numBlocks = uint64(viper.GetInt(common.CfgStorageStatePruningRetainedBlocks))
+	}
+	opts := dbcheck.Options{
+		NumBlocks: numBlocks,
+		// The rolling db layers do not keep reference counts
+		CheckRefs: !viper.GetBool(common.CfgStorageRollingEnabled),
+		Repair:    checkRepair,
+	}
+
+	log.Infof("Checking %v blocks from the last finalized block %v", numBlocks, stub.LastFinalizedBlock.Hex())
+	report := dbcheck.NewChecker(chain, rdb, db, opts).Check(stub.LastFinalizedBlock)
+	log.Infof("Checked %v blocks and %v trie nodes, found %v issues, %v not repaired",
+		report.NumBlocks, report.NumNodes, len(report.Issues), report.NumUnrepaired())
+
+	// The repairs are all written to the main db, the rolling db layers are only read
+	db.Close()
+	if report.NumUnrepaired() > 0 {
+		os.Exit(1)
+	}
+}
+
+var errNotEmpty = errors.New("Database is not empty")
+
+func isEmptyDatabase(db database.Database) bool {
+	iteratee, ok := db.(backend.Iteratee)
+	if !ok {
+		return true
+	}
+	err := iteratee.ForEach(func(key, value []byte, ref int) error {
+		return errNotEmpty
+	})
+	return err == nil
+}
+
+// findLatestStateRoot returns the state root of the last finalized block whose state is in the
+// db. The state of the latest blocks may only be in the rolling db layers.
+func findLatestStateRoot(db database.Database) (uint64, common.Hash, error) {
+	store := kvstore.NewKVStore(db)
+	stub, err := loadStateStub(db)
+	if err != nil {
+		return 0, common.Hash{}, err
+	}
+
+	hash := stub.LastFinalizedBlock
+	for i := 0; i < int(common.CheckpointInterval)*10 && !hash.IsEmpty(); i++ {
+		block := &core.ExtendedBlock{}
+		if err := store.Get(hash[:], block); err != nil {
+			return 0, common.Hash{}, fmt.Errorf("Failed to load block %v: %v", hash.Hex(), err)
+		}
+		if has, _ := db.Has(block.StateHash[:]); has {
+			return block.Height, block.StateHash, nil
+		}
+		hash = block.Parent
+	}
+	return 0, common.Hash{}, fmt.Errorf("No finalized state found")
+}
+
+// loadStateStub loads the consensus state saved by the node.
+func loadStateStub(db database.Database) (*consensus.StateStub, error) {
+	stub := &consensus.StateStub{}
+	if err := kvstore.NewKVStore(db).Get([]byte(consensus.DBStateStubKey), stub); err != nil {
+		return nil, fmt.Errorf("Failed to load the consensus state: %v", err)
+	}
+	return stub, nil
+}
+
+// recomputeStateRoot rebuilds the state trie with the given root from its leaves, and returns
+// the root of the rebuilt trie.
+func recomputeStateRoot(root common.Hash, db database.Database) (common.Hash, error) {
+	tr, err := trie.New(root, trie.NewDatabase(db))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	rebuilt, err := trie.New(common.Hash{}, trie.NewDatabase(backend.NewMemDatabase()))
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	it := trie.NewIterator(tr.NodeIterator(nil))
+	for it.Next() {
+		if err := rebuilt.TryUpdate(common.CopyBytes(it.Key), common.CopyBytes(it.Value)); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	if it.Err != nil {
+		return common.Hash{}, it.Err
+	}
+	return rebuilt.Hash(), nil
+}
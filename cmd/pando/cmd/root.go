@@ -16,6 +16,7 @@ import (
 
 var cfgPath string
 var snapshotPath string
+var snapshotURL string
 var chainImportDirPath string
 var chainCorrectionPath string
 
@@ -43,6 +44,7 @@ func init() {
 	viper.BindPFlag(common.CfgConfigPath, RootCmd.PersistentFlags().Lookup("config"))
 
 	RootCmd.PersistentFlags().StringVar(&snapshotPath, "snapshot", "", "snapshot path")
+	RootCmd.PersistentFlags().StringVar(&snapshotURL, "snapshot_url", "", "URL of a chunked snapshot to download into the snapshot path")
 	RootCmd.PersistentFlags().StringVar(&chainImportDirPath, "chain_import", "", "chain import path")
 	RootCmd.PersistentFlags().StringVar(&chainCorrectionPath, "chain_correction", "", "chain correction path")
 	//RootCmd.PersistentFlags().StringVar(&snapshotPath, "snapshot", getDefaultSnapshotPath(), fmt.Sprintf("snapshot path (default is %s)", getDefaultSnapshotPath()))
@@ -10,6 +10,7 @@ import (
 	"path"
 	"runtime"
 	"strings"
+	"syscall"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -17,6 +18,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/pandotoken/pando/cmd/pandocli/cmd/utils"
 	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/metrics"
 	"github.com/pandotoken/pando/common/util"
 	"github.com/pandotoken/pando/core"
 	"github.com/pandotoken/pando/crypto"
@@ -52,26 +54,55 @@ func runStart(cmd *cobra.Command, args []string) {
 		log.Fatalf("Failed to load or create key: %v", err)
 	}
 
+	// The metrics need to be enabled before the subsystems create their collectors
+	if viper.GetBool(common.CfgMetricsEnabled) {
+		metrics.Enabled = true
+		go metrics.CollectProcessMetrics(3 * time.Second)
+	}
+
 	// Open database
 	dbPath := viper.GetString(common.CfgDataPath)
 	if dbPath == "" {
 		dbPath = cfgPath
 	}
 
-	mainDBPath := path.Join(dbPath, "db", "main")
-	refDBPath := path.Join(dbPath, "db", "ref")
-	db, err := backend.NewLDBDatabase(mainDBPath, refDBPath,
+	if viper.GetBool(common.CfgStorageArchiveEnabled) {
+		// An archive node keeps the state of every block, the existing rolling layers are kept as well
+		viper.Set(common.CfgStorageRollingEnabled, false)
+		viper.Set(common.CfgStorageStatePruningEnabled, false)
+	}
+
+	storageBackend := viper.GetString(common.CfgStorageBackend)
+	db, err := backend.NewDatabase(storageBackend, path.Join(dbPath, "db"),
 		viper.GetInt(common.CfgStorageLevelDBCacheSize),
 		viper.GetInt(common.CfgStorageLevelDBHandles))
+	if err != nil {
+		log.Fatalf("Failed to connect to the db. backend: %v, path: %v, err: %v",
+			storageBackend, path.Join(dbPath, "db"), err)
+	}
 
 	rdb := rollingdb.NewRollingDB(dbPath, db)
+	if oldest := rdb.OldestStateHeight(); oldest > 0 && viper.GetBool(common.CfgStorageArchiveEnabled) {
+		log.Warnf("The states before height %v have already been pruned, the archive only has the states from this height on", oldest)
+	}
 
-	if err != nil {
-		log.Fatalf("Failed to connect to the db. main: %v, ref: %v, err: %v",
-			mainDBPath, refDBPath, err)
+	if metrics.Enabled {
+		if ldb, ok := db.(*backend.LDBDatabase); ok {
+			ldb.Meter("db/main/")
+		}
+		rdb.Meter("db/rolling/")
 	}
 
 	// load snapshot
+	if len(snapshotURL) != 0 {
+		if len(snapshotPath) == 0 {
+			snapshotPath = path.Join(cfgPath, "snapshot_chunked")
+		}
+		log.Infof("Downloading snapshot from %v to %v", snapshotURL, snapshotPath)
+		if err := snapshot.DownloadChunkedSnapshot(snapshotURL, snapshotPath); err != nil {
+			log.Fatalf("Failed to download snapshot, err: %v", err)
+		}
+	}
 	if len(snapshotPath) == 0 {
 		snapshotPath = path.Join(cfgPath, "snapshot")
 	}
@@ -143,6 +174,7 @@ func runStart(cmd *cobra.Command, args []string) {
 		Network:             network,
 		DB:                  db,
 		RollingDB:           rdb,
+		DataPath:            dbPath,
 		SnapshotPath:        snapshotPath,
 		ChainImportDirPath:  chainImportDirPath,
 		ChainCorrectionPath: chainCorrectionPath,
@@ -165,6 +197,23 @@ func runStart(cmd *cobra.Command, args []string) {
 
 	n.Start(ctx)
 
+	// SIGHUP reloads the config file, as does the admin RPC pando.ReloadConfig
+	if err := common.InitConfigReload(); err != nil {
+		log.Warnf("Config reload is not available: %v", err)
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			changedKeys, err := common.ReloadConfig()
+			if err != nil {
+				log.Errorf("Failed to reload config: %v", err)
+				continue
+			}
+			log.Infof("Reloaded config, changed keys: %v", changedKeys)
+		}
+	}()
+
 	if viper.GetBool(common.CfgProfEnabled) {
 		go func() {
 			log.Println(http.ListenAndServe("localhost:6060", nil))
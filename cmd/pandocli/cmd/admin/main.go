@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pandotoken/pando/cmd/pandocli/cmd/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	addressFlag string
+	peerIDFlag  string
+	moduleFlag  string
+	levelFlag   string
+)
+
+// AdminCmd represents the admin command. The admin methods are called over the admin socket
+// of the node if --admin_socket is set.
+var AdminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Operate the node",
+	Long:  `Operate the node with the admin RPC methods.`,
+}
+
+func init() {
+	AdminCmd.AddCommand(addPeerCmd)
+	AdminCmd.AddCommand(removePeerCmd)
+	AdminCmd.AddCommand(setLogLevelCmd)
+	AdminCmd.AddCommand(pruneStateCmd)
+	AdminCmd.AddCommand(flushMempoolCmd)
+	AdminCmd.AddCommand(consensusStateCmd)
+	AdminCmd.AddCommand(reloadConfigCmd)
+}
+
+func callAdmin(method string, args interface{}) {
+	client := utils.NewAdminRPCClient()
+
+	res, err := client.Call(method, args)
+	if err != nil {
+		utils.Error("Failed to call %v: %v\n", method, err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to call %v: %v\n", method, res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%v\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"github.com/pandotoken/pando/rpc"
+
+	"github.com/spf13/cobra"
+)
+
+// setLogLevelCmd represents the set log level command.
+// Example:
+//		pandocli admin set_log_level --module=consensus --level=debug
+var setLogLevelCmd = &cobra.Command{
+	Use:     "set_log_level",
+	Short:   "Set the log level of a module",
+	Long:    `Set the log level of a module. Module "*" sets the default level.`,
+	Example: `pandocli admin set_log_level --module=consensus --level=debug`,
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin("pando.SetLogLevel", rpc.SetLogLevelArgs{Module: moduleFlag, Level: levelFlag})
+	},
+}
+
+// pruneStateCmd represents the prune state command.
+// Example:
+//		pandocli admin prune_state
+var pruneStateCmd = &cobra.Command{
+	Use:     "prune_state",
+	Short:   "Prune the state",
+	Long:    `Prune the state right away instead of waiting for the next compaction of the rolling db. The states of the last storage.statePruningRetainedBlocks finalized blocks are retained. Archive nodes reject the request.`,
+	Example: `pandocli admin prune_state`,
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin("pando.PruneState", rpc.PruneStateArgs{})
+	},
+}
+
+// flushMempoolCmd represents the flush mempool command.
+// Example:
+//		pandocli admin flush_mempool
+var flushMempoolCmd = &cobra.Command{
+	Use:     "flush_mempool",
+	Short:   "Drop all the transactions of the mempool",
+	Long:    `Drop all the transactions of the mempool.`,
+	Example: `pandocli admin flush_mempool`,
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin("pando.FlushMempool", rpc.FlushMempoolArgs{})
+	},
+}
+
+// consensusStateCmd represents the consensus state command.
+// Example:
+//		pandocli admin consensus_state
+var consensusStateCmd = &cobra.Command{
+	Use:     "consensus_state",
+	Short:   "Dump the consensus state",
+	Long:    `Dump the consensus state.`,
+	Example: `pandocli admin consensus_state`,
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin("pando.DumpConsensusState", rpc.DumpConsensusStateArgs{})
+	},
+}
+
+// reloadConfigCmd represents the reload config command.
+// Example:
+//		pandocli admin reload_config
+var reloadConfigCmd = &cobra.Command{
+	Use:     "reload_config",
+	Short:   "Reload the config file of the node",
+	Long:    `Reload the config file of the node. Only the log levels, the max number of peers and the RPC settings can be changed without restarting the node.`,
+	Example: `pandocli admin reload_config`,
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin("pando.ReloadConfig", rpc.ReloadConfigArgs{})
+	},
+}
+
+func init() {
+	setLogLevelCmd.Flags().StringVar(&moduleFlag, "module", "*", "Module, or * for the default level")
+	setLogLevelCmd.Flags().StringVar(&levelFlag, "level", "", "Log level: debug, info, warn, error, fatal or panic")
+	setLogLevelCmd.MarkFlagRequired("level")
+}
@@ -0,0 +1,41 @@
+package admin
+
+import (
+	"github.com/pandotoken/pando/rpc"
+
+	"github.com/spf13/cobra"
+)
+
+// addPeerCmd represents the add peer command.
+// Example:
+//		pandocli admin add_peer --address=127.0.0.1:50001
+var addPeerCmd = &cobra.Command{
+	Use:     "add_peer",
+	Short:   "Connect to a peer",
+	Long:    `Connect to a peer at an "ip:port" address, or at a libp2p multiaddress including the peer ID.`,
+	Example: `pandocli admin add_peer --address=127.0.0.1:50001`,
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin("pando.AddPeer", rpc.AddPeerArgs{Address: addressFlag})
+	},
+}
+
+// removePeerCmd represents the remove peer command.
+// Example:
+//		pandocli admin remove_peer --peer_id=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab
+var removePeerCmd = &cobra.Command{
+	Use:     "remove_peer",
+	Short:   "Disconnect from a peer",
+	Long:    `Disconnect from a peer.`,
+	Example: `pandocli admin remove_peer --peer_id=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab`,
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin("pando.RemovePeer", rpc.RemovePeerArgs{PeerID: peerIDFlag})
+	},
+}
+
+func init() {
+	addPeerCmd.Flags().StringVar(&addressFlag, "address", "", "Address of the peer")
+	addPeerCmd.MarkFlagRequired("address")
+
+	removePeerCmd.Flags().StringVar(&peerIDFlag, "peer_id", "", "ID of the peer")
+	removePeerCmd.MarkFlagRequired("peer_id")
+}
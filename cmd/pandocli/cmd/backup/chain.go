@@ -8,8 +8,6 @@ import (
 	"github.com/pandotoken/pando/rpc"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	rpcc "github.com/ybbus/jsonrpc"
 )
 
 var (
@@ -29,7 +27,7 @@ var chainCmd = &cobra.Command{
 }
 
 func doChainCmd(cmd *cobra.Command, args []string) {
-	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+	client := utils.NewAdminRPCClient()
 
 	res, err := client.Call("pando.BackupChain", rpc.BackupChainArgs{Start: startFlag, End: endFlag, Config: configFlag})
 	if err != nil {
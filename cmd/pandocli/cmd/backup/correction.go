@@ -9,8 +9,6 @@ import (
 	"github.com/pandotoken/pando/rpc"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	rpcc "github.com/ybbus/jsonrpc"
 )
 
 var (
@@ -29,7 +27,7 @@ var chainCorrectionCmd = &cobra.Command{
 }
 
 func doChainCorrectionCmd(cmd *cobra.Command, args []string) {
-	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+	client := utils.NewAdminRPCClient()
 
 	res, err := client.Call("pando.BackupChainCorrection", rpc.BackupChainCorrectionArgs{SnapshotHeight: heightFlag, EndBlockHash: common.HexToHash(hashFlag), Config: configFlag, ExclusionTxs: exclusionTxsFlag})
 	if err != nil {
@@ -7,6 +7,9 @@ var (
 	versionFlag uint64
 	hashFlag    string
 	configFlag  string
+
+	baseHeightFlag uint64
+	chunkSizeFlag  uint64
 )
 
 // BackupCmd represents the backup command
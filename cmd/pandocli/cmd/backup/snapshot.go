@@ -8,13 +8,12 @@ import (
 	"github.com/pandotoken/pando/rpc"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	rpcc "github.com/ybbus/jsonrpc"
 )
 
 // snapshotCmd represents the snapshot backup command.
 // Example:
 //		pandocli backup snapshot
+//		pandocli backup snapshot --version=5 --base_height=1000
 var snapshotCmd = &cobra.Command{
 	Use:     "snapshot",
 	Short:   "backup snapshot",
@@ -24,9 +23,10 @@ var snapshotCmd = &cobra.Command{
 }
 
 func doSnapshotCmd(cmd *cobra.Command, args []string) {
-	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+	client := utils.NewAdminRPCClient()
 
-	res, err := client.Call("pando.BackupSnapshot", rpc.BackupSnapshotArgs{Config: configFlag, Height: heightFlag, Version: versionFlag})
+	res, err := client.Call("pando.BackupSnapshot", rpc.BackupSnapshotArgs{Config: configFlag, Height: heightFlag, Version: versionFlag,
+		BaseHeight: baseHeightFlag, ChunkSize: chunkSizeFlag})
 	if err != nil {
 		utils.Error("Failed to get backup snapshot call details: %v\n", err)
 	}
@@ -44,5 +44,7 @@ func init() {
 	snapshotCmd.Flags().StringVar(&configFlag, "config", "", "Config dir")
 	snapshotCmd.MarkFlagRequired("config")
 	snapshotCmd.Flags().Uint64Var(&heightFlag, "height", 0, "Snapshot height")
-	snapshotCmd.Flags().Uint64Var(&versionFlag, "version", 0, "Snapshot version.(2 or 3. Default is 2)")
+	snapshotCmd.Flags().Uint64Var(&versionFlag, "version", 0, "Snapshot version.(2, 3, 4 or 5. Default is 2. Version 5 is the chunked snapshot)")
+	snapshotCmd.Flags().Uint64Var(&baseHeightFlag, "base_height", 0, "Export a delta from the snapshot at this height (version 5 only)")
+	snapshotCmd.Flags().Uint64Var(&chunkSizeFlag, "chunk_size", 0, "Max chunk size in bytes (version 5 only)")
 }
@@ -14,6 +14,8 @@ var (
 	gasPriceFlag string
 	gasLimitFlag uint64
 	dataFlag     string
+	heightFlag   uint64
+	blockFlag    string
 	verboseFlag  bool
 )
 
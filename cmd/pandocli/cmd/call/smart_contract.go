@@ -34,6 +34,9 @@ var smartContractCmd = &cobra.Command{
 	
 	[Call an API of a smart contract (local only)]
 	pandocli call smart_contract --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x7ad6cea2bc3162e30a3c98d84f821b3233c22647 --gas_price=3 --gas_limit=50000
+
+	[Call an API of a smart contract on the state of block 1000]
+	pandocli call smart_contract --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x7ad6cea2bc3162e30a3c98d84f821b3233c22647 --gas_price=3 --gas_limit=50000 --height=1000
 	`,
 	Long: `smartContractCmd represents the smart_contract command, which can be used to calls the specified smart contract.
 		However, calling a smart contract does NOT modify the globally consensus state. It can be used for dry run, or for retrieving info from smart contracts without actually spending gas.`,
@@ -82,6 +85,8 @@ func doSmartContractCmd(cmd *cobra.Command, args []string) {
 
 	rpcCallArgs := rpc.CallSmartContractArgs{
 		SctxBytes: hex.EncodeToString(sctxBytes),
+		Height:    common.JSONUint64(heightFlag),
+		BlockHash: blockFlag,
 	}
 
 	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
@@ -109,6 +114,8 @@ func init() {
 	smartContractCmd.Flags().Uint64Var(&gasLimitFlag, "gas_limit", 0, "The gas limit")
 	smartContractCmd.Flags().StringVar(&dataFlag, "data", "", "The data for the smart contract")
 	smartContractCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction")
+	smartContractCmd.Flags().Uint64Var(&heightFlag, "height", 0, "Call on the state of the finalized block at this height, defaults to the latest state")
+	smartContractCmd.Flags().StringVar(&blockFlag, "block_hash", "", "Call on the state of the block with this hash")
 	smartContractCmd.Flags().BoolVar(&verboseFlag, "verbose", false, "")
 
 	smartContractCmd.MarkFlagRequired("from")
@@ -0,0 +1,52 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pandotoken/pando/cmd/pandocli/cmd/utils"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/rpc"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// accountTxsCmd represents the account-txs command.
+// Example:
+//		pandocli query account-txs --address=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab --page=0 --limit=20
+var accountTxsCmd = &cobra.Command{
+	Use:     "account-txs",
+	Short:   "Get the transactions that touched an account",
+	Long:    `Get the finalized transactions that touched an account, most recent first. Requires the account transaction index to be enabled on the node.`,
+	Example: `pandocli query account-txs --address=0x2E833968E5bB786Ae419c4d13189fB081Cc43bab --page=0 --limit=20`,
+	Run:     doAccountTxsCmd,
+}
+
+func doAccountTxsCmd(cmd *cobra.Command, args []string) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("pando.GetAccountTransactions", rpc.GetAccountTransactionsArgs{
+		Address: addressFlag,
+		Page:    common.JSONUint64(pageFlag),
+		Limit:   common.JSONUint64(limitFlag)})
+	if err != nil {
+		utils.Error("Failed to get account transactions: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get account transactions: %v\n", res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%v\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
+
+func init() {
+	accountTxsCmd.Flags().StringVar(&addressFlag, "address", "", "Address of the account")
+	accountTxsCmd.Flags().Uint64Var(&pageFlag, "page", uint64(0), "Page number, starting from 0")
+	accountTxsCmd.Flags().Uint64Var(&limitFlag, "limit", uint64(20), "Number of transactions per page")
+	accountTxsCmd.MarkFlagRequired("address")
+}
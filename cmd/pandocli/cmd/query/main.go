@@ -15,6 +15,8 @@ var (
 	endFlag              uint64
 	skipEdgeNodeFlag     bool
 	includeEthTxHashFlag bool
+	pageFlag             uint64
+	limitFlag            uint64
 )
 
 // QueryCmd represents the query command
@@ -26,6 +28,7 @@ var QueryCmd = &cobra.Command{
 func init() {
 	QueryCmd.AddCommand(statusCmd)
 	QueryCmd.AddCommand(accountCmd)
+	QueryCmd.AddCommand(accountTxsCmd)
 	QueryCmd.AddCommand(guardianCmd)
 	QueryCmd.AddCommand(blockCmd)
 	QueryCmd.AddCommand(txCmd)
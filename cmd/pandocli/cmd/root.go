@@ -6,6 +6,7 @@ import (
 	"path"
 	"strings"
 
+	"github.com/pandotoken/pando/cmd/pandocli/cmd/admin"
 	"github.com/pandotoken/pando/cmd/pandocli/cmd/backup"
 
 	homedir "github.com/mitchellh/go-homedir"
@@ -16,6 +17,7 @@ import (
 	"github.com/pandotoken/pando/cmd/pandocli/cmd/key"
 	"github.com/pandotoken/pando/cmd/pandocli/cmd/query"
 	"github.com/pandotoken/pando/cmd/pandocli/cmd/tx"
+	"github.com/pandotoken/pando/cmd/pandocli/cmd/utils"
 )
 
 var cfgPath string
@@ -40,6 +42,8 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	RootCmd.PersistentFlags().StringVar(&cfgPath, "config", getDefaultConfigPath(), fmt.Sprintf("config path (default is %s)", getDefaultConfigPath()))
+	RootCmd.PersistentFlags().String("admin_socket", "", "admin socket of the node, e.g. ~/.pando/admin.sock, for the admin and backup commands")
+	viper.BindPFlag(utils.CfgAdminSocket, RootCmd.PersistentFlags().Lookup("admin_socket"))
 
 	RootCmd.AddCommand(daemon.DaemonCmd)
 	RootCmd.AddCommand(key.KeyCmd)
@@ -47,6 +51,7 @@ func init() {
 	RootCmd.AddCommand(query.QueryCmd)
 	RootCmd.AddCommand(call.CallCmd)
 	RootCmd.AddCommand(backup.BackupCmd)
+	RootCmd.AddCommand(admin.AdminCmd)
 	RootCmd.AddCommand(versionCmd)
 }
 
@@ -21,6 +21,8 @@ var (
 	reserveFundInPTXFlag       string
 	reserveCollateralInPTXFlag string
 	reserveSeqFlag               uint64
+	paymentSeqFlag               uint64
+	voucherFlag                  string
 	addressesFlag                []string
 	percentagesFlag              []string
 	valueFlag                    string
@@ -48,6 +50,7 @@ var TxCmd = &cobra.Command{
 func init() {
 	TxCmd.AddCommand(sendCmd)
 	TxCmd.AddCommand(reserveFundCmd)
+	TxCmd.AddCommand(servicePaymentCmd)
 	//TxCmd.AddCommand(releaseFundCmd) // No need for releaseFundCmd since auto-release is already implemented
 	TxCmd.AddCommand(splitRuleCmd)
 	TxCmd.AddCommand(smartContractCmd)
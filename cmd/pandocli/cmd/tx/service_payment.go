@@ -0,0 +1,244 @@
+package tx
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"path"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/pandotoken/pando/cmd/pandocli/cmd/utils"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/micropayment"
+	"github.com/pandotoken/pando/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// servicePaymentCmd represents the service payment command, which pays for a resource off-chain
+// with vouchers against a fund reserved by the reserve command.
+var servicePaymentCmd = &cobra.Command{
+	Use:   "service-payment",
+	Short: "Pay for a resource with off-chain micropayment vouchers",
+	Long: `Pay for a resource with off-chain micropayment vouchers. The source of a reserved fund creates
+vouchers paying incremental amounts, the target accepts them after verifying them against the
+on-chain reserve, and settles the latest voucher on-chain.`,
+}
+
+// createServicePaymentCmd creates a voucher as the source of a reserved fund.
+// Example:
+//		pandocli tx service-payment create --chain="pandonet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --reserve_seq=6 --resource_id=die_another_day --amount=10
+var createServicePaymentCmd = &cobra.Command{
+	Use:     "create",
+	Short:   "Create a voucher paying an additional amount for a resource",
+	Example: `pandocli tx service-payment create --chain="pandonet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --reserve_seq=6 --resource_id=die_another_day --amount=10`,
+	Run:     doCreateServicePaymentCmd,
+}
+
+// acceptServicePaymentCmd verifies and records a voucher as its target.
+// Example:
+//		pandocli tx service-payment accept --chain="pandonet" --voucher=<hex encoded voucher>
+var acceptServicePaymentCmd = &cobra.Command{
+	Use:     "accept",
+	Short:   "Verify a received voucher against the on-chain reserve and record it",
+	Example: `pandocli tx service-payment accept --chain="pandonet" --voucher=<hex encoded voucher>`,
+	Run:     doAcceptServicePaymentCmd,
+}
+
+// settleServicePaymentCmd co-signs and broadcasts the latest voucher of a resource as its target.
+// Example:
+//		pandocli tx service-payment settle --chain="pandonet" --from=9F1233798E905E173560071255140b4A8aBd3Ec6 --resource_id=die_another_day --seq=3
+var settleServicePaymentCmd = &cobra.Command{
+	Use:     "settle",
+	Short:   "Settle the latest voucher of a resource on-chain",
+	Example: `pandocli tx service-payment settle --chain="pandonet" --from=9F1233798E905E173560071255140b4A8aBd3Ec6 --resource_id=die_another_day --seq=3`,
+	Run:     doSettleServicePaymentCmd,
+}
+
+// openVoucherStore opens the store of the vouchers issued or received by the address.
+func openVoucherStore(cmd *cobra.Command, kind string, address common.Address) *micropayment.VoucherStore {
+	cfgPath := cmd.Flag("config").Value.String()
+	filePath := path.Join(cfgPath, "vouchers", kind, address.Hex()+".json")
+	store, err := micropayment.NewVoucherStore(filePath)
+	if err != nil {
+		utils.Error("Failed to open voucher store %v: %v\n", filePath, err)
+	}
+	return store
+}
+
+func doCreateServicePaymentCmd(cmd *cobra.Command, args []string) {
+	wallet, fromAddress, err := walletUnlock(cmd, fromFlag, passwordFlag)
+	if err != nil {
+		return
+	}
+	defer wallet.Lock(fromAddress)
+
+	amount, ok := types.ParseCoinAmount(ptxAmountFlag)
+	if !ok {
+		utils.Error("Failed to parse amount")
+	}
+
+	store := openVoucherStore(cmd, "issued", fromAddress)
+	issuer := micropayment.NewIssuer(chainIDFlag, fromAddress, reserveSeqFlag, wallet, store)
+	voucher, err := issuer.Issue(common.HexToAddress(toFlag), resourceIDFlag, paymentSeqFlag, amount)
+	if err != nil {
+		utils.Error("Failed to create voucher: %v\n", err)
+	}
+
+	raw, err := types.TxToBytes(voucher)
+	if err != nil {
+		utils.Error("Failed to encode voucher: %v\n", err)
+	}
+	fmt.Printf("Voucher paying %v PTXWei in total for resource %v, payment sequence %v:\n%v\n",
+		voucher.Source.Coins.PTXWei, voucher.ResourceID, voucher.PaymentSequence, hex.EncodeToString(raw))
+}
+
+func doAcceptServicePaymentCmd(cmd *cobra.Command, args []string) {
+	raw, err := hex.DecodeString(voucherFlag)
+	if err != nil {
+		utils.Error("Failed to decode voucher: %v\n", err)
+	}
+	tx, err := types.TxFromBytes(raw)
+	if err != nil {
+		utils.Error("Failed to decode voucher: %v\n", err)
+	}
+	voucher, ok := tx.(*types.ServicePaymentTx)
+	if !ok {
+		utils.Error("Voucher is not a service payment\n")
+	}
+
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("pando.GetStatus", rpc.GetStatusArgs{})
+	if err != nil {
+		utils.Error("Failed to get node status: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get node status: %v\n", res.Error)
+	}
+	status := &rpc.GetStatusResult{}
+	if err := res.GetObject(status); err != nil {
+		utils.Error("Failed to parse server response: %v\n", err)
+	}
+	if status.ChainID != chainIDFlag {
+		utils.Error("Node is on chain %v instead of %v\n", status.ChainID, chainIDFlag)
+	}
+
+	// The reserve is checked at the latest finalized height, which cannot be rolled back
+	height := status.LatestFinalizedBlockHeight
+	res, err = client.Call("pando.GetAccount", rpc.GetAccountArgs{
+		Address: voucher.Source.Address.Hex(),
+		Height:  height,
+	})
+	if err != nil {
+		utils.Error("Failed to get source account: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get source account: %v\n", res.Error)
+	}
+	account := &rpc.GetAccountResult{Account: &types.Account{}}
+	if err := res.GetObject(account); err != nil {
+		utils.Error("Failed to parse server response: %v\n", err)
+	}
+	account.Account.Address = voucher.Source.Address
+
+	store := openVoucherStore(cmd, "received", voucher.Target.Address)
+	receiver := micropayment.NewReceiver(chainIDFlag, voucher.Target.Address, store)
+	increment, err := receiver.Accept(voucher, account.Account, uint64(height))
+	if err != nil {
+		utils.Error("Invalid voucher: %v\n", err)
+	}
+	fmt.Printf("Accepted voucher paying %v PTXWei more for resource %v, %v PTXWei in total.\n",
+		increment, voucher.ResourceID, voucher.Source.Coins.PTXWei)
+}
+
+func doSettleServicePaymentCmd(cmd *cobra.Command, args []string) {
+	wallet, fromAddress, err := walletUnlock(cmd, fromFlag, passwordFlag)
+	if err != nil {
+		return
+	}
+	defer wallet.Lock(fromAddress)
+
+	fee, ok := types.ParseCoinAmount(feeFlag)
+	if !ok {
+		utils.Error("Failed to parse fee")
+	}
+
+	store := openVoucherStore(cmd, "received", fromAddress)
+	receiver := micropayment.NewReceiver(chainIDFlag, fromAddress, store)
+	settlement, err := receiver.Settle(resourceIDFlag, types.Coins{PandoWei: new(big.Int).SetUint64(0), PTXWei: fee}, seqFlag, wallet)
+	if err != nil {
+		utils.Error("Failed to settle: %v\n", err)
+	}
+
+	raw, err := types.TxToBytes(settlement)
+	if err != nil {
+		utils.Error("Failed to encode transaction: %v\n", err)
+	}
+	signedTx := hex.EncodeToString(raw)
+
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	var res *rpcc.RPCResponse
+	if asyncFlag {
+		res, err = client.Call("pando.BroadcastRawTransactionAsync", rpc.BroadcastRawTransactionArgs{TxBytes: signedTx})
+	} else {
+		res, err = client.Call("pando.BroadcastRawTransaction", rpc.BroadcastRawTransactionArgs{TxBytes: signedTx})
+	}
+	if err != nil {
+		utils.Error("Failed to broadcast transaction: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Server returned error: %v\n", res.Error)
+	}
+	if err := receiver.Settled(resourceIDFlag); err != nil {
+		utils.Error("Failed to drop the settled voucher: %v\n", err)
+	}
+	fmt.Printf("Successfully broadcasted transaction settling %v PTXWei for resource %v.\n",
+		settlement.Source.Coins.PTXWei, resourceIDFlag)
+}
+
+func init() {
+	createServicePaymentCmd.Flags().StringVar(&chainIDFlag, "chain", "", "Chain ID")
+	createServicePaymentCmd.Flags().StringVar(&fromFlag, "from", "", "Address of the reserved fund source")
+	createServicePaymentCmd.Flags().StringVar(&toFlag, "to", "", "Address of the payment target")
+	createServicePaymentCmd.Flags().Uint64Var(&reserveSeqFlag, "reserve_seq", 0, "Sequence number of the reserve fund transaction")
+	createServicePaymentCmd.Flags().Uint64Var(&paymentSeqFlag, "payment_seq", 0, "Payment sequence, defaults to the latest payment of the resource")
+	createServicePaymentCmd.Flags().StringVar(&resourceIDFlag, "resource_id", "", "Resource ID")
+	createServicePaymentCmd.Flags().StringVar(&ptxAmountFlag, "amount", "0", "PTX amount added to the latest voucher")
+	createServicePaymentCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano)")
+	createServicePaymentCmd.Flags().StringVar(&passwordFlag, "password", "", "password to unlock the wallet")
+
+	createServicePaymentCmd.MarkFlagRequired("chain")
+	createServicePaymentCmd.MarkFlagRequired("from")
+	createServicePaymentCmd.MarkFlagRequired("to")
+	createServicePaymentCmd.MarkFlagRequired("reserve_seq")
+	createServicePaymentCmd.MarkFlagRequired("resource_id")
+	createServicePaymentCmd.MarkFlagRequired("amount")
+
+	acceptServicePaymentCmd.Flags().StringVar(&chainIDFlag, "chain", "", "Chain ID")
+	acceptServicePaymentCmd.Flags().StringVar(&voucherFlag, "voucher", "", "Hex encoded voucher")
+
+	acceptServicePaymentCmd.MarkFlagRequired("chain")
+	acceptServicePaymentCmd.MarkFlagRequired("voucher")
+
+	settleServicePaymentCmd.Flags().StringVar(&chainIDFlag, "chain", "", "Chain ID")
+	settleServicePaymentCmd.Flags().StringVar(&fromFlag, "from", "", "Address of the payment target")
+	settleServicePaymentCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction")
+	settleServicePaymentCmd.Flags().StringVar(&resourceIDFlag, "resource_id", "", "Resource ID")
+	settleServicePaymentCmd.Flags().StringVar(&feeFlag, "fee", fmt.Sprintf("%dwei", types.MinimumTransactionFeePTXWeiJune2021), "Fee")
+	settleServicePaymentCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano)")
+	settleServicePaymentCmd.Flags().BoolVar(&asyncFlag, "async", false, "block until tx has been included in the blockchain")
+	settleServicePaymentCmd.Flags().StringVar(&passwordFlag, "password", "", "password to unlock the wallet")
+
+	settleServicePaymentCmd.MarkFlagRequired("chain")
+	settleServicePaymentCmd.MarkFlagRequired("from")
+	settleServicePaymentCmd.MarkFlagRequired("seq")
+	settleServicePaymentCmd.MarkFlagRequired("resource_id")
+
+	servicePaymentCmd.AddCommand(createServicePaymentCmd)
+	servicePaymentCmd.AddCommand(acceptServicePaymentCmd)
+	servicePaymentCmd.AddCommand(settleServicePaymentCmd)
+}
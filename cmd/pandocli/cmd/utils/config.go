@@ -4,10 +4,12 @@ import "github.com/spf13/viper"
 
 const (
 	CfgRemoteRPCEndpoint = "remoteRPCEndpoint"
+	CfgAdminSocket       = "adminSocket"
 	CfgDebug             = "debug"
 )
 
 func init() {
 	viper.SetDefault(CfgRemoteRPCEndpoint, "http://localhost:16888/rpc")
+	viper.SetDefault(CfgAdminSocket, "")
 	viper.SetDefault(CfgDebug, false)
 }
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/spf13/viper"
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// NewAdminRPCClient creates the client of the admin RPC methods. It connects to the admin socket
+// of the node if one is configured, and to the remote RPC endpoint otherwise.
+func NewAdminRPCClient() *rpcc.RPCClient {
+	socketPath := viper.GetString(CfgAdminSocket)
+	if socketPath == "" {
+		return rpcc.NewRPCClient(viper.GetString(CfgRemoteRPCEndpoint))
+	}
+
+	// The host of the URL is ignored, the requests are sent over the socket
+	client := rpcc.NewRPCClient("http://unix/rpc")
+	client.SetHTTPClient(&http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	})
+	return client
+}
@@ -51,6 +51,12 @@ const (
 	CfgStorageLevelDBHandles = "storage.levelDBHandles"
 	// CfgStorageRollingInterval is the block interval that we start new db layer
 	CfgStorageRollingInterval = "storage.rollingInterval"
+	// CfgStorageAccountTxIndexEnabled indicates whether finalized transactions are indexed by the addresses they touch
+	CfgStorageAccountTxIndexEnabled = "storage.accountTxIndexEnabled"
+	// CfgStorageBackend is the storage backend of the main database: leveldb, badger, memdb, mongodb or aerospike
+	CfgStorageBackend = "storage.backend"
+	// CfgStorageArchiveEnabled indicates whether the state of every block is kept, which disables the state pruning and rolling
+	CfgStorageArchiveEnabled = "storage.archiveEnabled"
 
 	// CfgSyncMessageQueueSize defines the capacity of Sync Manager message queue.
 	CfgSyncMessageQueueSize = "sync.messageQueueSize"
@@ -58,6 +64,11 @@ const (
 	CfgSyncDownloadByHash = "sync.downloadByHash"
 	// CfgSyncDownloadByHeader indicates whether should download blocks using header.
 	CfgSyncDownloadByHeader = "sync.downloadByHeader"
+	// CfgSyncStateSyncServe indicates whether to serve the state of the recent checkpoints to the peers.
+	CfgSyncStateSyncServe = "sync.stateSync.serve"
+	// CfgSyncStateSyncCheckpoint is the hash of a trusted checkpoint block. If set, the node downloads the
+	// state of the checkpoint from the peers and starts syncing blocks from the checkpoint.
+	CfgSyncStateSyncCheckpoint = "sync.stateSync.checkpoint"
 
 	// CfgP2POpt sets which P2P network to use: p2p, libp2p, or both.
 	CfgP2POpt = "p2p.opt"
@@ -111,6 +122,19 @@ const (
 	// CfgSyncInboundResponseWhitelist filters inbound messages based on peer ID.
 	CfgSyncInboundResponseWhitelist = "sync.inboundResponseWhitelist"
 
+	// CfgMempoolReplacementPriceBump is the minimal percentage by which the effective gas price of a transaction
+	// must exceed that of the pending transaction with the same sequence number to replace it.
+	CfgMempoolReplacementPriceBump = "mempool.replacementPriceBump"
+	// CfgMempoolMaxNumTxs is the maximal number of transactions in the mempool.
+	CfgMempoolMaxNumTxs = "mempool.maxNumTxs"
+	// CfgMempoolMaxNumBytes is the maximal total size (in bytes) of the transactions in the mempool.
+	CfgMempoolMaxNumBytes = "mempool.maxNumBytes"
+	// CfgMempoolMaxNumTxsPerAddress is the maximal number of pending transactions from a single address.
+	CfgMempoolMaxNumTxsPerAddress = "mempool.maxNumTxsPerAddress"
+	// CfgMempoolJournalEnabled indicates whether the accepted transactions are journaled to the data directory,
+	// so that the pending transactions can be restored after the node restarts.
+	CfgMempoolJournalEnabled = "mempool.journalEnabled"
+
 	// CfgRPCEnabled sets whether to run RPC service.
 	CfgRPCEnabled = "rpc.enabled"
 	// CfgRPCAddress sets the binding address of RPC service.
@@ -121,6 +145,32 @@ const (
 	CfgRPCMaxConnections = "rpc.maxConnections"
 	// CfgRPCTimeoutSecs set a timeout for RPC.
 	CfgRPCTimeoutSecs = "rpc.timeoutSecs"
+	// CfgRPCHealthMaxFinalizedBlockAgeSecs sets the maximal age of the last finalized block for the node to be ready.
+	CfgRPCHealthMaxFinalizedBlockAgeSecs = "rpc.health.maxFinalizedBlockAgeSecs"
+	// CfgRPCHealthMinNumPeers sets the minimal number of peers for the node to be ready.
+	CfgRPCHealthMinNumPeers = "rpc.health.minNumPeers"
+	// CfgRPCHealthMaxConsensusIdleSecs sets the maximal time the consensus main loop can stay idle for the node to be alive.
+	CfgRPCHealthMaxConsensusIdleSecs = "rpc.health.maxConsensusIdleSecs"
+	// CfgRPCNamespaces sets the RPC namespaces ("public", "admin" and "debug") served by the RPC port. Only
+	// the public namespace is served by default, the admin and debug methods are served on the admin socket.
+	CfgRPCNamespaces = "rpc.namespaces"
+	// CfgRPCAuthNamespaces sets the RPC namespaces which can only be called by authenticated clients.
+	CfgRPCAuthNamespaces = "rpc.auth.namespaces"
+	// CfgRPCAuthAPIKeys sets the API keys accepted from the RPC clients.
+	CfgRPCAuthAPIKeys = "rpc.auth.apiKeys"
+	// CfgRPCAuthJWTSecret sets the secret of the HS256 JSON Web Tokens accepted from the RPC clients.
+	CfgRPCAuthJWTSecret = "rpc.auth.jwtSecret"
+	// CfgRPCRateLimitRequestsPerSecond sets the number of RPC calls per second allowed for each client
+	// IP or key. Zero disables the rate limit.
+	CfgRPCRateLimitRequestsPerSecond = "rpc.rateLimit.requestsPerSecond"
+	// CfgRPCRateLimitBurst sets the number of RPC calls a client can make in a burst.
+	CfgRPCRateLimitBurst = "rpc.rateLimit.burst"
+	// CfgRPCCORSAllowedOrigins sets the origins allowed to make cross-origin RPC requests.
+	CfgRPCCORSAllowedOrigins = "rpc.cors.allowedOrigins"
+	// CfgRPCAdminSocketEnabled enables the admin RPC served on a Unix domain socket.
+	CfgRPCAdminSocketEnabled = "rpc.adminSocket.enabled"
+	// CfgRPCAdminSocketPath sets the path of the admin socket, "admin.sock" in the data directory by default.
+	CfgRPCAdminSocketPath = "rpc.adminSocket.path"
 
 	// CfgLogLevels sets the log level.
 	CfgLogLevels = "log.levels"
@@ -133,6 +183,12 @@ const (
 
 	// Graphite Server to collet metrics
 	CfgMetricsServer = "metrics.server"
+	// CfgMetricsEnabled sets whether to collect metrics and serve them on the /metrics endpoint.
+	CfgMetricsEnabled = "metrics.enabled"
+	// CfgMetricsAddress sets the binding address of the /metrics endpoint.
+	CfgMetricsAddress = "metrics.address"
+	// CfgMetricsPort sets the port of the /metrics endpoint.
+	CfgMetricsPort = "metrics.port"
 
 	// CfgProfEnabled to enable profiling
 	CfgProfEnabled = "prof.enabled"
@@ -169,6 +225,8 @@ func init() {
 	viper.SetDefault(CfgSyncMessageQueueSize, 512)
 	viper.SetDefault(CfgSyncDownloadByHash, false)
 	viper.SetDefault(CfgSyncDownloadByHeader, true)
+	viper.SetDefault(CfgSyncStateSyncServe, true)
+	viper.SetDefault(CfgSyncStateSyncCheckpoint, "")
 
 	viper.SetDefault(CfgStorageRollingEnabled, true)
 	viper.SetDefault(CfgStorageStatePruningEnabled, true)
@@ -178,6 +236,15 @@ func init() {
 	viper.SetDefault(CfgStorageLevelDBCacheSize, 256)
 	viper.SetDefault(CfgStorageLevelDBHandles, 16)
 	viper.SetDefault(CfgStorageRollingInterval, 14400) // approximately 1 days by default
+	viper.SetDefault(CfgStorageAccountTxIndexEnabled, false)
+	viper.SetDefault(CfgStorageBackend, "leveldb")
+	viper.SetDefault(CfgStorageArchiveEnabled, false)
+
+	viper.SetDefault(CfgMempoolReplacementPriceBump, 10)
+	viper.SetDefault(CfgMempoolMaxNumTxs, 25600)
+	viper.SetDefault(CfgMempoolMaxNumBytes, 64*1024*1024) // 64 MB
+	viper.SetDefault(CfgMempoolMaxNumTxsPerAddress, 256)
+	viper.SetDefault(CfgMempoolJournalEnabled, true)
 
 	viper.SetDefault(CfgRPCEnabled, false)
 	viper.SetDefault(CfgP2PMessageQueueSize, 512)
@@ -205,6 +272,18 @@ func init() {
 	viper.SetDefault(CfgRPCPort, "16888")
 	viper.SetDefault(CfgRPCMaxConnections, 200)
 	viper.SetDefault(CfgRPCTimeoutSecs, 60)
+	viper.SetDefault(CfgRPCHealthMaxFinalizedBlockAgeSecs, 120)
+	viper.SetDefault(CfgRPCHealthMinNumPeers, 1)
+	viper.SetDefault(CfgRPCHealthMaxConsensusIdleSecs, 60)
+	viper.SetDefault(CfgRPCNamespaces, []string{"public"})
+	viper.SetDefault(CfgRPCAuthNamespaces, []string{})
+	viper.SetDefault(CfgRPCAuthAPIKeys, []string{})
+	viper.SetDefault(CfgRPCAuthJWTSecret, "")
+	viper.SetDefault(CfgRPCRateLimitRequestsPerSecond, 0)
+	viper.SetDefault(CfgRPCRateLimitBurst, 0)
+	viper.SetDefault(CfgRPCCORSAllowedOrigins, []string{"*"})
+	viper.SetDefault(CfgRPCAdminSocketEnabled, true)
+	viper.SetDefault(CfgRPCAdminSocketPath, "")
 
 	viper.SetDefault(CfgLogLevels, "*:debug")
 	viper.SetDefault(CfgLogPrintSelfID, false)
@@ -212,6 +291,9 @@ func init() {
 	viper.SetDefault(CfgGuardianRoundLength, 30)
 
 	viper.SetDefault(CfgMetricsServer, "guardian-metrics.pandotoken.org")
+	viper.SetDefault(CfgMetricsEnabled, false)
+	viper.SetDefault(CfgMetricsAddress, "127.0.0.1")
+	viper.SetDefault(CfgMetricsPort, "16900")
 
 	viper.SetDefault(CfgProfEnabled, false)
 	viper.SetDefault(CfgForceGCEnabled, true)
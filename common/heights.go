@@ -33,6 +33,11 @@ const HeightSupportPandoTokenInSmartContract uint64 = 1 // approximate time: 5pm
 // HeightValidatorStakeChangedTo200K specifies the block height to lower the validator stake to 200,000 Pando
 const HeightValidatorStakeChangedTo200K uint64 = 1 // approximate time: 12pm Mar 14, 2022 PT
 
+// HeightEnableValidatorSlashing specifies the block height to enable slashing the validators that equivocate
+// and the sources that overspend their reserved funds. No fork height has been scheduled yet, so both kinds
+// of slashing are inert: no SlashTx is added to a block or accepted by the executor below this height.
+const HeightEnableValidatorSlashing uint64 = 10000000000000000 // to be scheduled
+
 // CheckpointInterval defines the interval between checkpoints.
 const CheckpointInterval = int64(100)
 
@@ -17,6 +17,13 @@ func NewHealthcheck(f func(Healthcheck)) Healthcheck {
 	return &StandardHealthcheck{nil, f}
 }
 
+// NewStandardHealthcheck constructs a new StandardHealthcheck regardless of
+// whether the metrics system is enabled, for the checks the node relies on,
+// e.g. the readiness probes.
+func NewStandardHealthcheck(f func(Healthcheck)) *StandardHealthcheck {
+	return &StandardHealthcheck{nil, f}
+}
+
 // NilHealthcheck is a no-op.
 type NilHealthcheck struct{}
 
@@ -0,0 +1,164 @@
+// Package prometheus exposes the go-metrics registry in the Prometheus text exposition format
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pandotoken/pando/common/metrics"
+)
+
+const contentType = "text/plain; version=0.0.4"
+
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999}
+
+// Handler returns an HTTP handler which serves the metrics of the registry.
+//
+// A metric name may carry Prometheus labels, e.g. `rpc/duration{method="pando.GetStatus"}`. The part
+// before the labels is converted into a Prometheus metric name, e.g. rpc_duration, and all the metrics
+// sharing it are exposed as one metric family. Meters are exposed as counters, timers and histograms
+// as summaries. Durations are in nanoseconds.
+func Handler(reg metrics.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write(Export(reg))
+	})
+}
+
+// Export renders the metrics of the registry in the Prometheus text exposition format.
+func Export(reg metrics.Registry) []byte {
+	names := []string{}
+	all := make(map[string]interface{})
+	reg.Each(func(name string, i interface{}) {
+		names = append(names, name)
+		all[name] = i
+	})
+	sort.Strings(names)
+
+	c := &collector{
+		buf:   &bytes.Buffer{},
+		types: make(map[string]string),
+	}
+	for _, name := range names {
+		family, labels := splitName(name)
+		switch m := all[name].(type) {
+		case metrics.Counter:
+			c.addSample(family, "counter", labels, float64(m.Count()))
+		case metrics.Gauge:
+			c.addSample(family, "gauge", labels, float64(m.Value()))
+		case metrics.GaugeFloat64:
+			c.addSample(family, "gauge", labels, m.Value())
+		case metrics.Meter:
+			c.addSample(family, "counter", labels, float64(m.Count()))
+		case metrics.Histogram:
+			ms := m.Snapshot()
+			c.addSummary(family, labels, ms.Count(), float64(ms.Sum()), ms.Percentiles(quantiles))
+		case metrics.Timer:
+			ms := m.Snapshot()
+			c.addSummary(family, labels, ms.Count(), float64(ms.Sum()), ms.Percentiles(quantiles))
+		case metrics.ResettingTimer:
+			ms := m.Snapshot()
+			values := ms.Values()
+			if len(values) == 0 {
+				continue
+			}
+			sum := int64(0)
+			for _, v := range values {
+				sum += v
+			}
+			ps := ms.Percentiles([]float64{50, 75, 95, 99, 99.9})
+			percentiles := make([]float64, len(ps))
+			for i, p := range ps {
+				percentiles[i] = float64(p)
+			}
+			c.addSummary(family, labels, int64(len(values)), float64(sum), percentiles)
+		}
+	}
+	return c.buf.Bytes()
+}
+
+type collector struct {
+	buf   *bytes.Buffer
+	types map[string]string // metric family -> type
+}
+
+func (c *collector) addType(family string, typ string) bool {
+	if existing, ok := c.types[family]; ok {
+		return existing == typ
+	}
+	c.types[family] = typ
+	fmt.Fprintf(c.buf, "# TYPE %s %s\n", family, typ)
+	return true
+}
+
+func (c *collector) addSample(family string, typ string, labels string, value float64) {
+	if !c.addType(family, typ) {
+		return // a metric family can only have one type
+	}
+	fmt.Fprintf(c.buf, "%s%s %s\n", family, formatLabels(labels), formatValue(value))
+}
+
+func (c *collector) addSummary(family string, labels string, count int64, sum float64, percentiles []float64) {
+	if !c.addType(family, "summary") {
+		return
+	}
+	for i, q := range quantiles {
+		quantileLabel := fmt.Sprintf("quantile=%q", strconv.FormatFloat(q, 'f', -1, 64))
+		fmt.Fprintf(c.buf, "%s%s %s\n", family, formatLabels(joinLabels(labels, quantileLabel)), formatValue(percentiles[i]))
+	}
+	fmt.Fprintf(c.buf, "%s_sum%s %s\n", family, formatLabels(labels), formatValue(sum))
+	fmt.Fprintf(c.buf, "%s_count%s %d\n", family, formatLabels(labels), count)
+}
+
+// splitName splits a go-metrics name into the Prometheus metric family and its labels.
+func splitName(name string) (family string, labels string) {
+	if idx := strings.Index(name, "{"); idx >= 0 && strings.HasSuffix(name, "}") {
+		labels = name[idx+1 : len(name)-1]
+		name = name[:idx]
+	}
+	return sanitize(name), labels
+}
+
+// sanitize converts the name into a valid Prometheus metric name, i.e. [a-zA-Z_:][a-zA-Z0-9_:]*
+func sanitize(name string) string {
+	var sb strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
+
+func joinLabels(labels ...string) string {
+	nonEmpty := []string{}
+	for _, l := range labels {
+		if l != "" {
+			nonEmpty = append(nonEmpty, l)
+		}
+	}
+	return strings.Join(nonEmpty, ",")
+}
+
+func formatLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
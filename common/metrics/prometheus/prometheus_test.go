@@ -0,0 +1,59 @@
+package prometheus
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pandotoken/pando/common/metrics"
+)
+
+func init() {
+	metrics.Enabled = true
+}
+
+func TestExport(t *testing.T) {
+	r := metrics.NewRegistry()
+	metrics.NewRegisteredCounter("mempool/evicted", r).Inc(3)
+	metrics.NewRegisteredGauge("consensus/epoch", r).Update(42)
+	metrics.NewRegisteredCounter(`p2p/peer/ingress{peer="a"}`, r).Inc(10)
+	metrics.NewRegisteredCounter(`p2p/peer/ingress{peer="b"}`, r).Inc(20)
+	timer := metrics.NewRegisteredTimer(`rpc/duration{method="pando.GetStatus"}`, r)
+	timer.Update(2 * time.Millisecond)
+	timer.Update(4 * time.Millisecond)
+
+	out := string(Export(r))
+	expected := []string{
+		"# TYPE mempool_evicted counter\nmempool_evicted 3\n",
+		"# TYPE consensus_epoch gauge\nconsensus_epoch 42\n",
+		"# TYPE p2p_peer_ingress counter\np2p_peer_ingress{peer=\"a\"} 10\np2p_peer_ingress{peer=\"b\"} 20\n",
+		"# TYPE rpc_duration summary\n",
+		"rpc_duration{method=\"pando.GetStatus\",quantile=\"0.5\"} 3e+06\n",
+		"rpc_duration_sum{method=\"pando.GetStatus\"} 6e+06\n",
+		"rpc_duration_count{method=\"pando.GetStatus\"} 2\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("Expected %q in output:\n%s", e, out)
+		}
+	}
+	if strings.Count(out, "# TYPE p2p_peer_ingress") != 1 {
+		t.Errorf("Expected a single TYPE line per metric family:\n%s", out)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := metrics.NewRegistry()
+	metrics.NewRegisteredCounter("sync.pending-blocks", r).Inc(1)
+
+	rec := httptest.NewRecorder()
+	Handler(r).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != contentType {
+		t.Errorf("Unexpected content type: %v", ct)
+	}
+	if body := rec.Body.String(); body != "# TYPE sync_pending_blocks counter\nsync_pending_blocks 1\n" {
+		t.Errorf("Unexpected body: %q", body)
+	}
+}
@@ -0,0 +1,166 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// ConfigReloadHandler handles the reload of a set of config keys. Validate checks the reloaded
+// config file before anything is applied, and Apply is called once the new values are loaded
+// into viper. Both are optional.
+type ConfigReloadHandler struct {
+	Keys     []string
+	Validate func(config *viper.Viper) error
+	Apply    func()
+}
+
+var (
+	configReloadMu       = &sync.Mutex{}
+	configReloadHandlers []*ConfigReloadHandler
+	configFileSettings   map[string]interface{} // settings of the config file currently applied
+)
+
+// AddConfigReloadHandler makes the keys of the handler reloadable at runtime. The other keys
+// can only be changed by restarting the node.
+func AddConfigReloadHandler(handler *ConfigReloadHandler) {
+	configReloadMu.Lock()
+	defer configReloadMu.Unlock()
+
+	configReloadHandlers = append(configReloadHandlers, handler)
+}
+
+// InitConfigReload records the settings of the config file read by viper at startup, against
+// which the reloaded config file is compared.
+func InitConfigReload() error {
+	configReloadMu.Lock()
+	defer configReloadMu.Unlock()
+
+	settings, err := readConfigFile(viper.ConfigFileUsed())
+	if err != nil {
+		return err
+	}
+	configFileSettings = settings
+	return nil
+}
+
+// ReloadConfig reads the config file again, and applies the keys changed since the last load.
+// The reload is rejected as a whole if it changes a key which is not reloadable, e.g. the chain
+// ID or the data paths, or if a handler rejects the new values. It returns the changed keys.
+func ReloadConfig() ([]string, error) {
+	configReloadMu.Lock()
+	defer configReloadMu.Unlock()
+
+	if configFileSettings == nil {
+		return nil, errors.New("No config file loaded")
+	}
+	settings, err := readConfigFile(viper.ConfigFileUsed())
+	if err != nil {
+		return nil, err
+	}
+
+	changedKeys := diffConfigSettings(configFileSettings, settings)
+	if len(changedKeys) == 0 {
+		return changedKeys, nil
+	}
+
+	reloadable := make(map[string]bool)
+	for _, handler := range configReloadHandlers {
+		for _, key := range handler.Keys {
+			reloadable[strings.ToLower(key)] = true
+		}
+	}
+	immutableKeys := []string{}
+	for _, key := range changedKeys {
+		if !reloadable[key] {
+			immutableKeys = append(immutableKeys, key)
+		}
+	}
+	if len(immutableKeys) > 0 {
+		return nil, fmt.Errorf("Config keys cannot be changed without restarting the node: %v", strings.Join(immutableKeys, ", "))
+	}
+
+	config := viper.New()
+	config.SetConfigFile(viper.ConfigFileUsed())
+	if err := config.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	handlers := changedConfigHandlers(changedKeys)
+	for _, handler := range handlers {
+		if handler.Validate == nil {
+			continue
+		}
+		if err := handler.Validate(config); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	configFileSettings = settings
+
+	for _, handler := range handlers {
+		if handler.Apply != nil {
+			handler.Apply()
+		}
+	}
+	return changedKeys, nil
+}
+
+// changedConfigHandlers returns the handlers of the changed keys. Must be called with
+// configReloadMu held.
+func changedConfigHandlers(changedKeys []string) []*ConfigReloadHandler {
+	changed := make(map[string]bool)
+	for _, key := range changedKeys {
+		changed[key] = true
+	}
+	handlers := []*ConfigReloadHandler{}
+	for _, handler := range configReloadHandlers {
+		for _, key := range handler.Keys {
+			if changed[strings.ToLower(key)] {
+				handlers = append(handlers, handler)
+				break
+			}
+		}
+	}
+	return handlers
+}
+
+func readConfigFile(file string) (map[string]interface{}, error) {
+	if file == "" {
+		return nil, errors.New("No config file loaded")
+	}
+	config := viper.New()
+	config.SetConfigFile(file)
+	if err := config.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	settings := make(map[string]interface{})
+	for _, key := range config.AllKeys() {
+		settings[key] = config.Get(key)
+	}
+	return settings, nil
+}
+
+// diffConfigSettings returns the sorted keys added, removed or changed.
+func diffConfigSettings(old, new map[string]interface{}) []string {
+	changedKeys := []string{}
+	for key, value := range new {
+		if oldValue, ok := old[key]; !ok || !reflect.DeepEqual(oldValue, value) {
+			changedKeys = append(changedKeys, key)
+		}
+	}
+	for key := range old {
+		if _, ok := new[key]; !ok {
+			changedKeys = append(changedKeys, key)
+		}
+	}
+	sort.Strings(changedKeys)
+	return changedKeys
+}
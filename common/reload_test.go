@@ -0,0 +1,70 @@
+package common
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/spf13/viper"
+)
+
+func TestReloadConfig(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "config")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "config.yaml")
+	writeConfig := func(content string) {
+		require.Nil(ioutil.WriteFile(file, []byte(content), 0600))
+	}
+
+	writeConfig("genesis:\n  chainID: privatenet\np2p:\n  maxNumPeers: 10\n")
+	viper.SetConfigFile(file)
+	require.Nil(viper.ReadInConfig())
+	require.Nil(InitConfigReload())
+
+	numApplied := 0
+	AddConfigReloadHandler(&ConfigReloadHandler{
+		Keys: []string{CfgP2PMaxNumPeers, CfgLogLevels},
+		Validate: func(config *viper.Viper) error {
+			if config.IsSet(CfgP2PMaxNumPeers) && config.GetInt(CfgP2PMaxNumPeers) <= 0 {
+				return errors.New("Invalid max number of peers")
+			}
+			return nil
+		},
+		Apply: func() { numApplied++ },
+	})
+
+	changedKeys, err := ReloadConfig()
+	require.Nil(err)
+	assert.Equal([]string{}, changedKeys)
+	assert.Equal(0, numApplied)
+
+	writeConfig("genesis:\n  chainID: privatenet\np2p:\n  maxNumPeers: 20\nlog:\n  levels: \"*:info\"\n")
+	changedKeys, err = ReloadConfig()
+	require.Nil(err)
+	assert.Equal([]string{"log.levels", "p2p.maxnumpeers"}, changedKeys)
+	assert.Equal(1, numApplied)
+	assert.Equal(20, viper.GetInt(CfgP2PMaxNumPeers))
+
+	// Rejected by the handler
+	writeConfig("genesis:\n  chainID: privatenet\np2p:\n  maxNumPeers: 0\nlog:\n  levels: \"*:info\"\n")
+	_, err = ReloadConfig()
+	assert.NotNil(err)
+	assert.Equal(20, viper.GetInt(CfgP2PMaxNumPeers))
+
+	// The chain ID cannot change at runtime, the whole reload is rejected
+	writeConfig("genesis:\n  chainID: mainnet\np2p:\n  maxNumPeers: 30\nlog:\n  levels: \"*:info\"\n")
+	_, err = ReloadConfig()
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "genesis.chainid")
+	assert.Equal(20, viper.GetInt(CfgP2PMaxNumPeers))
+	assert.Equal("privatenet", viper.GetString(CfgGenesisChainID))
+	assert.Equal(1, numApplied)
+}
@@ -409,6 +409,15 @@ const (
 
 	// ChannelIDAggregatedEliteEdgeNodeVotes indicates the channel for Elite Edge Node aggregated vote messages
 	ChannelIDAggregatedEliteEdgeNodeVotes
+
+	// ChannelIDEvidence indicates the channel for validator equivocation evidence
+	ChannelIDEvidence
+
+	// ChannelIDStateCheckpoint indicates the channel for the checkpoints served by the state sync
+	ChannelIDStateCheckpoint
+
+	// ChannelIDStateNode indicates the channel for the state trie nodes served by the state sync
+	ChannelIDStateNode
 )
 
 // P2POptEnum defines the p2p network
@@ -2,14 +2,20 @@ package util
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/pandotoken/pando/common"
 )
 
-var logLevels map[string]string
+var (
+	logMu     = &sync.Mutex{}
+	logLevels map[string]string
+	loggers   = make(map[string][]*log.Logger) // the loggers created for each module
+)
 
 const (
 	panicLevel = "panic"
@@ -21,21 +27,31 @@ const (
 )
 const defaultLevel = warnLevel
 
+func init() {
+	// The levels of the existing loggers are updated when the config is reloaded
+	common.AddConfigReloadHandler(&common.ConfigReloadHandler{
+		Keys: []string{common.CfgLogLevels},
+		Validate: func(config *viper.Viper) error {
+			if !config.IsSet(common.CfgLogLevels) {
+				return nil
+			}
+			return validateLogLevelConfig(config.GetString(common.CfgLogLevels))
+		},
+		Apply: InitLog,
+	})
+}
+
 func InitLog() {
+	logMu.Lock()
+	defer logMu.Unlock()
+
 	logLevels = parseLogLevelConfig(viper.GetString(common.CfgLogLevels))
 	log.Infof("Log settings: %v, %v", logLevels, viper.GetString(common.CfgLogLevels))
-	if logLevels["*"] == panicLevel {
-		log.SetLevel(log.PanicLevel)
-	} else if logLevels["*"] == fatalLevel {
-		log.SetLevel(log.FatalLevel)
-	} else if logLevels["*"] == errorLevel {
-		log.SetLevel(log.ErrorLevel)
-	} else if logLevels["*"] == warnLevel {
-		log.SetLevel(log.WarnLevel)
-	} else if logLevels["*"] == infoLevel {
-		log.SetLevel(log.InfoLevel)
-	} else {
-		log.SetLevel(log.DebugLevel)
+	setGlobalLevel(logLevels["*"])
+
+	// Loggers created before the settings are loaded, e.g. the package level ones, are updated
+	for module := range loggers {
+		applyModuleLevel(module)
 	}
 }
 
@@ -57,6 +73,63 @@ func parseLogLevelConfig(config string) map[string]string {
 	return levels
 }
 
+// validateLogLevelConfig checks the log level config, which parseLogLevelConfig panics on.
+func validateLogLevelConfig(config string) error {
+	for _, moduleAndLevel := range strings.Split(config, ",") {
+		tokens := strings.Split(moduleAndLevel, ":")
+		if len(tokens) != 2 {
+			return fmt.Errorf("Failed to parse module log level: \"%v\"", moduleAndLevel)
+		}
+		if _, ok := toLogrusLevel(strings.TrimSpace(tokens[1])); !ok {
+			return fmt.Errorf("Invalid log level: %v", strings.TrimSpace(tokens[1]))
+		}
+	}
+	return nil
+}
+
+func toLogrusLevel(level string) (log.Level, bool) {
+	switch level {
+	case panicLevel:
+		return log.PanicLevel, true
+	case fatalLevel:
+		return log.FatalLevel, true
+	case errorLevel:
+		return log.ErrorLevel, true
+	case warnLevel:
+		return log.WarnLevel, true
+	case infoLevel:
+		return log.InfoLevel, true
+	case debugLevel:
+		return log.DebugLevel, true
+	}
+	return log.DebugLevel, false
+}
+
+func setGlobalLevel(level string) {
+	// Unknown levels fall back to the debug level
+	logrusLevel, _ := toLogrusLevel(level)
+	log.SetLevel(logrusLevel)
+}
+
+func moduleLevel(module string) string {
+	level, ok := logLevels[module]
+	if !ok {
+		level = logLevels["*"]
+	}
+	return level
+}
+
+// applyModuleLevel sets the level of the loggers of the module. Must be called with logMu held.
+func applyModuleLevel(module string) {
+	logrusLevel, ok := toLogrusLevel(moduleLevel(module))
+	if !ok {
+		return
+	}
+	for _, logger := range loggers[module] {
+		logger.SetLevel(logrusLevel)
+	}
+}
+
 // GetLoggerForModule returns the logger for given module.
 func GetLoggerForModule(module string) *log.Entry {
 	customFormatter := new(TextFormatter)
@@ -68,24 +141,56 @@ func GetLoggerForModule(module string) *log.Entry {
 	logger := log.New()
 	logger.Formatter = customFormatter
 
-	level, ok := logLevels[module]
-	if !ok {
-		level = logLevels["*"]
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	loggers[module] = append(loggers[module], logger)
+	applyModuleLevel(module)
+
+	return logger.WithFields(log.Fields{"prefix": module})
+}
+
+// SetLogLevel changes the log level of the module at runtime, including the loggers already
+// created for the module. Module "*" sets the global level, and the level of the modules which
+// do not have their own level.
+func SetLogLevel(module string, level string) error {
+	module = strings.TrimSpace(module)
+	level = strings.TrimSpace(strings.ToLower(level))
+	if module == "" {
+		return fmt.Errorf("Module is not specified")
+	}
+	if _, ok := toLogrusLevel(level); !ok {
+		return fmt.Errorf("Invalid log level: %v", level)
 	}
 
-	if level == panicLevel {
-		logger.SetLevel(log.PanicLevel)
-	} else if level == fatalLevel {
-		logger.SetLevel(log.FatalLevel)
-	} else if level == errorLevel {
-		logger.SetLevel(log.ErrorLevel)
-	} else if level == warnLevel {
-		logger.SetLevel(log.WarnLevel)
-	} else if level == infoLevel {
-		logger.SetLevel(log.InfoLevel)
-	} else if level == debugLevel {
-		logger.SetLevel(log.DebugLevel)
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	if logLevels == nil {
+		logLevels = map[string]string{"*": defaultLevel}
 	}
+	logLevels[module] = level
 
-	return logger.WithFields(log.Fields{"prefix": module})
+	if module != "*" {
+		applyModuleLevel(module)
+		return nil
+	}
+	setGlobalLevel(level)
+	for module := range loggers {
+		applyModuleLevel(module)
+	}
+	return nil
+}
+
+// GetLogLevels returns the configured log levels, as "module:level" sorted by module.
+func GetLogLevels() []string {
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	levels := []string{}
+	for module, level := range logLevels {
+		levels = append(levels, module+":"+level)
+	}
+	sort.Strings(levels)
+	return levels
 }
@@ -35,3 +35,35 @@ func TestGetLoggerForModule(t *testing.T) {
 	assert.Equal(log.InfoLevel, GetLoggerForModule("consensus").Logger.Level)
 	assert.Equal(log.ErrorLevel, GetLoggerForModule("sync").Logger.Level)
 }
+
+func TestSetLogLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	logLevels = parseLogLevelConfig("*:error,p2p:debug")
+	p2pLogger := GetLoggerForModule("p2p")
+	syncLogger := GetLoggerForModule("sync")
+
+	assert.Nil(SetLogLevel("p2p", "info"))
+	assert.Equal(log.InfoLevel, p2pLogger.Logger.Level)
+	assert.Equal(log.ErrorLevel, syncLogger.Logger.Level)
+
+	// The modules without their own level follow the default level
+	assert.Nil(SetLogLevel("*", "warn"))
+	assert.Equal(log.InfoLevel, p2pLogger.Logger.Level)
+	assert.Equal(log.WarnLevel, syncLogger.Logger.Level)
+	assert.Equal(log.WarnLevel, log.GetLevel())
+
+	assert.NotNil(SetLogLevel("p2p", "verbose"))
+	assert.NotNil(SetLogLevel("", "info"))
+	assert.Equal(log.InfoLevel, p2pLogger.Logger.Level)
+
+	assert.Equal([]string{"*:warn", "p2p:info"}, GetLogLevels())
+}
+
+func TestValidateLogLevelConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(validateLogLevelConfig("*:error,p2p:debug"))
+	assert.NotNil(validateLogLevelConfig("*:error,p2p"))
+	assert.NotNil(validateLogLevelConfig("*:verbose"))
+}
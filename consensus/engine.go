@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pandotoken/pando/crypto/bls"
@@ -40,11 +41,15 @@ type ConsensusEngine struct {
 	ledger           core.Ledger
 	guardian         *GuardianEngine
 	eliteEdgeNode    *EliteEdgeNodeEngine
+	evidencePool     *EvidencePool
 
 	incoming        chan interface{}
+	newBlocks       chan *core.Block
 	finalizedBlocks chan *core.Block
 	hasSynced       bool
 
+	lastMainLoopTime int64 // unix time in nanoseconds of the last main loop iteration, accessed atomically
+
 	// Life cycle
 	wg      *sync.WaitGroup
 	ctx     context.Context
@@ -71,6 +76,7 @@ func NewConsensusEngine(privateKey *crypto.PrivateKey, db store.Store, chain *bl
 		privateKey: privateKey,
 
 		incoming:        make(chan interface{}, viper.GetInt(common.CfgConsensusMessageQueueSize)),
+		newBlocks:       make(chan *core.Block, viper.GetInt(common.CfgConsensusMessageQueueSize)),
 		finalizedBlocks: make(chan *core.Block, viper.GetInt(common.CfgConsensusMessageQueueSize)),
 
 		wg: &sync.WaitGroup{},
@@ -93,6 +99,8 @@ func NewConsensusEngine(privateKey *crypto.PrivateKey, db store.Store, chain *bl
 	}
 	e.guardian = NewGuardianEngine(e, blsKey)
 	e.eliteEdgeNode = NewEliteEdgeNodeEngine(e, blsKey)
+	e.evidencePool = NewEvidencePool(db, chain)
+	e.registerMetrics()
 
 	e.logger.WithFields(log.Fields{"state": e.state}).Info("Starting state")
 
@@ -158,6 +166,7 @@ func (e *ConsensusEngine) Start(ctx context.Context) {
 
 	e.checkSyncStatus()
 
+	atomic.StoreInt64(&e.lastMainLoopTime, time.Now().UnixNano())
 	e.wg.Add(1)
 	go e.mainLoop()
 }
@@ -256,6 +265,16 @@ func (e *ConsensusEngine) Wait() {
 	e.wg.Wait()
 }
 
+// LastMainLoopTime returns the time of the last main loop iteration, or the zero time if the
+// engine has not been started. The epoch timer wakes the main loop up at least once per epoch.
+func (e *ConsensusEngine) LastMainLoopTime() time.Time {
+	nanos := atomic.LoadInt64(&e.lastMainLoopTime)
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
 func (e *ConsensusEngine) mainLoop() {
 	defer e.wg.Done()
 
@@ -264,6 +283,7 @@ func (e *ConsensusEngine) mainLoop() {
 		e.propose()
 	Epoch:
 		for {
+			atomic.StoreInt64(&e.lastMainLoopTime, time.Now().UnixNano())
 			select {
 			case <-e.ctx.Done():
 				e.stopped = true
@@ -307,6 +327,8 @@ func (e *ConsensusEngine) mainLoop() {
 func (e *ConsensusEngine) enterEpoch() {
 	logger.Debugf("Enter epoch %v", e.GetEpoch())
 
+	e.evidencePool.Prune(e.GetEpoch())
+
 	// Reset timers.
 	if e.epochTimer != nil {
 		e.epochTimer.Stop()
@@ -357,6 +379,9 @@ func (e *ConsensusEngine) processMessage(msg interface{}) (endEpoch bool) {
 	case *core.AggregatedEENVotes:
 		// e.logger.WithFields(log.Fields{"aggregated elite edge node vote": m}).Debug("Received agggregated elite edge node vote")
 		e.handleAggregatedEliteEdgeNodeVote(m)
+	case *core.EquivocationEvidence:
+		e.logger.WithFields(log.Fields{"evidence": m}).Debug("Received equivocation evidence")
+		e.handleEvidence(m)
 	default:
 		// Should not happen.
 		log.Errorf("Unknown message type: %v", m)
@@ -721,6 +746,9 @@ func (e *ConsensusEngine) handleNormalBlock(eb *core.ExtendedBlock) {
 		}).Debug("Ignore processed block")
 		return
 	}
+	if evidence := e.evidencePool.CheckProposal(block.BlockHeader); evidence != nil {
+		e.handleEvidence(evidence)
+	}
 	parent, err := e.chain.FindBlock(block.Parent)
 	if err != nil {
 		// Should not happen since netsync layer ensures order of blocks.
@@ -790,6 +818,12 @@ func (e *ConsensusEngine) handleNormalBlock(eb *core.ExtendedBlock) {
 
 	e.chain.MarkBlockValid(block.Hash())
 
+	select {
+	case e.newBlocks <- block:
+	default:
+		e.logger.Debugf("Failed to notify new block, height=%v", block.Height)
+	}
+
 	// Skip voting for block older than current best known epoch.
 	// Allow block with one epoch behind since votes are processed first and might advance epoch
 	// before block is processed.
@@ -919,6 +953,9 @@ func (e *ConsensusEngine) handleVote(vote core.Vote) (endEpoch bool) {
 	if !e.validateVote(vote) {
 		return
 	}
+	if evidence := e.evidencePool.CheckVote(vote); evidence != nil {
+		e.handleEvidence(evidence)
+	}
 
 	// Save vote.
 	err := e.state.AddVote(&vote)
@@ -1092,11 +1129,59 @@ func (e *ConsensusEngine) broadcastAggregatedEliteEdgeNodeVotes(vote *core.Aggre
 	e.dispatcher.SendData([]string{}, voteMsg)
 }
 
+// handleEvidence verifies the equivocation evidence, and adds it to the evidence pool and gossips
+// it to the peers if it is new.
+func (e *ConsensusEngine) handleEvidence(evidence *core.EquivocationEvidence) {
+	if res := evidence.Validate(e.chain.ChainID); res.IsError() {
+		e.logger.WithFields(log.Fields{
+			"evidence": evidence,
+			"err":      res.String(),
+		}).Warn("Ignoring invalid equivocation evidence")
+		return
+	}
+
+	// Only the evidences against the current validators are accepted to prevent spamming.
+	lfb := e.state.GetLastFinalizedBlock()
+	if !e.shouldVoteByID(evidence.Offender(), lfb.Hash()) {
+		e.logger.WithFields(log.Fields{"evidence": evidence}).Debug("Ignoring equivocation evidence against non-validator")
+		return
+	}
+
+	if !e.evidencePool.AddEvidence(evidence) {
+		return
+	}
+	e.logger.WithFields(log.Fields{"evidence": evidence}).Warn("Validator equivocation detected")
+	e.broadcastEvidence(evidence)
+}
+
+func (e *ConsensusEngine) broadcastEvidence(evidence *core.EquivocationEvidence) {
+	payload, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		e.logger.WithFields(log.Fields{"evidence": evidence}).Error("Failed to encode evidence")
+		return
+	}
+	evidenceMsg := dispatcher.DataResponse{
+		ChannelID: common.ChannelIDEvidence,
+		Payload:   payload,
+	}
+	e.dispatcher.SendData([]string{}, evidenceMsg)
+}
+
+// GetPendingEvidence returns the equivocation evidences that are waiting to be included in a block.
+func (e *ConsensusEngine) GetPendingEvidence() []*core.EquivocationEvidence {
+	return e.evidencePool.GetPending()
+}
+
 // GetSummary returns a summary of consensus state.
 func (e *ConsensusEngine) GetSummary() *StateStub {
 	return e.state.GetSummary()
 }
 
+// NewBlocks returns a channel that will be published with blocks that have been validated and applied by the engine.
+func (e *ConsensusEngine) NewBlocks() chan *core.Block {
+	return e.newBlocks
+}
+
 // FinalizedBlocks returns a channel that will be published with finalized blocks by the engine.
 func (e *ConsensusEngine) FinalizedBlocks() chan *core.Block {
 	return e.finalizedBlocks
@@ -1156,6 +1241,7 @@ func (e *ConsensusEngine) finalizeBlock(block *core.ExtendedBlock) error {
 	// Force update TX index on block finalization so that the index doesn't point to
 	// duplicate TX in fork.
 	e.chain.AddTxsToIndex(block, true)
+	e.chain.AddLogsToIndex(block)
 
 	// Guardians and Elite Edge Nodes to vote for checkpoint blocks.
 	if common.IsCheckPointHeight(block.Height) {
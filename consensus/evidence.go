@@ -0,0 +1,191 @@
+package consensus
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/store"
+)
+
+const (
+	DBPendingEvidenceKey = "cs/pe"
+
+	// EvidenceMaxAge is the number of epochs for which the votes and proposals are kept for the
+	// equivocation detection, and for which the pending evidences are kept for inclusion in a block.
+	EvidenceMaxAge = uint64(1000)
+)
+
+type voteRecordKey struct {
+	id     common.Address
+	epoch  uint64
+	height uint64
+}
+
+type proposalRecordKey struct {
+	proposer common.Address
+	epoch    uint64
+}
+
+//
+// EvidencePool records the recent votes and block proposals to detect the validators that
+// equivocate, and keeps the resulting evidences until they expire.
+//
+type EvidencePool struct {
+	mu *sync.Mutex
+
+	db    store.Store
+	chain *blockchain.Chain
+
+	votes     map[voteRecordKey]core.Vote
+	proposals map[proposalRecordKey]*core.BlockHeader
+	pending   map[common.Hash]*core.EquivocationEvidence
+}
+
+// NewEvidencePool creates a new instance of EvidencePool, and loads the pending evidences from the db.
+func NewEvidencePool(db store.Store, chain *blockchain.Chain) *EvidencePool {
+	ep := &EvidencePool{
+		mu:        &sync.Mutex{},
+		db:        db,
+		chain:     chain,
+		votes:     make(map[voteRecordKey]core.Vote),
+		proposals: make(map[proposalRecordKey]*core.BlockHeader),
+		pending:   make(map[common.Hash]*core.EquivocationEvidence),
+	}
+
+	evidences := []*core.EquivocationEvidence{}
+	if err := db.Get([]byte(DBPendingEvidenceKey), &evidences); err == nil {
+		for _, evidence := range evidences {
+			ep.pending[evidence.Hash()] = evidence
+		}
+	}
+	return ep
+}
+
+// CheckVote records the given vote, and returns the evidence if the validator has voted for a
+// different block of the same height in the same epoch. The vote should have been validated.
+func (ep *EvidencePool) CheckVote(vote core.Vote) *core.EquivocationEvidence {
+	// The vote height is not signed, so only the votes for known blocks are checked.
+	block, err := ep.chain.FindBlock(vote.Block)
+	if err != nil {
+		return nil
+	}
+
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	key := voteRecordKey{id: vote.ID, epoch: vote.Epoch, height: block.Height}
+	prev, ok := ep.votes[key]
+	if !ok {
+		ep.votes[key] = vote
+		return nil
+	}
+	if prev.Block == vote.Block {
+		return nil
+	}
+	prevBlock, err := ep.chain.FindBlock(prev.Block)
+	if err != nil {
+		return nil
+	}
+	return core.NewDoubleVoteEvidence(prev, prevBlock.BlockHeader, vote, block.BlockHeader)
+}
+
+// CheckProposal records the given block, and returns the evidence if its proposer has proposed a
+// different block in the same epoch.
+func (ep *EvidencePool) CheckProposal(header *core.BlockHeader) *core.EquivocationEvidence {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	key := proposalRecordKey{proposer: header.Proposer, epoch: header.Epoch}
+	prev, ok := ep.proposals[key]
+	if !ok {
+		ep.proposals[key] = header
+		return nil
+	}
+	if prev.Hash() == header.Hash() {
+		return nil
+	}
+	return core.NewDoubleProposalEvidence(prev, header)
+}
+
+// AddEvidence adds the evidence to the pending evidences. It returns false if the evidence is already known.
+func (ep *EvidencePool) AddEvidence(evidence *core.EquivocationEvidence) bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	hash := evidence.Hash()
+	if _, ok := ep.pending[hash]; ok {
+		return false
+	}
+	ep.pending[hash] = evidence
+	ep.commit()
+	return true
+}
+
+// GetPending returns the pending evidences ordered by hash.
+func (ep *EvidencePool) GetPending() []*core.EquivocationEvidence {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	return ep.getPending()
+}
+
+func (ep *EvidencePool) getPending() []*core.EquivocationEvidence {
+	hashes := make([]common.Hash, 0, len(ep.pending))
+	for hash := range ep.pending {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i].Bytes(), hashes[j].Bytes()) < 0
+	})
+
+	ret := make([]*core.EquivocationEvidence, 0, len(hashes))
+	for _, hash := range hashes {
+		ret = append(ret, ep.pending[hash])
+	}
+	return ret
+}
+
+// Prune removes the votes, proposals and pending evidences older than EvidenceMaxAge epochs.
+func (ep *EvidencePool) Prune(currentEpoch uint64) {
+	if currentEpoch <= EvidenceMaxAge {
+		return
+	}
+	minEpoch := currentEpoch - EvidenceMaxAge
+
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	for key := range ep.votes {
+		if key.epoch < minEpoch {
+			delete(ep.votes, key)
+		}
+	}
+	for key := range ep.proposals {
+		if key.epoch < minEpoch {
+			delete(ep.proposals, key)
+		}
+	}
+
+	pruned := false
+	for hash, evidence := range ep.pending {
+		if evidence.Epoch() < minEpoch {
+			delete(ep.pending, hash)
+			pruned = true
+		}
+	}
+	if pruned {
+		ep.commit()
+	}
+}
+
+func (ep *EvidencePool) commit() {
+	err := ep.db.Put([]byte(DBPendingEvidenceKey), ep.getPending())
+	if err != nil {
+		logger.WithFields(log.Fields{"error": err}).Error("Failed to save pending evidences")
+	}
+}
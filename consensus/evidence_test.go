@@ -0,0 +1,183 @@
+package consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/store/database/backend"
+	"github.com/pandotoken/pando/store/kvstore"
+)
+
+func createSignedTestVote(privKey *crypto.PrivateKey, block common.Hash, epoch uint64) core.Vote {
+	vote := core.Vote{
+		Block: block,
+		ID:    privKey.PublicKey().Address(),
+		Epoch: epoch,
+	}
+	vote.Sign(privKey)
+	return vote
+}
+
+func createSignedTestHeader(privKey *crypto.PrivateKey, parent *core.Block, epoch uint64, stateHash string) *core.BlockHeader {
+	header := &core.BlockHeader{
+		ChainID:   "testchain",
+		Epoch:     epoch,
+		Height:    parent.Height + 1,
+		Parent:    parent.Hash(),
+		StateHash: common.HexToHash(stateHash),
+		Timestamp: big.NewInt(1),
+		Proposer:  privKey.PublicKey().Address(),
+	}
+	header.HCC.BlockHash = parent.Hash()
+	header.Signature, _ = privKey.Sign(header.SignBytes())
+	return header
+}
+
+func TestEvidencePoolDoubleVote(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	core.ResetTestBlocks()
+
+	db := kvstore.NewKVStore(backend.NewMemDatabase())
+	chain := blockchain.CreateTestChainByBlocks([]string{
+		"A1", "A0",
+		"B1", "A0",
+		"A2", "A1",
+	})
+	a1 := core.GetTestBlock("A1")
+	b1 := core.GetTestBlock("B1")
+	a2 := core.GetTestBlock("A2")
+
+	privKey, _, _ := crypto.GenerateKeyPair()
+	ep := NewEvidencePool(db, chain)
+
+	assert.Nil(ep.CheckVote(createSignedTestVote(privKey, a1.Hash(), 5)))
+	assert.Nil(ep.CheckVote(createSignedTestVote(privKey, a1.Hash(), 5)))
+
+	// Voting for blocks of different heights in the same epoch is not an equivocation
+	assert.Nil(ep.CheckVote(createSignedTestVote(privKey, a2.Hash(), 5)))
+
+	// Voting for a different block of the same height in a different epoch is not an equivocation either
+	assert.Nil(ep.CheckVote(createSignedTestVote(privKey, b1.Hash(), 6)))
+
+	// Votes for unknown blocks are ignored
+	assert.Nil(ep.CheckVote(createSignedTestVote(privKey, common.HexToHash("abc"), 5)))
+
+	evidence := ep.CheckVote(createSignedTestVote(privKey, b1.Hash(), 5))
+	require.NotNil(evidence)
+	assert.Equal(core.EquivocationDoubleVote, evidence.Type)
+	assert.Equal(privKey.PublicKey().Address(), evidence.Offender())
+	assert.Equal(uint64(5), evidence.Epoch())
+	assert.True(evidence.Validate("testchain").IsOK())
+	assert.True(evidence.Validate("otherchain").IsError())
+
+	// The evidence survives the RLP encoding
+	decoded, err := core.EquivocationEvidenceFromBytes(evidence.Bytes())
+	require.Nil(err)
+	assert.Equal(evidence.Hash(), decoded.Hash())
+	assert.True(decoded.Validate("testchain").IsOK())
+
+	// The evidence is the same regardless of the order in which the votes are seen
+	ep2 := NewEvidencePool(kvstore.NewKVStore(backend.NewMemDatabase()), chain)
+	assert.Nil(ep2.CheckVote(createSignedTestVote(privKey, b1.Hash(), 5)))
+	evidence2 := ep2.CheckVote(createSignedTestVote(privKey, a1.Hash(), 5))
+	require.NotNil(evidence2)
+	assert.Equal(evidence.Hash(), evidence2.Hash())
+
+	// Tampered evidences are rejected
+	otherKey, _, _ := crypto.GenerateKeyPair()
+	tampered, _ := core.EquivocationEvidenceFromBytes(evidence.Bytes())
+	otherVote := createSignedTestVote(otherKey, tampered.VoteB.Block, 5)
+	tampered.VoteB = &otherVote
+	assert.True(tampered.Validate("testchain").IsError())
+
+	tampered, _ = core.EquivocationEvidenceFromBytes(evidence.Bytes())
+	tampered.VoteB.Epoch = 6
+	assert.True(tampered.Validate("testchain").IsError())
+
+	tampered, _ = core.EquivocationEvidenceFromBytes(evidence.Bytes())
+	tampered.HeaderA, tampered.HeaderB = tampered.HeaderB, tampered.HeaderA
+	tampered.VoteA, tampered.VoteB = tampered.VoteB, tampered.VoteA
+	assert.True(tampered.Validate("testchain").IsError())
+}
+
+func TestEvidencePoolDoubleProposal(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	core.ResetTestBlocks()
+
+	db := kvstore.NewKVStore(backend.NewMemDatabase())
+	chain := blockchain.CreateTestChainByBlocks([]string{
+		"A1", "A0",
+	})
+	a1 := core.GetTestBlock("A1")
+
+	privKey, _, _ := crypto.GenerateKeyPair()
+	ep := NewEvidencePool(db, chain)
+
+	header1 := createSignedTestHeader(privKey, a1, 10, "aa")
+	header2 := createSignedTestHeader(privKey, a1, 10, "bb")
+	header3 := createSignedTestHeader(privKey, a1, 11, "cc")
+
+	assert.Nil(ep.CheckProposal(header1))
+	assert.Nil(ep.CheckProposal(header1))
+	assert.Nil(ep.CheckProposal(header3))
+
+	evidence := ep.CheckProposal(header2)
+	require.NotNil(evidence)
+	assert.Equal(core.EquivocationDoubleProposal, evidence.Type)
+	assert.Equal(privKey.PublicKey().Address(), evidence.Offender())
+	assert.Equal(uint64(10), evidence.Epoch())
+	assert.True(evidence.Validate("testchain").IsOK())
+
+	// Unsigned proposals are not a proof of equivocation
+	unsigned := createSignedTestHeader(privKey, a1, 10, "dd")
+	otherKey, _, _ := crypto.GenerateKeyPair()
+	unsigned.Signature, _ = otherKey.Sign(unsigned.SignBytes())
+	assert.True(core.NewDoubleProposalEvidence(header1, unsigned).Validate("testchain").IsError())
+}
+
+func TestEvidencePoolPending(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	core.ResetTestBlocks()
+
+	db := kvstore.NewKVStore(backend.NewMemDatabase())
+	chain := blockchain.CreateTestChainByBlocks([]string{
+		"A1", "A0",
+	})
+	a1 := core.GetTestBlock("A1")
+
+	privKey, _, _ := crypto.GenerateKeyPair()
+	ep := NewEvidencePool(db, chain)
+
+	evidence1 := core.NewDoubleProposalEvidence(
+		createSignedTestHeader(privKey, a1, 10, "aa"), createSignedTestHeader(privKey, a1, 10, "bb"))
+	evidence2 := core.NewDoubleProposalEvidence(
+		createSignedTestHeader(privKey, a1, 2000, "aa"), createSignedTestHeader(privKey, a1, 2000, "bb"))
+
+	assert.True(ep.AddEvidence(evidence1))
+	assert.False(ep.AddEvidence(evidence1))
+	assert.True(ep.AddEvidence(evidence2))
+	assert.Equal(2, len(ep.GetPending()))
+
+	// Pending evidences are persisted
+	ep2 := NewEvidencePool(db, chain)
+	pending := ep2.GetPending()
+	require.Equal(2, len(pending))
+	assert.Equal(ep.GetPending()[0].Hash(), pending[0].Hash())
+	assert.Equal(ep.GetPending()[1].Hash(), pending[1].Hash())
+
+	// Outdated evidences are pruned
+	ep2.Prune(2500)
+	pending = ep2.GetPending()
+	require.Equal(1, len(pending))
+	assert.Equal(evidence2.Hash(), pending[0].Hash())
+	assert.Equal(1, len(NewEvidencePool(db, chain).GetPending()))
+}
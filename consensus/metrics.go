@@ -0,0 +1,30 @@
+package consensus
+
+import (
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/metrics"
+)
+
+// registerMetrics exposes the consensus progress of the engine as gauges.
+func (e *ConsensusEngine) registerMetrics() {
+	metrics.NewRegisteredFunctionalGauge("consensus/epoch", nil, func() int64 {
+		return int64(e.GetSummary().Epoch)
+	})
+	metrics.NewRegisteredFunctionalGauge("consensus/finalized_height", nil, func() int64 {
+		return e.heightOf(e.GetSummary().LastFinalizedBlock)
+	})
+	metrics.NewRegisteredFunctionalGauge("consensus/highest_cc_height", nil, func() int64 {
+		return e.heightOf(e.GetSummary().HighestCCBlock)
+	})
+	metrics.NewRegisteredFunctionalGauge("consensus/pending_evidences", nil, func() int64 {
+		return int64(len(e.evidencePool.GetPending()))
+	})
+}
+
+func (e *ConsensusEngine) heightOf(hash common.Hash) int64 {
+	block, err := e.chain.FindBlock(hash)
+	if err != nil {
+		return 0
+	}
+	return int64(block.Height)
+}
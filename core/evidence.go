@@ -0,0 +1,182 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/result"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/rlp"
+)
+
+// EquivocationType specifies the kind of misbehavior an EquivocationEvidence proves.
+type EquivocationType byte
+
+const (
+	// EquivocationDoubleVote means the validator voted for two different blocks of the same height in the same epoch.
+	EquivocationDoubleVote EquivocationType = EquivocationType(iota + 1)
+
+	// EquivocationDoubleProposal means the validator proposed two different blocks in the same epoch.
+	EquivocationDoubleProposal
+)
+
+func (t EquivocationType) String() string {
+	switch t {
+	case EquivocationDoubleVote:
+		return "DoubleVote"
+	case EquivocationDoubleProposal:
+		return "DoubleProposal"
+	default:
+		return fmt.Sprintf("Unknown(%d)", byte(t))
+	}
+}
+
+// EquivocationEvidence proves that a validator signed two conflicting consensus messages. It is
+// self-contained, i.e. it can be verified without access to the blocks it refers to.
+//
+// For a double vote, HeaderA and HeaderB are the headers of the blocks voted by VoteA and VoteB.
+// They are needed since the vote signature does not cover the block height. For a double proposal,
+// HeaderA and HeaderB are the two conflicting blocks signed by the proposer, and the votes are nil.
+// The two sides are ordered by block hash, so each equivocation has exactly one valid evidence.
+type EquivocationEvidence struct {
+	Type    EquivocationType
+	VoteA   *Vote `rlp:"nil"`
+	VoteB   *Vote `rlp:"nil"`
+	HeaderA *BlockHeader
+	HeaderB *BlockHeader
+}
+
+// NewDoubleVoteEvidence creates the evidence of two conflicting votes. The headers are the
+// headers of the blocks the votes are for.
+func NewDoubleVoteEvidence(voteA Vote, headerA *BlockHeader, voteB Vote, headerB *BlockHeader) *EquivocationEvidence {
+	if bytes.Compare(headerA.Hash().Bytes(), headerB.Hash().Bytes()) > 0 {
+		voteA, voteB = voteB, voteA
+		headerA, headerB = headerB, headerA
+	}
+	return &EquivocationEvidence{
+		Type:    EquivocationDoubleVote,
+		VoteA:   &voteA,
+		VoteB:   &voteB,
+		HeaderA: headerA,
+		HeaderB: headerB,
+	}
+}
+
+// NewDoubleProposalEvidence creates the evidence of two conflicting block proposals.
+func NewDoubleProposalEvidence(headerA *BlockHeader, headerB *BlockHeader) *EquivocationEvidence {
+	if bytes.Compare(headerA.Hash().Bytes(), headerB.Hash().Bytes()) > 0 {
+		headerA, headerB = headerB, headerA
+	}
+	return &EquivocationEvidence{
+		Type:    EquivocationDoubleProposal,
+		HeaderA: headerA,
+		HeaderB: headerB,
+	}
+}
+
+// EquivocationEvidenceFromBytes decodes an RLP encoded evidence.
+func EquivocationEvidenceFromBytes(raw common.Bytes) (*EquivocationEvidence, error) {
+	evidence := &EquivocationEvidence{}
+	if err := rlp.DecodeBytes(raw, evidence); err != nil {
+		return nil, err
+	}
+	return evidence, nil
+}
+
+// Bytes returns the RLP encoding of the evidence.
+func (ev *EquivocationEvidence) Bytes() common.Bytes {
+	raw, _ := rlp.EncodeToBytes(ev)
+	return raw
+}
+
+// Hash calculates the evidence's hash.
+func (ev *EquivocationEvidence) Hash() common.Hash {
+	return crypto.Keccak256Hash(ev.Bytes())
+}
+
+// Offender returns the address of the equivocating validator.
+func (ev *EquivocationEvidence) Offender() common.Address {
+	if ev.Type == EquivocationDoubleVote && ev.VoteA != nil {
+		return ev.VoteA.ID
+	}
+	if ev.HeaderA != nil {
+		return ev.HeaderA.Proposer
+	}
+	return common.Address{}
+}
+
+// Epoch returns the epoch in which the validator equivocated.
+func (ev *EquivocationEvidence) Epoch() uint64 {
+	if ev.Type == EquivocationDoubleVote && ev.VoteA != nil {
+		return ev.VoteA.Epoch
+	}
+	if ev.HeaderA != nil {
+		return ev.HeaderA.Epoch
+	}
+	return 0
+}
+
+func (ev *EquivocationEvidence) String() string {
+	return fmt.Sprintf("EquivocationEvidence{Type: %v, Offender: %v, Epoch: %v, BlockA: %v, BlockB: %v}",
+		ev.Type, ev.Offender(), ev.Epoch(), ev.HeaderA.Hash().Hex(), ev.HeaderB.Hash().Hex())
+}
+
+// Validate checks the evidence proves an equivocation on the given chain.
+func (ev *EquivocationEvidence) Validate(chainID string) result.Result {
+	if ev.HeaderA == nil || ev.HeaderB == nil {
+		return result.Error("Block headers are not specified")
+	}
+	hashA := ev.HeaderA.Hash()
+	hashB := ev.HeaderB.Hash()
+	if bytes.Compare(hashA.Bytes(), hashB.Bytes()) >= 0 {
+		return result.Error("Block headers must be distinct and ordered by hash")
+	}
+	if ev.HeaderA.ChainID != chainID || ev.HeaderB.ChainID != chainID {
+		return result.Error("ChainID mismatch")
+	}
+
+	switch ev.Type {
+	case EquivocationDoubleVote:
+		if ev.VoteA == nil || ev.VoteB == nil {
+			return result.Error("Votes are not specified")
+		}
+		if res := ev.VoteA.Validate(); res.IsError() {
+			return res
+		}
+		if res := ev.VoteB.Validate(); res.IsError() {
+			return res
+		}
+		if ev.VoteA.ID != ev.VoteB.ID {
+			return result.Error("Votes are signed by different validators")
+		}
+		if ev.VoteA.Epoch != ev.VoteB.Epoch {
+			return result.Error("Votes are in different epochs")
+		}
+		if ev.VoteA.Block != hashA || ev.VoteB.Block != hashB {
+			return result.Error("Votes do not match the block headers")
+		}
+		if ev.HeaderA.Height != ev.HeaderB.Height {
+			return result.Error("Voted blocks are at different heights")
+		}
+	case EquivocationDoubleProposal:
+		if ev.VoteA != nil || ev.VoteB != nil {
+			return result.Error("Votes should not be specified for a double proposal")
+		}
+		if res := ev.HeaderA.Validate(chainID); res.IsError() {
+			return res
+		}
+		if res := ev.HeaderB.Validate(chainID); res.IsError() {
+			return res
+		}
+		if ev.HeaderA.Proposer != ev.HeaderB.Proposer {
+			return result.Error("Blocks are proposed by different validators")
+		}
+		if ev.HeaderA.Epoch != ev.HeaderB.Epoch {
+			return result.Error("Blocks are proposed in different epochs")
+		}
+	default:
+		return result.Error("Unknown equivocation type: %v", ev.Type)
+	}
+	return result.OK
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 
 	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/crypto"
 	"github.com/pandotoken/pando/rlp"
 )
 
@@ -60,6 +61,47 @@ type LastCheckpoint struct {
 	IntermediateHeaders []*BlockHeader
 }
 
+// SnapshotTriePosition locates a node of a chunked snapshot: the index of the trie in
+// SnapshotManifest.Tries, and the path of the node within the trie.
+type SnapshotTriePosition struct {
+	Trie uint64
+	Path common.Bytes
+}
+
+// SnapshotChunkInfo describes a chunk of a chunked snapshot. A chunk holds the trie nodes
+// between Start and End (both inclusive) in the export order.
+type SnapshotChunkInfo struct {
+	Start      SnapshotTriePosition
+	End        SnapshotTriePosition
+	NumRecords uint64
+	Size       uint64      // size of the chunk file in bytes
+	Hash       common.Hash // keccak256 hash of the chunk file
+}
+
+// SnapshotManifest lists the chunks of a chunked snapshot. The StateHash has to match the state
+// hash of the snapshot block, so the manifest is committed to by the votes on the snapshot block.
+// For a delta snapshot, BaseHeight and BaseStateHash identify the snapshot the delta applies to,
+// and the chunks only hold the trie nodes not present in the base state.
+type SnapshotManifest struct {
+	Height        uint64
+	StateHash     common.Hash
+	BaseHeight    uint64
+	BaseStateHash common.Hash
+	Tries         []common.Hash // roots of the tries the chunks are exported from
+	Chunks        []SnapshotChunkInfo
+}
+
+// IsDelta returns whether the manifest is for a delta snapshot.
+func (m *SnapshotManifest) IsDelta() bool {
+	return !m.BaseStateHash.IsEmpty()
+}
+
+// Hash calculates the hash of the manifest.
+func (m *SnapshotManifest) Hash() common.Hash {
+	raw, _ := rlp.EncodeToBytes(*m)
+	return crypto.Keccak256Hash(raw)
+}
+
 func WriteSnapshotHeader(writer *bufio.Writer, snapshotHeader *SnapshotHeader) error {
 	raw, err := rlp.EncodeToBytes(*snapshotHeader)
 	if err != nil {
@@ -90,6 +132,16 @@ func WriteMetadata(writer *bufio.Writer, metadata *SnapshotMetadata) error {
 	return err
 }
 
+func WriteManifest(writer *bufio.Writer, manifest *SnapshotManifest) error {
+	raw, err := rlp.EncodeToBytes(*manifest)
+	if err != nil {
+		logger.Errorf("Failed to encode manifest: %v", err)
+		return err
+	}
+	err = writeBytes(writer, raw)
+	return err
+}
+
 func WriteRecord(writer *bufio.Writer, k, v common.Bytes) error {
 	record := SnapshotTrieRecord{K: k, V: v}
 	raw, err := rlp.EncodeToBytes(record)
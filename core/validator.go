@@ -255,6 +255,18 @@ func (vcp *ValidatorCandidatePool) WithdrawStake(source common.Address, holder c
 	return nil
 }
 
+// SlashStakeHolder removes the stake holder and all its stakes, including the withdrawn stakes which
+// have not been returned yet, from the pool. The slashed stakes are burned.
+func (vcp *ValidatorCandidatePool) SlashStakeHolder(holder common.Address) (*StakeHolder, error) {
+	for idx, candidate := range vcp.SortedCandidates {
+		if candidate.Holder == holder {
+			vcp.SortedCandidates = append(vcp.SortedCandidates[:idx], vcp.SortedCandidates[idx+1:]...)
+			return candidate, nil
+		}
+	}
+	return nil, fmt.Errorf("No matched stake holder address found: %v", holder)
+}
+
 func (vcp *ValidatorCandidatePool) ReturnStakes(currentHeight uint64) []*Stake {
 	returnedStakes := []*Stake{}
 
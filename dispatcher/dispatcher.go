@@ -2,7 +2,9 @@ package dispatcher
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 
 	"github.com/spf13/viper"
@@ -180,6 +182,67 @@ func (dp *Dispatcher) PeerExists(peerID string) bool {
 	return false
 }
 
+// peerManager is implemented by the networks which can connect to and disconnect from peers
+// on demand.
+type peerManager interface {
+	ConnectPeer(address string) (string, error)
+	DisconnectPeer(peerID string) error
+}
+
+// peerLimiter is implemented by the networks which can apply a new max number of peers.
+type peerLimiter interface {
+	LimitPeers(maxNumPeers int)
+}
+
+// ConnectPeer connects to the peer at the given address, and returns the ID of the peer.
+// Multiaddresses, e.g. /ip4/1.2.3.4/tcp/15000/p2p/<peer ID>, are handled by the libp2p
+// network, and "ip:port" addresses by the other one.
+func (dp *Dispatcher) ConnectPeer(address string) (string, error) {
+	var network interface{}
+	if strings.HasPrefix(address, "/") {
+		if !reflect.ValueOf(dp.p2plnet).IsNil() {
+			network = dp.p2plnet
+		}
+	} else if !reflect.ValueOf(dp.p2pnet).IsNil() {
+		network = dp.p2pnet
+	}
+	pm, ok := network.(peerManager)
+	if !ok {
+		return "", fmt.Errorf("No network can connect to %v", address)
+	}
+	return pm.ConnectPeer(address)
+}
+
+// DisconnectPeer disconnects from the given neighboring peer.
+func (dp *Dispatcher) DisconnectPeer(peerID string) error {
+	if !reflect.ValueOf(dp.p2pnet).IsNil() && dp.p2pnet.PeerExists(peerID) {
+		if pm, ok := dp.p2pnet.(peerManager); ok {
+			return pm.DisconnectPeer(peerID)
+		}
+	}
+	if !reflect.ValueOf(dp.p2plnet).IsNil() && dp.p2plnet.PeerExists(peerID) {
+		if pm, ok := dp.p2plnet.(peerManager); ok {
+			return pm.DisconnectPeer(peerID)
+		}
+	}
+	return fmt.Errorf("Peer %v is not connected", peerID)
+}
+
+// LimitPeers disconnects from peers until the number of peers of each network is within the
+// given limit.
+func (dp *Dispatcher) LimitPeers(maxNumPeers int) {
+	if !reflect.ValueOf(dp.p2pnet).IsNil() {
+		if pl, ok := dp.p2pnet.(peerLimiter); ok {
+			pl.LimitPeers(maxNumPeers)
+		}
+	}
+	if !reflect.ValueOf(dp.p2plnet).IsNil() {
+		if pl, ok := dp.p2plnet.(peerLimiter); ok {
+			pl.LimitPeers(maxNumPeers)
+		}
+	}
+}
+
 // send delivers message directly to a list of peers.
 func (dp *Dispatcher) send(peerIDs []string, channelID common.ChannelIDEnum, content interface{}) {
 	messageOld := p2ptypes.Message{
@@ -34,8 +34,8 @@ type Executor struct {
 	valMgr    core.ValidatorManager
 	ledger    core.Ledger
 
-	coinbaseTxExec *CoinbaseTxExecutor
-	// slashTxExec          *SlashTxExecutor
+	coinbaseTxExec                *CoinbaseTxExecutor
+	slashTxExec                   *SlashTxExecutor
 	sendTxExec                    *SendTxExecutor
     rametronStakeTxExec           *RametronStakeTxExecutor
 	withdrawrametronStakeTxExec   *WithdrawRametronStakeTxExecutor
@@ -60,7 +60,7 @@ func NewExecutor(db database.Database, chain *blockchain.Chain, state *st.Ledger
 		consensus:      consensus,
 		valMgr:         valMgr,
 		coinbaseTxExec: NewCoinbaseTxExecutor(db, chain, state, consensus, valMgr),
-		// slashTxExec:          NewSlashTxExecutor(consensus, valMgr),
+		slashTxExec:                   NewSlashTxExecutor(consensus, valMgr),
 		sendTxExec:                    NewSendTxExecutor(state),
 		rametronStakeTxExec:           NewRametronStakeTxExecutor(state),
 		withdrawrametronStakeTxExec:   NewWithdrawRametronStakeTxExecutor(state),
@@ -99,6 +99,39 @@ func (exec *Executor) ScreenTx(tx types.Tx) (common.Hash, result.Result) {
 	return exec.processTx(tx, core.ScreenedView)
 }
 
+// ScreenReplacementTx checks the validity of the given transaction, which replaces a screened transaction
+// with the same sequence number. Since the screened view already reflects the screened transaction, the
+// check is done on a copy of the screened view with the sequence of the account rolled back. The balance
+// is not rolled back, so the account needs to afford both transactions. The screened view is not updated.
+func (exec *Executor) ScreenReplacementTx(tx types.Tx) result.Result {
+	txInfo, res := exec.GetTxInfo(tx)
+	if res.IsError() {
+		return res
+	}
+	if txInfo == nil || txInfo.Sequence == 0 {
+		return result.Error("Transaction cannot be replaced")
+	}
+
+	committed := exec.state.Delivered().GetAccount(txInfo.Address)
+	if committed != nil && committed.Sequence >= txInfo.Sequence {
+		return result.Error("Transaction with sequence %v has already been committed", txInfo.Sequence).
+			WithErrorCode(result.CodeInvalidSequence)
+	}
+
+	view, err := exec.state.Screened().Copy()
+	if err != nil {
+		return result.Error("Failed to copy the screened view: %v", err)
+	}
+	account := view.GetAccount(txInfo.Address)
+	if account == nil || account.Sequence < txInfo.Sequence {
+		return result.Error("No screened transaction to replace").WithErrorCode(result.CodeInvalidSequence)
+	}
+	account.Sequence = txInfo.Sequence - 1
+	view.SetAccount(txInfo.Address, account)
+
+	return exec.sanityCheck(exec.state.GetChainID(), view, core.ScreenedView, tx)
+}
+
 // GetTxInfo extracts tx information used by mempool to sort Txs.
 func (exec *Executor) GetTxInfo(tx types.Tx) (*core.TxInfo, result.Result) {
 	txExecutor := exec.getTxExecutor(tx)
@@ -185,6 +218,10 @@ func (exec *Executor) isTxTypeSupported(view *st.StoreView, tx types.Tx) bool {
 		if blockHeight < common.HeightEnablePando3 {
 			return false
 		}
+	case *types.SlashTx:
+		if blockHeight < common.HeightEnableValidatorSlashing {
+			return false
+		}
 	default:
 		return true
 	}
@@ -197,8 +234,8 @@ func (exec *Executor) getTxExecutor(tx types.Tx) TxExecutor {
 	switch tx.(type) {
 	case *types.CoinbaseTx:
 		txExecutor = exec.coinbaseTxExec
-	// case *types.SlashTx:
-	// 	txExecutor = exec.slashTxExec
+	case *types.SlashTx:
+		txExecutor = exec.slashTxExec
 	case *types.SendTx:
 		txExecutor = exec.sendTxExec
 	case *types.RametronStakeTx:
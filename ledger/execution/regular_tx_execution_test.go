@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/pandotoken/pando/common"
 	"github.com/pandotoken/pando/common/result"
+	"github.com/pandotoken/pando/core"
 	"github.com/pandotoken/pando/ledger/types"
 )
 
@@ -504,7 +505,7 @@ func TestWithdrawRametronStakeDuplicatedInputOutput(t *testing.T) {
 // 	}
 // 	tx.Proposer.Signature = va1.Sign(tx.SignBytes(et.chainID))
 
-// 	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	assert.True(res.IsOK(), res.String())
 
 // 	// Pando should never inflate
@@ -518,7 +519,7 @@ func TestWithdrawRametronStakeDuplicatedInputOutput(t *testing.T) {
 // 		}},
 // 		BlockHeight: 1e7,
 // 	}
-// 	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	assert.True(res.IsError(), res.String())
 
 // 	// For the initial Mainnet release, PTX should not inflate
@@ -532,7 +533,7 @@ func TestWithdrawRametronStakeDuplicatedInputOutput(t *testing.T) {
 // 		}},
 // 		BlockHeight: 1e7,
 // 	}
-// 	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	assert.True(res.IsError(), res.String())
 
 // 	// //Error if reward Pando amount is incorrect
@@ -546,7 +547,7 @@ func TestWithdrawRametronStakeDuplicatedInputOutput(t *testing.T) {
 // 	// 	}},
 // 	// 	BlockHeight: 1e7,
 // 	// }
-// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	// assert.True(res.IsError(), res.String())
 
 // 	// //Error if reward PTX amount is incorrect
@@ -560,7 +561,7 @@ func TestWithdrawRametronStakeDuplicatedInputOutput(t *testing.T) {
 // 	// 	}},
 // 	// 	BlockHeight: 1e7,
 // 	// }
-// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	// assert.True(res.IsError(), res.String())
 
 // 	// //Error if Validator 2 is not rewarded
@@ -572,7 +573,7 @@ func TestWithdrawRametronStakeDuplicatedInputOutput(t *testing.T) {
 // 	// 	}},
 // 	// 	BlockHeight: 1e7,
 // 	// }
-// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	// assert.True(res.IsError(), res.String())
 
 // 	// //Error if non-validator is rewarded
@@ -588,7 +589,7 @@ func TestWithdrawRametronStakeDuplicatedInputOutput(t *testing.T) {
 // 	// 	}},
 // 	// 	BlockHeight: 1e7,
 // 	// }
-// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	// assert.True(res.IsError(), res.String())
 
 // 	// //Error if validator address is changed
@@ -602,7 +603,7 @@ func TestWithdrawRametronStakeDuplicatedInputOutput(t *testing.T) {
 // 	// 	}},
 // 	// 	BlockHeight: 1e7,
 // 	// }
-// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	// assert.True(res.IsError(), res.String())
 
 // 	// //Process should update validator account
@@ -617,7 +618,7 @@ func TestWithdrawRametronStakeDuplicatedInputOutput(t *testing.T) {
 // 	// 	BlockHeight: 1e7,
 // 	// }
 
-// 	// _, res = et.executor.getTxExecutor(tx).process(et.chainID, et.state().Delivered(), tx)
+// 	// _, res = et.executor.getTxExecutor(tx).process(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	// assert.True(res.IsOK(), res.String())
 
 // 	// va1balance := et.state().Delivered().GetAccount(va1.Account.PubKey.Address()).Balance
@@ -665,7 +666,7 @@ func TestReserveFundTx(t *testing.T) {
 		Duration:    1000,
 	}
 	tx.Source.Signature = user1.Sign(tx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeReservedFundNotSpecified)
 
@@ -682,7 +683,7 @@ func TestReserveFundTx(t *testing.T) {
 		Duration:    1000,
 	}
 	tx.Source.Signature = user1.Sign(tx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeInsufficientFund)
 
@@ -699,7 +700,7 @@ func TestReserveFundTx(t *testing.T) {
 		Duration:    1000,
 	}
 	tx.Source.Signature = user1.Sign(tx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeReserveFundCheckFailed, res.Message)
 
@@ -716,9 +717,9 @@ func TestReserveFundTx(t *testing.T) {
 		Duration:    1000,
 	}
 	tx.Source.Signature = user1.Sign(tx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 	assert.True(res.IsOK(), res.String())
-	_, res = et.executor.getTxExecutor(tx).process(et.chainID, et.state().Delivered(), tx)
+	_, res = et.executor.getTxExecutor(tx).process(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 	assert.True(res.IsOK(), res.String())
 
 	retrievedUserAcc := et.state().Delivered().GetAccount(user1.Address)
@@ -757,9 +758,9 @@ func TestReleaseFundTx(t *testing.T) {
 		Duration:    1000,
 	}
 	reserveFundTx.Source.Signature = user1.Sign(reserveFundTx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(reserveFundTx).sanityCheck(et.chainID, et.state().Delivered(), reserveFundTx)
+	res = et.executor.getTxExecutor(reserveFundTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, reserveFundTx)
 	assert.True(res.IsOK(), res.String())
-	_, res = et.executor.getTxExecutor(reserveFundTx).process(et.chainID, et.state().Delivered(), reserveFundTx)
+	_, res = et.executor.getTxExecutor(reserveFundTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, reserveFundTx)
 	assert.True(res.IsOK(), res.String())
 
 	et.state().Commit()
@@ -774,7 +775,7 @@ func TestReleaseFundTx(t *testing.T) {
 		ReserveSequence: 1,
 	}
 	releaseFundTx.Source.Signature = user1.Sign(releaseFundTx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), releaseFundTx)
+	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, releaseFundTx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeInvalidFee, res.String())
 
@@ -787,7 +788,7 @@ func TestReleaseFundTx(t *testing.T) {
 		ReserveSequence: 1,
 	}
 	releaseFundTx.Source.Signature = user1.Sign(releaseFundTx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), releaseFundTx)
+	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, releaseFundTx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeInvalidFee, res.String())
 
@@ -801,7 +802,7 @@ func TestReleaseFundTx(t *testing.T) {
 		ReserveSequence: 1,
 	}
 	releaseFundTx.Source.Signature = user1.Sign(releaseFundTx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), releaseFundTx)
+	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, releaseFundTx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeReleaseFundCheckFailed, res.String())
 
@@ -817,7 +818,7 @@ func TestReleaseFundTx(t *testing.T) {
 		ReserveSequence: 99,
 	}
 	releaseFundTx.Source.Signature = user1.Sign(releaseFundTx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), releaseFundTx)
+	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, releaseFundTx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeReleaseFundCheckFailed, res.String())
 
@@ -836,7 +837,7 @@ func TestReleaseFundTx(t *testing.T) {
 		ReserveSequence: 1,
 	}
 	releaseFundTx.Source.Signature = user1.Sign(releaseFundTx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), releaseFundTx)
+	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, releaseFundTx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeReleaseFundCheckFailed, res.String())
 }
@@ -860,9 +861,9 @@ func TestServicePaymentTxNormalExecutionAndSlash(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 10*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 50*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx1 := createServicePaymentTx(et.chainID, &alice, &bob, payAmount1, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res := et.executor.getTxExecutor(servicePaymentTx1).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx1)
+	res := et.executor.getTxExecutor(servicePaymentTx1).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx1)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(servicePaymentTx1).process(et.chainID, et.state().Delivered(), servicePaymentTx1)
+	_, res = et.executor.getTxExecutor(servicePaymentTx1).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx1)
 	assert.True(res.IsOK(), res.Message)
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
 
@@ -879,9 +880,9 @@ func TestServicePaymentTxNormalExecutionAndSlash(t *testing.T) {
 	srcSeq, tgtSeq, paymentSeq, reserveSeq = 1, 2, 2, 1
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 30*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx2 := createServicePaymentTx(et.chainID, &alice, &bob, payAmount2, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx2).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx2)
+	res = et.executor.getTxExecutor(servicePaymentTx2).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx2)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(servicePaymentTx2).process(et.chainID, et.state().Delivered(), servicePaymentTx2)
+	_, res = et.executor.getTxExecutor(servicePaymentTx2).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx2)
 	assert.True(res.IsOK(), res.Message)
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
 
@@ -897,9 +898,9 @@ func TestServicePaymentTxNormalExecutionAndSlash(t *testing.T) {
 	srcSeq, tgtSeq, paymentSeq, reserveSeq = 1, 1, 3, 1
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 30*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx3 := createServicePaymentTx(et.chainID, &alice, &carol, payAmount3, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx3).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx3)
+	res = et.executor.getTxExecutor(servicePaymentTx3).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx3)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(servicePaymentTx3).process(et.chainID, et.state().Delivered(), servicePaymentTx3)
+	_, res = et.executor.getTxExecutor(servicePaymentTx3).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx3)
 	assert.True(res.IsOK(), res.Message)
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
 
@@ -915,13 +916,159 @@ func TestServicePaymentTxNormalExecutionAndSlash(t *testing.T) {
 	srcSeq, tgtSeq, paymentSeq, reserveSeq = 1, 2, 4, 1
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 70000*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx4 := createServicePaymentTx(et.chainID, &alice, &carol, payAmount4, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx4).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx4)
+	res = et.executor.getTxExecutor(servicePaymentTx4).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx4)
 	assert.True(res.IsOK(), res.Message) // the following process() call will create an SlashIntent
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx4).process(et.chainID, et.state().Delivered(), servicePaymentTx4)
+	_, res = et.executor.getTxExecutor(servicePaymentTx4).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx4)
+	assert.True(res.IsOK(), res.Message)
+	assert.Equal(1, len(et.state().Delivered().GetSlashIntents()))
+
+	// The overspending payment is not transferred
+	retrievedAliceAcc4 := et.state().Delivered().GetAccount(alice.Address)
+	assert.Equal(types.Coins{PTXWei: big.NewInt(payAmount1 + payAmount2 + payAmount3), PandoWei: big.NewInt(0)}, retrievedAliceAcc4.ReservedFunds[0].UsedFund)
+	retrievedCarolAcc4 := et.state().Delivered().GetAccount(carol.Address)
+	assert.Equal(carolInitBalance.Plus(types.Coins{PTXWei: big.NewInt(payAmount3 - 2*txFee)}), retrievedCarolAcc4.Balance) // the target still pays the tx fee
+
+	// The proof contains the payments signed by Alice to both Bob and Carol
+	slashIntent := et.state().Delivered().GetSlashIntents()[0]
+	assert.Equal(alice.Address, slashIntent.Address)
+	assert.Equal(uint64(1), slashIntent.ReserveSequence)
+	var overspendingProof types.OverspendingProof
+	assert.Nil(types.FromBytes(slashIntent.Proof, &overspendingProof))
+	assert.Equal(4, len(overspendingProof.ServicePayments))
+	assert.Equal(bob.Address, overspendingProof.ServicePayments[0].Target.Address)
+	assert.Equal(carol.Address, overspendingProof.ServicePayments[3].Target.Address)
+
+	proposer := et.accProposer
+	proposerInitBalance := proposer.Account.Balance
+	et.acc2State(proposer)
+
+	// The collateral and the remaining reserved fund are forfeited to the proposer
+	aliceReservedFund := retrievedAliceAcc4.ReservedFunds[0]
+	expectedAliceSlashedAmount := aliceReservedFund.Collateral.Plus(aliceReservedFund.InitialFund.Minus(aliceReservedFund.UsedFund))
+
+	slashTx := &types.SlashTx{
+		Proposer: types.TxInput{
+			Address:  proposer.Address,
+			Sequence: 1,
+		},
+		SlashedAddress:  slashIntent.Address,
+		ReserveSequence: slashIntent.ReserveSequence,
+		SlashProof:      slashIntent.Proof,
+	}
+	slashTx.Proposer.Signature = proposer.Sign(slashTx.SignBytes(et.chainID))
+
+	res = et.executor.getTxExecutor(slashTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, slashTx)
+	assert.True(res.IsOK(), res.Message)
+	_, res = et.executor.getTxExecutor(slashTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, slashTx)
+	assert.True(res.IsOK(), res.Message)
+
+	retrievedProposerAccount := et.state().Delivered().GetAccount(proposer.Address)
+	assert.Equal(proposerInitBalance.Plus(expectedAliceSlashedAmount), retrievedProposerAccount.Balance)
+	retrievedAliceAcc5 := et.state().Delivered().GetAccount(alice.Address)
+	assert.Equal(0, len(retrievedAliceAcc5.ReservedFunds))
+
+	// The reserved fund can only be slashed once
+	res = et.executor.getTxExecutor(slashTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, slashTx)
+	assert.False(res.IsOK(), res.Message)
+}
+
+func TestSlashTxExecutionAtSlashingHeight(t *testing.T) {
+	assert := assert.New(t)
+	et, resourceID, alice, bob, carol, _, _, _ := setupForServicePayment(assert)
+	et.state().Commit()
+
+	txFee := getMinimumTxFee()
+
+	// Alice pays Bob, and then overspends her reserved fund paying Carol
+	servicePaymentTx1 := createServicePaymentTx(et.chainID, &alice, &bob, 80*txFee, 1, 1, 1, 1, resourceID)
+	_, res := et.executor.getTxExecutor(servicePaymentTx1).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx1)
+	assert.True(res.IsOK(), res.Message)
+	et.state().Commit()
+
+	servicePaymentTx2 := createServicePaymentTx(et.chainID, &alice, &carol, 2000*txFee, 1, 1, 2, 1, resourceID)
+	_, res = et.executor.getTxExecutor(servicePaymentTx2).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx2)
 	assert.True(res.IsOK(), res.Message)
-	//assert.Equal(1, len(et.state().Delivered().GetSlashIntents()))
+	assert.Equal(1, len(et.state().Delivered().GetSlashIntents()))
+	slashIntent := et.state().Delivered().GetSlashIntents()[0]
+
+	proposer := et.accProposer
+	proposerInitBalance := proposer.Account.Balance
+	et.acc2State(proposer)
+
+	aliceReservedFund := et.state().Delivered().GetAccount(alice.Address).ReservedFunds[0]
+	expectedAliceSlashedAmount := aliceReservedFund.Collateral.Plus(aliceReservedFund.InitialFund.Minus(aliceReservedFund.UsedFund))
+
+	slashTx := &types.SlashTx{
+		Proposer: types.TxInput{
+			Address:  proposer.Address,
+			Sequence: 1,
+		},
+		SlashedAddress:  slashIntent.Address,
+		ReserveSequence: slashIntent.ReserveSequence,
+		SlashProof:      slashIntent.Proof,
+	}
+	slashTx.Proposer.Signature = proposer.Sign(slashTx.SignBytes(et.chainID))
+
+	// Below the slashing height the executor rejects the slash
+	_, res = et.executor.ExecuteTx(slashTx)
+	assert.True(res.IsError(), res.Message)
+	assert.Equal(1, len(et.state().Delivered().GetAccount(alice.Address).ReservedFunds))
+	assert.Equal(proposerInitBalance, et.state().Delivered().GetAccount(proposer.Address).Balance)
+
+	// The view points to the parent of the block, so the next block is at the slashing height
+	assert.True(et.fastforwardTo(common.HeightEnableValidatorSlashing - 1))
+
+	_, res = et.executor.ExecuteTx(slashTx)
+	assert.True(res.IsOK(), res.Message)
+	assert.Equal(0, len(et.state().Delivered().GetAccount(alice.Address).ReservedFunds))
+	assert.Equal(proposerInitBalance.Plus(expectedAliceSlashedAmount), et.state().Delivered().GetAccount(proposer.Address).Balance)
+}
+
+func TestSlashTxVerifyOverspendingProof(t *testing.T) {
+	assert := assert.New(t)
+	et, resourceID, alice, bob, carol, _, _, _ := setupForServicePayment(assert)
+	et.state().Commit()
+
+	txFee := getMinimumTxFee()
+	srcSeq, tgtSeq, reserveSeq := 1, 1, 1
+
+	slashTxExec := et.executor.getTxExecutor(&types.SlashTx{}).(*SlashTxExecutor)
+	verify := func(payments ...*types.ServicePaymentTx) bool {
+		overspendingProof := types.OverspendingProof{ReserveSequence: 1}
+		for _, payment := range payments {
+			overspendingProof.ServicePayments = append(overspendingProof.ServicePayments, *payment)
+		}
+		overspendingProofBytes, err := types.ToBytes(&overspendingProof)
+		assert.Nil(err)
+
+		aliceAcc := et.state().Delivered().GetAccount(alice.Address)
+		return slashTxExec.verifySlashProof(et.chainID, aliceAcc, overspendingProofBytes)
+	}
+
+	// Payments within the reserved fund are not an overspend
+	toBob := createServicePaymentTx(et.chainID, &alice, &bob, 500*txFee, srcSeq, tgtSeq, 1, reserveSeq, resourceID)
+	toCarol := createServicePaymentTx(et.chainID, &alice, &carol, 500*txFee, srcSeq, tgtSeq, 1, reserveSeq, resourceID)
+	assert.False(verify(toBob, toCarol))
+
+	// Partial payments of the same payment cannot be summed up
+	toBobPartial := createServicePaymentTx(et.chainID, &alice, &bob, 800*txFee, srcSeq, tgtSeq, 1, reserveSeq, resourceID)
+	toBobFull := createServicePaymentTx(et.chainID, &alice, &bob, 900*txFee, srcSeq, tgtSeq, 1, reserveSeq, resourceID)
+	assert.False(verify(toBobPartial, toBobFull))
+
+	// Payments not signed by the slashed account are rejected
+	toCarolForged := createServicePaymentTx(et.chainID, &alice, &carol, 900*txFee, srcSeq, tgtSeq, 1, reserveSeq, resourceID)
+	toCarolForged.Source.Signature = bob.Sign(toCarolForged.SourceSignBytes(et.chainID))
+	assert.False(verify(toBobFull, toCarolForged))
+
+	// Conflicting payments to different targets prove the overspend
+	toCarol = createServicePaymentTx(et.chainID, &alice, &carol, 900*txFee, srcSeq, tgtSeq, 1, reserveSeq, resourceID)
+	assert.True(verify(toBobFull, toCarol))
+
+	// So do several payments to the same target
+	toBobNext := createServicePaymentTx(et.chainID, &alice, &bob, 200*txFee, srcSeq, tgtSeq, 2, reserveSeq, resourceID)
+	assert.True(verify(toBobFull, toBobNext))
 }
 
 func TestServicePaymentTxExpiration(t *testing.T) {
@@ -943,9 +1090,9 @@ func TestServicePaymentTxExpiration(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 10*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 50*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx1 := createServicePaymentTx(et.chainID, &alice, &bob, payAmount1, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res := et.executor.getTxExecutor(servicePaymentTx1).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx1)
+	res := et.executor.getTxExecutor(servicePaymentTx1).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx1)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(servicePaymentTx1).process(et.chainID, et.state().Delivered(), servicePaymentTx1)
+	_, res = et.executor.getTxExecutor(servicePaymentTx1).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx1)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -962,7 +1109,7 @@ func TestServicePaymentTxExpiration(t *testing.T) {
 	srcSeq, tgtSeq, paymentSeq, reserveSeq = 1, 2, 2, 1
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 30*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx2 := createServicePaymentTx(et.chainID, &alice, &bob, payAmount2, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx2).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx2)
+	res = et.executor.getTxExecutor(servicePaymentTx2).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx2)
 	assert.False(res.IsOK(), res.Message)
 	assert.Equal(result.CodeCheckTransferReservedFundFailed, res.Code)
 	log.Infof("Service payment check message: %v", res.Message)
@@ -993,11 +1140,11 @@ func TestServicePaymentTxExpiration(t *testing.T) {
 // 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 10*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 // 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 50*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 // 	servicePaymentTx1 := createServicePaymentTx(et.chainID, &alice, &bob, payAmount1, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-// 	res := et.executor.getTxExecutor(servicePaymentTx1).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx1)
+// 	res := et.executor.getTxExecutor(servicePaymentTx1).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx1)
 // 	assert.True(res.IsOK(), res.Message)
 
 // 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-// 	_, res = et.executor.getTxExecutor(servicePaymentTx1).process(et.chainID, et.state().Delivered(), servicePaymentTx1)
+// 	_, res = et.executor.getTxExecutor(servicePaymentTx1).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx1)
 // 	assert.True(res.IsOK(), res.Message)
 // 	assert.Equal(1, len(et.state().Delivered().GetSlashIntents()))
 
@@ -1018,9 +1165,9 @@ func TestServicePaymentTxExpiration(t *testing.T) {
 // 	signBytes := slashTx.SignBytes(et.chainID)
 // 	slashTx.Proposer.Signature = proposer.Sign(signBytes)
 
-// 	res = et.executor.getTxExecutor(slashTx).sanityCheck(et.chainID, et.state().Delivered(), slashTx)
+// 	res = et.executor.getTxExecutor(slashTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, slashTx)
 // 	assert.True(res.IsOK(), res.Message)
-// 	_, res = et.executor.getTxExecutor(slashTx).process(et.chainID, et.state().Delivered(), slashTx)
+// 	_, res = et.executor.getTxExecutor(slashTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, slashTx)
 // 	assert.True(res.IsOK(), res.Message)
 
 // 	retrievedProposerAccount := et.state().Delivered().GetAccount(proposer.Address)
@@ -1064,9 +1211,9 @@ func TestSplitRuleTxNormalExecution(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1075,11 +1222,11 @@ func TestSplitRuleTxNormalExecution(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &bob, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1126,9 +1273,9 @@ func TestSplitRuleTxExpiration(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.fastforwardBy(105) // The split rule should expire after the fastforward
@@ -1139,11 +1286,11 @@ func TestSplitRuleTxExpiration(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 100, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 500, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &bob, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1192,9 +1339,9 @@ func TestSplitRuleTxUpdate(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	splitRule := et.executor.state.Delivered().GetSplitRule(resourceID)
@@ -1216,10 +1363,10 @@ func TestSplitRuleTxUpdate(t *testing.T) {
 	signBytes = fakeSplitRuleUpdateTx.SignBytes(et.chainID)
 	fakeSplitRuleUpdateTx.Initiator.Signature = fakeInitiator.Sign(signBytes)
 
-	res = et.executor.getTxExecutor(fakeSplitRuleUpdateTx).sanityCheck(et.chainID, et.state().Delivered(), fakeSplitRuleUpdateTx)
+	res = et.executor.getTxExecutor(fakeSplitRuleUpdateTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, fakeSplitRuleUpdateTx)
 	assert.False(res.IsOK(), res.Message)
 	assert.Equal(result.CodeUnauthorizedToUpdateSplitRule, res.Code)
-	_, res = et.executor.getTxExecutor(fakeSplitRuleUpdateTx).process(et.chainID, et.state().Delivered(), fakeSplitRuleUpdateTx)
+	_, res = et.executor.getTxExecutor(fakeSplitRuleUpdateTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, fakeSplitRuleUpdateTx)
 	assert.False(res.IsOK(), res.Message)
 	assert.Equal(result.CodeUnauthorizedToUpdateSplitRule, res.Code)
 
@@ -1244,9 +1391,9 @@ func TestSplitRuleTxUpdate(t *testing.T) {
 	signBytes = splitRuleUpdateTx.SignBytes(et.chainID)
 	splitRuleUpdateTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res = et.executor.getTxExecutor(splitRuleUpdateTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleUpdateTx)
+	res = et.executor.getTxExecutor(splitRuleUpdateTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleUpdateTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleUpdateTx).process(et.chainID, et.state().Delivered(), splitRuleUpdateTx)
+	_, res = et.executor.getTxExecutor(splitRuleUpdateTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleUpdateTx)
 	assert.True(res.IsOK(), res.Message)
 
 	splitRule2 := et.executor.state.Delivered().GetSplitRule(resourceID)
@@ -1339,9 +1486,9 @@ func TestSplitRuleTxTargetAddressAlsoSplits(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1352,11 +1499,11 @@ func TestSplitRuleTxTargetAddressAlsoSplits(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1434,9 +1581,9 @@ func TestSplitRuleTxManyDups(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1445,11 +1592,11 @@ func TestSplitRuleTxManyDups(t *testing.T) {
 
 	// Alice send the service payment to Carol, whose address is included in the split address list
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1524,9 +1671,9 @@ func TestSplitRuleTxSmallAmount(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1535,13 +1682,13 @@ func TestSplitRuleTxSmallAmount(t *testing.T) {
 
 	// Alice send the service payment to Carol, whose address is included in the split address list
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	log.Infof("Payment amount: %v", payAmount)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1630,9 +1777,9 @@ func TestSplitRuleTxSmallPercentage(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1641,13 +1788,13 @@ func TestSplitRuleTxSmallPercentage(t *testing.T) {
 
 	// Alice send the service payment to Carol, whose address is included in the split address list
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	log.Infof("Payment amount: %v", payAmount)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1720,9 +1867,9 @@ func TestSplitRuleHundredPercSplits(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1733,11 +1880,11 @@ func TestSplitRuleHundredPercSplits(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1802,7 +1949,7 @@ func TestSplitRuleOverHundredPercSplits(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.False(res.IsOK(), res.Message) // should be rejected
 }
 
@@ -1837,9 +1984,9 @@ func TestSplitRuleSingleHundredPercSplits(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1850,11 +1997,11 @@ func TestSplitRuleSingleHundredPercSplits(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1917,9 +2064,9 @@ func TestSplitRuleSplitZeroPercSplits(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1930,11 +2077,11 @@ func TestSplitRuleSplitZeroPercSplits(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1989,9 +2136,9 @@ func TestSplitRuleSplitEmptyRule(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -2002,11 +2149,11 @@ func TestSplitRuleSplitEmptyRule(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -2071,9 +2218,9 @@ func TestSplitRuleSplitZeroPayment(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -2084,11 +2231,11 @@ func TestSplitRuleSplitZeroPayment(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 0, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 0, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -2154,9 +2301,9 @@ func TestSplitRuleTxSplitPaymentRounding(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -2167,11 +2314,11 @@ func TestSplitRuleTxSplitPaymentRounding(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -2240,9 +2387,9 @@ func TestSplitRuleExpiration(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 	et.state().Commit()
 
@@ -2266,11 +2413,11 @@ func TestSplitRuleExpiration(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -2338,9 +2485,9 @@ func TestSplitRuleZeroDuration(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 	et.state().Commit()
 
@@ -2363,9 +2510,9 @@ func TestSplitRuleZeroDuration(t *testing.T) {
 	signBytes2 := splitRuleTx2.SignBytes(et.chainID)
 	splitRuleTx2.Initiator.Signature = initiator.Sign(signBytes2)
 
-	res = et.executor.getTxExecutor(splitRuleTx2).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx2)
+	res = et.executor.getTxExecutor(splitRuleTx2).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx2)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx2).process(et.chainID, et.state().Delivered(), splitRuleTx2)
+	_, res = et.executor.getTxExecutor(splitRuleTx2).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx2)
 	assert.True(res.IsOK(), res.Message)
 	et.state().Commit()
 
@@ -82,7 +82,7 @@ func TestSimpleSmartContractDeploymentAndExecution(t *testing.T) {
 // contract TestCustomToken {
 //     using SafeMath for uint;
 //     mapping (address => uint) balances;
-//     address public constant ADMIN = 0x4d4ce78b09F8A06C0d3063a315dC9c011F6e876E;
+//     address public constant ADMIN = 0xdB58A9e59eF9Fb7AF7EB369b088C5a973972FE37;
 //
 //     function mint() public {
 //         require(msg.sender == ADMIN);
@@ -115,7 +115,7 @@ func TestCustomTokenSmartContract(t *testing.T) {
 	user2PrivAcc := &privAccounts[3]
 
 	adminAddr := adminPrivAcc.Address
-	assert.Equal(common.HexToAddress("0x4d4ce78b09F8A06C0d3063a315dC9c011F6e876E"), adminAddr)
+	assert.Equal(common.HexToAddress("0xdB58A9e59eF9Fb7AF7EB369b088C5a973972FE37"), adminAddr)
 	deployerAddr := deployerPrivAcc.Address
 	user1Addr := user1PrivAcc.Address
 	user2Addr := user2PrivAcc.Address
@@ -274,7 +274,7 @@ func deploySmartContract(et *execTest, deployerPrivAcc *types.PrivAccount,
 	parentBlock := &core.Block{
 		BlockHeader: &core.BlockHeader{
 			Height:    1,
-			Timestamp: 1601599331,
+			Timestamp: big.NewInt(1601599331),
 		},
 	}
 	stateCopy, err := et.state().Delivered().Copy()
@@ -284,9 +284,9 @@ func deploySmartContract(et *execTest, deployerPrivAcc *types.PrivAccount,
 	log.Infof("[Deployment] gas used: %v", gasUsed)
 
 	// The actual on-chain deplpoyment
-	res := et.executor.getTxExecutor(deploySCTx).sanityCheck(et.chainID, et.state().Delivered(), deploySCTx)
+	res := et.executor.getTxExecutor(deploySCTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, deploySCTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(deploySCTx).process(et.chainID, et.state().Delivered(), deploySCTx)
+	_, res = et.executor.getTxExecutor(deploySCTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, deploySCTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -340,10 +340,10 @@ func callSmartContract(et *execTest, contractAddr common.Address, callerPrivAcc
 	parentBlock := &core.Block{
 		BlockHeader: &core.BlockHeader{
 			Height:    1,
-			Timestamp: 1601599331,
+			Timestamp: big.NewInt(1601599331),
 		},
 	}
-	vmRet, execContractAddr, gasUsed, vmErr := vm.Execute(callSCTX, stateCopy)
+	vmRet, execContractAddr, gasUsed, vmErr := vm.Execute(parentBlock, callSCTX, stateCopy)
 	assert.Equal(contractAddr, execContractAddr)
 	log.Infof("[Call      ] gas used: %v", gasUsed)
 
@@ -373,9 +373,9 @@ func executeSmartContract(et *execTest, contractAddr common.Address, callerPrivA
 	execSCTX.From.Signature = callerPrivAcc.Sign(signBytes)
 
 	// Execute the on-chain smart contract
-	res := et.executor.getTxExecutor(execSCTX).sanityCheck(et.chainID, et.state().Delivered(), execSCTX)
+	res := et.executor.getTxExecutor(execSCTX).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, execSCTX)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(execSCTX).process(et.chainID, et.state().Delivered(), execSCTX)
+	_, res = et.executor.getTxExecutor(execSCTX).process(et.chainID, et.state().Delivered(), core.DeliveredView, execSCTX)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -25,6 +25,7 @@ import (
 
 type TestConsensusEngine struct {
 	privKey *crypto.PrivateKey
+	ledger  *TestLedger
 }
 
 func (tce *TestConsensusEngine) ID() string                        { return tce.privKey.PublicKey().Address().Hex() }
@@ -33,16 +34,36 @@ func (tce *TestConsensusEngine) GetTip(bool) *core.ExtendedBlock   { return nil
 func (tce *TestConsensusEngine) GetEpoch() uint64                  { return 100 }
 func (tce *TestConsensusEngine) AddMessage(msg interface{})        {}
 func (tce *TestConsensusEngine) FinalizedBlocks() chan *core.Block { return nil }
-func (tce *TestConsensusEngine) GetLedger() core.Ledger            { return nil }
+func (tce *TestConsensusEngine) GetLedger() core.Ledger            { return tce.ledger }
 func (tce *TestConsensusEngine) GetLastFinalizedBlock() *core.ExtendedBlock {
 	return &core.ExtendedBlock{}
 }
+func (tce *TestConsensusEngine) GetPendingEvidence() []*core.EquivocationEvidence {
+	return nil
+}
+
+// noopTagger ignores the state roots committed by the tests, which do not use the rolling db.
+type noopTagger struct{}
+
+func (noopTagger) Tag(height uint64, root common.Hash) {}
 
 func NewTestConsensusEngine(seed string) *TestConsensusEngine {
 	privKey, _, _ := crypto.TEST_GenerateKeyPairWithSeed(seed)
-	return &TestConsensusEngine{privKey}
+	return &TestConsensusEngine{
+		privKey: privKey,
+		ledger:  &TestLedger{currentBlock: &core.Block{BlockHeader: &core.BlockHeader{}}},
+	}
 }
 
+// TestLedger only serves the current block, which the executors use to look up the validator set.
+type TestLedger struct {
+	core.Ledger
+
+	currentBlock *core.Block
+}
+
+func (tl *TestLedger) GetCurrentBlock() *core.Block { return tl.currentBlock }
+
 type TestValidatorManager struct {
 	proposer core.Validator
 	valSet   *core.ValidatorSet
@@ -109,7 +130,7 @@ func (et *execTest) reset() {
 		},
 	}
 	db := backend.NewMemDatabase()
-	ledgerState := st.NewLedgerState(chainID, db, nil)
+	ledgerState := st.NewLedgerState(chainID, db, noopTagger{})
 	//ledgerState.ResetState(initHeight, initRootHash)
 	ledgerState.ResetState(initBlock)
 
@@ -123,7 +144,7 @@ func (et *execTest) reset() {
 	valMgr := NewTestValidatorManager(propser, valSet)
 
 	chain := blockchain.CreateTestChain()
-	executor := NewExecutor(db, chain, ledgerState, consensus, valMgr, nil)
+	executor := NewExecutor(db, chain, ledgerState, consensus, valMgr, consensus.GetLedger())
 
 	et.chainID = chainID
 	et.executor = executor
@@ -370,8 +391,8 @@ func setupForSmartContract(ast *assert.Assertions, numAccounts int) (et *execTes
 		secret := "acc_secret_" + strconv.FormatInt(int64(i), 16)
 		privAccount := types.MakeAccWithInitBalance(secret,
 			types.Coins{
-				big.NewInt(0),
-				big.NewInt(1).Mul(big.NewInt(9000000), big.NewInt(int64(types.MinimumGasPriceJune2021))),
+				PandoWei: big.NewInt(0),
+				PTXWei:   big.NewInt(1).Mul(big.NewInt(9000000), big.NewInt(int64(types.MinimumGasPriceJune2021))),
 			})
 		privAccounts = append(privAccounts, privAccount)
 		et.acc2State(privAccount)
@@ -139,9 +139,13 @@ func (exec *ServicePaymentTxExecutor) process(chainID string, view *st.StoreView
 
 	currentBlockHeight := view.Height()
 	reserveSequence := tx.ReserveSequence
-	shouldSlash, _ := sourceAccount.TransferReservedFund(accCoinsMap, currentBlockHeight, reserveSequence, tx)
+	shouldSlash, slashIntent := sourceAccount.TransferReservedFund(accCoinsMap, currentBlockHeight, reserveSequence, tx)
 	if shouldSlash {
-		//view.AddSlashIntent(slashIntent)
+		// The source has signed payments beyond its reserved fund. No fund is transferred, and the
+		// proposer turns the slash intent into a SlashTx which forfeits the collateral
+		logger.Infof("Reserved fund overspent: source = %v, reserveSequence = %v, tx = %v",
+			sourceAddress.Hex(), reserveSequence, tx)
+		view.AddSlashIntent(slashIntent)
 	}
 	if !chargeFee(targetAccount, tx.Fee) {
 		// should charge after transfer the fund, so an empty address has some fund to pay the tx fee
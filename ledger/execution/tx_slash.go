@@ -1,6 +1,7 @@
 package execution
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/pandotoken/pando/common"
@@ -56,6 +57,10 @@ func (exec *SlashTxExecutor) sanityCheck(chainID string, view *st.StoreView, vie
 		return result.Error("SignBytes: %X", signBytes)
 	}
 
+	if tx.IsEquivocationSlash() {
+		return exec.sanityCheckEquivocation(chainID, view, tx)
+	}
+
 	slashedAddress := tx.SlashedAddress
 	slashedAccount := view.GetAccount(slashedAddress)
 	if slashedAccount == nil {
@@ -92,6 +97,10 @@ func (exec *SlashTxExecutor) sanityCheck(chainID string, view *st.StoreView, vie
 func (exec *SlashTxExecutor) process(chainID string, view *st.StoreView, viewSel core.ViewSelector, transaction types.Tx) (common.Hash, result.Result) {
 	tx := transaction.(*types.SlashTx)
 
+	if tx.IsEquivocationSlash() {
+		return exec.processEquivocation(chainID, view, tx)
+	}
+
 	slashedAddress := tx.SlashedAddress
 	slashedAccount := view.GetAccount(slashedAddress)
 
@@ -137,6 +146,65 @@ func (exec *SlashTxExecutor) process(chainID string, view *st.StoreView, viewSel
 	return txHash, result.OK
 }
 
+func (exec *SlashTxExecutor) sanityCheckEquivocation(chainID string, view *st.StoreView, tx *types.SlashTx) result.Result {
+	evidence, err := core.EquivocationEvidenceFromBytes(tx.SlashProof)
+	if err != nil {
+		return result.Error("Failed to parse equivocation evidence: %v", err)
+	}
+	if res := evidence.Validate(chainID); res.IsError() {
+		return result.Error("Invalid equivocation evidence: %v", res.Message)
+	}
+	if evidence.Offender() != tx.SlashedAddress {
+		return result.Error("Slashed address %v is not the equivocating validator %v", tx.SlashedAddress, evidence.Offender())
+	}
+	if view.Get(st.EquivocationEvidenceKey(evidence.Hash())) != nil {
+		return result.Error("Equivocation evidence %v has already been processed", evidence.Hash().Hex())
+	}
+
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil || vcp.FindStakeDelegate(tx.SlashedAddress) == nil {
+		return result.Error("Validator %v has no stake to slash", tx.SlashedAddress)
+	}
+
+	return result.OK
+}
+
+// processEquivocation burns all the stakes of the equivocating validator, which also removes it from
+// the validator candidate pool. Similar to the stake withdrawal, the validator set change takes effect
+// after the block is finalized.
+func (exec *SlashTxExecutor) processEquivocation(chainID string, view *st.StoreView, tx *types.SlashTx) (common.Hash, result.Result) {
+	evidence, err := core.EquivocationEvidenceFromBytes(tx.SlashProof)
+	if err != nil {
+		return common.Hash{}, result.Error("Failed to parse equivocation evidence: %v", err)
+	}
+
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil {
+		return common.Hash{}, result.Error("Validator candidate pool not found")
+	}
+	slashedStakeHolder, err := vcp.SlashStakeHolder(tx.SlashedAddress)
+	if err != nil {
+		return common.Hash{}, result.Error("Failed to slash validator, err: %v", err)
+	}
+	view.UpdateValidatorCandidatePool(vcp)
+
+	hl := view.GetStakeTransactionHeightList()
+	if hl == nil {
+		hl = &types.HeightList{}
+	}
+	blockHeight := view.Height() + 1 // the view points to the parent of the current block
+	hl.Append(blockHeight)
+	view.UpdateStakeTransactionHeightList(hl)
+
+	txHash := types.TxID(chainID, tx)
+	view.Set(st.EquivocationEvidenceKey(evidence.Hash()), txHash[:])
+
+	logger.Infof("Validator slashed for equivocation: validator = %v, stake = %v, evidence = %v",
+		tx.SlashedAddress, slashedStakeHolder.TotalStake(), evidence)
+
+	return txHash, result.OK
+}
+
 func (exec *SlashTxExecutor) verifySlashProof(chainID string, slashedAccount *types.Account, overspendingProofBytes []byte) bool {
 	var overspendingProof types.OverspendingProof
 	err := types.FromBytes(overspendingProofBytes, &overspendingProof)
@@ -170,7 +238,7 @@ func (exec *SlashTxExecutor) verifySlashProof(chainID string, slashedAccount *ty
 				return false // servicePaymentTx not signed by the slashed account
 			}
 
-			paymentKey := string(servicePaymentTx.Target.Address[:]) + "." + string(servicePaymentTx.PaymentSequence)
+			paymentKey := fmt.Sprintf("%v.%v", servicePaymentTx.Target.Address.Hex(), servicePaymentTx.PaymentSequence)
 			_, targetExists := settledPaymentLookup[paymentKey]
 			if targetExists {
 				return false // to prevent using partial payments as proof
@@ -2,6 +2,7 @@ package ledger
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"strconv"
 	"sync"
@@ -102,6 +103,72 @@ func (ledger *Ledger) GetFinalizedSnapshot() (*st.StoreView, error) {
 	return ledger.state.Finalized().Copy()
 }
 
+// StateHistory is implemented by the databases which drop the old states, e.g. the rolling db.
+type StateHistory interface {
+	// OldestStateHeight returns the height of the oldest state kept, or 0 if no state was dropped.
+	OldestStateHeight() uint64
+}
+
+// StateCompactor is implemented by the databases which can drop the old states on demand, e.g. the rolling db.
+type StateCompactor interface {
+	// Compact drops the states older than the retained blocks before the given height.
+	Compact(height uint64) error
+}
+
+// StateNotAvailableError is returned when the state of a block has been pruned.
+type StateNotAvailableError struct {
+	Height            uint64
+	OldestStateHeight uint64
+}
+
+func (err *StateNotAvailableError) Error() string {
+	return fmt.Sprintf("State not available, the state for height %v has been pruned, the oldest available state is at height %v",
+		err.Height, err.OldestStateHeight)
+}
+
+// OldestStateHeight returns the height of the oldest finalized block whose state is available.
+// The states of all the finalized blocks after it are available as well.
+func (ledger *Ledger) OldestStateHeight() uint64 {
+	height := ledger.chain.Root().Height
+	if history, ok := ledger.db.(StateHistory); ok {
+		if oldest := history.OldestStateHeight(); oldest > height {
+			height = oldest
+		}
+	}
+	return height
+}
+
+// CompactState drops the states older than the retained blocks before the last finalized block,
+// like the periodic compactions of the rolling db. It fails in archive mode.
+func (ledger *Ledger) CompactState() error {
+	if viper.GetBool(common.CfgStorageArchiveEnabled) {
+		return errors.New("State pruning is disabled in archive mode")
+	}
+	compactor, ok := ledger.db.(StateCompactor)
+	if !ok {
+		return errors.New("State pruning is not supported by the database")
+	}
+	lastFinalizedBlock := ledger.consensus.GetLastFinalizedBlock()
+	if lastFinalizedBlock == nil {
+		return errors.New("No finalized block")
+	}
+	return compactor.Compact(lastFinalizedBlock.Height)
+}
+
+// GetSnapshotAfterBlock returns a snapshot of the state right after the given block was committed.
+// It returns a StateNotAvailableError if the state has been pruned.
+func (ledger *Ledger) GetSnapshotAfterBlock(block *core.ExtendedBlock) (*st.StoreView, error) {
+	oldest := ledger.OldestStateHeight()
+	if block.Height < oldest {
+		return nil, &StateNotAvailableError{Height: block.Height, OldestStateHeight: oldest}
+	}
+	view := st.NewStoreView(block.Height, block.StateHash, ledger.state.DB())
+	if view == nil {
+		return nil, &StateNotAvailableError{Height: block.Height, OldestStateHeight: oldest}
+	}
+	return view, nil
+}
+
 // GetFinalizedValidatorCandidatePool returns the validator candidate pool of the latest DIRECTLY finalized block
 func (ledger *Ledger) GetFinalizedValidatorCandidatePool(blockHash common.Hash, isNext bool) (*core.ValidatorCandidatePool, error) {
 	db := ledger.state.DB()
@@ -251,6 +318,36 @@ func (ledger *Ledger) ScreenTx(rawTx common.Bytes) (txInfo *core.TxInfo, res res
 	return txInfo, res
 }
 
+// ScreenReplacementTx screens the given transaction, which replaces a screened transaction with the same
+// sequence number. Unlike ScreenTx, it does not apply the transaction to the screened view.
+func (ledger *Ledger) ScreenReplacementTx(rawTx common.Bytes) (txInfo *core.TxInfo, res result.Result) {
+	var tx types.Tx
+	tx, err := types.TxFromBytes(rawTx)
+	if err != nil {
+		return nil, result.Error("Error decoding tx: %v", err)
+	}
+
+	if ledger.shouldSkipCheckTx(tx) {
+		return nil, result.Error("Unauthorized transaction, should skip").
+			WithErrorCode(result.CodeUnauthorizedTx)
+	}
+
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+
+	res = ledger.executor.ScreenReplacementTx(tx)
+	if res.IsError() {
+		return nil, res
+	}
+
+	txInfo, res = ledger.executor.GetTxInfo(tx)
+	if res.IsError() {
+		return nil, res
+	}
+
+	return txInfo, res
+}
+
 // ProposeBlockTxs collects and executes a list of transactions, which will be used to assemble the next blockl
 // It also clears these transactions from the mempool.
 func (ledger *Ledger) ProposeBlockTxs(block *core.Block, shouldIncludeValidatorUpdateTxs bool) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
@@ -303,6 +400,9 @@ func (ledger *Ledger) ProposeBlockTxs(block *core.Block, shouldIncludeValidatorU
 			if _, ok := tx.(*types.WithdrawStakeTx); ok {
 				continue
 			}
+			if stx, ok := tx.(*types.SlashTx); ok && stx.IsEquivocationSlash() {
+				continue
+			}
 		}
 
 		_, res := ledger.executor.CheckTx(tx)
@@ -313,6 +413,24 @@ func (ledger *Ledger) ProposeBlockTxs(block *core.Block, shouldIncludeValidatorU
 		blockRawTxs = append(blockRawTxs, rawTxCandidate)
 	}
 
+	// Slash the sources that overspent their reserved funds in the service payments above. The
+	// slash transactions are placed after the service payments, so the overspent reserved funds
+	// still exist when the slash proofs are verified
+	overspendingSlashRawTxs := []common.Bytes{}
+	ledger.addOverspendingSlashTxs(block, view, &overspendingSlashRawTxs)
+	for _, slashRawTx := range overspendingSlashRawTxs {
+		tx, err := types.TxFromBytes(slashRawTx)
+		if err != nil {
+			continue
+		}
+		_, res := ledger.executor.CheckTx(tx)
+		if res.IsError() {
+			logger.Errorf("Slash transaction check failed: errMsg = %v, tx = %v", res.Message, tx)
+			continue
+		}
+		blockRawTxs = append(blockRawTxs, slashRawTx)
+	}
+
 	logger.Debugf("ProposeBlockTxs: block transactions executed, block.height = %v", block.Height)
 	execTxsTime := time.Since(start)
 	start = time.Now()
@@ -372,6 +490,8 @@ func (ledger *Ledger) ApplyBlockTxs(block *core.Block) result.Result {
 			hasValidatorUpdate = true
 		} else if wtx, ok := tx.(*types.WithdrawStakeTx); ok && wtx.Purpose == core.StakeForValidator {
 			hasValidatorUpdate = true
+		} else if stx, ok := tx.(*types.SlashTx); ok && stx.IsEquivocationSlash() {
+			hasValidatorUpdate = true
 		}
 		_, res := ledger.executor.ExecuteTx(tx)
 		if res.IsError() {
@@ -453,6 +573,8 @@ func (ledger *Ledger) ApplyBlockTxsForChainCorrection(block *core.Block) (common
 			hasValidatorUpdate = true
 		} else if wtx, ok := tx.(*types.WithdrawStakeTx); ok && wtx.Purpose == core.StakeForValidator {
 			hasValidatorUpdate = true
+		} else if stx, ok := tx.(*types.SlashTx); ok && stx.IsEquivocationSlash() {
+			hasValidatorUpdate = true
 		}
 		_, res := ledger.executor.ExecuteTx(tx)
 		if res.IsError() {
@@ -771,7 +893,10 @@ func (ledger *Ledger) addSpecialTransactions(block *core.Block, view *st.StoreVi
 	validatorSet := ledger.valMgr.GetNextValidatorSet(parentBlkHash)
 
 	ledger.addCoinbaseTx(view, &proposer, validatorSet, rawTxs)
-	//ledger.addSlashTxs(view, &proposer, &validators, rawTxs)
+	if block.Height >= common.HeightEnableValidatorSlashing {
+		ledger.addEquivocationSlashIntents(view)
+		ledger.addSlashTxs(view, &proposer, validatorSet, rawTxs)
+	}
 }
 
 // addCoinbaseTx adds a Coinbase transaction
@@ -827,6 +952,40 @@ func (ledger *Ledger) addCoinbaseTx(view *st.StoreView, proposer *core.Validator
 	logger.Debugf("Adding coinbase transction: tx: %v, bytes: %v", coinbaseTx, hex.EncodeToString(coinbaseTxBytes))
 }
 
+// addEquivocationSlashIntents turns the pending equivocation evidences collected by the consensus
+// engine into slash intents, skipping the evidences that have already been processed
+func (ledger *Ledger) addEquivocationSlashIntents(view *st.StoreView) {
+	for _, evidence := range ledger.consensus.GetPendingEvidence() {
+		if view.Get(st.EquivocationEvidenceKey(evidence.Hash())) != nil {
+			continue
+		}
+		view.AddSlashIntent(types.SlashIntent{
+			Address:         evidence.Offender(),
+			ReserveSequence: 0, // zero reserve sequence indicates an equivocation slash
+			Proof:           evidence.Bytes(),
+		})
+	}
+}
+
+// addOverspendingSlashTxs adds Slash transactions for the slash intents raised while checking the
+// service payment transactions of the block, i.e. for the sources that overspent their reserved funds.
+// Slash transactions are only accepted from HeightEnableValidatorSlashing, below which the slash
+// intents are dropped
+func (ledger *Ledger) addOverspendingSlashTxs(block *core.Block, view *st.StoreView, rawTxs *[]common.Bytes) {
+	if block == nil || len(view.GetSlashIntents()) == 0 {
+		return
+	}
+	if block.Height < common.HeightEnableValidatorSlashing {
+		view.ClearSlashIntents()
+		return
+	}
+
+	parentBlkHash := block.Parent
+	proposer := ledger.valMgr.GetNextProposer(parentBlkHash, block.Epoch)
+	validatorSet := ledger.valMgr.GetNextValidatorSet(parentBlkHash)
+	ledger.addSlashTxs(view, &proposer, validatorSet, rawTxs)
+}
+
 // addsSlashTx adds Slash transactions
 func (ledger *Ledger) addSlashTxs(view *st.StoreView, proposer *core.Validator, validatorSet *core.ValidatorSet, rawTxs *[]common.Bytes) {
 	proposerAddress := proposer.Address
@@ -835,7 +994,16 @@ func (ledger *Ledger) addSlashTxs(view *st.StoreView, proposer *core.Validator,
 	}
 
 	slashIntents := view.GetSlashIntents()
+	slashed := make(map[string]bool)
 	for _, slashIntent := range slashIntents {
+		// Several overspending payments of the same reserved fund raise one slash intent each,
+		// but the reserved fund can only be slashed once
+		slashKey := fmt.Sprintf("%v.%v", slashIntent.Address.Hex(), slashIntent.ReserveSequence)
+		if slashed[slashKey] {
+			continue
+		}
+		slashed[slashKey] = true
+
 		slashTx := &types.SlashTx{
 			Proposer:        proposerTxIn,
 			SlashedAddress:  slashIntent.Address,
@@ -0,0 +1,140 @@
+// Package proof verifies the merkle proofs of accounts and contract storage returned by the
+// pando.GetProof RPC, so that clients can check them against a block header they trust.
+package proof
+
+import (
+	"fmt"
+
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/hexutil"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/rlp"
+	"github.com/pandotoken/pando/store/trie"
+)
+
+// emptyRoot is the root of an empty trie.
+var emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+// AccountKey returns the key of the account in the state trie. It matches state.AccountKey.
+func AccountKey(addr common.Address) common.Bytes {
+	return append(common.Bytes("ls/a/"), addr[:]...)
+}
+
+// ProofList holds the trie nodes on the path from the root to a key, in that order.
+type ProofList []hexutil.Bytes
+
+// Put implements the database.Putter interface, so that the trie can write a proof to it.
+func (pl *ProofList) Put(key []byte, value []byte) error {
+	*pl = append(*pl, common.CopyBytes(value))
+	return nil
+}
+
+// proofDB indexes the nodes of a proof by hash, the way trie.VerifyProof looks them up.
+type proofDB map[common.Hash][]byte
+
+func newProofDB(pl ProofList) proofDB {
+	db := make(proofDB)
+	for _, node := range pl {
+		db[crypto.Keccak256Hash(node)] = node
+	}
+	return db
+}
+
+func (db proofDB) Get(key []byte) ([]byte, error) {
+	if node, ok := db[common.BytesToHash(key)]; ok {
+		return node, nil
+	}
+	return nil, fmt.Errorf("Proof node %v not found", common.Bytes2Hex(key))
+}
+
+func (db proofDB) Has(key []byte) (bool, error) {
+	_, ok := db[common.BytesToHash(key)]
+	return ok, nil
+}
+
+// StorageProof is the proof of a storage slot of a contract against the storage root of its account.
+type StorageProof struct {
+	Key   common.Hash `json:"key"`
+	Value common.Hash `json:"value"`
+	Proof ProofList   `json:"proof"`
+}
+
+// AccountProof is the proof of an account against a state root, along with the proofs of some
+// of its storage slots.
+type AccountProof struct {
+	Address       common.Address  `json:"address"`
+	Account       *types.Account  `json:"account"` // nil if the account does not exist
+	Proof         ProofList       `json:"account_proof"`
+	StorageProofs []*StorageProof `json:"storage_proofs"`
+}
+
+// VerifyAccount checks the proof of the account with the given address against the state root,
+// and returns the proven account. It returns nil if the proof shows the account does not exist.
+func VerifyAccount(stateRoot common.Hash, addr common.Address, proof ProofList) (*types.Account, error) {
+	value, _, err := trie.VerifyProof(stateRoot, AccountKey(addr), newProofDB(proof))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid account proof: %v", err)
+	}
+	if len(value) == 0 {
+		return nil, nil
+	}
+	account := &types.Account{}
+	if err := types.FromBytes(value, account); err != nil {
+		return nil, fmt.Errorf("Failed to decode the proven account: %v", err)
+	}
+	return account, nil
+}
+
+// VerifyStorage checks the proof of the storage slot against the storage root of an account, and
+// returns the proven value. Unset slots have a zero value.
+func VerifyStorage(storageRoot common.Hash, key common.Hash, proof ProofList) (common.Hash, error) {
+	if storageRoot == (common.Hash{}) || storageRoot == emptyRoot {
+		return common.Hash{}, nil
+	}
+	enc, _, err := trie.VerifyProof(storageRoot, key[:], newProofDB(proof))
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("Invalid storage proof for key %v: %v", key.Hex(), err)
+	}
+	if len(enc) == 0 {
+		return common.Hash{}, nil
+	}
+	_, content, _, err := rlp.Split(enc)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("Failed to decode the proven value of key %v: %v", key.Hex(), err)
+	}
+	return common.BytesToHash(content), nil
+}
+
+// Verify checks the account proof against the state root and the storage proofs against the
+// proven storage root. It also checks the account and the storage values carried by the proof
+// match the proven ones, and returns the proven account.
+func (ap *AccountProof) Verify(stateRoot common.Hash) (*types.Account, error) {
+	account, err := VerifyAccount(stateRoot, ap.Address, ap.Proof)
+	if err != nil {
+		return nil, err
+	}
+	if (account == nil) != (ap.Account == nil) {
+		return nil, fmt.Errorf("Account %v existence does not match the proof", ap.Address.Hex())
+	}
+
+	storageRoot := common.Hash{}
+	if account != nil {
+		if account.Sequence != ap.Account.Sequence || !account.Balance.IsEqual(ap.Account.Balance) ||
+			account.Root != ap.Account.Root || account.CodeHash != ap.Account.CodeHash {
+			return nil, fmt.Errorf("Account %v does not match the proof", ap.Address.Hex())
+		}
+		storageRoot = account.Root
+	}
+
+	for _, sp := range ap.StorageProofs {
+		value, err := VerifyStorage(storageRoot, sp.Key, sp.Proof)
+		if err != nil {
+			return nil, err
+		}
+		if value != sp.Value {
+			return nil, fmt.Errorf("Value of key %v does not match the proof: %v vs %v", sp.Key.Hex(), sp.Value.Hex(), value.Hex())
+		}
+	}
+	return account, nil
+}
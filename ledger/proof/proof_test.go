@@ -0,0 +1,144 @@
+package proof
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/ledger/state"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/store/database/backend"
+)
+
+type proofTestEnv struct {
+	view     *state.StoreView
+	root     common.Hash
+	contract common.Address
+	user     common.Address
+}
+
+func newProofTestEnv() *proofTestEnv {
+	db := backend.NewMemDatabase()
+	sv := state.NewStoreView(100, common.Hash{}, db)
+	for i := 0; i < 50; i++ {
+		addr := common.BytesToAddress([]byte(fmt.Sprintf("account-%04d", i)))
+		acc := types.NewAccount(addr)
+		acc.Balance = types.NewCoins(int64(i), int64(i*2))
+		sv.SetAccount(addr, acc)
+	}
+	contract := common.BytesToAddress([]byte("contract"))
+	for i := 0; i < 20; i++ {
+		sv.SetState(contract, common.BytesToHash([]byte(fmt.Sprintf("key-%04d", i))), common.BytesToHash([]byte(fmt.Sprintf("value-%04d", i))))
+	}
+	root := sv.Save()
+
+	return &proofTestEnv{
+		view:     state.NewStoreView(100, root, db),
+		root:     root,
+		contract: contract,
+		user:     common.BytesToAddress([]byte("account-0007")),
+	}
+}
+
+func (env *proofTestEnv) prove(t *testing.T, addr common.Address, keys ...common.Hash) *AccountProof {
+	ap := &AccountProof{
+		Address:       addr,
+		Account:       env.view.GetAccount(addr),
+		Proof:         ProofList{},
+		StorageProofs: []*StorageProof{},
+	}
+	require.Nil(t, env.view.ProveAccount(addr, &ap.Proof))
+	for _, key := range keys {
+		sp := &StorageProof{Key: key, Value: env.view.GetState(addr, key), Proof: ProofList{}}
+		require.Nil(t, env.view.ProveState(addr, key, &sp.Proof))
+		ap.StorageProofs = append(ap.StorageProofs, sp)
+	}
+	return ap
+}
+
+func TestAccountKey(t *testing.T) {
+	addr := common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab")
+	assert.Equal(t, state.AccountKey(addr), AccountKey(addr))
+}
+
+func TestVerifyAccount(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	env := newProofTestEnv()
+	ap := env.prove(t, env.user)
+	require.NotEmpty(ap.Proof)
+
+	account, err := ap.Verify(env.root)
+	require.Nil(err)
+	require.NotNil(account)
+	assert.True(account.Balance.IsEqual(types.NewCoins(7, 14)))
+
+	// The proof of a missing account shows it does not exist
+	missing := common.BytesToAddress([]byte("missing"))
+	account, err = env.prove(t, missing).Verify(env.root)
+	assert.Nil(err)
+	assert.Nil(account)
+
+	// The proof does not hold against another state root
+	_, err = ap.Verify(common.HexToHash("0x1234"))
+	assert.NotNil(err)
+
+	// Nor does it prove another account
+	account, err = VerifyAccount(env.root, common.BytesToAddress([]byte("account-0008")), ap.Proof)
+	assert.True(err != nil || account == nil)
+
+	// A tampered account is rejected
+	ap.Account.Balance = types.NewCoins(7, 15)
+	_, err = ap.Verify(env.root)
+	assert.NotNil(err)
+
+	// So is a tampered proof node
+	ap = env.prove(t, env.user)
+	last := ap.Proof[len(ap.Proof)-1]
+	last[len(last)-1] ^= 0xff
+	_, err = ap.Verify(env.root)
+	assert.NotNil(err)
+}
+
+func TestVerifyStorage(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	env := newProofTestEnv()
+	key := common.BytesToHash([]byte("key-0003"))
+	unset := common.BytesToHash([]byte("unset"))
+	ap := env.prove(t, env.contract, key, unset)
+
+	account, err := ap.Verify(env.root)
+	require.Nil(err)
+	require.NotNil(account)
+	assert.Equal(common.BytesToHash([]byte("value-0003")), ap.StorageProofs[0].Value)
+	assert.Equal(common.Hash{}, ap.StorageProofs[1].Value)
+
+	value, err := VerifyStorage(account.Root, key, ap.StorageProofs[0].Proof)
+	require.Nil(err)
+	assert.Equal(common.BytesToHash([]byte("value-0003")), value)
+
+	// The proofs survive a JSON round trip
+	raw, err := json.Marshal(ap)
+	require.Nil(err)
+	decoded := &AccountProof{}
+	require.Nil(json.Unmarshal(raw, decoded))
+	_, err = decoded.Verify(env.root)
+	assert.Nil(err)
+
+	// A tampered value is rejected
+	ap.StorageProofs[0].Value = common.BytesToHash([]byte("value-0004"))
+	_, err = ap.Verify(env.root)
+	assert.NotNil(err)
+
+	// The accounts without storage have no stored values
+	ap = env.prove(t, env.user, key)
+	_, err = ap.Verify(env.root)
+	assert.Nil(err)
+	assert.Equal(common.Hash{}, ap.StorageProofs[0].Value)
+}
@@ -68,6 +68,11 @@ func StakeTransactionHeightListKey() common.Bytes {
 	return common.Bytes("ls/sthl")
 }
 
+// EquivocationEvidenceKey returns the state key for an equivocation evidence that has been processed
+func EquivocationEvidenceKey(evidenceHash common.Hash) common.Bytes {
+	return append(common.Bytes("ls/eqe/"), evidenceHash[:]...)
+}
+
 // StatePruningProgressKey returns the key for the state pruning progress
 func StatePruningProgressKey() common.Bytes {
 	return common.Bytes("ls/spp")
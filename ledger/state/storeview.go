@@ -111,6 +111,22 @@ func (sv *StoreView) ProveVCP(vcpKey []byte, vp *core.VCPProof) error {
 	return sv.store.ProveVCP(vcpKey, vp)
 }
 
+// ProveAccount writes the merkle proof of the account against the state root to proofDb. The
+// proof shows the account does not exist if it is not in the state.
+func (sv *StoreView) ProveAccount(addr common.Address, proofDb database.Putter) error {
+	return sv.store.Prove(AccountKey(addr), 0, proofDb)
+}
+
+// ProveState writes the merkle proof of the storage slot against the storage root of the account
+// to proofDb. Nothing is written if the account does not exist.
+func (sv *StoreView) ProveState(addr common.Address, key common.Hash, proofDb database.Putter) error {
+	account := sv.GetAccount(addr)
+	if account == nil {
+		return nil
+	}
+	return sv.getAccountStorage(account).Prove(key[:], 0, proofDb)
+}
+
 // Delete removes the value corresponding to the key
 func (sv *StoreView) Delete(key common.Bytes) {
 	sv.store.Delete(key)
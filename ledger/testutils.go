@@ -242,12 +242,19 @@ func newTesetValidatorManager(consensus core.ConsensusEngine) core.ValidatorMana
 
 func newTestMempool(peerID string, messenger p2p.Network, messengerL p2pl.Network) *mp.Mempool {
 	dispatcher := dp.NewDispatcher(messenger, nil)
-	mempool := mp.CreateMempool(dispatcher, nil)
+	mempool := mp.CreateMempool(dispatcher, syncedConsensusEngine{})
 	txMsgHandler := mp.CreateMempoolMessageHandler(mempool)
 	messenger.RegisterMessageHandler(txMsgHandler)
 	return mempool
 }
 
+// syncedConsensusEngine lets the test mempool screen the transactions right away
+type syncedConsensusEngine struct{}
+
+func (syncedConsensusEngine) HasSynced() bool {
+	return true
+}
+
 func prepareInitLedgerState(ledger *Ledger, numInAccs int) (accOut types.PrivAccount, accIns []types.PrivAccount) {
 	txFee := getMinimumTxFee()
 	validators := ledger.valMgr.GetValidatorSet(common.Hash{}).Validators()
@@ -269,6 +269,10 @@ func (tx *CoinbaseTx) String() string {
 
 //-----------------------------------------------------------------------------
 
+// SlashTx slashes an account that misbehaved. A SlashTx with a zero ReserveSequence slashes the
+// stake of a validator that equivocated, and its SlashProof is an RLP encoded core.EquivocationEvidence.
+// Otherwise it slashes the reserved fund of an account that overspent, and its SlashProof is an
+// RLP encoded OverspendingProof.
 type SlashTx struct {
 	Proposer        TxInput
 	SlashedAddress  common.Address
@@ -316,6 +320,11 @@ func (a *SlashTx) UnmarshalJSON(data []byte) error {
 
 func (_ *SlashTx) AssertIsTx() {}
 
+// IsEquivocationSlash returns whether the transaction slashes a validator for equivocation.
+func (tx *SlashTx) IsEquivocationSlash() bool {
+	return tx.ReserveSequence == 0
+}
+
 func (tx *SlashTx) SignBytes(chainID string) []byte {
 	signBytes := encodeToBytes(chainID)
 	sig := tx.Proposer.Signature
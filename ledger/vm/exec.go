@@ -13,6 +13,13 @@ import (
 
 // Execute executes the given smart contract
 func Execute(parentBlock *core.Block, tx *types.SmartContractTx, storeView *state.StoreView) (evmRet common.Bytes,
+	contractAddr common.Address, gasUsed uint64, evmErr error) {
+	return ExecuteWithConfig(parentBlock, tx, storeView, Config{})
+}
+
+// ExecuteWithConfig executes the given smart contract transaction with the given EVM
+// configuration, e.g. to attach a Tracer.
+func ExecuteWithConfig(parentBlock *core.Block, tx *types.SmartContractTx, storeView *state.StoreView, config Config) (evmRet common.Bytes,
 	contractAddr common.Address, gasUsed uint64, evmErr error) {
 	context := Context{
 		CanTransfer: CanTransfer,
@@ -28,7 +35,6 @@ func Execute(parentBlock *core.Block, tx *types.SmartContractTx, storeView *stat
 	chainConfig := &params.ChainConfig{
 		ChainID: chainIDBigInt,
 	}
-	config := Config{}
 	evm := NewEVM(context, storeView, chainConfig, config)
 
 	value := tx.From.Coins.PTXWei
@@ -82,6 +88,16 @@ func Execute(parentBlock *core.Block, tx *types.SmartContractTx, storeView *stat
 	return evmRet, contractAddr, gasUsed, evmErr
 }
 
+// IntrinsicGas computes the 'intrinsic gas' for a smart contract transaction with the given data.
+func IntrinsicGas(data []byte, createContract bool) (uint64, error) {
+	return calculateIntrinsicGas(data, createContract)
+}
+
+// IsExecutionReverted returns whether the execution error was caused by the REVERT opcode.
+func IsExecutionReverted(err error) bool {
+	return err == errExecutionReverted
+}
+
 // calculateIntrinsicGas computes the 'intrinsic gas' for a message with the given data.
 func calculateIntrinsicGas(data []byte, createContract bool) (uint64, error) {
 	// Set the starting gas for the raw transaction
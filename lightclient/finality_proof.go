@@ -0,0 +1,90 @@
+package lightclient
+
+import (
+	"fmt"
+
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/ledger/state"
+	"github.com/pandotoken/pando/store/database"
+)
+
+// FinalityProof proves that a block is finalized. Trio.First is the finalized block, Trio.Second
+// is its child linked to it by both Parent and HCC, and the HCC of Trio.Third carries the
+// validator votes committing Trio.Second. Trio.First.Proof proves the validator candidate pool
+// in the state of the finalized block, which determines the validators from two blocks later.
+type FinalityProof struct {
+	Trio core.SnapshotBlockTrio
+
+	// GuardianProof proves the guardian candidate pool in the state of the block voted by the
+	// guardians. It is only required if the finalized block carries guardian votes.
+	GuardianProof *core.VCPProof `rlp:"nil"`
+}
+
+// BuildFinalityProof builds the finality proof of the given block from the chain and the state
+// db of a full node.
+func BuildFinalityProof(chain *blockchain.Chain, db database.Database, blockHash common.Hash) (*FinalityProof, error) {
+	block, err := chain.FindBlock(blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find block %v: %v", blockHash.Hex(), err)
+	}
+	child, grandchild, err := findCommittedDescendants(chain, block)
+	if err != nil {
+		return nil, err
+	}
+
+	vcpProof, err := proveStateKey(block.BlockHeader, db, state.ValidatorCandidatePoolKey())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to prove VCP of block %v: %v", blockHash.Hex(), err)
+	}
+	fp := &FinalityProof{
+		Trio: core.SnapshotBlockTrio{
+			First:  core.SnapshotFirstBlock{Header: block.BlockHeader, Proof: *vcpProof},
+			Second: core.SnapshotSecondBlock{Header: child.BlockHeader},
+			Third:  core.SnapshotThirdBlock{Header: grandchild.BlockHeader, VoteSet: chain.FindVotesByHash(grandchild.Hash())},
+		},
+	}
+
+	if block.GuardianVotes != nil {
+		voted, err := chain.FindBlock(block.GuardianVotes.Block)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to find block %v voted by the guardians: %v", block.GuardianVotes.Block.Hex(), err)
+		}
+		fp.GuardianProof, err = proveStateKey(voted.BlockHeader, db, state.GuardianCandidatePoolKey())
+		if err != nil {
+			return nil, fmt.Errorf("Failed to prove GCP of block %v: %v", voted.Hash().Hex(), err)
+		}
+	}
+	return fp, nil
+}
+
+// findCommittedDescendants finds the child linked to the block by both Parent and HCC, and the
+// grandchild whose HCC carries the votes committing that child.
+func findCommittedDescendants(chain *blockchain.Chain, block *core.ExtendedBlock) (*core.ExtendedBlock, *core.ExtendedBlock, error) {
+	blockHash := block.Hash()
+	for _, childHash := range block.Children {
+		child, err := chain.FindBlock(childHash)
+		if err != nil || child.HCC.BlockHash != blockHash {
+			continue
+		}
+		for _, grandchildHash := range child.Children {
+			grandchild, err := chain.FindBlock(grandchildHash)
+			if err != nil || grandchild.HCC.BlockHash != childHash {
+				continue
+			}
+			if grandchild.HCC.Votes == nil || grandchild.HCC.Votes.IsEmpty() {
+				continue
+			}
+			return child, grandchild, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("Block %v has no committed child linked by HCC", blockHash.Hex())
+}
+
+func proveStateKey(header *core.BlockHeader, db database.Database, key common.Bytes) (*core.VCPProof, error) {
+	sv := state.NewStoreView(header.Height, header.StateHash, db)
+	vp := &core.VCPProof{}
+	err := sv.ProveVCP(key, vp)
+	return vp, err
+}
@@ -0,0 +1,179 @@
+// Package lightclient follows the chain trustlessly from a trusted checkpoint by verifying the
+// validator and guardian signatures of the finalized blocks, without running a full node.
+package lightclient
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/util"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/ledger/proof"
+	"github.com/pandotoken/pando/ledger/state"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/snapshot"
+	"github.com/pandotoken/pando/store/trie"
+)
+
+var logger = util.GetLoggerForModule("lightclient")
+
+// maxRetainedHeaders is the number of the latest finalized headers kept by the light client. The
+// guardian votes of a block refer to a checkpoint at most a few checkpoint intervals earlier.
+const maxRetainedHeaders = 1024
+
+// ErrUnknownHeader is returned when a header is not among the finalized headers retained by the
+// light client.
+var ErrUnknownHeader = errors.New("Header is not a finalized header known to the light client")
+
+// LightClient follows the finalized headers of the chain from a trusted checkpoint, without
+// executing any block. Each finalized header is verified against the validator set proven by
+// the previous one, so the updates must not skip the blocks which change the validator set.
+type LightClient struct {
+	mu *sync.RWMutex
+
+	chainID string
+	latest  *core.BlockHeader  // latest finalized header
+	valSet  *core.ValidatorSet // validators proven by the latest finalized header
+
+	headers map[common.Hash]*core.BlockHeader
+	order   []common.Hash // hashes of the retained headers, from the oldest
+}
+
+// NewLightClient creates a LightClient trusting the given checkpoint header. vcpProof proves the
+// validator candidate pool in the state of the checkpoint.
+func NewLightClient(checkpoint *core.BlockHeader, vcpProof *core.VCPProof) (*LightClient, error) {
+	valSet, err := snapshot.GetValidatorSetFromVCPProof(checkpoint.StateHash, vcpProof)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve validator set of the checkpoint from VCP proof: %v", err)
+	}
+
+	lc := &LightClient{
+		mu:      &sync.RWMutex{},
+		chainID: checkpoint.ChainID,
+		latest:  checkpoint,
+		valSet:  valSet,
+		headers: make(map[common.Hash]*core.BlockHeader),
+		order:   []common.Hash{},
+	}
+	lc.addHeader(checkpoint)
+
+	logger.Infof("Light client starts from checkpoint %v at height %v, validators: %v",
+		checkpoint.Hash().Hex(), checkpoint.Height, valSet)
+	return lc, nil
+}
+
+// LatestHeader returns the latest finalized header.
+func (lc *LightClient) LatestHeader() *core.BlockHeader {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.latest
+}
+
+// ValidatorSet returns the validator set proven by the latest finalized header.
+func (lc *LightClient) ValidatorSet() *core.ValidatorSet {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.valSet.Copy()
+}
+
+// GetHeader returns the retained finalized header with the given hash.
+func (lc *LightClient) GetHeader(hash common.Hash) (*core.BlockHeader, bool) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	header, ok := lc.headers[hash]
+	return header, ok
+}
+
+// Update verifies the finality proof of a block above the latest finalized header, and makes
+// the block the latest finalized header.
+func (lc *LightClient) Update(fp *FinalityProof) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	header := fp.Trio.First.Header
+	if header == nil {
+		return fmt.Errorf("Finality proof has no header")
+	}
+	if header.ChainID != lc.chainID {
+		return fmt.Errorf("Chain ID mismatch: %v vs %v", header.ChainID, lc.chainID)
+	}
+	if header.Height <= lc.latest.Height {
+		return fmt.Errorf("Block %v at height %v is not above the latest finalized height %v",
+			header.Hash().Hex(), header.Height, lc.latest.Height)
+	}
+
+	valSet, err := snapshot.CheckBlockTrio(lc.valSet, &fp.Trio)
+	if err != nil {
+		return fmt.Errorf("Invalid finality proof for block %v: %v", header.Hash().Hex(), err)
+	}
+	if err := lc.checkGuardianVotes(header, fp.GuardianProof); err != nil {
+		return fmt.Errorf("Invalid guardian votes in block %v: %v", header.Hash().Hex(), err)
+	}
+
+	if !valSet.Equals(lc.valSet) {
+		logger.Infof("Validator set changed at height %v: %v", header.Height, valSet)
+	}
+	lc.latest = header
+	lc.valSet = valSet
+	lc.addHeader(header)
+	return nil
+}
+
+// VerifyAccount verifies the account proof against the state of the retained finalized header
+// with the given hash, and returns the proven account.
+func (lc *LightClient) VerifyAccount(blockHash common.Hash, ap *proof.AccountProof) (*types.Account, error) {
+	header, ok := lc.GetHeader(blockHash)
+	if !ok {
+		return nil, ErrUnknownHeader
+	}
+	return ap.Verify(header.StateHash)
+}
+
+// checkGuardianVotes checks the aggregated guardian signature of the block, if any, against the
+// guardian candidate pool in the state of the voted block. The voted block is an earlier
+// checkpoint, so it must be a finalized header known to the light client.
+func (lc *LightClient) checkGuardianVotes(header *core.BlockHeader, gcpProof *core.VCPProof) error {
+	votes := header.GuardianVotes
+	if votes == nil {
+		return nil
+	}
+	voted, ok := lc.headers[votes.Block]
+	if !ok {
+		return fmt.Errorf("Voted block %v: %v", votes.Block.Hex(), ErrUnknownHeader)
+	}
+	if gcpProof == nil {
+		return fmt.Errorf("Missing guardian candidate pool proof")
+	}
+	gcp, err := getGuardianPoolFromGCPProof(voted.StateHash, gcpProof)
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve guardian candidate pool from GCP proof: %v", err)
+	}
+	if res := votes.Validate(gcp); res.IsError() {
+		return fmt.Errorf("%v", res.Message)
+	}
+	return nil
+}
+
+func (lc *LightClient) addHeader(header *core.BlockHeader) {
+	hash := header.Hash()
+	lc.headers[hash] = header
+	lc.order = append(lc.order, hash)
+	for len(lc.order) > maxRetainedHeaders {
+		delete(lc.headers, lc.order[0])
+		lc.order = lc.order[1:]
+	}
+}
+
+func getGuardianPoolFromGCPProof(stateHash common.Hash, gcpProof *core.VCPProof) (*core.GuardianCandidatePool, error) {
+	serializedGCP, _, err := trie.VerifyProof(stateHash, state.GuardianCandidatePoolKey(), gcpProof)
+	if err != nil {
+		return nil, err
+	}
+	gcp := &core.GuardianCandidatePool{}
+	if err := types.FromBytes(serializedGCP, gcp); err != nil {
+		return nil, err
+	}
+	return gcp, nil
+}
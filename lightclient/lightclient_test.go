@@ -0,0 +1,299 @@
+package lightclient
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/crypto/bls"
+	"github.com/pandotoken/pando/ledger/proof"
+	"github.com/pandotoken/pando/ledger/state"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/store/database"
+	"github.com/pandotoken/pando/store/database/backend"
+	"github.com/pandotoken/pando/store/kvstore"
+)
+
+const (
+	lightClientTestChainID = "lightclienttest"
+
+	// The validator set changes in the state of this block, and is in effect two blocks later
+	valSetChangeHeight = 5
+)
+
+type lightClientTestEnv struct {
+	db    database.Database
+	chain *blockchain.Chain
+
+	oldValidators []*crypto.PrivateKey
+	newValidators []*crypto.PrivateKey
+	guardians     []*bls.SecretKey
+	gcp           *core.GuardianCandidatePool
+
+	oldState common.Hash
+	newState common.Hash
+	user     common.Address
+
+	blocks map[uint64]*core.Block // blocks of the main fork by height
+}
+
+func newLightClientTestEnv(t *testing.T, numBlocks uint64) *lightClientTestEnv {
+	require := require.New(t)
+
+	env := &lightClientTestEnv{
+		db:     backend.NewMemDatabase(),
+		user:   common.BytesToAddress([]byte("user")),
+		blocks: make(map[uint64]*core.Block),
+	}
+
+	sv := state.NewStoreView(1, common.Hash{}, env.db)
+	vcp := &core.ValidatorCandidatePool{}
+	for i := 0; i < 4; i++ {
+		privKey, _, err := crypto.GenerateKeyPair()
+		require.Nil(err)
+		addr := privKey.PublicKey().Address()
+		require.Nil(vcp.DepositStake(addr, addr, core.MinValidatorStakeDeposit, 1))
+		env.oldValidators = append(env.oldValidators, privKey)
+	}
+	sv.UpdateValidatorCandidatePool(vcp)
+
+	gcp := core.NewGuardianCandidatePool()
+	for i := 0; i < 4; i++ {
+		_, pubKey, err := crypto.GenerateKeyPair()
+		require.Nil(err)
+		blsKey, err := bls.RandKey()
+		require.Nil(err)
+		require.Nil(gcp.DepositStake(pubKey.Address(), pubKey.Address(), core.MinGuardianStakeDeposit, blsKey.PublicKey(), 1))
+		env.guardians = append(env.guardians, blsKey)
+	}
+	sv.UpdateGuardianCandidatePool(gcp)
+	env.gcp = gcp
+
+	userAcc := types.NewAccount(env.user)
+	userAcc.Balance = types.NewCoins(100, 200)
+	sv.SetAccount(env.user, userAcc)
+	env.oldState = sv.Save()
+
+	// The new validator holds most of the stake, so the old validators alone have no majority
+	sv = state.NewStoreView(valSetChangeHeight, env.oldState, env.db)
+	privKey, _, err := crypto.GenerateKeyPair()
+	require.Nil(err)
+	addr := privKey.PublicKey().Address()
+	require.Nil(vcp.DepositStake(addr, addr, new(big.Int).Mul(core.MinValidatorStakeDeposit, big.NewInt(10)), valSetChangeHeight))
+	sv.UpdateValidatorCandidatePool(vcp)
+	env.newValidators = append(append(env.newValidators, env.oldValidators...), privKey)
+
+	userAcc = sv.GetAccount(env.user)
+	userAcc.Balance = types.NewCoins(300, 400)
+	sv.SetAccount(env.user, userAcc)
+	env.newState = sv.Save()
+
+	root := env.newBlock(nil, 1, nil)
+	env.chain = blockchain.NewChain(lightClientTestChainID, kvstore.NewKVStore(env.db), root)
+	env.blocks[1] = root
+
+	for height := uint64(2); height <= numBlocks; height++ {
+		var guardianVotes *core.AggregatedVotes
+		if height > 1 && common.IsCheckPointHeight(height) {
+			guardianVotes = env.signGuardianVotes(env.blocks[height-uint64(common.CheckpointInterval)], env.guardians)
+		}
+		env.blocks[height] = env.addBlock(t, env.blocks[height-1], guardianVotes)
+	}
+	return env
+}
+
+func (env *lightClientTestEnv) newBlock(parent *core.Block, height uint64, guardianVotes *core.AggregatedVotes) *core.Block {
+	block := core.NewBlock()
+	block.ChainID = lightClientTestChainID
+	block.Epoch = height
+	block.Height = height
+	block.StateHash = env.oldState
+	if height >= valSetChangeHeight {
+		block.StateHash = env.newState
+	}
+	block.Timestamp = big.NewInt(int64(height))
+	block.GuardianVotes = guardianVotes
+	if parent != nil {
+		block.Parent = parent.Hash()
+		block.HCC = core.CommitCertificate{BlockHash: parent.Hash(), Votes: env.signVotes(parent, env.validatorsAt(parent.Height))}
+	}
+	return block
+}
+
+// addBlock adds a child block whose HCC carries the votes for the parent.
+func (env *lightClientTestEnv) addBlock(t *testing.T, parent *core.Block, guardianVotes *core.AggregatedVotes) *core.Block {
+	block := env.newBlock(parent, parent.Height+1, guardianVotes)
+	_, err := env.chain.AddBlock(block)
+	require.Nil(t, err)
+	return block
+}
+
+func (env *lightClientTestEnv) validatorsAt(height uint64) []*crypto.PrivateKey {
+	if height >= valSetChangeHeight+2 {
+		return env.newValidators
+	}
+	return env.oldValidators
+}
+
+func (env *lightClientTestEnv) signVotes(block *core.Block, validators []*crypto.PrivateKey) *core.VoteSet {
+	votes := core.NewVoteSet()
+	for _, privKey := range validators {
+		vote := core.Vote{
+			Block:  block.Hash(),
+			Height: block.Height,
+			Epoch:  block.Epoch,
+			ID:     privKey.PublicKey().Address(),
+		}
+		vote.Sign(privKey)
+		votes.AddVote(vote)
+	}
+	return votes
+}
+
+func (env *lightClientTestEnv) signGuardianVotes(block *core.Block, signers []*bls.SecretKey) *core.AggregatedVotes {
+	votes := core.NewAggregateVotes(block.Hash(), env.gcp)
+	for _, key := range signers {
+		votes.Sign(key, env.gcp.WithStake().Index(key.PublicKey()))
+	}
+	return votes
+}
+
+func (env *lightClientTestEnv) newLightClient(t *testing.T) *LightClient {
+	checkpoint := env.blocks[1].BlockHeader
+	vcpProof, err := proveStateKey(checkpoint, env.db, state.ValidatorCandidatePoolKey())
+	require.Nil(t, err)
+	lc, err := NewLightClient(checkpoint, vcpProof)
+	require.Nil(t, err)
+	return lc
+}
+
+func (env *lightClientTestEnv) finalityProof(t *testing.T, height uint64) *FinalityProof {
+	fp, err := BuildFinalityProof(env.chain, env.db, env.blocks[height].Hash())
+	require.Nil(t, err)
+	return fp
+}
+
+func (env *lightClientTestEnv) proveUser(t *testing.T, stateHash common.Hash) *proof.AccountProof {
+	sv := state.NewStoreView(0, stateHash, env.db)
+	ap := &proof.AccountProof{Address: env.user, Account: sv.GetAccount(env.user)}
+	require.Nil(t, sv.ProveAccount(env.user, &ap.Proof))
+	return ap
+}
+
+func TestLightClientFollowsChain(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	env := newLightClientTestEnv(t, 104)
+	lc := env.newLightClient(t)
+	assert.Equal(4, lc.ValidatorSet().Size())
+
+	require.Nil(lc.Update(env.finalityProof(t, 3)))
+	assert.Equal(env.blocks[3].Hash(), lc.LatestHeader().Hash())
+	assert.NotNil(lc.Update(env.finalityProof(t, 3)))
+
+	// The validator set changed in the state of block 5
+	require.Nil(lc.Update(env.finalityProof(t, valSetChangeHeight)))
+	assert.Equal(5, lc.ValidatorSet().Size())
+
+	// The following blocks are committed by the new validators
+	require.Nil(lc.Update(env.finalityProof(t, 8)))
+	assert.Equal(uint64(8), lc.LatestHeader().Height)
+
+	// The account proofs are verified against the finalized headers
+	account, err := lc.VerifyAccount(env.blocks[8].Hash(), env.proveUser(t, env.newState))
+	require.Nil(err)
+	assert.True(account.Balance.IsEqual(types.NewCoins(300, 400)))
+	_, err = lc.VerifyAccount(env.blocks[3].Hash(), env.proveUser(t, env.newState))
+	assert.NotNil(err)
+	_, err = lc.VerifyAccount(env.blocks[4].Hash(), env.proveUser(t, env.oldState))
+	assert.Equal(ErrUnknownHeader, err)
+
+	// The checkpoint block carries the guardian votes for the previous checkpoint
+	fp := env.finalityProof(t, 101)
+	require.NotNil(fp.GuardianProof)
+	require.Nil(lc.Update(fp))
+	assert.Equal(uint64(101), lc.LatestHeader().Height)
+}
+
+func TestLightClientRejectsSkippedValidatorChange(t *testing.T) {
+	env := newLightClientTestEnv(t, 10)
+	lc := env.newLightClient(t)
+
+	// The old validators do not know the new validator voting for block 9
+	assert.NotNil(t, lc.Update(env.finalityProof(t, 8)))
+	assert.Equal(t, uint64(1), lc.LatestHeader().Height)
+}
+
+func TestLightClientRejectsInvalidVotes(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	env := newLightClientTestEnv(t, 5)
+	lc := env.newLightClient(t)
+
+	// Votes from keys outside the validator set
+	fp := env.finalityProof(t, 3)
+	third := *fp.Trio.Third.Header
+	outsiders := []*crypto.PrivateKey{}
+	for i := 0; i < 4; i++ {
+		privKey, _, err := crypto.GenerateKeyPair()
+		require.Nil(err)
+		outsiders = append(outsiders, privKey)
+	}
+	third.HCC.Votes = env.signVotes(env.blocks[4], outsiders)
+	fp.Trio.Third.Header = &third
+	assert.NotNil(lc.Update(fp))
+
+	// Missing votes
+	third.HCC.Votes = nil
+	assert.NotNil(lc.Update(fp))
+
+	// A validator set proof from another state
+	fp = env.finalityProof(t, 3)
+	vcpProof, err := proveStateKey(env.blocks[valSetChangeHeight].BlockHeader, env.db, state.ValidatorCandidatePoolKey())
+	require.Nil(err)
+	fp.Trio.First.Proof = *vcpProof
+	assert.NotNil(lc.Update(fp))
+
+	// A broken HCC link
+	fp = env.finalityProof(t, 3)
+	fp.Trio.Second.Header = env.blocks[2].BlockHeader
+	assert.NotNil(lc.Update(fp))
+
+	assert.Equal(uint64(1), lc.LatestHeader().Height)
+	require.Nil(lc.Update(env.finalityProof(t, 3)))
+}
+
+func TestLightClientRejectsInvalidGuardianVotes(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	env := newLightClientTestEnv(t, 103)
+	lc := env.newLightClient(t)
+	require.Nil(lc.Update(env.finalityProof(t, valSetChangeHeight)))
+
+	// The guardian candidate pool proof is required
+	fp := env.finalityProof(t, 101)
+	fp.GuardianProof = nil
+	assert.NotNil(lc.Update(fp))
+
+	// A fork whose checkpoint block carries votes signed by keys outside the guardian pool
+	forged := env.signGuardianVotes(env.blocks[1], env.guardians[:2])
+	outsider, err := bls.RandKey()
+	require.Nil(err)
+	forged.Signature.Aggregate(outsider.Sign(common.Bytes("forged")))
+	fork := env.addBlock(t, env.blocks[100], forged)
+	env.addBlock(t, env.addBlock(t, fork, nil), nil)
+
+	fp, err = BuildFinalityProof(env.chain, env.db, fork.Hash())
+	require.Nil(err)
+	assert.NotNil(lc.Update(fp))
+
+	require.Nil(lc.Update(env.finalityProof(t, 101)))
+}
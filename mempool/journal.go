@@ -0,0 +1,111 @@
+package mempool
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/rlp"
+)
+
+//
+// txJournal is an append-only log of the raw transactions accepted by the mempool, which
+// allows the pending transactions to survive node restarts.
+//
+type txJournal struct {
+	path   string
+	writer *os.File
+}
+
+type journalEntry struct {
+	CreatedAt uint64 // unix timestamp of when the tx was first accepted
+	RawTx     common.Bytes
+}
+
+func (e *journalEntry) isOutdated() bool {
+	return time.Since(time.Unix(int64(e.CreatedAt), 0)) > maxTxLife
+}
+
+func newTxJournal(path string) *txJournal {
+	return &txJournal{
+		path: path,
+	}
+}
+
+// load reads all the entries from the journal. A truncated entry at the end of the journal,
+// e.g. caused by a crash during the write, is ignored.
+func (j *txJournal) load() ([]*journalEntry, error) {
+	file, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return []*journalEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := []*journalEntry{}
+	stream := rlp.NewStream(bufio.NewReader(file), 0)
+	for {
+		entry := &journalEntry{}
+		err := stream.Decode(entry)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logger.Warnf("Failed to decode mempool journal entry, skip the rest of the journal: %v", err)
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// insert appends the raw transaction to the journal.
+func (j *txJournal) insert(rawTx common.Bytes, createdAt time.Time) error {
+	if j.writer == nil {
+		if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+			return err
+		}
+		writer, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		j.writer = writer
+	}
+	return rlp.Encode(j.writer, &journalEntry{
+		CreatedAt: uint64(createdAt.Unix()),
+		RawTx:     rawTx,
+	})
+}
+
+// rotate replaces the content of the journal with the given entries.
+func (j *txJournal) rotate(entries []*journalEntry) error {
+	if err := j.close(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		if err := rlp.Encode(&buf, entry); err != nil {
+			return err
+		}
+	}
+	return common.WriteFileAtomic(j.path, buf.Bytes(), 0600)
+}
+
+func (j *txJournal) close() error {
+	if j.writer == nil {
+		return nil
+	}
+	err := j.writer.Close()
+	j.writer = nil
+	return err
+}
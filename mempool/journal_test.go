@@ -0,0 +1,110 @@
+package mempool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/common"
+	p2psim "github.com/pandotoken/pando/p2p/simulation"
+)
+
+func TestTxJournal(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "mempool_journal")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	journal := newTxJournal(filepath.Join(dir, "mempool", "journal"))
+	entries, err := journal.load()
+	require.Nil(err)
+	assert.Equal(0, len(entries))
+
+	now := time.Now()
+	require.Nil(journal.insert(createTestRawTx("tx1"), now))
+	require.Nil(journal.insert(createTestRawTx("tx2"), now))
+	require.Nil(journal.close())
+
+	entries, err = journal.load()
+	require.Nil(err)
+	require.Equal(2, len(entries))
+	assert.Equal("tx1", string(entries[0].RawTx))
+	assert.Equal(uint64(now.Unix()), entries[0].CreatedAt)
+	assert.Equal("tx2", string(entries[1].RawTx))
+
+	// A truncated entry at the end of the journal is ignored
+	file, err := os.OpenFile(journal.path, os.O_WRONLY|os.O_APPEND, 0600)
+	require.Nil(err)
+	_, err = file.Write([]byte{0xc8, 0x01})
+	require.Nil(err)
+	require.Nil(file.Close())
+	entries, err = journal.load()
+	require.Nil(err)
+	assert.Equal(2, len(entries))
+
+	require.Nil(journal.rotate([]*journalEntry{entries[1]}))
+	entries, err = journal.load()
+	require.Nil(err)
+	require.Equal(1, len(entries))
+	assert.Equal("tx2", string(entries[0].RawTx))
+}
+
+func TestMempoolJournal(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "mempool_journal")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	journalPath := filepath.Join(dir, "mempool", "journal")
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	mempool.SetLedger(newReplaceByFeeTestLedger())
+	mempool.SetJournalPath(journalPath)
+	ctx, cancel := context.WithCancel(context.Background())
+	require.Nil(mempool.Start(ctx))
+
+	// raw tx format: address:sequence:effectiveGasPrice
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("A1:1:100")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("A1:2:100")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("B1:1:200")))
+	mempool.Update([]common.Bytes{createTestRawTx("A1:1:100")})
+	assert.Equal(2, mempool.Size())
+	cancel()
+	mempool.Wait()
+
+	// An outdated journal entry should be dropped
+	outdated := &journalEntry{
+		CreatedAt: uint64(time.Now().Add(-2 * maxTxLife).Unix()),
+		RawTx:     createTestRawTx("C1:1:300"),
+	}
+	require.Nil(newTxJournal(journalPath).insert(outdated.RawTx, time.Unix(int64(outdated.CreatedAt), 0)))
+
+	// Restart the mempool, the pending transactions should be restored
+	ledger := newReplaceByFeeTestLedger().(*ReplaceByFeeTestLedger)
+	ledger.screenedSequences[common.HexToAddress("A1")] = 1 // A1:1:100 has been committed
+	mempool, _ = newTestMempool("peer1", p2psimnet)
+	mempool.SetLedger(ledger)
+	mempool.SetJournalPath(journalPath)
+	ctx, cancel = context.WithCancel(context.Background())
+	require.Nil(mempool.Start(ctx))
+	for i := 0; i < 100 && mempool.Size() < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	mempool.Wait()
+
+	assert.Equal(2, mempool.Size())
+	reapedRawTxs := mempool.Reap(-1)
+	require.Equal(2, len(reapedRawTxs))
+	assert.Equal("B1:1:200", string(reapedRawTxs[0]))
+	assert.Equal("A1:2:100", string(reapedRawTxs[1]))
+}
@@ -5,17 +5,19 @@ import (
 	"encoding/hex"
 	"errors"
 	"math/big"
+	"sort"
 	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 
 	"github.com/pandotoken/pando/common"
 	"github.com/pandotoken/pando/common/clist"
 	"github.com/pandotoken/pando/common/math"
+	"github.com/pandotoken/pando/common/metrics"
 	"github.com/pandotoken/pando/common/pqueue"
 	"github.com/pandotoken/pando/common/result"
-	"github.com/pandotoken/pando/consensus"
 	"github.com/pandotoken/pando/core"
 	dp "github.com/pandotoken/pando/dispatcher"
 )
@@ -30,8 +32,14 @@ func (m MempoolError) Error() string {
 
 const DuplicateTxError = MempoolError("Transaction already seen")
 const FastsyncSkipTxError = MempoolError("Skip tx during fastsync")
+const ReplacementUnderpricedError = MempoolError("Replacement transaction underpriced")
+const MempoolFullError = MempoolError("Mempool is full, please submit your transaction again later or with a higher gas price")
+const TooManyPendingTxsError = MempoolError("Too many pending transactions from the address")
 
-const MaxMempoolTxCount int = 25600
+const insertedTxsQueueSize = 1024
+
+const journalSyncCheckInterval = 1 * time.Second
+const journalRotationInterval = maxTxLife
 
 //
 // mempoolTransaction implements the pqueue.Element interface
@@ -101,12 +109,45 @@ func (mtg *mempoolTransactionGroup) PopTx() (common.Bytes, *core.TxInfo) {
 	return mptx.rawTransaction, mptx.txInfo
 }
 
+// findTx returns the transaction with the given sequence number, or nil if not found.
+func (mtg *mempoolTransactionGroup) findTx(sequence uint64) *mempoolTransaction {
+	for _, elem := range *mtg.txs.ElementList() {
+		mptx := elem.(*mempoolTransaction)
+		if mptx.txInfo.Sequence == sequence {
+			return mptx
+		}
+	}
+	return nil
+}
+
+// replaceTx replaces the given transaction of the group with the new transaction.
+func (mtg *mempoolTransactionGroup) replaceTx(mptx *mempoolTransaction, rawTx common.Bytes, txInfo *core.TxInfo) {
+	mtg.txs.Remove(mptx.GetIndex())
+	mtg.AddTx(rawTx, txInfo)
+}
+
 func (mtg *mempoolTransactionGroup) IsEmpty() bool {
 	return mtg.txs.IsEmpty()
 }
 
-// RemoveTxs removes matching Txs from transaction group. Returns number of Txs removed.
-func (mtg *mempoolTransactionGroup) RemoveTxs(committedRawTxMap map[string]bool) (numRemoved int) {
+func (mtg *mempoolTransactionGroup) NumTxs() int {
+	return mtg.txs.NumElements()
+}
+
+// txsFromTail returns the transactions of the group ordered by sequence number from high to low.
+func (mtg *mempoolTransactionGroup) txsFromTail() []*mempoolTransaction {
+	mptxs := []*mempoolTransaction{}
+	for _, elem := range *mtg.txs.ElementList() {
+		mptxs = append(mptxs, elem.(*mempoolTransaction))
+	}
+	sort.Slice(mptxs, func(i, j int) bool {
+		return mptxs[i].txInfo.Sequence > mptxs[j].txInfo.Sequence
+	})
+	return mptxs
+}
+
+// RemoveTxs removes matching Txs from transaction group. Returns number of Txs and bytes removed.
+func (mtg *mempoolTransactionGroup) RemoveTxs(committedRawTxMap map[string]bool) (numRemoved int, numBytesRemoved int) {
 	elementList := mtg.txs.ElementList()
 	elemsTobeRemoved := []pqueue.Element{}
 	for _, elem := range *elementList {
@@ -120,6 +161,7 @@ func (mtg *mempoolTransactionGroup) RemoveTxs(committedRawTxMap map[string]bool)
 	for _, elem := range elemsTobeRemoved {
 		mtg.txs.Remove(elem.GetIndex())
 		numRemoved++
+		numBytesRemoved += len(elem.(*mempoolTransaction).rawTransaction)
 	}
 	return
 }
@@ -133,6 +175,13 @@ func createMempoolTransactionGroup(rawTx common.Bytes, txInfo *core.TxInfo) *mem
 	return txGroup
 }
 
+//
+// ConsensusEngine is the part of the consensus engine the mempool relies on
+//
+type ConsensusEngine interface {
+	HasSynced() bool
+}
+
 //
 // Mempool manages the transactions submitted by the clients
 // or relayed from peers
@@ -140,15 +189,27 @@ func createMempoolTransactionGroup(rawTx common.Bytes, txInfo *core.TxInfo) *mem
 type Mempool struct {
 	mutex *sync.Mutex
 
-	consensus  *consensus.ConsensusEngine
+	consensus  ConsensusEngine
 	ledger     core.Ledger
 	dispatcher *dp.Dispatcher
 
 	newTxs           *clist.CList          // new transactions, to be gossiped to other nodes
+	insertedTxs      chan common.Bytes     // transactions that passed the screening, for subscribers
 	candidateTxs     *pqueue.PriorityQueue // candidate transactions for new block assembly, ordered by the transaction fee (high to low)
 	txBookeepper     transactionBookkeeper
 	addressToTxGroup map[common.Address]*mempoolTransactionGroup
 	size             int
+	numBytes         int // total size of the transactions in the candidate pool
+
+	replacementPriceBump int64 // minimal effective gas price increase (in percent) to replace a pending tx
+	maxNumTxs            int   // maximal number of transactions in the candidate pool
+	maxNumBytes          int   // maximal total size of the transactions in the candidate pool
+	maxNumTxsPerAddress  int   // maximal number of pending transactions from a single address
+
+	journal *txJournal // nil if the journal is disabled
+
+	evictedTxCounter  metrics.Counter // txs evicted to make room for higher priced txs
+	rejectedTxCounter metrics.Counter // txs rejected due to the mempool limits
 
 	// Life cycle
 	wg      *sync.WaitGroup
@@ -159,17 +220,39 @@ type Mempool struct {
 }
 
 // CreateMempool creates an instance of Mempool
-func CreateMempool(dispatcher *dp.Dispatcher, engine *consensus.ConsensusEngine) *Mempool {
-	return &Mempool{
+func CreateMempool(dispatcher *dp.Dispatcher, engine ConsensusEngine) *Mempool {
+	mp := &Mempool{
 		mutex:            &sync.Mutex{},
 		consensus:        engine,
 		dispatcher:       dispatcher,
 		newTxs:           clist.New(),
+		insertedTxs:      make(chan common.Bytes, insertedTxsQueueSize),
 		candidateTxs:     pqueue.CreatePriorityQueue(),
 		addressToTxGroup: make(map[common.Address]*mempoolTransactionGroup),
 		txBookeepper:     createTransactionBookkeeper(defaultMaxNumTxs),
 		wg:               &sync.WaitGroup{},
+
+		replacementPriceBump: viper.GetInt64(common.CfgMempoolReplacementPriceBump),
+		maxNumTxs:            viper.GetInt(common.CfgMempoolMaxNumTxs),
+		maxNumBytes:          viper.GetInt(common.CfgMempoolMaxNumBytes),
+		maxNumTxsPerAddress:  viper.GetInt(common.CfgMempoolMaxNumTxsPerAddress),
+
+		evictedTxCounter:  metrics.GetOrRegisterCounter("mempool/evicted", nil),
+		rejectedTxCounter: metrics.GetOrRegisterCounter("mempool/rejected", nil),
 	}
+
+	metrics.NewRegisteredFunctionalGauge("mempool/size", nil, func() int64 {
+		mp.mutex.Lock()
+		defer mp.mutex.Unlock()
+		return int64(mp.size)
+	})
+	metrics.NewRegisteredFunctionalGauge("mempool/bytes", nil, func() int64 {
+		mp.mutex.Lock()
+		defer mp.mutex.Unlock()
+		return int64(mp.numBytes)
+	})
+
+	return mp
 }
 
 // SetLedger sets the ledger for the mempool
@@ -177,22 +260,29 @@ func (mp *Mempool) SetLedger(ledger core.Ledger) {
 	mp.ledger = ledger
 }
 
+// SetJournalPath enables journaling the accepted transactions to the given file, so that
+// the pending transactions can be restored after the node restarts.
+func (mp *Mempool) SetJournalPath(path string) {
+	mp.journal = newTxJournal(path)
+}
+
 // InsertTransaction inserts the incoming transaction to mempool (submitted by the clients or relayed from peers)
 func (mp *Mempool) InsertTransaction(rawTx common.Bytes) error {
 	mp.mutex.Lock()
 	defer mp.mutex.Unlock()
 
+	return mp.insertTransactionUnsafe(rawTx, time.Now())
+}
+
+// insertTransactionUnsafe is the non-locking version of InsertTransaction. The createdAt is the time
+// when the transaction was first accepted.
+func (mp *Mempool) insertTransactionUnsafe(rawTx common.Bytes, createdAt time.Time) error {
 	if mp.txBookeepper.hasSeen(rawTx) {
 		logger.Debugf("Transaction already seen: %v, hash: 0x%v",
 			hex.EncodeToString(rawTx), getTransactionHash(rawTx))
 		return DuplicateTxError
 	}
 
-	// if mp.size >= MaxMempoolTxCount {
-	// 	logger.Debugf("Mempool is full")
-	// 	return errors.New("mempool is full, please submit your transaction again later")
-	// }
-
 	var txInfo *core.TxInfo
 	var checkTxRes result.Result
 
@@ -200,16 +290,29 @@ func (mp *Mempool) InsertTransaction(rawTx common.Bytes) error {
 	if mp.consensus.HasSynced() {
 		txInfo, checkTxRes = mp.ledger.ScreenTx(rawTx)
 		if !checkTxRes.IsOK() {
+			if checkTxRes.Code == result.CodeInvalidSequence {
+				// The tx might replace a pending tx with the same sequence number
+				if replaced, err := mp.replaceTransaction(rawTx, createdAt); replaced || err != nil {
+					return err
+				}
+			}
 			logger.Debugf("Transaction screening failed, tx: %v, error: %v", hex.EncodeToString(rawTx), checkTxRes.Message)
 			return errors.New(checkTxRes.Message)
 		}
 
+		if err := mp.makeRoomFor(rawTx, txInfo); err != nil {
+			mp.rejectedTxCounter.Inc(1)
+			logger.Debugf("Transaction rejected, tx.hash: 0x%v, error: %v", getTransactionHash(rawTx), err)
+			return err
+		}
+
 		// only record the transactions that passed the screening. This is because that
 		// an invalid transaction could becoume valid later on. For example, assume expected
 		// sequence for an account is 6. The account accidentally submits txA (seq = 7), got rejected.
 		// He then submit txB(seq = 6), and then txA(seq = 7) again. For the second submission, txA
 		// should not be rejected even though it has been submitted earlier.
-		mp.txBookeepper.record(rawTx)
+		mp.txBookeepper.recordAt(rawTx, createdAt)
+		mp.journalTx(rawTx, createdAt)
 
 		txGroup, ok := mp.addressToTxGroup[txInfo.Address]
 		if ok {
@@ -223,6 +326,8 @@ func (mp *Mempool) InsertTransaction(rawTx common.Bytes) error {
 		logger.Debugf("rawTx: %v, txInfo: %v", hex.EncodeToString(rawTx), txInfo)
 		logger.Infof("Insert tx, tx.hash: 0x%v", getTransactionHash(rawTx))
 		mp.size++
+		mp.numBytes += len(rawTx)
+		mp.notifyInsertedTx(rawTx)
 
 		return nil
 	}
@@ -230,15 +335,254 @@ func (mp *Mempool) InsertTransaction(rawTx common.Bytes) error {
 	return FastsyncSkipTxError
 }
 
+// makeRoomFor checks the mempool limits for the new transaction. If the mempool is full, it evicts the
+// transactions with the highest sequence numbers from the lowest priority transaction groups, as long as
+// they are priced lower than the new transaction. Otherwise the new transaction is rejected.
+func (mp *Mempool) makeRoomFor(rawTx common.Bytes, txInfo *core.TxInfo) error {
+	if txGroup, ok := mp.addressToTxGroup[txInfo.Address]; ok && txGroup.NumTxs() >= mp.maxNumTxsPerAddress {
+		return TooManyPendingTxsError
+	}
+
+	return mp.evictCheaperTxs(txInfo, 1, len(rawTx))
+}
+
+// evictCheaperTxs evicts the transactions of the other addresses priced lower than the given
+// transaction, so that numTxs more transactions and numBytes more bytes fit in the mempool.
+func (mp *Mempool) evictCheaperTxs(txInfo *core.TxInfo, numTxs int, numBytes int) error {
+	numTxsToFree := mp.size + numTxs - mp.maxNumTxs
+	numBytesToFree := mp.numBytes + numBytes - mp.maxNumBytes
+	if numTxsToFree <= 0 && numBytesToFree <= 0 {
+		return nil
+	}
+
+	cheaperTxGroups := []*mempoolTransactionGroup{}
+	for _, elem := range *mp.candidateTxs.ElementList() {
+		txGroup := elem.(*mempoolTransactionGroup)
+		if txGroup.address == txInfo.Address || txGroup.Priority().Cmp(txInfo.EffectiveGasPrice) >= 0 {
+			continue
+		}
+		cheaperTxGroups = append(cheaperTxGroups, txGroup)
+	}
+	sort.Slice(cheaperTxGroups, func(i, j int) bool {
+		return cheaperTxGroups[i].Priority().Cmp(cheaperTxGroups[j].Priority()) < 0
+	})
+
+	evictees := []*mempoolTransaction{}
+	evicteeGroups := []*mempoolTransactionGroup{}
+	for _, txGroup := range cheaperTxGroups {
+		for _, mptx := range txGroup.txsFromTail() {
+			if numTxsToFree <= 0 && numBytesToFree <= 0 {
+				break
+			}
+			evictees = append(evictees, mptx)
+			evicteeGroups = append(evicteeGroups, txGroup)
+			numTxsToFree--
+			numBytesToFree -= len(mptx.rawTransaction)
+		}
+	}
+	if numTxsToFree > 0 || numBytesToFree > 0 {
+		return MempoolFullError
+	}
+
+	for i, mptx := range evictees {
+		txGroup := evicteeGroups[i]
+		txGroup.txs.Remove(mptx.GetIndex())
+		if txGroup.IsEmpty() {
+			delete(mp.addressToTxGroup, txGroup.address)
+			mp.candidateTxs.Remove(txGroup.index)
+		}
+		mp.size--
+		mp.numBytes -= len(mptx.rawTransaction)
+		mp.txBookeepper.markAbandoned(mptx.rawTransaction)
+		mp.evictedTxCounter.Inc(1)
+		logger.Debugf("Evict tx, tx.hash: 0x%v, txInfo: %v", getTransactionHash(mptx.rawTransaction), mptx.txInfo)
+	}
+
+	return nil
+}
+
+// replaceTransaction replaces the pending transaction with the same address and sequence number, if the
+// effective gas price of the new transaction is at least replacementPriceBump percent higher. It returns
+// false if there is no pending transaction to replace.
+func (mp *Mempool) replaceTransaction(rawTx common.Bytes, createdAt time.Time) (bool, error) {
+	txInfo, checkTxRes := mp.ledger.ScreenReplacementTx(rawTx)
+	if !checkTxRes.IsOK() {
+		if checkTxRes.Code == result.CodeInvalidSequence {
+			return false, nil
+		}
+		logger.Debugf("Replacement transaction screening failed, tx: %v, error: %v", hex.EncodeToString(rawTx), checkTxRes.Message)
+		return false, errors.New(checkTxRes.Message)
+	}
+
+	txGroup, ok := mp.addressToTxGroup[txInfo.Address]
+	if !ok {
+		return false, nil
+	}
+	pendingTx := txGroup.findTx(txInfo.Sequence)
+	if pendingTx == nil {
+		return false, nil
+	}
+
+	minPrice := new(big.Int).Mul(pendingTx.txInfo.EffectiveGasPrice, big.NewInt(100+mp.replacementPriceBump))
+	if new(big.Int).Mul(txInfo.EffectiveGasPrice, big.NewInt(100)).Cmp(minPrice) < 0 {
+		logger.Debugf("Replacement transaction underpriced, tx.hash: 0x%v, price: %v, pending tx.hash: 0x%v, price: %v",
+			getTransactionHash(rawTx), txInfo.EffectiveGasPrice, getTransactionHash(pendingTx.rawTransaction), pendingTx.txInfo.EffectiveGasPrice)
+		return false, ReplacementUnderpricedError
+	}
+
+	if err := mp.evictCheaperTxs(txInfo, 0, len(rawTx)-len(pendingTx.rawTransaction)); err != nil {
+		mp.rejectedTxCounter.Inc(1)
+		logger.Debugf("Replacement transaction rejected, tx.hash: 0x%v, error: %v", getTransactionHash(rawTx), err)
+		return false, err
+	}
+
+	mp.txBookeepper.remove(pendingTx.rawTransaction)
+	mp.txBookeepper.recordAt(rawTx, createdAt)
+	mp.journalTx(rawTx, createdAt)
+
+	txGroup.replaceTx(pendingTx, rawTx, txInfo)
+	mp.numBytes += len(rawTx) - len(pendingTx.rawTransaction)
+	mp.candidateTxs.Remove(txGroup.index) // Need to re-insert txGroup into queue since its priority could change.
+	mp.candidateTxs.Push(txGroup)
+	logger.Infof("Replace tx, tx.hash: 0x%v, replaced tx.hash: 0x%v", getTransactionHash(rawTx), getTransactionHash(pendingTx.rawTransaction))
+
+	mp.notifyInsertedTx(rawTx)
+
+	return true, nil
+}
+
+func (mp *Mempool) notifyInsertedTx(rawTx common.Bytes) {
+	select {
+	case mp.insertedTxs <- rawTx:
+	default:
+		logger.Debugf("Failed to notify inserted tx, tx.hash: 0x%v", getTransactionHash(rawTx))
+	}
+}
+
+// InsertedTxs returns a channel that will be published with the transactions inserted into the mempool.
+func (mp *Mempool) InsertedTxs() chan common.Bytes {
+	return mp.insertedTxs
+}
+
 // Start needs to be called when the Mempool starts
 func (mp *Mempool) Start(ctx context.Context) error {
 	c, cancel := context.WithCancel(ctx)
 	mp.ctx = c
 	mp.cancel = cancel
 
+	if mp.journal != nil {
+		entries, err := mp.journal.load()
+		if err != nil {
+			logger.Warnf("Failed to load the mempool journal: %v", err)
+		}
+		mp.wg.Add(1)
+		go mp.journalLoop(entries)
+	}
+
 	return nil
 }
 
+// journalLoop re-inserts the journaled transactions once the node has synced, and then periodically
+// rotates the journal to drop the transactions that are no longer in the mempool.
+func (mp *Mempool) journalLoop(entries []*journalEntry) {
+	defer mp.wg.Done()
+	defer func() {
+		mp.mutex.Lock()
+		defer mp.mutex.Unlock()
+		if err := mp.journal.close(); err != nil {
+			logger.Warnf("Failed to close the mempool journal: %v", err)
+		}
+	}()
+
+	syncTicker := time.NewTicker(journalSyncCheckInterval)
+	defer syncTicker.Stop()
+	for !mp.consensus.HasSynced() {
+		select {
+		case <-mp.ctx.Done():
+			return
+		case <-syncTicker.C:
+		}
+	}
+
+	mp.reinsertJournaledTxs(entries)
+	mp.rotateJournal()
+
+	rotationTicker := time.NewTicker(journalRotationInterval)
+	defer rotationTicker.Stop()
+	for {
+		select {
+		case <-mp.ctx.Done():
+			mp.rotateJournal()
+			return
+		case <-rotationTicker.C:
+			mp.rotateJournal()
+		}
+	}
+}
+
+// reinsertJournaledTxs screens and inserts the journaled transactions, and gossips the ones accepted.
+// Transactions older than the bookkeeper's timeout are dropped.
+func (mp *Mempool) reinsertJournaledTxs(entries []*journalEntry) {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	numInserted := 0
+	for _, entry := range entries {
+		if entry.isOutdated() {
+			continue
+		}
+		err := mp.insertTransactionUnsafe(entry.RawTx, time.Unix(int64(entry.CreatedAt), 0))
+		if err != nil {
+			logger.Debugf("Failed to re-insert journaled tx, tx.hash: 0x%v, error: %v", getTransactionHash(entry.RawTx), err)
+			continue
+		}
+		mp.BroadcastTxUnsafe(entry.RawTx)
+		numInserted++
+	}
+	logger.Infof("Re-inserted %v out of %v journaled transactions", numInserted, len(entries))
+}
+
+// rotateJournal rewrites the journal with the transactions currently in the mempool.
+func (mp *Mempool) rotateJournal() {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	mptxs := []*mempoolTransaction{}
+	for _, txGroupElem := range *mp.candidateTxs.ElementList() {
+		for _, txElem := range *txGroupElem.(*mempoolTransactionGroup).txs.ElementList() {
+			mptxs = append(mptxs, txElem.(*mempoolTransaction))
+		}
+	}
+	// Transactions from the same address need to be re-inserted in the order of sequence numbers
+	sort.SliceStable(mptxs, func(i, j int) bool {
+		return mptxs[i].txInfo.Sequence < mptxs[j].txInfo.Sequence
+	})
+
+	entries := []*journalEntry{}
+	for _, mptx := range mptxs {
+		createdAt, exists := mp.txBookeepper.getCreatedAt(mptx.rawTransaction)
+		if !exists {
+			continue
+		}
+		entries = append(entries, &journalEntry{
+			CreatedAt: uint64(createdAt.Unix()),
+			RawTx:     mptx.rawTransaction,
+		})
+	}
+	if err := mp.journal.rotate(entries); err != nil {
+		logger.Warnf("Failed to rotate the mempool journal: %v", err)
+	}
+}
+
+func (mp *Mempool) journalTx(rawTx common.Bytes, createdAt time.Time) {
+	if mp.journal == nil {
+		return
+	}
+	if err := mp.journal.insert(rawTx, createdAt); err != nil {
+		logger.Warnf("Failed to journal tx, tx.hash: 0x%v, error: %v", getTransactionHash(rawTx), err)
+	}
+}
+
 // Stop needs to be called when the Mempool stops
 func (mp *Mempool) Stop() {
 	mp.cancel()
@@ -295,6 +639,8 @@ func (mp *Mempool) ReapUnsafe(maxNumTxs int) []common.Bytes {
 		}
 		txGroup := mp.candidateTxs.Pop().(*mempoolTransactionGroup)
 		rawTx, txInfo := txGroup.PopTx()
+		mp.size--
+		mp.numBytes -= len(rawTx)
 
 		// Check for outdated txs
 		txHash := getTransactionHash(rawTx)
@@ -314,8 +660,6 @@ func (mp *Mempool) ReapUnsafe(maxNumTxs int) []common.Bytes {
 			hex.EncodeToString(rawTx), txInfo)
 	}
 
-	mp.size -= len(txs)
-
 	return txs
 }
 
@@ -383,8 +727,9 @@ func (mp *Mempool) removeTxs(committedRawTxs []common.Bytes) {
 	elemsTobeRemoved := []pqueue.Element{}
 	for _, elem := range *elementList {
 		txGroup := elem.(*mempoolTransactionGroup)
-		numRemoved := txGroup.RemoveTxs(committedRawTxMap)
+		numRemoved, numBytesRemoved := txGroup.RemoveTxs(committedRawTxMap)
 		mp.size -= numRemoved
+		mp.numBytes -= numBytesRemoved
 		if txGroup.IsEmpty() {
 			delete(mp.addressToTxGroup, txGroup.address)
 			elemsTobeRemoved = append(elemsTobeRemoved, txGroup)
@@ -433,7 +778,9 @@ func (mp *Mempool) Flush() {
 	for !mp.candidateTxs.IsEmpty() {
 		mp.candidateTxs.Pop()
 	}
+	mp.addressToTxGroup = make(map[common.Address]*mempoolTransactionGroup)
 	mp.size = 0
+	mp.numBytes = 0
 }
 
 // BroadcastTx broadcast given raw transaction to the network
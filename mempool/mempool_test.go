@@ -4,6 +4,7 @@ import (
 	"context"
 	"math/big"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"github.com/pandotoken/pando/core"
 	dp "github.com/pandotoken/pando/dispatcher"
 	p2psim "github.com/pandotoken/pando/p2p/simulation"
+	p2plmessenger "github.com/pandotoken/pando/p2pl/messenger"
 	p2ptypes "github.com/pandotoken/pando/p2p/types"
 	"github.com/pandotoken/pando/rlp"
 )
@@ -297,6 +299,9 @@ func TestMempoolBigBatchUpdateAndReaping(t *testing.T) {
 	committedRawTxs := []common.Bytes{}
 	multiplier := 30
 	targetRemainder := 3
+	mempool.txBookeepper = createTransactionBookkeeper(uint(multiplier * core.MaxNumRegularTxsPerBlock)) // keep all the txs
+	mempool.maxNumTxs = multiplier * core.MaxNumRegularTxsPerBlock
+	mempool.maxNumTxsPerAddress = multiplier * core.MaxNumRegularTxsPerBlock
 	for i := 0; i < multiplier*core.MaxNumRegularTxsPerBlock; i++ {
 		tx := createTestRawTx("tx_" + strconv.FormatInt(int64(i), 10))
 		if i%multiplier == targetRemainder {
@@ -380,6 +385,11 @@ func TestMempoolTransactionGossip(t *testing.T) {
 	assert.Equal(3, mempool.Size())
 	log.Infof(">>> Client submitted tx1, tx2, tx3")
 
+	// The transactions are gossiped by the caller, e.g. the RPC server, once inserted.
+	mempool.BroadcastTx(tx1)
+	mempool.BroadcastTx(tx2)
+	mempool.BroadcastTx(tx3)
+
 	numGossippedTxs := 2 * 3 // 2 peers, each should receive 3 transactions
 	for i := 0; i < numGossippedTxs; i++ {
 		receivedMsg := <-netMsgIntercepter.ReceivedMessages
@@ -391,14 +401,139 @@ func TestMempoolTransactionGossip(t *testing.T) {
 	}
 }
 
+func TestMempoolReplaceByFee(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	mempool.SetLedger(newReplaceByFeeTestLedger())
+
+	// raw tx format: address:sequence:effectiveGasPrice
+	txA1 := createTestRawTx("A1:1:100")
+	txA2 := createTestRawTx("A1:2:100")
+	txB1 := createTestRawTx("B1:1:150")
+	assert.Nil(mempool.InsertTransaction(txA1))
+	assert.Nil(mempool.InsertTransaction(txA2))
+	assert.Nil(mempool.InsertTransaction(txB1))
+	assert.Equal(3, mempool.Size())
+
+	// The price bump is not enough to replace txA1
+	txA1Underpriced := createTestRawTx("A1:1:109")
+	assert.Equal(ReplacementUnderpricedError, mempool.InsertTransaction(txA1Underpriced))
+	assert.False(mempool.txBookeepper.hasSeen(txA1Underpriced))
+
+	// No pending tx with sequence 4 to replace, and the sequence is screened as invalid
+	txA4 := createTestRawTx("A1:4:1000")
+	assert.NotNil(mempool.InsertTransaction(txA4))
+	assert.Equal(3, len(mempool.InsertedTxs()))
+	for i := 0; i < 3; i++ {
+		<-mempool.InsertedTxs()
+	}
+
+	// Replace txA1, the replacement should move A1's transactions ahead of B1's
+	txA1Replacement := createTestRawTx("A1:1:200")
+	assert.Nil(mempool.InsertTransaction(txA1Replacement))
+	assert.Equal(3, mempool.Size())
+	assert.False(mempool.txBookeepper.hasSeen(txA1))
+	assert.True(mempool.txBookeepper.hasSeen(txA1Replacement))
+	assert.Equal(txA1Replacement, <-mempool.InsertedTxs())
+
+	reapedRawTxs := mempool.Reap(-1)
+	assert.Equal(3, len(reapedRawTxs))
+	assert.Equal("A1:1:200", string(reapedRawTxs[0]))
+	assert.Equal("B1:1:150", string(reapedRawTxs[1]))
+	assert.Equal("A1:2:100", string(reapedRawTxs[2]))
+	assert.Equal(0, mempool.Size())
+}
+
+func TestMempoolLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	mempool.SetLedger(newReplaceByFeeTestLedger())
+	mempool.maxNumTxs = 4
+	mempool.maxNumTxsPerAddress = 2
+
+	// raw tx format: address:sequence:effectiveGasPrice
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("A1:1:100")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("A1:2:100")))
+	assert.Equal(TooManyPendingTxsError, mempool.InsertTransaction(createTestRawTx("A1:3:100")))
+
+	txB1 := createTestRawTx("B1:1:050")
+	txB2 := createTestRawTx("B1:2:050")
+	assert.Nil(mempool.InsertTransaction(txB1))
+	assert.Nil(mempool.InsertTransaction(txB2))
+	assert.Equal(4, mempool.Size())
+
+	// The mempool is full, and the new tx is priced lower than all the pending txs
+	assert.Equal(MempoolFullError, mempool.InsertTransaction(createTestRawTx("C1:1:040")))
+	assert.Equal(4, mempool.Size())
+
+	// The tail of the lowest priority group is evicted to make room for a higher priced tx
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("D1:1:060")))
+	assert.Equal(4, mempool.Size())
+	status, _ := mempool.GetTransactionStatus(getTransactionHash(txB2))
+	assert.Equal(TxStatusAbandoned, status)
+	status, _ = mempool.GetTransactionStatus(getTransactionHash(txB1))
+	assert.Equal(TxStatusPending, status)
+
+	// The byte limit is also enforced, the lowest priority group is removed once empty
+	mempool.maxNumBytes = mempool.numBytes
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("E1:1:070")))
+	assert.Equal(4, mempool.Size())
+	_, ok := mempool.addressToTxGroup[common.HexToAddress("B1")]
+	assert.False(ok)
+
+	reapedRawTxs := mempool.Reap(-1)
+	assert.Equal(4, len(reapedRawTxs))
+	assert.Equal("A1:1:100", string(reapedRawTxs[0]))
+	assert.Equal("A1:2:100", string(reapedRawTxs[1]))
+	assert.Equal("E1:1:070", string(reapedRawTxs[2]))
+	assert.Equal("D1:1:060", string(reapedRawTxs[3]))
+	assert.Equal(0, mempool.numBytes)
+}
+
+func TestMempoolReplacementByteLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	mempool.SetLedger(newReplaceByFeeTestLedger())
+
+	// raw tx format: address:sequence:effectiveGasPrice
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("A1:1:100")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("B1:1:050")))
+	mempool.maxNumBytes = mempool.numBytes
+
+	// The larger replacement evicts the cheaper tx of another address to stay within the byte limit
+	txA1Replacement := createTestRawTx("A1:1:00000000200")
+	assert.Nil(mempool.InsertTransaction(txA1Replacement))
+	assert.Equal(1, mempool.Size())
+	assert.True(mempool.numBytes <= mempool.maxNumBytes)
+	_, ok := mempool.addressToTxGroup[common.HexToAddress("B1")]
+	assert.False(ok)
+
+	// Nothing left to evict, the replacement would overflow the byte limit
+	txA1Oversized := createTestRawTx("A1:1:000000000000000000400")
+	assert.Equal(MempoolFullError, mempool.InsertTransaction(txA1Oversized))
+	assert.Equal(1, mempool.Size())
+	assert.True(mempool.numBytes <= mempool.maxNumBytes)
+	assert.False(mempool.txBookeepper.hasSeen(txA1Oversized))
+
+	reapedRawTxs := mempool.Reap(-1)
+	assert.Equal(1, len(reapedRawTxs))
+	assert.Equal(string(txA1Replacement), string(reapedRawTxs[0]))
+}
+
 // --------------- Test Utilities --------------- //
 
 func newTestMempool(peerID string, simnet *p2psim.Simnet) (*Mempool, context.Context) {
 	ctx := context.Background()
 
 	messenger := simnet.AddEndpoint(peerID)
-	dispatcher := dp.NewDispatcher(messenger, nil)
-	mempool := CreateMempool(dispatcher)
+	dispatcher := dp.NewDispatcher(messenger, (*p2plmessenger.Messenger)(nil))
+	mempool := CreateMempool(dispatcher, &TestConsensusEngine{synced: true})
 	mempool.SetLedger(newTestLedger())
 	txMsgHandler := CreateMempoolMessageHandler(mempool)
 	messenger.RegisterMessageHandler(txMsgHandler)
@@ -406,6 +541,14 @@ func newTestMempool(peerID string, simnet *p2psim.Simnet) (*Mempool, context.Con
 	return mempool, ctx
 }
 
+type TestConsensusEngine struct {
+	synced bool
+}
+
+func (tce *TestConsensusEngine) HasSynced() bool {
+	return tce.synced
+}
+
 type TestLedger struct {
 	counter               int
 	effectiveGasPriceList []uint64
@@ -470,6 +613,10 @@ func (tl *TestLedger) ScreenTx(rawTx common.Bytes) (*core.TxInfo, result.Result)
 	return txInfo, result.OK
 }
 
+func (tl *TestLedger) ScreenReplacementTx(rawTx common.Bytes) (*core.TxInfo, result.Result) {
+	return nil, result.Error("No screened transaction to replace").WithErrorCode(result.CodeInvalidSequence)
+}
+
 func (tl *TestLedger) GetCurrentBlock() *core.Block {
 	return nil
 }
@@ -482,7 +629,7 @@ func (tl *TestLedger) ApplyBlockTxs(block *core.Block) result.Result {
 	return result.OK
 }
 
-func (tl *TestLedger) ResetState(height uint64, rootHash common.Hash) result.Result {
+func (tl *TestLedger) ResetState(block *core.Block) result.Result {
 	return result.OK
 }
 
@@ -498,6 +645,10 @@ func (tl *TestLedger) GetGuardianCandidatePool(blockHash common.Hash) (*core.Gua
 	return nil, nil
 }
 
+func (tl *TestLedger) GetEliteEdgeNodePoolOfLastCheckpoint(blockHash common.Hash) (core.EliteEdgeNodePool, error) {
+	return nil, nil
+}
+
 func (tl *TestLedger) PruneState(endHeight uint64) error {
 	return nil
 }
@@ -506,6 +657,51 @@ func (tl *TestLedger) ApplyBlockTxsForChainCorrection(block *core.Block) (common
 	return common.Hash{}, result.Result{}
 }
 
+// ReplaceByFeeTestLedger screens raw txs in the format of address:sequence:effectiveGasPrice. Like the
+// screened view, it tracks the latest screened sequence of each address.
+type ReplaceByFeeTestLedger struct {
+	TestLedger
+	screenedSequences map[common.Address]uint64
+}
+
+func newReplaceByFeeTestLedger() core.Ledger {
+	return &ReplaceByFeeTestLedger{
+		screenedSequences: make(map[common.Address]uint64),
+	}
+}
+
+func (tl *ReplaceByFeeTestLedger) parseTx(rawTx common.Bytes) *core.TxInfo {
+	fields := strings.Split(string(rawTx), ":")
+	sequence, _ := strconv.ParseUint(fields[1], 10, 64)
+	price, _ := strconv.ParseUint(fields[2], 10, 64)
+	return &core.TxInfo{
+		Address:           common.HexToAddress(fields[0]),
+		Sequence:          sequence,
+		EffectiveGasPrice: new(big.Int).SetUint64(price),
+	}
+}
+
+func (tl *ReplaceByFeeTestLedger) ScreenTx(rawTx common.Bytes) (*core.TxInfo, result.Result) {
+	txInfo := tl.parseTx(rawTx)
+	if tl.screenedSequences[txInfo.Address]+1 != txInfo.Sequence {
+		return nil, result.Error("Invalid sequence").WithErrorCode(result.CodeInvalidSequence)
+	}
+	tl.screenedSequences[txInfo.Address] = txInfo.Sequence
+	return txInfo, result.OK
+}
+
+func (tl *ReplaceByFeeTestLedger) ScreenTxUnsafe(rawTx common.Bytes) result.Result {
+	return result.OK
+}
+
+func (tl *ReplaceByFeeTestLedger) ScreenReplacementTx(rawTx common.Bytes) (*core.TxInfo, result.Result) {
+	txInfo := tl.parseTx(rawTx)
+	if tl.screenedSequences[txInfo.Address] < txInfo.Sequence {
+		return nil, result.Error("No screened transaction to replace").WithErrorCode(result.CodeInvalidSequence)
+	}
+	return txInfo, result.OK
+}
+
 type TestNetworkMessageInterceptor struct {
 	lock             *sync.Mutex
 	ReceivedMessages chan p2ptypes.Message
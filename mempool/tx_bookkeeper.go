@@ -106,6 +106,12 @@ func (tb *transactionBookkeeper) removeOutdatedTxsUnsafe() {
 }
 
 func (tb *transactionBookkeeper) record(rawTx common.Bytes) bool {
+	return tb.recordAt(rawTx, time.Now())
+}
+
+// recordAt records the transaction as seen at the given time, e.g. when it was first accepted
+// before a node restart.
+func (tb *transactionBookkeeper) recordAt(rawTx common.Bytes, createdAt time.Time) bool {
 	tb.mutex.Lock()
 	defer tb.mutex.Unlock()
 	txhash := getTransactionHash(rawTx)
@@ -127,7 +133,7 @@ func (tb *transactionBookkeeper) record(rawTx common.Bytes) bool {
 	record := &TxRecord{
 		Hash:      txhash,
 		Status:    TxStatusPending,
-		CreatedAt: time.Now(),
+		CreatedAt: createdAt,
 	}
 	tb.txMap[txhash] = record
 
@@ -136,6 +142,18 @@ func (tb *transactionBookkeeper) record(rawTx common.Bytes) bool {
 	return true
 }
 
+// getCreatedAt returns the time when the transaction was recorded, and a boolean of whether the tx is known.
+func (tb *transactionBookkeeper) getCreatedAt(rawTx common.Bytes) (time.Time, bool) {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	txRecord, exists := tb.txMap[getTransactionHash(rawTx)]
+	if !exists {
+		return time.Time{}, false
+	}
+	return txRecord.CreatedAt, true
+}
+
 func (tb *transactionBookkeeper) markAbandoned(rawTx common.Bytes) {
 	tb.mutex.Lock()
 	defer tb.mutex.Unlock()
@@ -0,0 +1,71 @@
+package micropayment
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/ledger/types"
+)
+
+// Issuer issues the vouchers of a source account against one of its reserved funds, and tracks
+// the latest voucher of each resource ID.
+type Issuer struct {
+	chainID         string
+	source          common.Address
+	reserveSequence uint64
+	signer          Signer
+	store           *VoucherStore
+}
+
+// NewIssuer creates an Issuer paying from the fund reserved by source with reserveSequence.
+func NewIssuer(chainID string, source common.Address, reserveSequence uint64, signer Signer, store *VoucherStore) *Issuer {
+	return &Issuer{
+		chainID:         chainID,
+		source:          source,
+		reserveSequence: reserveSequence,
+		signer:          signer,
+		store:           store,
+	}
+}
+
+// Issue signs a voucher paying the given PTX amount on top of the latest voucher of the same
+// payment for the resource. A zero paymentSequence continues the latest payment of the resource
+// to the target, or starts the first one. A voucher of another payment starts from zero, and
+// replaces the latest voucher of the resource.
+func (is *Issuer) Issue(target common.Address, resourceID string, paymentSequence uint64, amount *big.Int) (*types.ServicePaymentTx, error) {
+	if amount.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if target == is.source {
+		return nil, errors.New("Source and target of a payment cannot be identical")
+	}
+
+	latest, hasLatest := is.store.Get(resourceID)
+	if hasLatest && (latest.Source.Address != is.source || latest.Target.Address != target || latest.ReserveSequence != is.reserveSequence) {
+		hasLatest = false
+	}
+	if paymentSequence == 0 {
+		paymentSequence = 1
+		if hasLatest {
+			paymentSequence = latest.PaymentSequence
+		}
+	}
+
+	total := new(big.Int).Set(amount)
+	if hasLatest && latest.PaymentSequence == paymentSequence {
+		total.Add(total, latest.Source.Coins.NoNil().PTXWei)
+	}
+
+	voucher := NewVoucher(is.source, target, is.reserveSequence, paymentSequence, resourceID, total)
+	if err := SignVoucher(is.chainID, voucher, is.signer); err != nil {
+		return nil, err
+	}
+	if err := is.store.Put(voucher); err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("Issued voucher for resource %v to %v, payment sequence: %v, total: %v",
+		resourceID, target.Hex(), paymentSequence, total)
+	return voucher, nil
+}
@@ -0,0 +1,156 @@
+package micropayment
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/types"
+)
+
+const micropaymentTestChainID = "micropaymenttest"
+
+type testSigner map[common.Address]*crypto.PrivateKey
+
+func (s testSigner) Sign(address common.Address, msg common.Bytes) (*crypto.Signature, error) {
+	return s[address].Sign(msg)
+}
+
+type micropaymentTestEnv struct {
+	dir    string
+	signer testSigner
+	source *types.Account
+	target common.Address
+}
+
+func newMicropaymentTestEnv(t *testing.T) *micropaymentTestEnv {
+	dir, err := ioutil.TempDir("", "micropayment")
+	require.Nil(t, err)
+
+	signer := testSigner{}
+	addrs := []common.Address{}
+	for i := 0; i < 2; i++ {
+		privKey, _, err := crypto.GenerateKeyPair()
+		require.Nil(t, err)
+		signer[privKey.PublicKey().Address()] = privKey
+		addrs = append(addrs, privKey.PublicKey().Address())
+	}
+
+	source := types.NewAccount(addrs[0])
+	source.ReservedFunds = []types.ReservedFund{{
+		Collateral:      types.NewCoins(0, 2000),
+		InitialFund:     types.NewCoins(0, 1000),
+		UsedFund:        types.NewCoins(0, 0),
+		ResourceIDs:     []string{"video", "audio"},
+		EndBlockHeight:  500,
+		ReserveSequence: 3,
+	}}
+
+	return &micropaymentTestEnv{
+		dir:    dir,
+		signer: signer,
+		source: source,
+		target: addrs[1],
+	}
+}
+
+func (env *micropaymentTestEnv) store(t *testing.T, name string) *VoucherStore {
+	store, err := NewVoucherStore(filepath.Join(env.dir, name))
+	require.Nil(t, err)
+	return store
+}
+
+func TestVoucherIssueAcceptSettle(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	env := newMicropaymentTestEnv(t)
+	defer os.RemoveAll(env.dir)
+
+	issuer := NewIssuer(micropaymentTestChainID, env.source.Address, 3, env.signer, env.store(t, "issued.json"))
+	receiver := NewReceiver(micropaymentTestChainID, env.target, env.store(t, "received.json"))
+
+	// The vouchers of a payment are cumulative
+	for i := 0; i < 5; i++ {
+		voucher, err := issuer.Issue(env.target, "video", 0, big.NewInt(100))
+		require.Nil(err)
+		assert.Equal(uint64(1), voucher.PaymentSequence)
+		assert.Equal(big.NewInt(int64(100*(i+1))), voucher.Source.Coins.PTXWei)
+
+		increment, err := receiver.Accept(voucher, env.source, 100)
+		require.Nil(err)
+		assert.Equal(big.NewInt(100), increment)
+	}
+
+	// A replayed voucher pays nothing more
+	first := NewVoucher(env.source.Address, env.target, 3, 1, "video", big.NewInt(100))
+	require.Nil(SignVoucher(micropaymentTestChainID, first, env.signer))
+	_, err := receiver.Accept(first, env.source, 100)
+	assert.NotNil(err)
+
+	// The latest vouchers survive a restart
+	receiver = NewReceiver(micropaymentTestChainID, env.target, env.store(t, "received.json"))
+	settlement, err := receiver.Settle("video", types.NewCoins(0, 1), 7, env.signer)
+	require.Nil(err)
+	assert.Equal(big.NewInt(500), settlement.Source.Coins.PTXWei)
+	assert.Equal(uint64(7), settlement.Target.Sequence)
+	assert.True(settlement.Source.Signature.Verify(settlement.SourceSignBytes(micropaymentTestChainID), env.source.Address))
+	assert.True(settlement.Target.Signature.Verify(settlement.TargetSignBytes(micropaymentTestChainID), env.target))
+
+	// The next payment must wait for the settlement
+	voucher, err := issuer.Issue(env.target, "video", 2, big.NewInt(50))
+	require.Nil(err)
+	assert.Equal(big.NewInt(50), voucher.Source.Coins.PTXWei)
+	_, err = receiver.Accept(voucher, env.source, 100)
+	assert.NotNil(err)
+
+	// Once settled on-chain, the payment sequence 1 is used up
+	require.Nil(receiver.Settled("video"))
+	env.source.ReservedFunds[0].UsedFund = types.NewCoins(0, 500)
+	env.source.ReservedFunds[0].RecordTransfer(settlement)
+	_, err = receiver.Accept(first, env.source, 100)
+	assert.NotNil(err)
+	increment, err := receiver.Accept(voucher, env.source, 100)
+	require.Nil(err)
+	assert.Equal(big.NewInt(50), increment)
+	assert.Equal([]string{"video"}, receiver.store.ResourceIDs())
+}
+
+func TestVerifyVoucher(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	env := newMicropaymentTestEnv(t)
+	defer os.RemoveAll(env.dir)
+
+	newVoucher := func(reserveSeq uint64, resourceID string, amount int64) *types.ServicePaymentTx {
+		voucher := NewVoucher(env.source.Address, env.target, reserveSeq, 1, resourceID, big.NewInt(amount))
+		require.Nil(SignVoucher(micropaymentTestChainID, voucher, env.signer))
+		return voucher
+	}
+
+	assert.Nil(VerifyVoucher(micropaymentTestChainID, newVoucher(3, "audio", 1000), env.source, 500))
+	assert.Equal(ErrInsufficientFund, VerifyVoucher(micropaymentTestChainID, newVoucher(3, "audio", 1001), env.source, 500))
+	assert.Equal(ErrReservedFundExpired, VerifyVoucher(micropaymentTestChainID, newVoucher(3, "audio", 10), env.source, 501))
+	assert.Equal(ErrReservedFundNotFound, VerifyVoucher(micropaymentTestChainID, newVoucher(4, "audio", 10), env.source, 100))
+	assert.NotNil(VerifyVoucher(micropaymentTestChainID, newVoucher(3, "text", 10), env.source, 100))
+	assert.Equal(ErrInvalidAmount, VerifyVoucher(micropaymentTestChainID, newVoucher(3, "audio", 0), env.source, 100))
+
+	// The source signature covers the amount
+	voucher := newVoucher(3, "audio", 10)
+	voucher.Source.Coins.PTXWei = big.NewInt(20)
+	assert.Equal(ErrInvalidSignature, VerifyVoucher(micropaymentTestChainID, voucher, env.source, 100))
+
+	// Only the source can sign its vouchers
+	voucher = NewVoucher(env.source.Address, env.target, 3, 1, "audio", big.NewInt(10))
+	sig, err := env.signer[env.target].Sign(voucher.SourceSignBytes(micropaymentTestChainID))
+	require.Nil(err)
+	voucher.SetSourceSignature(sig)
+	assert.Equal(ErrInvalidSignature, VerifyVoucher(micropaymentTestChainID, voucher, env.source, 100))
+}
@@ -0,0 +1,75 @@
+package micropayment
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/ledger/types"
+)
+
+// Receiver verifies the vouchers paid to a target account, and tracks the latest voucher of each
+// resource ID until it is settled.
+type Receiver struct {
+	chainID string
+	target  common.Address
+	store   *VoucherStore
+}
+
+// NewReceiver creates a Receiver collecting the vouchers paid to target.
+func NewReceiver(chainID string, target common.Address, store *VoucherStore) *Receiver {
+	return &Receiver{
+		chainID: chainID,
+		target:  target,
+		store:   store,
+	}
+}
+
+// Accept verifies the voucher against the reserved fund of the source account at the given block
+// height, and makes it the latest voucher of its resource ID. The voucher must pay more than the
+// latest voucher of the same payment, and the latest voucher of another payment must be settled
+// first. It returns the amount added by the voucher.
+func (r *Receiver) Accept(voucher *types.ServicePaymentTx, source *types.Account, height uint64) (*big.Int, error) {
+	if voucher.Target.Address != r.target {
+		return nil, fmt.Errorf("Voucher is paid to %v instead of %v", voucher.Target.Address.Hex(), r.target.Hex())
+	}
+	if err := VerifyVoucher(r.chainID, voucher, source, height); err != nil {
+		return nil, err
+	}
+
+	increment := new(big.Int).Set(voucher.Source.Coins.NoNil().PTXWei)
+	if latest, ok := r.store.Get(voucher.ResourceID); ok {
+		if !samePayment(latest, voucher) {
+			return nil, fmt.Errorf("Latest voucher of resource %v from %v with payment sequence %v is not settled yet",
+				latest.ResourceID, latest.Source.Address.Hex(), latest.PaymentSequence)
+		}
+		increment.Sub(increment, latest.Source.Coins.NoNil().PTXWei)
+		if increment.Sign() <= 0 {
+			return nil, fmt.Errorf("Voucher does not pay more than the latest voucher of resource %v", voucher.ResourceID)
+		}
+	}
+
+	if err := r.store.Put(voucher); err != nil {
+		return nil, err
+	}
+	return increment, nil
+}
+
+// Settle co-signs the latest voucher of the resource ID, which can then be broadcast to collect
+// the payment. Settled must be called once the settlement is included in the chain.
+func (r *Receiver) Settle(resourceID string, fee types.Coins, sequence uint64, signer Signer) (*types.ServicePaymentTx, error) {
+	latest, ok := r.store.Get(resourceID)
+	if !ok {
+		return nil, fmt.Errorf("No voucher to settle for resource %v", resourceID)
+	}
+	if err := CoSignVoucher(r.chainID, latest, fee, sequence, signer); err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
+
+// Settled drops the latest voucher of the resource ID once it is settled. The next payment of
+// the resource must use a higher payment sequence.
+func (r *Receiver) Settled(resourceID string) error {
+	return r.store.Delete(resourceID)
+}
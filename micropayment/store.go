@@ -0,0 +1,92 @@
+package micropayment
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pandotoken/pando/ledger/types"
+)
+
+// VoucherStore keeps the latest voucher of each resource ID in a JSON file.
+type VoucherStore struct {
+	mu       *sync.Mutex
+	filePath string
+	vouchers map[string]*types.ServicePaymentTx
+}
+
+// NewVoucherStore creates a VoucherStore backed by the given file, and loads the vouchers already
+// in the file if it exists.
+func NewVoucherStore(filePath string) (*VoucherStore, error) {
+	vs := &VoucherStore{
+		mu:       &sync.Mutex{},
+		filePath: filePath,
+		vouchers: make(map[string]*types.ServicePaymentTx),
+	}
+	raw, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return vs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &vs.vouchers); err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+// Get returns the latest voucher of the resource ID.
+func (vs *VoucherStore) Get(resourceID string) (*types.ServicePaymentTx, bool) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	voucher, ok := vs.vouchers[resourceID]
+	return voucher, ok
+}
+
+// Put makes the voucher the latest voucher of its resource ID, and saves the store.
+func (vs *VoucherStore) Put(voucher *types.ServicePaymentTx) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	vs.vouchers[voucher.ResourceID] = voucher
+	return vs.save()
+}
+
+// Delete drops the latest voucher of the resource ID, and saves the store.
+func (vs *VoucherStore) Delete(resourceID string) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	delete(vs.vouchers, resourceID)
+	return vs.save()
+}
+
+// ResourceIDs returns the sorted resource IDs having a voucher.
+func (vs *VoucherStore) ResourceIDs() []string {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	resourceIDs := []string{}
+	for resourceID := range vs.vouchers {
+		resourceIDs = append(resourceIDs, resourceID)
+	}
+	sort.Strings(resourceIDs)
+	return resourceIDs
+}
+
+// save writes the store to a temporary file first, so that a crash does not lose the vouchers.
+func (vs *VoucherStore) save() error {
+	raw, err := json.MarshalIndent(vs.vouchers, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(vs.filePath), 0700); err != nil {
+		return err
+	}
+	tmpPath := vs.filePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, vs.filePath)
+}
@@ -0,0 +1,125 @@
+// Package micropayment implements the off-chain side of the resource oriented micropayment pool.
+// The source of a ReservedFund pays a target with vouchers, i.e. ServicePaymentTxs signed by the
+// source only. The amount of a voucher is cumulative, so each voucher of a payment supersedes the
+// previous ones, and the target co-signs and submits only the final voucher to settle on-chain.
+package micropayment
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/util"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/types"
+)
+
+var logger = util.GetLoggerForModule("micropayment")
+
+var (
+	ErrSourceMismatch       = errors.New("Voucher source does not match the account")
+	ErrInvalidSignature     = errors.New("Invalid source signature")
+	ErrInvalidAmount        = errors.New("Voucher amount must be a positive PTX amount")
+	ErrReservedFundNotFound = errors.New("No matching reserved fund")
+	ErrReservedFundExpired  = errors.New("Reserved fund already expired")
+	ErrInsufficientFund     = errors.New("Voucher amount exceeds the remaining reserved fund")
+)
+
+// Signer signs messages on behalf of an address. The wallets implement it.
+type Signer interface {
+	Sign(address common.Address, msg common.Bytes) (*crypto.Signature, error)
+}
+
+// NewVoucher creates an unsigned voucher paying the given cumulative PTX amount from the fund
+// reserved by source with reserveSequence.
+func NewVoucher(source, target common.Address, reserveSequence, paymentSequence uint64, resourceID string, amount *big.Int) *types.ServicePaymentTx {
+	return &types.ServicePaymentTx{
+		Fee: types.NewCoins(0, 0),
+		Source: types.TxInput{
+			Address: source,
+			Coins:   types.Coins{PandoWei: big.NewInt(0), PTXWei: new(big.Int).Set(amount)},
+		},
+		Target: types.TxInput{
+			Address: target,
+		},
+		PaymentSequence: paymentSequence,
+		ReserveSequence: reserveSequence,
+		ResourceID:      resourceID,
+	}
+}
+
+// SignVoucher signs the voucher as its source. The source signature does not cover the fee and
+// the target sequence, which are set by the target when settling.
+func SignVoucher(chainID string, voucher *types.ServicePaymentTx, signer Signer) error {
+	sig, err := signer.Sign(voucher.Source.Address, voucher.SourceSignBytes(chainID))
+	if err != nil {
+		return err
+	}
+	voucher.SetSourceSignature(sig)
+	return nil
+}
+
+// VerifyVoucher checks the voucher is signed by the source, and would be paid by the fund it
+// refers to in the given source account at the given block height.
+func VerifyVoucher(chainID string, voucher *types.ServicePaymentTx, source *types.Account, height uint64) error {
+	if voucher.Source.Address != source.Address {
+		return ErrSourceMismatch
+	}
+	amount := voucher.Source.Coins.NoNil()
+	if amount.PandoWei.Sign() != 0 || amount.PTXWei.Sign() <= 0 {
+		return ErrInvalidAmount
+	}
+	if voucher.Source.Signature == nil || !voucher.Source.Signature.Verify(voucher.SourceSignBytes(chainID), source.Address) {
+		return ErrInvalidSignature
+	}
+
+	reservedFund := findReservedFund(source, voucher.ReserveSequence)
+	if reservedFund == nil {
+		return ErrReservedFundNotFound
+	}
+	if !reservedFund.HasResourceID(voucher.ResourceID) {
+		return fmt.Errorf("Reserved fund %v does not cover resource %v", voucher.ReserveSequence, voucher.ResourceID)
+	}
+	if reservedFund.EndBlockHeight < height {
+		return ErrReservedFundExpired
+	}
+	if err := reservedFund.VerifyPaymentSequence(voucher.Target.Address, voucher.PaymentSequence); err != nil {
+		return err
+	}
+	remainingFund := reservedFund.InitialFund.Minus(reservedFund.UsedFund)
+	if !remainingFund.IsGTE(amount) {
+		return ErrInsufficientFund
+	}
+	return nil
+}
+
+// CoSignVoucher sets the fee and the sequence of the target, and signs the voucher as its target
+// so that it can be broadcast as a ServicePaymentTx.
+func CoSignVoucher(chainID string, voucher *types.ServicePaymentTx, fee types.Coins, sequence uint64, signer Signer) error {
+	voucher.Fee = fee
+	voucher.Target.Sequence = sequence
+	sig, err := signer.Sign(voucher.Target.Address, voucher.TargetSignBytes(chainID))
+	if err != nil {
+		return err
+	}
+	voucher.SetTargetSignature(sig)
+	return nil
+}
+
+// samePayment returns whether the two vouchers belong to the same payment, i.e. whether one
+// supersedes the other.
+func samePayment(a, b *types.ServicePaymentTx) bool {
+	return a.Source.Address == b.Source.Address && a.Target.Address == b.Target.Address &&
+		a.ReserveSequence == b.ReserveSequence && a.PaymentSequence == b.PaymentSequence &&
+		a.ResourceID == b.ResourceID
+}
+
+func findReservedFund(account *types.Account, reserveSequence uint64) *types.ReservedFund {
+	for idx := range account.ReservedFunds {
+		if account.ReservedFunds[idx].ReserveSequence == reserveSequence {
+			return &account.ReservedFunds[idx]
+		}
+	}
+	return nil
+}
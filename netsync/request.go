@@ -13,6 +13,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/pandotoken/pando/blockchain"
 	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/metrics"
 	"github.com/pandotoken/pando/common/util"
 	"github.com/pandotoken/pando/core"
 	"github.com/pandotoken/pando/dispatcher"
@@ -183,9 +184,30 @@ func NewRequestManager(syncMgr *SyncManager, reporter *rp.Reporter) *RequestMana
 	}
 	rm.logger = logger
 
+	rm.registerMetrics()
+
 	return rm
 }
 
+// registerMetrics exposes the depth of the download queues as gauges.
+func (rm *RequestManager) registerMetrics() {
+	metrics.NewRegisteredFunctionalGauge("sync/pending_blocks", nil, func() int64 {
+		rm.mu.RLock()
+		defer rm.mu.RUnlock()
+		return int64(rm.pendingBlocks.Len())
+	})
+	metrics.NewRegisteredFunctionalGauge("sync/pending_headers", nil, func() int64 {
+		rm.mu.RLock()
+		defer rm.mu.RUnlock()
+		return int64(rm.pendingBlocksWithHeader.Len())
+	})
+	metrics.NewRegisteredFunctionalGauge("sync/active_peers", nil, func() int64 {
+		rm.aplock.RLock()
+		defer rm.aplock.RUnlock()
+		return int64(len(rm.activePeers))
+	})
+}
+
 func (rm *RequestManager) mainLoop() {
 	defer rm.wg.Done()
 
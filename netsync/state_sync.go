@@ -0,0 +1,610 @@
+package netsync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/dispatcher"
+	"github.com/pandotoken/pando/ledger/types"
+	p2ptypes "github.com/pandotoken/pando/p2p/types"
+	"github.com/pandotoken/pando/rlp"
+	"github.com/pandotoken/pando/snapshot"
+	"github.com/pandotoken/pando/store/database"
+	"github.com/pandotoken/pando/store/treestore"
+	"github.com/pandotoken/pando/store/trie"
+)
+
+const StateSyncMaxServedCheckpoints = 4 // Max number of recent checkpoints advertised to the peers
+const StateSyncMaxNodesPerRequest = 128 // Max number of trie nodes per state node request
+const StateSyncRequestTimeout = 10 * time.Second
+const StateSyncDiscoveryInterval = 5 * time.Second
+const StateSyncCheckpointRequestInterval = 1 * time.Second // Min interval between the checkpoint requests served to a peer
+const stateSyncMaxThrottledPeers = 1024
+const stateSyncTickInterval = 1 * time.Second
+const stateSyncQueueSize = 64
+
+// StateCheckpoint proves a checkpoint block is finalized. It is sent by the peers serving the
+// state of the checkpoint.
+type StateCheckpoint struct {
+	LastCheckpoint core.LastCheckpoint
+	Metadata       core.SnapshotMetadata
+}
+
+// StateNodes holds the state trie nodes sent in response to a state node request.
+type StateNodes struct {
+	Nodes []common.Bytes
+}
+
+type stateSyncMessage struct {
+	peerID     string
+	entries    []string // the checkpoints advertised by the peer
+	checkpoint *StateCheckpoint
+	nodes      []common.Bytes
+}
+
+// StateSyncManager serves the state of the recent checkpoints to the peers, and downloads the
+// state of a trusted checkpoint from the peers, so that a new node can sync blocks from the
+// checkpoint instead of the genesis.
+//
+// A syncing node asks its peers for the checkpoints they serve, fetches the proof that the trusted
+// checkpoint is finalized, then downloads the state tries of the checkpoint node by node. The trie
+// nodes are content addressed, so each of them is verified against its hash as it arrives. Once
+// all the tries are complete, the state is checked against the validator set proven by the VCP
+// proof, as for a snapshot.
+type StateSyncManager struct {
+	syncMgr *SyncManager
+	db      database.Database
+	logger  *log.Entry
+
+	mu      *sync.Mutex
+	syncing bool
+
+	// The encoded StateCheckpoint of the served checkpoints by block hash, which never change
+	checkpointCache *lru.Cache
+	// The time of the last checkpoint request served to each peer
+	checkpointRequests *lru.Cache
+
+	// Owned by the goroutine running Sync
+	peers []string
+
+	advertisements chan stateSyncMessage
+	checkpoints    chan stateSyncMessage
+	nodes          chan stateSyncMessage
+}
+
+func NewStateSyncManager(syncMgr *SyncManager, db database.Database) *StateSyncManager {
+	checkpointCache, _ := lru.New(StateSyncMaxServedCheckpoints)
+	checkpointRequests, _ := lru.New(stateSyncMaxThrottledPeers)
+	return &StateSyncManager{
+		syncMgr: syncMgr,
+		db:      db,
+		logger:  syncMgr.logger.WithFields(log.Fields{"component": "statesync"}),
+		mu:      &sync.Mutex{},
+
+		checkpointCache:    checkpointCache,
+		checkpointRequests: checkpointRequests,
+
+		advertisements: make(chan stateSyncMessage, stateSyncQueueSize),
+		checkpoints:    make(chan stateSyncMessage, stateSyncQueueSize),
+		nodes:          make(chan stateSyncMessage, stateSyncQueueSize),
+	}
+}
+
+// handleMessage processes the state sync messages. It returns false for the other messages.
+func (ssm *StateSyncManager) handleMessage(message p2ptypes.Message, inboundAllowed bool) bool {
+	switch content := message.Content.(type) {
+	case dispatcher.InventoryRequest:
+		if content.ChannelID != common.ChannelIDStateCheckpoint {
+			return false
+		}
+		ssm.handleCheckpointsRequest(message.PeerID)
+	case dispatcher.InventoryResponse:
+		if content.ChannelID != common.ChannelIDStateCheckpoint {
+			return false
+		}
+		if inboundAllowed {
+			ssm.deliver(ssm.advertisements, stateSyncMessage{peerID: message.PeerID, entries: content.Entries})
+		}
+	case dispatcher.DataRequest:
+		switch content.ChannelID {
+		case common.ChannelIDStateCheckpoint:
+			ssm.handleCheckpointRequest(message.PeerID, content.Entries)
+		case common.ChannelIDStateNode:
+			ssm.handleNodesRequest(message.PeerID, content.Entries)
+		default:
+			return false
+		}
+	case dispatcher.DataResponse:
+		switch content.ChannelID {
+		case common.ChannelIDStateCheckpoint:
+			if inboundAllowed {
+				ssm.handleCheckpointResponse(message.PeerID, content.Payload)
+			}
+		case common.ChannelIDStateNode:
+			if inboundAllowed {
+				ssm.handleNodesResponse(message.PeerID, content.Payload)
+			}
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// ---------------------------------- Serving ----------------------------------
+
+func (ssm *StateSyncManager) isServing() bool {
+	return viper.GetBool(common.CfgSyncStateSyncServe)
+}
+
+// servedCheckpoints returns the recent checkpoint blocks whose state can be served.
+func (ssm *StateSyncManager) servedCheckpoints() []*core.ExtendedBlock {
+	ret := []*core.ExtendedBlock{}
+	lfb := ssm.syncMgr.consensus.GetLastFinalizedBlock()
+	if lfb == nil {
+		return ret
+	}
+
+	height := common.LastCheckPointHeight(lfb.Height)
+	for i := 0; i < StateSyncMaxServedCheckpoints; i++ {
+		if height <= lfb.Height {
+			if block := ssm.findServedCheckpoint(height); block != nil {
+				ret = append(ret, block)
+			}
+		}
+		if height <= uint64(common.CheckpointInterval) {
+			break
+		}
+		height -= uint64(common.CheckpointInterval)
+	}
+	return ret
+}
+
+func (ssm *StateSyncManager) findServedCheckpoint(height uint64) *core.ExtendedBlock {
+	for _, block := range ssm.syncMgr.chain.FindBlocksByHeight(height) {
+		if !block.Status.IsDirectlyFinalized() {
+			continue
+		}
+		if has, _ := ssm.db.Has(block.StateHash.Bytes()); has {
+			return block
+		}
+	}
+	return nil
+}
+
+func (ssm *StateSyncManager) handleCheckpointsRequest(peerID string) {
+	if !ssm.isServing() {
+		return
+	}
+	entries := []string{}
+	for _, block := range ssm.servedCheckpoints() {
+		entries = append(entries, block.Hash().Hex())
+	}
+	if len(entries) == 0 {
+		return
+	}
+	ssm.syncMgr.dispatcher.SendInventory([]string{peerID}, dispatcher.InventoryResponse{
+		ChannelID: common.ChannelIDStateCheckpoint,
+		Entries:   entries,
+	})
+}
+
+// allowCheckpointRequest returns whether a checkpoint request of the peer can be served, i.e. the
+// last one was served at least StateSyncCheckpointRequestInterval ago.
+func (ssm *StateSyncManager) allowCheckpointRequest(peerID string) bool {
+	now := time.Now()
+	if last, ok := ssm.checkpointRequests.Get(peerID); ok && now.Sub(last.(time.Time)) < StateSyncCheckpointRequestInterval {
+		return false
+	}
+	ssm.checkpointRequests.Add(peerID, now)
+	return true
+}
+
+func (ssm *StateSyncManager) handleCheckpointRequest(peerID string, entries []string) {
+	if !ssm.isServing() || len(entries) == 0 {
+		return
+	}
+	if !ssm.allowCheckpointRequest(peerID) {
+		ssm.logger.WithFields(log.Fields{"peerID": peerID}).Debug("Dropped checkpoint request, the peer sends requests too often")
+		return
+	}
+	hash := common.HexToHash(entries[0])
+	block, err := ssm.syncMgr.chain.FindBlock(hash)
+	if err != nil || !block.Status.IsDirectlyFinalized() {
+		ssm.logger.WithFields(log.Fields{"hash": hash.Hex(), "peerID": peerID}).Debug("Requested checkpoint is not served")
+		return
+	}
+	if has, _ := ssm.db.Has(block.StateHash.Bytes()); !has {
+		ssm.logger.WithFields(log.Fields{"hash": hash.Hex(), "peerID": peerID}).Debug("State of the requested checkpoint is not available")
+		return
+	}
+
+	payload, err := ssm.encodeStateCheckpoint(block)
+	if err != nil {
+		ssm.logger.WithFields(log.Fields{"hash": hash.Hex(), "err": err}).Warn("Failed to export state checkpoint")
+		return
+	}
+	ssm.syncMgr.dispatcher.SendData([]string{peerID}, dispatcher.DataResponse{
+		ChannelID: common.ChannelIDStateCheckpoint,
+		Payload:   payload,
+	})
+}
+
+// encodeStateCheckpoint returns the encoded StateCheckpoint of the checkpoint block. Exporting the
+// proof walks the chain and proves the VCP from the state, so it is only done once per block.
+func (ssm *StateSyncManager) encodeStateCheckpoint(block *core.ExtendedBlock) (common.Bytes, error) {
+	if payload, ok := ssm.checkpointCache.Get(block.Hash()); ok {
+		return payload.(common.Bytes), nil
+	}
+
+	lastCheckpoint, metadata, err := snapshot.ExportStateCheckpoint(ssm.syncMgr.chain, ssm.db, block)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := rlp.EncodeToBytes(StateCheckpoint{LastCheckpoint: *lastCheckpoint, Metadata: *metadata})
+	if err != nil {
+		return nil, err
+	}
+	ssm.checkpointCache.Add(block.Hash(), common.Bytes(payload))
+	return payload, nil
+}
+
+func (ssm *StateSyncManager) handleNodesRequest(peerID string, entries []string) {
+	if !ssm.isServing() {
+		return
+	}
+	resp := StateNodes{}
+	for idx, entry := range entries {
+		if idx >= StateSyncMaxNodesPerRequest {
+			break
+		}
+		hash := common.HexToHash(entry)
+		node, err := ssm.db.Get(hash.Bytes())
+		// Only serve the content addressed entries, i.e. the trie nodes
+		if err != nil || crypto.Keccak256Hash(node) != hash {
+			continue
+		}
+		resp.Nodes = append(resp.Nodes, node)
+	}
+	payload, err := rlp.EncodeToBytes(resp)
+	if err != nil {
+		ssm.logger.WithFields(log.Fields{"err": err}).Error("Failed to encode state nodes")
+		return
+	}
+	ssm.syncMgr.dispatcher.SendData([]string{peerID}, dispatcher.DataResponse{
+		ChannelID: common.ChannelIDStateNode,
+		Payload:   payload,
+	})
+}
+
+// ---------------------------------- Syncing ----------------------------------
+
+func (ssm *StateSyncManager) isSyncing() bool {
+	ssm.mu.Lock()
+	defer ssm.mu.Unlock()
+	return ssm.syncing
+}
+
+func (ssm *StateSyncManager) setSyncing(syncing bool) {
+	ssm.mu.Lock()
+	defer ssm.mu.Unlock()
+	ssm.syncing = syncing
+}
+
+// deliver passes a response to the goroutine running Sync. The response is dropped if it can't
+// keep up, the request is then sent again after it times out.
+func (ssm *StateSyncManager) deliver(ch chan stateSyncMessage, msg stateSyncMessage) {
+	if !ssm.isSyncing() {
+		return
+	}
+	select {
+	case ch <- msg:
+	default:
+		ssm.logger.WithFields(log.Fields{"peerID": msg.peerID}).Debug("State sync queue is full, dropping response")
+	}
+}
+
+func (ssm *StateSyncManager) handleCheckpointResponse(peerID string, payload common.Bytes) {
+	checkpoint := &StateCheckpoint{}
+	if err := rlp.DecodeBytes(payload, checkpoint); err != nil {
+		ssm.logger.WithFields(log.Fields{"peerID": peerID, "err": err}).Warn("Failed to decode state checkpoint")
+		return
+	}
+	ssm.deliver(ssm.checkpoints, stateSyncMessage{peerID: peerID, checkpoint: checkpoint})
+}
+
+func (ssm *StateSyncManager) handleNodesResponse(peerID string, payload common.Bytes) {
+	nodes := &StateNodes{}
+	if err := rlp.DecodeBytes(payload, nodes); err != nil {
+		ssm.logger.WithFields(log.Fields{"peerID": peerID, "err": err}).Warn("Failed to decode state nodes")
+		return
+	}
+	ssm.deliver(ssm.nodes, stateSyncMessage{peerID: peerID, nodes: nodes.Nodes})
+}
+
+// Sync downloads the state of the trusted checkpoint block from the peers, and imports it once it
+// is complete and verified. It returns the checkpoint block.
+func (ssm *StateSyncManager) Sync(ctx context.Context, checkpoint common.Hash) (*core.ExtendedBlock, error) {
+	ssm.setSyncing(true)
+	defer ssm.setSyncing(false)
+	ssm.peers = []string{}
+
+	ssm.logger.WithFields(log.Fields{"checkpoint": checkpoint.Hex()}).Info("Starting state sync")
+
+	sc, err := ssm.fetchCheckpoint(ctx, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	header := sc.Metadata.TailTrio.Second.Header
+	ssm.logger.WithFields(log.Fields{
+		"checkpoint": checkpoint.Hex(),
+		"height":     header.Height,
+		"stateHash":  header.StateHash.Hex(),
+	}).Info("Downloading checkpoint state")
+
+	if err := ssm.syncTries(ctx, checkpoint, snapshot.StateCheckpointTries(&sc.LastCheckpoint, &sc.Metadata)); err != nil {
+		return nil, err
+	}
+	storageRoots, err := accountStorageRoots(header.StateHash, ssm.db)
+	if err != nil {
+		return nil, err
+	}
+	if err := ssm.syncTries(ctx, checkpoint, storageRoots); err != nil {
+		return nil, err
+	}
+
+	if _, err := snapshot.ImportStateCheckpoint(&sc.LastCheckpoint, &sc.Metadata, ssm.db); err != nil {
+		return nil, err
+	}
+	block, err := ssm.syncMgr.chain.FindBlock(checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	ssm.logger.WithFields(log.Fields{"checkpoint": checkpoint.Hex(), "height": block.Height}).Info("State sync completed")
+	return block, nil
+}
+
+// discoverPeers asks the peers for the checkpoints they serve.
+func (ssm *StateSyncManager) discoverPeers() {
+	ssm.syncMgr.dispatcher.GetInventory([]string{}, dispatcher.InventoryRequest{
+		ChannelID: common.ChannelIDStateCheckpoint,
+	})
+}
+
+func (ssm *StateSyncManager) addPeer(msg stateSyncMessage, checkpoint common.Hash) {
+	for _, peerID := range ssm.peers {
+		if peerID == msg.peerID {
+			return
+		}
+	}
+	for _, entry := range msg.entries {
+		if common.HexToHash(entry) == checkpoint {
+			ssm.peers = append(ssm.peers, msg.peerID)
+			return
+		}
+	}
+}
+
+func (ssm *StateSyncManager) removePeer(peerID string) {
+	for i, p := range ssm.peers {
+		if p == peerID {
+			ssm.peers = append(ssm.peers[:i], ssm.peers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (ssm *StateSyncManager) fetchCheckpoint(ctx context.Context, checkpoint common.Hash) (*StateCheckpoint, error) {
+	ticker := time.NewTicker(stateSyncTickInterval)
+	defer ticker.Stop()
+
+	ssm.discoverPeers()
+	lastDiscovery := time.Now()
+
+	next := 0
+	requestedPeer := ""
+	var requestedAt time.Time
+	for {
+		if requestedPeer == "" && len(ssm.peers) > 0 {
+			requestedPeer = ssm.peers[next%len(ssm.peers)]
+			requestedAt = time.Now()
+			next++
+			ssm.syncMgr.dispatcher.GetData([]string{requestedPeer}, dispatcher.DataRequest{
+				ChannelID: common.ChannelIDStateCheckpoint,
+				Entries:   []string{checkpoint.Hex()},
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case msg := <-ssm.advertisements:
+			ssm.addPeer(msg, checkpoint)
+		case msg := <-ssm.checkpoints:
+			if msg.peerID != requestedPeer {
+				continue
+			}
+			requestedPeer = ""
+			err := snapshot.VerifyStateCheckpoint(checkpoint, &msg.checkpoint.LastCheckpoint, &msg.checkpoint.Metadata)
+			if err != nil {
+				ssm.logger.WithFields(log.Fields{"peerID": msg.peerID, "err": err}).Warn("Invalid state checkpoint")
+				ssm.removePeer(msg.peerID)
+				continue
+			}
+			return msg.checkpoint, nil
+		case <-ticker.C:
+			if requestedPeer != "" && time.Since(requestedAt) > StateSyncRequestTimeout {
+				requestedPeer = ""
+			}
+			if len(ssm.peers) == 0 && time.Since(lastDiscovery) > StateSyncDiscoveryInterval {
+				ssm.discoverPeers()
+				lastDiscovery = time.Now()
+			}
+		}
+	}
+}
+
+type stateNodeRequest struct {
+	hashes map[common.Hash]bool
+	sentAt time.Time
+}
+
+// syncTries downloads the missing nodes of the tries with the given roots.
+func (ssm *StateSyncManager) syncTries(ctx context.Context, checkpoint common.Hash, roots []common.Hash) error {
+	if len(roots) == 0 {
+		return nil
+	}
+	sched := trie.NewSync(roots[0], ssm.db, nil)
+	for _, root := range roots[1:] {
+		sched.AddSubTrie(root, 0, common.Hash{}, nil)
+	}
+
+	ticker := time.NewTicker(stateSyncTickInterval)
+	defer ticker.Stop()
+	lastDiscovery := time.Now()
+
+	batch := ssm.db.NewBatch()
+	writer := &stateNodeWriter{batch: batch}
+	numNodes := 0
+
+	retries := []common.Hash{}
+	inflight := make(map[string]*stateNodeRequest) // peer ID -> outstanding request
+	for sched.Pending() > 0 {
+		// Assign the missing nodes to the idle peers
+		for _, peerID := range ssm.peers {
+			if _, ok := inflight[peerID]; ok {
+				continue
+			}
+			hashes := retries
+			if len(hashes) > StateSyncMaxNodesPerRequest {
+				hashes = hashes[:StateSyncMaxNodesPerRequest]
+			}
+			retries = retries[len(hashes):]
+			if len(hashes) < StateSyncMaxNodesPerRequest {
+				hashes = append(hashes, sched.Missing(StateSyncMaxNodesPerRequest-len(hashes))...)
+			}
+			if len(hashes) == 0 {
+				break
+			}
+
+			req := &stateNodeRequest{hashes: make(map[common.Hash]bool), sentAt: time.Now()}
+			entries := make([]string, 0, len(hashes))
+			for _, hash := range hashes {
+				req.hashes[hash] = true
+				entries = append(entries, hash.Hex())
+			}
+			inflight[peerID] = req
+			ssm.syncMgr.dispatcher.GetData([]string{peerID}, dispatcher.DataRequest{
+				ChannelID: common.ChannelIDStateNode,
+				Entries:   entries,
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-ssm.advertisements:
+			ssm.addPeer(msg, checkpoint)
+		case msg := <-ssm.nodes:
+			req, ok := inflight[msg.peerID]
+			if !ok {
+				continue
+			}
+			delete(inflight, msg.peerID)
+
+			for _, node := range msg.nodes {
+				hash := crypto.Keccak256Hash(node)
+				if !req.hashes[hash] {
+					continue // not requested from this peer
+				}
+				if _, _, err := sched.Process([]trie.SyncResult{{Hash: hash, Data: node}}); err != nil {
+					ssm.logger.WithFields(log.Fields{"peerID": msg.peerID, "hash": hash.Hex(), "err": err}).Warn("Failed to process state node")
+					continue
+				}
+				delete(req.hashes, hash)
+				numNodes++
+			}
+			for hash := range req.hashes {
+				retries = append(retries, hash)
+			}
+
+			if _, err := sched.Commit(writer); err != nil {
+				return err
+			}
+			if batch.ValueSize() > database.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					return err
+				}
+				batch.Reset()
+				ssm.logger.WithFields(log.Fields{"nodes": numNodes, "pending": sched.Pending()}).Info("Downloading state")
+			}
+		case <-ticker.C:
+			for peerID, req := range inflight {
+				if time.Since(req.sentAt) > StateSyncRequestTimeout {
+					for hash := range req.hashes {
+						retries = append(retries, hash)
+					}
+					delete(inflight, peerID)
+					ssm.removePeer(peerID)
+				}
+			}
+			if len(ssm.peers) < MaxNumPeersToSendRequests && time.Since(lastDiscovery) > StateSyncDiscoveryInterval {
+				ssm.discoverPeers()
+				lastDiscovery = time.Now()
+			}
+		}
+	}
+
+	if _, err := sched.Commit(writer); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// stateNodeWriter writes the downloaded trie nodes with the same ref count as the snapshot import.
+type stateNodeWriter struct {
+	batch database.Batch
+}
+
+func (w *stateNodeWriter) Put(key []byte, value []byte) error {
+	if err := w.batch.Put(key, value); err != nil {
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		if err := w.batch.Reference(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accountStorageRoots returns the roots of the account storage tries in the given state.
+func accountStorageRoots(root common.Hash, db database.Database) ([]common.Hash, error) {
+	roots := []common.Hash{}
+	var err error
+	treestore.NewTreeStore(root, db).Traverse(common.Bytes("ls/a/"), func(k, v common.Bytes) bool {
+		if err != nil {
+			return false
+		}
+		account := &types.Account{}
+		if err = types.FromBytes([]byte(v), account); err != nil {
+			err = fmt.Errorf("Failed to decode account %v: %v", k, err)
+			return false
+		}
+		if account.Root != (common.Hash{}) {
+			roots = append(roots, account.Root)
+		}
+		return true
+	})
+	return roots, err
+}
@@ -0,0 +1,202 @@
+package netsync
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/dispatcher"
+	"github.com/pandotoken/pando/ledger/state"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/p2p/simulation"
+	p2plmessenger "github.com/pandotoken/pando/p2pl/messenger"
+	"github.com/pandotoken/pando/store/database"
+	"github.com/pandotoken/pando/store/database/backend"
+	"github.com/pandotoken/pando/store/kvstore"
+)
+
+const stateSyncTestChainID = "statesynctest"
+
+func newStateSyncTestBlock(height uint64, parent common.Hash, stateHash common.Hash) *core.Block {
+	block := core.NewBlock()
+	block.ChainID = stateSyncTestChainID
+	block.Epoch = height
+	block.Height = height
+	block.Parent = parent
+	block.HCC.BlockHash = parent
+	block.StateHash = stateHash
+	block.Timestamp = big.NewInt(int64(height))
+	block.AddTxs([]common.Bytes{})
+	return block
+}
+
+// buildStateSyncTestState builds the state of the parent of the checkpoint block, and the state
+// of the checkpoint block on top of it.
+func buildStateSyncTestState(t *testing.T, db database.Database) (parentState, checkpointState common.Hash) {
+	vcp := &core.ValidatorCandidatePool{}
+	validator := common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab")
+	require.Nil(t, vcp.DepositStake(validator, validator, core.MinValidatorStakeDeposit200K, 100))
+
+	sv := state.NewStoreView(100, common.Hash{}, db)
+	sv.UpdateValidatorCandidatePool(vcp)
+	sv.UpdateStakeTransactionHeightList(&types.HeightList{Heights: []uint64{1}})
+	for i := 0; i < 200; i++ {
+		addr := common.BytesToAddress([]byte(fmt.Sprintf("account-%04d", i)))
+		acc := types.NewAccount(addr)
+		acc.Balance = types.NewCoins(int64(i), int64(i))
+		sv.SetAccount(addr, acc)
+	}
+	parentState = sv.Save()
+
+	sv = state.NewStoreView(101, parentState, db)
+	contract := common.BytesToAddress([]byte("contract"))
+	for i := 0; i < 50; i++ {
+		sv.SetState(contract, common.BytesToHash([]byte(fmt.Sprintf("key-%04d", i))), common.BytesToHash([]byte(fmt.Sprintf("value-%04d", i))))
+	}
+	checkpointState = sv.Save()
+	return
+}
+
+func newStateSyncTestManager(chain *blockchain.Chain, lfb *core.ExtendedBlock, simnet *simulation.Simnet, id string, db database.Database) *SyncManager {
+	net := simnet.AddEndpoint(id)
+	dispatch := dispatcher.NewDispatcher(net, (*p2plmessenger.Messenger)(nil))
+	sm := NewSyncManager(chain, NewMockConsensus(chain, lfb), net, (*p2plmessenger.Messenger)(nil), dispatch, NewMockMessageConsumer(), nil)
+	sm.EnableStateSync(db)
+	return sm
+}
+
+func TestStateSync(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// The serving node has the checkpoint block at height 101 finalized
+	serverDB := backend.NewMemDatabase()
+	parentState, checkpointState := buildStateSyncTestState(t, serverDB)
+
+	parent := newStateSyncTestBlock(100, common.Hash{}, parentState)
+	checkpoint := newStateSyncTestBlock(101, parent.Hash(), checkpointState)
+	child := newStateSyncTestBlock(102, checkpoint.Hash(), checkpointState)
+
+	serverChain := blockchain.NewChain(stateSyncTestChainID, kvstore.NewKVStore(serverDB), parent)
+	_, err := serverChain.AddBlock(checkpoint)
+	require.Nil(err)
+	_, err = serverChain.AddBlock(child)
+	require.Nil(err)
+	require.Nil(serverChain.FinalizePreviousBlocks(checkpoint.Hash()))
+	serverChain.CommitBlock(child.Hash())
+	lfb, err := serverChain.FindBlock(checkpoint.Hash())
+	require.Nil(err)
+
+	// The syncing node only has the genesis block
+	clientDB := backend.NewMemDatabase()
+	genesis := newStateSyncTestBlock(0, common.Hash{}, common.Hash{})
+	clientChain := blockchain.NewChain(stateSyncTestChainID, kvstore.NewKVStore(clientDB), genesis)
+
+	simnet := simulation.NewSimnet()
+	server := newStateSyncTestManager(serverChain, lfb, simnet, "server", serverDB)
+	client := newStateSyncTestManager(clientChain, clientChain.Root(), simnet, "client", clientDB)
+	simnet.Start(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.Start(ctx)
+
+	// The server advertises the checkpoint it can serve
+	assert.Equal([]string{checkpoint.Hash().Hex()}, []string{server.stateSyncMgr.servedCheckpoints()[0].Hash().Hex()})
+
+	syncCtx, syncCancel := context.WithTimeout(ctx, 30*time.Second)
+	defer syncCancel()
+	block, err := client.SyncState(syncCtx, checkpoint.Hash())
+	require.Nil(err)
+	assert.Equal(checkpoint.Hash(), block.Hash())
+	assert.Equal(uint64(101), block.Height)
+
+	// The synced state is identical to the state of the serving node
+	sv := state.NewStoreView(101, checkpointState, clientDB)
+	for i := 0; i < 200; i++ {
+		addr := common.BytesToAddress([]byte(fmt.Sprintf("account-%04d", i)))
+		acc := sv.GetAccount(addr)
+		require.NotNil(acc)
+		assert.Equal(int64(i), acc.Balance.PandoWei.Int64())
+	}
+	contract := common.BytesToAddress([]byte("contract"))
+	for i := 0; i < 50; i++ {
+		val := sv.GetState(contract, common.BytesToHash([]byte(fmt.Sprintf("key-%04d", i))))
+		assert.Equal(common.BytesToHash([]byte(fmt.Sprintf("value-%04d", i))), val)
+	}
+	assert.NotNil(state.NewStoreView(100, parentState, clientDB).GetValidatorCandidatePool())
+}
+
+func TestStateSyncUnknownCheckpoint(t *testing.T) {
+	assert := assert.New(t)
+
+	serverDB := backend.NewMemDatabase()
+	genesis := newStateSyncTestBlock(0, common.Hash{}, common.Hash{})
+	serverChain := blockchain.NewChain(stateSyncTestChainID, kvstore.NewKVStore(serverDB), genesis)
+
+	clientDB := backend.NewMemDatabase()
+	clientChain := blockchain.NewChain(stateSyncTestChainID, kvstore.NewKVStore(clientDB), genesis)
+
+	simnet := simulation.NewSimnet()
+	server := newStateSyncTestManager(serverChain, serverChain.Root(), simnet, "server", serverDB)
+	client := newStateSyncTestManager(clientChain, clientChain.Root(), simnet, "client", clientDB)
+	simnet.Start(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.Start(ctx)
+
+	// No peer serves the checkpoint, the state sync waits until it is canceled
+	syncCtx, syncCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer syncCancel()
+	_, err := client.SyncState(syncCtx, common.HexToHash("0x1234"))
+	assert.Equal(context.DeadlineExceeded, err)
+}
+
+func TestStateSyncCheckpointRequestThrottle(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	serverDB := backend.NewMemDatabase()
+	parentState, checkpointState := buildStateSyncTestState(t, serverDB)
+
+	parent := newStateSyncTestBlock(100, common.Hash{}, parentState)
+	checkpoint := newStateSyncTestBlock(101, parent.Hash(), checkpointState)
+	child := newStateSyncTestBlock(102, checkpoint.Hash(), checkpointState)
+
+	serverChain := blockchain.NewChain(stateSyncTestChainID, kvstore.NewKVStore(serverDB), parent)
+	_, err := serverChain.AddBlock(checkpoint)
+	require.Nil(err)
+	_, err = serverChain.AddBlock(child)
+	require.Nil(err)
+	require.Nil(serverChain.FinalizePreviousBlocks(checkpoint.Hash()))
+	serverChain.CommitBlock(child.Hash())
+	lfb, err := serverChain.FindBlock(checkpoint.Hash())
+	require.Nil(err)
+
+	simnet := simulation.NewSimnet()
+	server := newStateSyncTestManager(serverChain, lfb, simnet, "server", serverDB)
+	ssm := server.stateSyncMgr
+
+	// The encoded checkpoint is cached after the first request
+	ssm.handleCheckpointRequest("peer1", []string{checkpoint.Hash().Hex()})
+	payload, ok := ssm.checkpointCache.Get(checkpoint.Hash())
+	require.True(ok)
+	cached, err := ssm.encodeStateCheckpoint(lfb)
+	require.Nil(err)
+	assert.Equal(payload, cached)
+
+	// The requests of a peer are throttled, independently of the other peers
+	assert.False(ssm.allowCheckpointRequest("peer1"))
+	assert.True(ssm.allowCheckpointRequest("peer2"))
+	assert.False(ssm.allowCheckpointRequest("peer2"))
+	ssm.checkpointRequests.Add("peer1", time.Now().Add(-StateSyncCheckpointRequestInterval))
+	assert.True(ssm.allowCheckpointRequest("peer1"))
+}
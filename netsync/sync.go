@@ -2,6 +2,7 @@ package netsync
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"strings"
 	"sync"
@@ -19,6 +20,7 @@ import (
 	"github.com/pandotoken/pando/p2pl"
 	rp "github.com/pandotoken/pando/report"
 	"github.com/pandotoken/pando/rlp"
+	"github.com/pandotoken/pando/store/database"
 )
 
 const voteCacheLimit = 512
@@ -48,6 +50,8 @@ type SyncManager struct {
 	dispatcher *dispatcher.Dispatcher
 	requestMgr *RequestManager
 
+	stateSyncMgr *StateSyncManager
+
 	wg       *sync.WaitGroup
 	ctx      context.Context
 	cancel   context.CancelFunc
@@ -95,6 +99,40 @@ func NewSyncManager(chain *blockchain.Chain, cons core.ConsensusEngine, networkO
 	return sm
 }
 
+// EnableStateSync enables serving the state of the recent checkpoints to the peers, and syncing
+// the state of a checkpoint with SyncState.
+func (sm *SyncManager) EnableStateSync(db database.Database) {
+	sm.stateSyncMgr = NewStateSyncManager(sm, db)
+}
+
+// SyncState downloads the state of the given trusted checkpoint from the peers. It must be called
+// before Start. Only the state sync messages are processed until the state sync completes.
+func (sm *SyncManager) SyncState(ctx context.Context, checkpoint common.Hash) (*core.ExtendedBlock, error) {
+	if sm.stateSyncMgr == nil {
+		return nil, fmt.Errorf("State sync is not enabled")
+	}
+
+	c, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-c.Done():
+				return
+			case msg := <-sm.incoming:
+				sm.stateSyncMgr.handleMessage(msg, sm.isInboundAllowed(msg.PeerID))
+			}
+		}
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	return sm.stateSyncMgr.Sync(c, checkpoint)
+}
+
 func (sm *SyncManager) Start(ctx context.Context) {
 	c, cancel := context.WithCancel(ctx)
 	sm.ctx = c
@@ -140,6 +178,9 @@ func (sm *SyncManager) GetChannelIDs() []common.ChannelIDEnum {
 		common.ChannelIDGuardian,
 		common.ChannelIDEliteEdgeNodeVote,
 		common.ChannelIDAggregatedEliteEdgeNodeVotes,
+		common.ChannelIDEvidence,
+		common.ChannelIDStateCheckpoint,
+		common.ChannelIDStateNode,
 	}
 }
 
@@ -166,18 +207,26 @@ func (sm *SyncManager) HandleMessage(msg p2ptypes.Message) (err error) {
 	return
 }
 
-func (sm *SyncManager) processMessage(message p2ptypes.Message) {
-	inboundAllowed := true
-	// If whitelist is set, only process message from peers in the whitelist.
-	if len(sm.whitelist) > 0 {
-		inboundAllowed = false
-		for _, peerID := range sm.whitelist {
-			if strings.ToLower(peerID) == strings.ToLower(message.PeerID) {
-				inboundAllowed = true
-				break
-			}
+// isInboundAllowed returns whether the responses from the peer are processed. If whitelist is set,
+// only process message from peers in the whitelist.
+func (sm *SyncManager) isInboundAllowed(peerID string) bool {
+	if len(sm.whitelist) == 0 {
+		return true
+	}
+	for _, p := range sm.whitelist {
+		if strings.ToLower(p) == strings.ToLower(peerID) {
+			return true
 		}
 	}
+	return false
+}
+
+func (sm *SyncManager) processMessage(message p2ptypes.Message) {
+	inboundAllowed := sm.isInboundAllowed(message.PeerID)
+
+	if sm.stateSyncMgr != nil && sm.stateSyncMgr.handleMessage(message, inboundAllowed) {
+		return
+	}
 
 	switch content := message.Content.(type) {
 	case dispatcher.InventoryRequest:
@@ -659,6 +708,23 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 			"peer":            peerID,
 		}).Debug("Received aggregated elite edge node vote")
 		m.handleAggregatedEliteEdgeNodeVotes(vote)
+	case common.ChannelIDEvidence:
+		evidence := &core.EquivocationEvidence{}
+		err := rlp.DecodeBytes(data.Payload, evidence)
+		if err != nil {
+			m.logger.WithFields(log.Fields{
+				"channelID": data.ChannelID,
+				"payload":   data.Payload,
+				"error":     err,
+				"peerID":    peerID,
+			}).Warn("Failed to decode DataResponse payload")
+			return
+		}
+		m.logger.WithFields(log.Fields{
+			"evidence": evidence,
+			"peer":     peerID,
+		}).Debug("Received equivocation evidence")
+		m.handleEvidence(evidence)
 	case common.ChannelIDHeader:
 		headers := &Headers{}
 		err := rlp.DecodeBytes(data.Payload, headers)
@@ -825,3 +891,7 @@ func (sm *SyncManager) handleEliteEdgeNodeVote(vote *core.EENVote) {
 func (sm *SyncManager) handleAggregatedEliteEdgeNodeVotes(vote *core.AggregatedEENVotes) {
 	sm.PassdownMessage(vote)
 }
+
+func (sm *SyncManager) handleEvidence(evidence *core.EquivocationEvidence) {
+	sm.PassdownMessage(evidence)
+}
@@ -21,10 +21,15 @@ import (
 	"github.com/pandotoken/pando/blockchain"
 	"github.com/pandotoken/pando/p2p/simulation"
 	"github.com/pandotoken/pando/p2p/types"
+	p2plmessenger "github.com/pandotoken/pando/p2pl/messenger"
 )
 
 type MockMessageConsumer struct {
 	Received []interface{}
+
+	// Chain, if set, gets the received blocks marked as valid like the consensus engine does,
+	// so the sync manager passes down their children.
+	Chain *blockchain.Chain
 }
 
 func NewMockMessageConsumer() *MockMessageConsumer {
@@ -35,6 +40,9 @@ func NewMockMessageConsumer() *MockMessageConsumer {
 
 func (m *MockMessageConsumer) AddMessage(msg interface{}) {
 	m.Received = append(m.Received, msg)
+	if block, ok := msg.(*core.Block); ok && m.Chain != nil {
+		m.Chain.MarkBlockValid(block.Hash())
+	}
 }
 
 type MockMsgHandler struct {
@@ -85,11 +93,12 @@ func TestSyncManager(t *testing.T) {
 	privKey, _, _ := crypto.GenerateKeyPair()
 	valMgr := consensus.NewFixedValidatorManager()
 	db := kvstore.NewKVStore(backend.NewMemDatabase())
-	dispatch := dispatcher.NewDispatcher(net1, nil)
+	dispatch := dispatcher.NewDispatcher(net1, (*p2plmessenger.Messenger)(nil))
 	consensus := consensus.NewConsensusEngine(privKey, db, initChain, dispatch, valMgr)
 	mockMsgConsumer := NewMockMessageConsumer()
+	mockMsgConsumer.Chain = initChain
 
-	sm := NewSyncManager(initChain, consensus, net1, nil, dispatch, mockMsgConsumer)
+	sm := NewSyncManager(initChain, consensus, net1, (*p2plmessenger.Messenger)(nil), dispatch, mockMsgConsumer, nil)
 	sm.Start(context.Background())
 
 	// Send block A4 to node1
@@ -100,29 +109,40 @@ func TestSyncManager(t *testing.T) {
 			ChannelID: common.ChannelIDBlock,
 			Payload:   payload,
 		},
-	})
+	}, false)
+
+	// node1 should gossip A4 with an InventoryResponse and a header DataResponse, and request the
+	// missing blocks with an InventoryRequest. The simulated network delivers the messages
+	// concurrently, so they can arrive in any order.
+	var msg1 dispatcher.InventoryResponse
+	var msg11 dispatcher.DataResponse
+	var msg2 dispatcher.InventoryRequest
+	for i := 0; i < 3; i++ {
+		switch res := (<-mockMsgHandler.C).(type) {
+		case dispatcher.InventoryResponse:
+			msg1 = res
+		case dispatcher.DataResponse:
+			msg11 = res
+		case dispatcher.InventoryRequest:
+			msg2 = res
+		default:
+			t.Fatalf("Unexpected message: %v", res)
+		}
+	}
 
-	// node1 should broadcast InventoryResponse
-	var res interface{}
-	res = <-mockMsgHandler.C
-	msg1, ok := res.(dispatcher.InventoryResponse)
-	assert.True(ok)
 	assert.Equal(common.ChannelIDBlock, msg1.ChannelID)
-	assert.Equal(core.GetTestBlock("A4").Hash().Hex(), msg1.Entries[0])
+	if assert.Equal(1, len(msg1.Entries)) {
+		assert.Equal(core.GetTestBlock("A4").Hash().Hex(), msg1.Entries[0])
+	}
 
-	res = <-mockMsgHandler.C
-	msg11, ok := res.(dispatcher.DataResponse)
-	assert.True(ok)
 	assert.Equal(common.ChannelIDHeader, msg11.ChannelID)
 
-	res = <-mockMsgHandler.C
-	msg2, ok := res.(dispatcher.InventoryRequest)
-	assert.True(ok)
 	assert.Equal(common.ChannelIDBlock, msg2.ChannelID)
-	assert.Equal(3, len(msg2.Starts))
-	assert.Equal(core.GetTestBlock("B2").Hash().Hex(), msg2.Starts[0])
-	assert.Equal(core.GetTestBlock("A1").Hash().Hex(), msg2.Starts[1])
-	assert.Equal(core.GetTestBlock("A0").Hash().Hex(), msg2.Starts[2])
+	if assert.Equal(3, len(msg2.Starts)) {
+		assert.Equal(core.GetTestBlock("B2").Hash().Hex(), msg2.Starts[0])
+		assert.Equal(core.GetTestBlock("A1").Hash().Hex(), msg2.Starts[1])
+		assert.Equal(core.GetTestBlock("A0").Hash().Hex(), msg2.Starts[2])
+	}
 
 	// node2 replies with InventoryReponse
 	entries := []string{}
@@ -135,7 +155,7 @@ func TestSyncManager(t *testing.T) {
 			ChannelID: common.ChannelIDBlock,
 			Entries:   entries,
 		},
-	})
+	}, false)
 
 	// node2 replies with A3 first
 	payload, _ = rlp.EncodeToBytes(core.CreateTestBlock("A3", "A2"))
@@ -145,7 +165,7 @@ func TestSyncManager(t *testing.T) {
 			ChannelID: common.ChannelIDBlock,
 			Payload:   payload,
 		},
-	})
+	}, false)
 
 	time.Sleep(1 * time.Second)
 
@@ -157,9 +177,11 @@ func TestSyncManager(t *testing.T) {
 			ChannelID: common.ChannelIDBlock,
 			Payload:   payload,
 		},
-	})
+	}, false)
 
-	time.Sleep(1 * time.Second)
+	// A3 and A4 are passed down on the next ticks of the request manager, once their parents
+	// are marked as valid
+	time.Sleep(3 * time.Second)
 
 	sm.Stop()
 	sm.Wait()
@@ -202,7 +224,7 @@ func (c *MockConsensus) PrivateKey() *crypto.PrivateKey {
 }
 
 func (c *MockConsensus) GetTip(includePendingBlockingLeaf bool) *core.ExtendedBlock {
-	return nil
+	return c.lfb
 }
 
 func (c *MockConsensus) GetEpoch() uint64 {
@@ -221,6 +243,9 @@ func (c *MockConsensus) FinalizedBlocks() chan *core.Block {
 func (c *MockConsensus) GetLastFinalizedBlock() *core.ExtendedBlock {
 	return c.lfb
 }
+func (c *MockConsensus) GetPendingEvidence() []*core.EquivocationEvidence {
+	return nil
+}
 
 func TestCollectBlocks(t *testing.T) {
 	assert := assert.New(t)
@@ -245,12 +270,12 @@ func TestCollectBlocks(t *testing.T) {
 	net2.RegisterMessageHandler(mockMsgHandler)
 	simnet.Start(context.Background())
 
-	dispatch := dispatcher.NewDispatcher(net1, nil)
+	dispatch := dispatcher.NewDispatcher(net1, (*p2plmessenger.Messenger)(nil))
 	a3, _ := initChain.FindBlock(core.GetTestBlock("A3").Hash())
 	consensus := NewMockConsensus(initChain, a3)
 	mockMsgConsumer := NewMockMessageConsumer()
 
-	sm := NewSyncManager(initChain, consensus, net1, nil, dispatch, mockMsgConsumer)
+	sm := NewSyncManager(initChain, consensus, net1, (*p2plmessenger.Messenger)(nil), dispatch, mockMsgConsumer, nil)
 
 	blocks := sm.collectBlocks(core.GetTestBlock("A1").Hash(), core.GetTestBlock("A5").Hash())
 	// Expected blocks: [A1, A2, A3, A4, D4, A5, A3]
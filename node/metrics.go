@@ -0,0 +1,83 @@
+package node
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/metrics"
+	"github.com/pandotoken/pando/common/metrics/prometheus"
+	"github.com/pandotoken/pando/common/util"
+)
+
+var metricsLogger = util.GetLoggerForModule("metrics")
+
+// MetricsServer serves the metrics of the node on the /metrics endpoint in the Prometheus format.
+type MetricsServer struct {
+	server *http.Server
+
+	// Life cycle
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewMetricsServer creates a new instance of MetricsServer.
+func NewMetricsServer() *MetricsServer {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", prometheus.Handler(metrics.DefaultRegistry))
+
+	return &MetricsServer{
+		server: &http.Server{
+			Handler: mux,
+		},
+		wg: &sync.WaitGroup{},
+	}
+}
+
+// Start creates the main goroutine.
+func (ms *MetricsServer) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	ms.ctx = c
+	ms.cancel = cancel
+
+	ms.wg.Add(1)
+	go ms.mainLoop()
+}
+
+func (ms *MetricsServer) mainLoop() {
+	defer ms.wg.Done()
+
+	go ms.serve()
+
+	<-ms.ctx.Done()
+	ms.server.Shutdown(context.Background())
+}
+
+func (ms *MetricsServer) serve() {
+	address := viper.GetString(common.CfgMetricsAddress)
+	port := viper.GetString(common.CfgMetricsPort)
+	l, err := net.Listen("tcp", address+":"+port)
+	if err != nil {
+		metricsLogger.WithFields(log.Fields{"error": err}).Error("Failed to create listener")
+		return
+	}
+	metricsLogger.WithFields(log.Fields{"address": address, "port": port}).Info("Metrics server started")
+	defer l.Close()
+
+	metricsLogger.Info(ms.server.Serve(l))
+}
+
+// Stop notifies all goroutines to stop without blocking.
+func (ms *MetricsServer) Stop() {
+	ms.cancel()
+}
+
+// Wait blocks until all goroutines stop.
+func (ms *MetricsServer) Wait() {
+	ms.wg.Wait()
+}
@@ -2,7 +2,9 @@ package node
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"path"
 	"reflect"
 	"sync"
 
@@ -37,6 +39,7 @@ type Node struct {
 	Ledger           core.Ledger
 	Mempool          *mp.Mempool
 	RPC              *rpc.PandoRPCServer
+	Metrics          *MetricsServer
 	reporter         *rp.Reporter
 
 	// Life cycle
@@ -55,6 +58,7 @@ type Params struct {
 	Network             p2pl.Network
 	DB                  database.Database
 	RollingDB           *rollingdb.RollingDB
+	DataPath            string
 	SnapshotPath        string
 	ChainImportDirPath  string
 	ChainCorrectionPath string
@@ -63,6 +67,7 @@ type Params struct {
 func NewNode(params *Params) *Node {
 	store := kvstore.NewKVStore(params.DB)
 	chain := blockchain.NewChain(params.ChainID, store, params.Root)
+	chain.SetAccountTxIndexEnabled(viper.GetBool(common.CfgStorageAccountTxIndexEnabled))
 	params.RollingDB.SetChain(chain)
 
 	validatorManager := consensus.NewRotatingValidatorManager()
@@ -72,7 +77,11 @@ func NewNode(params *Params) *Node {
 
 	// TODO: check if this is a guardian node
 	syncMgr := netsync.NewSyncManager(chain, consensus, params.NetworkOld, params.Network, dispatcher, consensus, reporter)
+	syncMgr.EnableStateSync(params.DB)
 	mempool := mp.CreateMempool(dispatcher, consensus)
+	if viper.GetBool(common.CfgMempoolJournalEnabled) && params.DataPath != "" {
+		mempool.SetJournalPath(path.Join(params.DataPath, "mempool", "journal"))
+	}
 	ledger := ld.NewLedger(params.ChainID, params.RollingDB, params.RollingDB, chain, consensus, validatorManager, mempool)
 
 	validatorManager.SetConsensusEngine(consensus)
@@ -92,11 +101,19 @@ func NewNode(params *Params) *Node {
 		snapshotPath := params.SnapshotPath
 		chainImportDirPath := params.ChainImportDirPath
 		chainCorrectionPath := params.ChainCorrectionPath
+		var snapshotBlockHeader *core.BlockHeader
 		var lastCC *core.ExtendedBlock
 		var err error
-		if _, lastCC, err = snapshot.ImportSnapshot(snapshotPath, chainImportDirPath, chainCorrectionPath, chain, params.DB, ledger); err != nil {
+		if snapshotBlockHeader, lastCC, err = snapshot.ImportSnapshot(snapshotPath, chainImportDirPath, chainCorrectionPath, chain, params.DB, ledger); err != nil {
 			log.Fatalf("Failed to load snapshot: %v, err: %v", snapshotPath, err)
 		}
+		if lastCC == nil && snapshotBlockHeader.Height > currentHeight {
+			// A delta snapshot moves an existing node forward to the snapshot block
+			lastCC, err = chain.FindBlock(snapshotBlockHeader.Hash())
+			if err != nil {
+				log.Fatalf("Failed to find the snapshot block: %v, err: %v", snapshotBlockHeader.Hash().Hex(), err)
+			}
+		}
 		if lastCC != nil {
 			state := consensus.State()
 			state.SetLastFinalizedBlock(lastCC)
@@ -119,26 +136,113 @@ func NewNode(params *Params) *Node {
 	}
 
 	if viper.GetBool(common.CfgRPCEnabled) {
-		node.RPC = rpc.NewPandoRPCServer(mempool, ledger, dispatcher, chain, consensus)
+		node.RPC = rpc.NewPandoRPCServer(mempool, ledger, dispatcher, chain, consensus, params.DataPath)
+	}
+	if viper.GetBool(common.CfgMetricsEnabled) {
+		node.Metrics = NewMetricsServer()
 	}
+	node.addConfigReloadHandlers()
 	return node
 }
 
+// addConfigReloadHandlers makes the peer limit and the RPC settings reloadable at runtime. The
+// log levels are reloaded by the util package.
+func (n *Node) addConfigReloadHandlers() {
+	common.AddConfigReloadHandler(&common.ConfigReloadHandler{
+		Keys: []string{common.CfgP2PMaxNumPeers},
+		Validate: func(config *viper.Viper) error {
+			if config.IsSet(common.CfgP2PMaxNumPeers) && config.GetInt(common.CfgP2PMaxNumPeers) <= 0 {
+				return fmt.Errorf("Invalid %v: %v", common.CfgP2PMaxNumPeers, config.Get(common.CfgP2PMaxNumPeers))
+			}
+			return nil
+		},
+		Apply: func() {
+			n.Dispatcher.LimitPeers(viper.GetInt(common.CfgP2PMaxNumPeers))
+		},
+	})
+
+	// The health settings are read for each request
+	common.AddConfigReloadHandler(&common.ConfigReloadHandler{
+		Keys: []string{
+			common.CfgRPCMaxConnections,
+			common.CfgRPCHealthMaxFinalizedBlockAgeSecs,
+			common.CfgRPCHealthMinNumPeers,
+			common.CfgRPCHealthMaxConsensusIdleSecs,
+			common.CfgRPCNamespaces,
+			common.CfgRPCAuthNamespaces,
+			common.CfgRPCAuthAPIKeys,
+			common.CfgRPCAuthJWTSecret,
+			common.CfgRPCRateLimitRequestsPerSecond,
+			common.CfgRPCRateLimitBurst,
+			common.CfgRPCCORSAllowedOrigins,
+		},
+		Validate: rpc.ValidateConfig,
+		Apply: func() {
+			if n.RPC != nil {
+				n.RPC.ApplyConfig()
+			}
+		},
+	})
+}
+
 // Start starts sub components and kick off the main loop.
 func (n *Node) Start(ctx context.Context) {
 	c, cancel := context.WithCancel(ctx)
 	n.ctx = c
 	n.cancel = cancel
 
+	dispatcherStarted := false
+	if checkpoint := viper.GetString(common.CfgSyncStateSyncCheckpoint); checkpoint != "" {
+		// The peers are needed to download the checkpoint state
+		n.Dispatcher.Start(n.ctx)
+		dispatcherStarted = true
+		if !n.syncState(common.HexToHash(checkpoint)) {
+			return
+		}
+	}
+
 	n.Consensus.Start(n.ctx)
 	n.SyncManager.Start(n.ctx)
-	n.Dispatcher.Start(n.ctx)
+	if !dispatcherStarted {
+		n.Dispatcher.Start(n.ctx)
+	}
 	n.Mempool.Start(n.ctx)
 	n.reporter.Start(n.ctx)
 
 	if viper.GetBool(common.CfgRPCEnabled) {
 		n.RPC.Start(n.ctx)
 	}
+	if n.Metrics != nil {
+		n.Metrics.Start(n.ctx)
+	}
+}
+
+// syncState downloads the state of the trusted checkpoint from the peers, unless the node is
+// already past the checkpoint, and moves the consensus state to the checkpoint block. It returns
+// false if the node is stopped before the state sync completes.
+func (n *Node) syncState(checkpoint common.Hash) bool {
+	if block, err := n.Chain.FindBlock(checkpoint); err == nil && block.Status.IsFinalized() {
+		return true
+	}
+	if lfb := n.Consensus.GetLastFinalizedBlock(); lfb.Height > core.GenesisBlockHeight {
+		log.Printf("Skipping state sync, the node is already synced to height %v", lfb.Height)
+		return true
+	}
+
+	block, err := n.SyncManager.SyncState(n.ctx, checkpoint)
+	if err != nil {
+		if n.ctx.Err() != nil {
+			return false
+		}
+		log.Fatalf("Failed to sync the state of checkpoint %v, err: %v", checkpoint.Hex(), err)
+	}
+
+	state := n.Consensus.State()
+	state.SetLastFinalizedBlock(block)
+	state.SetHighestCCBlock(block)
+	state.SetLastVote(core.Vote{})
+	state.SetLastProposal(core.Proposal{})
+	return true
 }
 
 // Stop notifies all sub components to stop without blocking.
@@ -150,7 +254,11 @@ func (n *Node) Stop() {
 func (n *Node) Wait() {
 	n.Consensus.Wait()
 	n.SyncManager.Wait()
+	n.Mempool.Wait()
 	if n.RPC != nil {
 		n.RPC.Wait()
 	}
+	if n.Metrics != nil {
+		n.Metrics.Wait()
+	}
 }
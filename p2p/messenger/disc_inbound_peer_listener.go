@@ -119,7 +119,6 @@ func (ipl *InboundPeerListener) listenRoutine() {
 	defer ipl.wg.Done()
 
 	seedPeerOnly := viper.GetBool(common.CfgP2PSeedPeerOnly)
-	maxNumPeers := GetDefaultPeerDiscoveryManagerConfig().MaxNumPeers
 	logger.Infof("InboundPeerListener listen routine started, seedPeerOnly set to %v", seedPeerOnly)
 
 	//purgeAllNonSeedPeersInterval := time.Duration(viper.GetInt(common.CfgP2PBootstrapNodePurgePeerInterval)) * time.Second
@@ -149,6 +148,8 @@ func (ipl *InboundPeerListener) listenRoutine() {
 				logger.Infof("Accept inbound connection from seed peer %v", remoteAddr.String())
 			}
 		} else {
+			// Read for each connection, since the limit can be changed by a config reload
+			maxNumPeers := GetDefaultPeerDiscoveryManagerConfig().MaxNumPeers
 			skipEdgeNode := !viper.GetBool(common.CfgP2PIsBootstrapNode)
 			numPeers := int(ipl.discMgr.peerTable.GetTotalNumPeers(skipEdgeNode))
 			if numPeers >= maxNumPeers {
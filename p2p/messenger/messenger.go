@@ -2,6 +2,7 @@ package messenger
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"sync"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/pandotoken/pando/common/util"
 	"github.com/pandotoken/pando/crypto"
 	"github.com/pandotoken/pando/p2p"
+	"github.com/pandotoken/pando/p2p/netutil"
 	pr "github.com/pandotoken/pando/p2p/peer"
 	p2ptypes "github.com/pandotoken/pando/p2p/types"
 )
@@ -255,6 +257,49 @@ func (msgr *Messenger) PeerExists(peerID string) bool {
 	return msgr.peerTable.PeerExists(peerID)
 }
 
+// ConnectPeer connects to the peer at the given "ip:port" address. The peer is persistent, i.e.
+// the messenger tries to re-connect if the connection is lost.
+func (msgr *Messenger) ConnectPeer(address string) (string, error) {
+	netAddr, err := netutil.NewNetAddressString(address)
+	if err != nil {
+		return "", err
+	}
+	if msgr.peerTable.PeerAddrExists(netAddr) {
+		return "", fmt.Errorf("Already connected to %v", address)
+	}
+	peer, err := msgr.discMgr.connectToOutboundPeer(netAddr, true)
+	if err != nil {
+		return "", err
+	}
+	return peer.ID(), nil
+}
+
+// DisconnectPeer disconnects from the given peer. The peer is removed from the peer table
+// before it is stopped, so that the messenger does not try to re-connect.
+func (msgr *Messenger) DisconnectPeer(peerID string) error {
+	peer := msgr.peerTable.GetPeer(peerID)
+	if peer == nil {
+		return fmt.Errorf("Peer %v is not connected", peerID)
+	}
+	msgr.peerTable.DeletePeer(peerID)
+	peer.Stop()
+	return nil
+}
+
+// LimitPeers disconnects from the oldest non-seed peers until the number of peers is within
+// the given limit. It is called when the limit is lowered by a config reload.
+func (msgr *Messenger) LimitPeers(maxNumPeers int) {
+	skipEdgeNode := !viper.GetBool(common.CfgP2PIsBootstrapNode)
+	for int(msgr.peerTable.GetTotalNumPeers(skipEdgeNode)) > maxNumPeers {
+		peer := msgr.peerTable.PurgeOldestNonSeedPeer()
+		if peer == nil {
+			return // only seeds are left, which are kept connected
+		}
+		peer.Stop()
+		logger.Infof("Disconnected from peer %v to stay within the max number of peers %v", peer.ID(), maxNumPeers)
+	}
+}
+
 // RegisterMessageHandler registers the message handler
 func (msgr *Messenger) RegisterMessageHandler(msgHandler p2p.MessageHandler) {
 	channelIDs := msgHandler.GetChannelIDs()
@@ -274,12 +319,16 @@ func (msgr *Messenger) ID() string {
 
 // AttachMessageHandlersToPeer attaches the registered message handlers to the given peer
 func (msgr *Messenger) AttachMessageHandlersToPeer(peer *pr.Peer) {
+	ingressCounter := p2ptypes.PeerIngressCounter(peer.ID())
+	egressCounter := p2ptypes.PeerEgressCounter(peer.ID())
+
 	messageParser := func(channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (p2ptypes.Message, error) {
 		peerID := peer.ID()
 		msgHandler := msgr.msgHandlerMap[channelID]
 		if msgHandler == nil {
 			logger.Errorf("Failed to setup message parser for channelID %v", channelID)
 		}
+		ingressCounter.Inc(int64(len(rawMessageBytes)))
 		message, err := msgHandler.ParseMessage(peerID, channelID, rawMessageBytes)
 		return message, err
 	}
@@ -287,7 +336,11 @@ func (msgr *Messenger) AttachMessageHandlersToPeer(peer *pr.Peer) {
 
 	messageEncoder := func(channelID common.ChannelIDEnum, message interface{}) (common.Bytes, error) {
 		msgHandler := msgr.msgHandlerMap[channelID]
-		return msgHandler.EncodeMessage(message)
+		raw, err := msgHandler.EncodeMessage(message)
+		if err == nil {
+			egressCounter.Inc(int64(len(raw)))
+		}
+		return raw, err
 	}
 	peer.GetConnection().SetMessageEncoder(messageEncoder)
 
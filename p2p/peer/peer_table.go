@@ -11,6 +11,7 @@ import (
 	"github.com/pandotoken/pando/common"
 	mm "github.com/pandotoken/pando/common/math"
 	nu "github.com/pandotoken/pando/p2p/netutil"
+	p2ptypes "github.com/pandotoken/pando/p2p/types"
 
 	"github.com/spf13/viper"
 	"github.com/syndtr/goleveldb/leveldb"
@@ -118,6 +119,7 @@ func (pt *PeerTable) DeletePeer(peerID string) {
 
 	delete(pt.peerMap, peerID)
 	delete(pt.addrMap, peer.NetAddress().String())
+	p2ptypes.UnregisterPeerMetrics(peerID)
 	for idx, peer := range pt.peers {
 		if peer.ID() == peerID {
 			pt.peers = append(pt.peers[:idx], pt.peers[idx+1:]...) // not to break in case there are multiple matches
@@ -153,6 +155,28 @@ func (pt *PeerTable) PurgeOldestPeer() *Peer {
 	return peer
 }
 
+// PurgeOldestNonSeedPeer purges the oldest peer which is not a seed from the PeerTable. It returns
+// nil if all the peers are seeds
+func (pt *PeerTable) PurgeOldestNonSeedPeer() *Peer {
+	pt.mutex.Lock()
+	defer pt.mutex.Unlock()
+
+	for idx, peer := range pt.peers {
+		if peer.IsSeed() {
+			continue
+		}
+		delete(pt.peerMap, peer.ID())
+		delete(pt.addrMap, peer.NetAddress().String())
+		pt.peers = append(pt.peers[:idx], pt.peers[idx+1:]...)
+
+		logger.Infof("Purged the oldest non-seed peer %v from the peer table, idx: %v", peer.ID(), idx)
+
+		pt.persistPeers()
+		return peer
+	}
+	return nil
+}
+
 // GetPeer returns the peer for the given peerID (if exists)
 func (pt *PeerTable) GetPeer(peerID string) *Peer {
 	pt.mutex.Lock()
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/pandotoken/pando/crypto"
 	cn "github.com/pandotoken/pando/p2p/connection"
+	nu "github.com/pandotoken/pando/p2p/netutil"
 	p2ptypes "github.com/pandotoken/pando/p2p/types"
 )
 
@@ -136,6 +137,38 @@ func TestDefaultPeerIterationOrder(t *testing.T) {
 	assert.Equal((*allPeers)[5], peer3)
 }
 
+func TestDefaultPeerTablePurgeOldestNonSeedPeer(t *testing.T) {
+	assert := assert.New(t)
+
+	pt := newTestEmptyPeerTable()
+	assert.Nil(pt.PurgeOldestNonSeedPeer())
+
+	port := 37859
+	netconn := newIncomingNetconn(port)
+
+	seed1 := newSimulatedInboundPeer(netconn, p2ptypes.GetTestRandPubKey())
+	seed1.SetSeed(true)
+	peer2 := newSimulatedInboundPeer(netconn, p2ptypes.GetTestRandPubKey())
+	seed3 := newSimulatedInboundPeer(netconn, p2ptypes.GetTestRandPubKey())
+	seed3.SetSeed(true)
+	peer4 := newSimulatedInboundPeer(netconn, p2ptypes.GetTestRandPubKey())
+
+	assert.True(pt.AddPeer(seed1))
+	assert.True(pt.AddPeer(peer2))
+	assert.True(pt.AddPeer(seed3))
+	assert.True(pt.AddPeer(peer4))
+
+	assert.Equal(peer2, pt.PurgeOldestNonSeedPeer())
+	assert.Equal(peer4, pt.PurgeOldestNonSeedPeer())
+	assert.Nil(pt.PurgeOldestNonSeedPeer()) // the seeds are never purged
+
+	assert.Equal(uint(2), pt.GetTotalNumPeers(true))
+	assert.True(pt.PeerExists(seed1.ID()))
+	assert.True(pt.PeerExists(seed3.ID()))
+	assert.False(pt.PeerExists(peer2.ID()))
+	assert.False(pt.PeerExists(peer4.ID()))
+}
+
 // --------------- Test Utilities --------------- //
 
 func newTestEmptyPeerTable() PeerTable {
@@ -154,6 +187,7 @@ func newSimulatedInboundPeer(netconn net.Conn, pubKey *crypto.PublicKey) *Peer {
 	_, portStr, _ := net.SplitHostPort(netconn.LocalAddr().String())
 	port, _ := strconv.ParseUint(portStr, 16, 16)
 	inboundPeer.nodeInfo = p2ptypes.CreateNodeInfo(pubKey, uint16(port))
+	inboundPeer.SetNetAddress(nu.NewNetAddress(netconn.RemoteAddr()))
 	return inboundPeer
 }
 
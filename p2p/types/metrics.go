@@ -0,0 +1,32 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/pandotoken/pando/common/metrics"
+)
+
+func peerIngressMetricName(peerID string) string {
+	return fmt.Sprintf("p2p/peer/ingress{peer=%q}", peerID)
+}
+
+func peerEgressMetricName(peerID string) string {
+	return fmt.Sprintf("p2p/peer/egress{peer=%q}", peerID)
+}
+
+// PeerIngressCounter returns the counter of the bytes received from the given peer.
+func PeerIngressCounter(peerID string) metrics.Counter {
+	return metrics.GetOrRegisterCounter(peerIngressMetricName(peerID), nil)
+}
+
+// PeerEgressCounter returns the counter of the bytes sent to the given peer.
+func PeerEgressCounter(peerID string) metrics.Counter {
+	return metrics.GetOrRegisterCounter(peerEgressMetricName(peerID), nil)
+}
+
+// UnregisterPeerMetrics removes the metrics of the given peer, so that the disconnected
+// peers do not accumulate in the registry.
+func UnregisterPeerMetrics(peerID string) {
+	metrics.DefaultRegistry.Unregister(peerIngressMetricName(peerID))
+	metrics.DefaultRegistry.Unregister(peerEgressMetricName(peerID))
+}
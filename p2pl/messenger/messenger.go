@@ -676,7 +676,64 @@ func (msgr *Messenger) PeerExists(peerID string) bool {
 	return msgr.peerTable.PeerExists(prID)
 }
 
-func (msgr *Messenger) recordReceivedBytes(cid common.ChannelIDEnum, size int) {
+// ConnectPeer connects to the peer at the given multiaddress, which must include the peer ID,
+// e.g. /ip4/127.0.0.1/tcp/15000/p2p/<peer ID>.
+func (msgr *Messenger) ConnectPeer(address string) (string, error) {
+	addr, err := ma.NewMultiaddr(address)
+	if err != nil {
+		return "", err
+	}
+	info, err := peerstore.InfoFromP2pAddr(addr)
+	if err != nil {
+		return "", err
+	}
+	if msgr.peerTable.PeerExists(info.ID) {
+		return "", fmt.Errorf("Already connected to %v", info.ID.Pretty())
+	}
+	if err := msgr.host.Connect(msgr.ctx, *info); err != nil {
+		return "", err
+	}
+	return info.ID.Pretty(), nil
+}
+
+// DisconnectPeer closes the connections to the given peer. The peer is removed from the peer
+// table when the connections are closed.
+func (msgr *Messenger) DisconnectPeer(peerID string) error {
+	prID, err := pr.IDB58Decode(peerID)
+	if err != nil {
+		return err
+	}
+	if !msgr.peerTable.PeerExists(prID) {
+		return fmt.Errorf("Peer %v is not connected", peerID)
+	}
+	return msgr.host.Network().ClosePeer(prID)
+}
+
+// LimitPeers disconnects from non-seed peers until the number of peers is within the given
+// limit. It is called when the limit is lowered by a config reload. Note that the libp2p
+// connection manager keeps the limit the node was started with.
+func (msgr *Messenger) LimitPeers(maxNumPeers int) {
+	numPeers := int(msgr.peerTable.GetTotalNumPeers(true))
+	for _, peer := range *msgr.peerTable.GetAllPeers(true) {
+		if numPeers <= maxNumPeers {
+			return
+		}
+		if msgr.isSeedPeer(peer.ID()) {
+			continue
+		}
+		msgr.host.Network().ClosePeer(peer.ID())
+		numPeers--
+		logger.Infof("Disconnected from peer %v to stay within the max number of peers %v", peer.ID().Pretty(), maxNumPeers)
+	}
+}
+
+// recordReceivedBytes records the bytes received on the channel. The peerID is empty for the
+// gossiped messages, since the pubsub does not tell which neighbor relayed them.
+func (msgr *Messenger) recordReceivedBytes(peerID string, cid common.ChannelIDEnum, size int) {
+	if peerID != "" {
+		p2ptypes.PeerIngressCounter(peerID).Inc(int64(size))
+	}
+
 	if !msgr.statsEnabled {
 		return
 	}
@@ -697,7 +754,7 @@ func (msgr *Messenger) printStats() {
 	defer msgr.statsLock.Unlock()
 
 	ret := "Received bytes:"
-	for k := byte(0); k <= byte(common.ChannelIDAggregatedEliteEdgeNodeVotes); k++ {
+	for k := byte(0); k <= byte(common.ChannelIDStateNode); k++ {
 		v, ok := msgr.statsCounter[common.ChannelIDEnum(k)]
 		if !ok {
 			continue
@@ -752,7 +809,7 @@ func (msgr *Messenger) RegisterMessageHandler(msgHandler p2pl.MessageHandler) {
 					return
 				}
 
-				msgr.recordReceivedBytes(channelID, len(msg.Data))
+				msgr.recordReceivedBytes("", channelID, len(msg.Data))
 
 				msgHandler.HandleMessage(message)
 			}
@@ -813,7 +870,7 @@ func (msgr *Messenger) registerStreamHandler(channelID common.ChannelIDEnum) {
 				return
 			}
 
-			msgr.recordReceivedBytes(channelID, len(rawPeerMsg))
+			msgr.recordReceivedBytes(peerID.String(), channelID, len(rawPeerMsg))
 
 			msgHandler.HandleMessage(message)
 		}
@@ -872,7 +929,7 @@ func (msgr *Messenger) readPeerMessageRoutine(stream *transport.BufferedStream,
 			return
 		}
 
-		msgr.recordReceivedBytes(channelID, len(rawPeerMsg))
+		msgr.recordReceivedBytes(peerID, channelID, len(rawPeerMsg))
 
 		msgHandler.HandleMessage(message)
 	}
@@ -880,6 +937,8 @@ func (msgr *Messenger) readPeerMessageRoutine(stream *transport.BufferedStream,
 
 // attachHandlersToPeer attaches the registerred message/stream handlers to the given peer
 func (msgr *Messenger) attachHandlersToPeer(peer *peer.Peer) {
+	egressCounter := p2ptypes.PeerEgressCounter(peer.ID().String())
+
 	messageParser := func(channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (p2ptypes.Message, error) {
 		peerID := peer.ID()
 		msgHandler := msgr.msgHandlerMap[channelID]
@@ -888,7 +947,7 @@ func (msgr *Messenger) attachHandlersToPeer(peer *peer.Peer) {
 		}
 		message, err := msgHandler.ParseMessage(peerID.String(), channelID, rawMessageBytes)
 
-		msgr.recordReceivedBytes(channelID, len(rawMessageBytes))
+		msgr.recordReceivedBytes(peerID.String(), channelID, len(rawMessageBytes))
 
 		return message, err
 	}
@@ -896,7 +955,11 @@ func (msgr *Messenger) attachHandlersToPeer(peer *peer.Peer) {
 
 	messageEncoder := func(channelID common.ChannelIDEnum, message interface{}) (common.Bytes, error) {
 		msgHandler := msgr.msgHandlerMap[channelID]
-		return msgHandler.EncodeMessage(message)
+		raw, err := msgHandler.EncodeMessage(message)
+		if err == nil {
+			egressCounter.Inc(int64(len(raw)))
+		}
+		return raw, err
 	}
 	peer.SetMessageEncoder(messageEncoder)
 
@@ -32,6 +32,9 @@ var Channels = []cmn.ChannelIDEnum{
 	cmn.ChannelIDGuardian,
 	cmn.ChannelIDEliteEdgeNodeVote,
 	cmn.ChannelIDAggregatedEliteEdgeNodeVotes,
+	cmn.ChannelIDEvidence,
+	cmn.ChannelIDStateCheckpoint,
+	cmn.ChannelIDStateNode,
 }
 
 //
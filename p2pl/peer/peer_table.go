@@ -10,6 +10,7 @@ import (
 	pr "github.com/libp2p/go-libp2p-core/peer"
 	"github.com/pandotoken/pando/common"
 	mm "github.com/pandotoken/pando/common/math"
+	p2ptypes "github.com/pandotoken/pando/p2p/types"
 
 	"github.com/spf13/viper"
 	"github.com/syndtr/goleveldb/leveldb"
@@ -106,6 +107,7 @@ func (pt *PeerTable) DeletePeer(peerID pr.ID) {
 	}
 
 	delete(pt.peerMap, peerID)
+	p2ptypes.UnregisterPeerMetrics(peerID.String())
 	for idx, peer := range pt.peers {
 		if peer.ID() == peerID {
 			pt.peers = append(pt.peers[:idx], pt.peers[idx+1:]...)
@@ -0,0 +1,173 @@
+package rpc
+
+import (
+	"errors"
+
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/util"
+	"github.com/pandotoken/pando/core"
+)
+
+// ------------------------------- AddPeer -----------------------------------
+
+type AddPeerArgs struct {
+	Address string `json:"address"` // "ip:port", or a multiaddress including the peer ID for libp2p
+}
+
+type AddPeerResult struct {
+	PeerID string `json:"peer_id"`
+}
+
+func (t *PandoRPCService) AddPeer(args *AddPeerArgs, result *AddPeerResult) (err error) {
+	if args.Address == "" {
+		return errors.New("Peer address is not specified")
+	}
+	result.PeerID, err = t.dispatcher.ConnectPeer(args.Address)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Connected to peer %v at %v through the admin RPC", result.PeerID, args.Address)
+	return nil
+}
+
+// ------------------------------- RemovePeer -----------------------------------
+
+type RemovePeerArgs struct {
+	PeerID string `json:"peer_id"`
+}
+
+type RemovePeerResult struct {
+}
+
+func (t *PandoRPCService) RemovePeer(args *RemovePeerArgs, result *RemovePeerResult) error {
+	if err := t.dispatcher.DisconnectPeer(args.PeerID); err != nil {
+		return err
+	}
+	logger.Infof("Disconnected from peer %v through the admin RPC", args.PeerID)
+	return nil
+}
+
+// ------------------------------- SetLogLevel -----------------------------------
+
+type SetLogLevelArgs struct {
+	Module string `json:"module"` // "*" sets the default level
+	Level  string `json:"level"`
+}
+
+type SetLogLevelResult struct {
+	LogLevels []string `json:"log_levels"`
+}
+
+func (t *PandoRPCService) SetLogLevel(args *SetLogLevelArgs, result *SetLogLevelResult) error {
+	if err := util.SetLogLevel(args.Module, args.Level); err != nil {
+		return err
+	}
+	result.LogLevels = util.GetLogLevels()
+	return nil
+}
+
+// ------------------------------- PruneState -----------------------------------
+
+type PruneStateArgs struct {
+}
+
+type PruneStateResult struct {
+	OldestStateHeight common.JSONUint64 `json:"oldest_state_height"`
+}
+
+// PruneState runs a compaction of the rolling db right away, dropping the states older than the
+// last storage.statePruningRetainedBlocks finalized blocks. It is rejected in archive mode.
+func (t *PandoRPCService) PruneState(args *PruneStateArgs, result *PruneStateResult) error {
+	if err := t.ledger.CompactState(); err != nil {
+		return err
+	}
+	result.OldestStateHeight = common.JSONUint64(t.ledger.OldestStateHeight())
+	logger.Infof("Pruned the state through the admin RPC, oldest state height: %v", result.OldestStateHeight)
+	return nil
+}
+
+// ------------------------------- FlushMempool -----------------------------------
+
+type FlushMempoolArgs struct {
+}
+
+type FlushMempoolResult struct {
+	NumFlushedTxs common.JSONUint64 `json:"num_flushed_txs"`
+}
+
+func (t *PandoRPCService) FlushMempool(args *FlushMempoolArgs, result *FlushMempoolResult) error {
+	result.NumFlushedTxs = common.JSONUint64(t.mempool.Size())
+	t.mempool.Flush()
+	logger.Infof("Flushed %v transactions from the mempool through the admin RPC", result.NumFlushedTxs)
+	return nil
+}
+
+// ------------------------------- DumpConsensusState -----------------------------------
+
+type DumpConsensusStateArgs struct {
+}
+
+type DumpConsensusStateResult struct {
+	Epoch                    common.JSONUint64 `json:"epoch"`
+	Root                     common.Hash       `json:"root"`
+	HighestCCBlockHash       common.Hash       `json:"highest_cc_block_hash"`
+	HighestCCBlockHeight     common.JSONUint64 `json:"highest_cc_block_height"`
+	LastFinalizedBlockHash   common.Hash       `json:"last_finalized_block_hash"`
+	LastFinalizedBlockHeight common.JSONUint64 `json:"last_finalized_block_height"`
+	LastProposalBlockHash    common.Hash       `json:"last_proposal_block_hash"`
+	LastProposalBlockHeight  common.JSONUint64 `json:"last_proposal_block_height"`
+	LastProposalProposer     common.Address    `json:"last_proposal_proposer"`
+	LastVote                 core.Vote         `json:"last_vote"`
+	EpochVotes               []core.Vote       `json:"epoch_votes"`
+}
+
+func (t *PandoRPCService) DumpConsensusState(args *DumpConsensusStateArgs, result *DumpConsensusStateResult) error {
+	state := t.consensus.State()
+	s := state.GetSummary()
+
+	result.Epoch = common.JSONUint64(s.Epoch)
+	result.Root = s.Root
+	result.HighestCCBlockHash = s.HighestCCBlock
+	if block, err := t.chain.FindBlock(s.HighestCCBlock); err == nil {
+		result.HighestCCBlockHeight = common.JSONUint64(block.Height)
+	}
+	result.LastFinalizedBlockHash = s.LastFinalizedBlock
+	if block, err := t.chain.FindBlock(s.LastFinalizedBlock); err == nil {
+		result.LastFinalizedBlockHeight = common.JSONUint64(block.Height)
+	}
+	if s.LastProposal.Block != nil {
+		result.LastProposalBlockHash = s.LastProposal.Block.Hash()
+		result.LastProposalBlockHeight = common.JSONUint64(s.LastProposal.Block.Height)
+	}
+	result.LastProposalProposer = s.LastProposal.ProposerID
+	result.LastVote = s.LastVote
+
+	epochVotes, err := state.GetEpochVotes()
+	if err != nil {
+		return err
+	}
+	result.EpochVotes = []core.Vote{}
+	if epochVotes != nil {
+		result.EpochVotes = epochVotes.Votes()
+	}
+	return nil
+}
+
+// ------------------------------- ReloadConfig -----------------------------------
+
+type ReloadConfigArgs struct {
+}
+
+type ReloadConfigResult struct {
+	ChangedKeys []string `json:"changed_keys"`
+}
+
+// ReloadConfig reloads the config file, see common.ReloadConfig.
+func (t *PandoRPCService) ReloadConfig(args *ReloadConfigArgs, result *ReloadConfigResult) (err error) {
+	result.ChangedKeys, err = common.ReloadConfig()
+	if err != nil {
+		return err
+	}
+	logger.Infof("Reloaded config through the admin RPC, changed keys: %v", result.ChangedKeys)
+	return nil
+}
@@ -0,0 +1,173 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/rpc"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/util"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/ledger"
+	"github.com/pandotoken/pando/rpc/lib/rpc-codec/jsonrpc2"
+	"github.com/pandotoken/pando/store/database/backend"
+	"github.com/pandotoken/pando/store/kvstore"
+)
+
+func TestListenAdminSocket(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "admin_socket")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	socketPath := path.Join(dir, "admin.sock")
+
+	l, err := listenAdminSocket(socketPath)
+	require.Nil(err)
+	info, err := os.Stat(socketPath)
+	require.Nil(err)
+	assert.Equal(os.FileMode(0600), info.Mode().Perm())
+
+	// The temporary directory is removed, and the socket is removed on close
+	files, err := ioutil.ReadDir(dir)
+	require.Nil(err)
+	assert.Equal(1, len(files))
+	l.Close()
+	l.Close()
+	_, err = os.Stat(socketPath)
+	assert.True(os.IsNotExist(err))
+
+	// The socket left by a previous run is replaced
+	leftover, err := net.Listen("unix", socketPath)
+	require.Nil(err)
+	leftover.(*net.UnixListener).SetUnlinkOnClose(false)
+	leftover.Close()
+	l, err = listenAdminSocket(socketPath)
+	require.Nil(err)
+	info, err = os.Stat(socketPath)
+	require.Nil(err)
+	assert.Equal(os.FileMode(0600), info.Mode().Perm())
+	l.Close()
+
+	// Other files are not replaced
+	filePath := path.Join(dir, "file")
+	require.Nil(ioutil.WriteFile(filePath, []byte{}, 0600))
+	_, err = listenAdminSocket(filePath)
+	assert.NotNil(err)
+}
+
+func TestAdminSocketRPC(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "admin_socket")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	socketPath := path.Join(dir, "admin.sock")
+
+	s := rpc.NewServer()
+	s.RegisterName("pando", &PandoRPCService{})
+	l, err := listenAdminSocket(socketPath)
+	require.Nil(err)
+	server := &http.Server{Handler: newAdminRPCPolicy().middleware(jsonrpc2.HTTPHandler(s))}
+	go server.Serve(l)
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	call := func(method, params string) map[string]json.RawMessage {
+		body := `{"jsonrpc":"2.0","method":"` + method + `","params":[` + params + `],"id":1}`
+		// Credentials are not required, and ignored on the admin socket
+		req, err := http.NewRequest("POST", "http://unix/rpc", strings.NewReader(body))
+		require.Nil(err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Api-Key", "unknown key")
+		resp, err := client.Do(req)
+		require.Nil(err)
+		defer resp.Body.Close()
+		var res map[string]json.RawMessage
+		require.Nil(json.NewDecoder(resp.Body).Decode(&res))
+		return res
+	}
+
+	res := call("pando.SetLogLevel", `{"module":"rpc","level":"info"}`)
+	require.Nil(res["error"])
+	var result SetLogLevelResult
+	require.Nil(json.Unmarshal(res["result"], &result))
+	assert.Contains(result.LogLevels, "rpc:info")
+
+	res = call("pando.SetLogLevel", `{"module":"rpc","level":"verbose"}`)
+	assert.Contains(string(res["error"]), "Invalid log level")
+
+	// The admin methods are not available on a public listener
+	assert.Equal(NamespaceAdmin, MethodNamespace("pando.SetLogLevel"))
+	assert.Equal(NamespaceAdmin, MethodNamespace("pando.FlushMempool"))
+	policy := newTestRPCPolicy([]string{NamespacePublic}, nil)
+	assert.Equal(rpcErrCodeMethodNotAvailable, rpcErrorCode(policy.methodFilter(rpcClient{id: "ip:127.0.0.1"})("pando.SetLogLevel")))
+}
+
+// compactedDB mocks a db which drops the old states when compacted.
+type compactedDB struct {
+	prunedDB
+	compactedHeight uint64
+}
+
+func (db *compactedDB) Compact(height uint64) error {
+	db.compactedHeight = height
+	db.oldestHeight = height - 100
+	return nil
+}
+
+// finalizedConsensus mocks a consensus engine with the given last finalized block.
+type finalizedConsensus struct {
+	core.ConsensusEngine
+	lastFinalizedBlock *core.ExtendedBlock
+}
+
+func (c *finalizedConsensus) GetLastFinalizedBlock() *core.ExtendedBlock {
+	return c.lastFinalizedBlock
+}
+
+func TestPruneState(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	logger = util.GetLoggerForModule("rpc")
+	db := &compactedDB{prunedDB: prunedDB{Database: backend.NewMemDatabase()}}
+	block100 := newQueryTestBlock(100, common.Hash{}, common.Hash{})
+	chain := blockchain.NewChain("querytest", kvstore.NewKVStore(db), block100)
+	consensus := &finalizedConsensus{lastFinalizedBlock: &core.ExtendedBlock{Block: newQueryTestBlock(1000, common.Hash{}, common.Hash{})}}
+	service := &PandoRPCService{
+		ledger: ledger.NewLedger("querytest", db, db, chain, consensus, nil, nil),
+	}
+
+	result := &PruneStateResult{}
+	require.Nil(service.PruneState(&PruneStateArgs{}, result))
+	assert.Equal(uint64(1000), db.compactedHeight)
+	assert.Equal(common.JSONUint64(900), result.OldestStateHeight)
+
+	// Archive nodes keep all the states
+	viper.Set(common.CfgStorageArchiveEnabled, true)
+	defer viper.Set(common.CfgStorageArchiveEnabled, false)
+	db.compactedHeight = 0
+	assert.NotNil(service.PruneState(&PruneStateArgs{}, &PruneStateResult{}))
+	assert.Equal(uint64(0), db.compactedHeight)
+}
@@ -11,9 +11,11 @@ import (
 // ------------------------------- BackupSnapshot -----------------------------------
 
 type BackupSnapshotArgs struct {
-	Config  string `json:"config"`
-	Height  uint64 `json:"height"`
-	Version uint64 `json:"version"`
+	Config     string `json:"config"`
+	Height     uint64 `json:"height"`
+	Version    uint64 `json:"version"`
+	BaseHeight uint64 `json:"base_height"` // version 5 only, exports the delta from the snapshot at this height
+	ChunkSize  uint64 `json:"chunk_size"`  // version 5 only, max size of a chunk in bytes
 }
 
 type BackupSnapshotResult struct {
@@ -43,6 +45,10 @@ func (t *PandoRPCService) BackupSnapshot(args *BackupSnapshotArgs, result *Backu
 		snapshotFile, err := snapshot.ExportSnapshotV3(db, consensus, chain, snapshotDir, args.Height)
 		result.SnapshotFile = snapshotFile
 		return err
+	} else if args.Version == snapshot.ChunkedSnapshotVersion {
+		snapshotDirname, err := snapshot.ExportChunkedSnapshot(db, consensus, chain, snapshotDir, args.Height, args.BaseHeight, args.ChunkSize)
+		result.SnapshotFile = snapshotDirname
+		return err
 	}
 
 	snapshotFile, err := snapshot.ExportSnapshotV4(db, consensus, chain, snapshotDir, args.Height)
@@ -1,10 +1,14 @@
 package rpc
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/big"
 
 	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
 	"github.com/pandotoken/pando/ledger/state"
 	"github.com/pandotoken/pando/ledger/types"
 	"github.com/pandotoken/pando/ledger/vm"
@@ -13,7 +17,9 @@ import (
 // ------------------------------- CallSmartContract -----------------------------------
 
 type CallSmartContractArgs struct {
-	SctxBytes string `json:"sctx_bytes"`
+	SctxBytes string            `json:"sctx_bytes"`
+	Height    common.JSONUint64 `json:"height"`     // optional, call on the state of the finalized block at this height
+	BlockHash string            `json:"block_hash"` // optional, call on the state of this block
 }
 
 type CallSmartContractResult struct {
@@ -25,8 +31,69 @@ type CallSmartContractResult struct {
 
 // CallSmartContract calls the smart contract. However, calling a smart contract does NOT modify
 // the globally consensus state. It can be used for dry run, or for retrieving info from smart contracts
-// without actually spending gas.
+// without actually spending gas. By default the call is executed on the latest state. If the height or
+// the block hash is specified, it is executed on the state right after that block was committed.
 func (t *PandoRPCService) CallSmartContract(args *CallSmartContractArgs, result *CallSmartContractResult) (err error) {
+	var ledgerState *state.StoreView
+	var parentBlock *core.Block
+	historical := args.Height != 0 || args.BlockHash != ""
+	if !historical {
+		ledgerState, err = t.ledger.GetDeliveredSnapshot()
+		if err != nil {
+			return err
+		}
+		parentBlock = t.ledger.State().ParentBlock()
+	} else {
+		block, err := t.findBlockByHeightOrHash(uint64(args.Height), args.BlockHash)
+		if err != nil {
+			return err
+		}
+		ledgerState, err = t.stateAfterBlock(block)
+		if err != nil {
+			return err
+		}
+		parentBlock = block.Block
+	}
+
+	blockHeight := ledgerState.Height() + 1 // the view points to the parent of the current block
+	if blockHeight < common.HeightEnableSmartContract {
+		return fmt.Errorf("Smart contract feature not enabled until block height %v.", common.HeightEnableSmartContract)
+	}
+
+	sctx, err := decodeSmartContractTx(args.SctxBytes)
+	if err != nil {
+		return err
+	}
+
+	vmRet, contractAddr, gasUsed, vmErr := vm.Execute(parentBlock, sctx, ledgerState)
+	if !historical {
+		ledgerState.Save()
+	}
+
+	result.VmReturn = hex.EncodeToString(vmRet)
+	result.ContractAddress = contractAddr
+	result.GasUsed = common.JSONUint64(gasUsed)
+	if vmErr != nil {
+		result.VmError = vmErr.Error()
+	}
+
+	return nil
+}
+
+// ------------------------------- EstimateGas -----------------------------------
+
+type EstimateGasArgs struct {
+	SctxBytes string `json:"sctx_bytes"`
+}
+
+type EstimateGasResult struct {
+	GasEstimate common.JSONUint64 `json:"gas_estimate"`
+}
+
+// EstimateGas returns the lowest gas limit with which the smart contract transaction executes
+// without error on the current state. Unlike the GasUsed returned by CallSmartContract, it accounts
+// for gas refunds and the gas reserved for nested calls. The transaction doesn't need to be signed.
+func (t *PandoRPCService) EstimateGas(args *EstimateGasArgs, result *EstimateGasResult) (err error) {
 	var ledgerState *state.StoreView
 	ledgerState, err = t.ledger.GetDeliveredSnapshot()
 	if err != nil {
@@ -38,30 +105,117 @@ func (t *PandoRPCService) CallSmartContract(args *CallSmartContractArgs, result
 		return fmt.Errorf("Smart contract feature not enabled until block height %v.", common.HeightEnableSmartContract)
 	}
 
-	sctxBytes, err := hex.DecodeString(args.SctxBytes)
+	sctx, err := decodeSmartContractTx(args.SctxBytes)
 	if err != nil {
 		return err
 	}
 
-	tx, err := types.TxFromBytes(sctxBytes)
+	parentBlock := t.ledger.State().ParentBlock()
+	gasEstimate, err := estimateGas(parentBlock, sctx, ledgerState)
 	if err != nil {
-		return fmt.Errorf("Failed to parse SmartContractTx, error: %v", err)
+		return err
 	}
-	sctx, ok := tx.(*types.SmartContractTx)
-	if !ok {
-		return fmt.Errorf("Failed to parse SmartContractTx: %v", args.SctxBytes)
+	result.GasEstimate = common.JSONUint64(gasEstimate)
+
+	return nil
+}
+
+// estimateGas binary searches the lowest gas limit between the intrinsic gas and the maximum
+// gas limit with which the transaction executes without error. Each attempt runs on a copy of the view.
+func estimateGas(parentBlock *core.Block, sctx *types.SmartContractTx, view *state.StoreView) (uint64, error) {
+	createContract := (sctx.To.Address == common.Address{})
+	intrinsicGas, err := vm.IntrinsicGas(sctx.Data, createContract)
+	if err != nil {
+		return 0, err
 	}
 
-	parentBlock := t.ledger.State().ParentBlock()
-	vmRet, contractAddr, gasUsed, vmErr := vm.Execute(parentBlock, sctx, ledgerState)
-	ledgerState.Save()
+	execute := func(gasLimit uint64) (vmRet common.Bytes, vmErr error, err error) {
+		trialView, err := view.Copy()
+		if err != nil {
+			return nil, nil, err
+		}
+		trialTx := *sctx
+		trialTx.GasLimit = gasLimit
+		if trialTx.GasPrice == nil {
+			trialTx.GasPrice = big.NewInt(0)
+		}
+		vmRet, _, _, vmErr = vm.Execute(parentBlock, &trialTx, trialView)
+		return vmRet, vmErr, nil
+	}
 
-	result.VmReturn = hex.EncodeToString(vmRet)
-	result.ContractAddress = contractAddr
-	result.GasUsed = common.JSONUint64(gasUsed)
+	lo := intrinsicGas - 1 // the highest gas limit known to fail
+	hi := types.MaximumTxGasLimitJune2021
+	if lo >= hi {
+		return 0, fmt.Errorf("Intrinsic gas %v exceeds the maximum gas limit %v", intrinsicGas, hi)
+	}
+
+	vmRet, vmErr, err := execute(hi)
+	if err != nil {
+		return 0, err
+	}
 	if vmErr != nil {
-		result.VmError = vmErr.Error()
+		if vm.IsExecutionReverted(vmErr) {
+			if reason, ok := decodeRevertReason(vmRet); ok {
+				return 0, fmt.Errorf("Execution reverted: %v", reason)
+			}
+			return 0, errors.New("Execution reverted")
+		}
+		return 0, fmt.Errorf("Execution fails with the maximum gas limit %v: %v", hi, vmErr)
 	}
 
-	return nil
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		_, vmErr, err := execute(mid)
+		if err != nil {
+			return 0, err
+		}
+		if vmErr != nil {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi, nil
+}
+
+// revertReasonSelector is the function selector of Error(string), which Solidity uses to encode revert reasons.
+var revertReasonSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+
+// decodeRevertReason extracts the message from the ABI encoded Error(string) returned by a reverted call.
+func decodeRevertReason(ret []byte) (string, bool) {
+	if len(ret) < 4+32+32 || !bytes.Equal(ret[:4], revertReasonSelector) {
+		return "", false
+	}
+	data := ret[4:]
+	// Compare without adding, the offset and the length are controlled by the contract
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return "", false
+	}
+	start := offset.Uint64() + 32
+	length := new(big.Int).SetBytes(data[start-32 : start])
+	if !length.IsUint64() || length.Uint64() > uint64(len(data))-start {
+		return "", false
+	}
+	return string(data[start : start+length.Uint64()]), true
+}
+
+// ------------------------------ Utils ------------------------------
+
+// decodeSmartContractTx decodes the hex encoded SmartContractTx.
+func decodeSmartContractTx(sctxHex string) (*types.SmartContractTx, error) {
+	sctxBytes, err := hex.DecodeString(sctxHex)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := types.TxFromBytes(sctxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse SmartContractTx, error: %v", err)
+	}
+	sctx, ok := tx.(*types.SmartContractTx)
+	if !ok {
+		return nil, fmt.Errorf("Failed to parse SmartContractTx: %v", sctxHex)
+	}
+	return sctx, nil
 }
@@ -0,0 +1,137 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/ledger/state"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/ledger/vm"
+	"github.com/pandotoken/pando/store/database/backend"
+)
+
+func TestEstimateGas(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	view := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	deployer := types.MakeAccWithInitBalance("deployer", types.NewCoins(0, 50000000000))
+	view.SetAccount(deployer.Address, &deployer.Account)
+	view.IncrementHeight()
+	view.Save()
+
+	parentBlock := core.NewBlock()
+	parentBlock.ChainID = "testchain"
+	parentBlock.Height = view.Height()
+
+	// ASM:
+	// push 0x1
+	// push 0x0
+	// sstore
+	// stop
+	deployCode, _ := hex.DecodeString("600160005500")
+	sctx := &types.SmartContractTx{
+		From: types.TxInput{
+			Address: deployer.Address,
+			Coins:   types.NewCoins(0, 0),
+		},
+		Data: deployCode,
+	}
+	gasEstimate, err := estimateGas(parentBlock, sctx, view)
+	require.Nil(err)
+
+	sctx.GasPrice = big.NewInt(0)
+	sctx.GasLimit = gasEstimate
+	trialView, _ := view.Copy()
+	_, _, _, vmErr := vm.Execute(parentBlock, sctx, trialView)
+	assert.Nil(vmErr)
+	sctx.GasLimit = gasEstimate - 1
+	trialView, _ = view.Copy()
+	_, _, _, vmErr = vm.Execute(parentBlock, sctx, trialView)
+	assert.NotNil(vmErr)
+
+	// The estimation should not modify the view
+	assert.Equal(uint64(0), view.GetAccount(deployer.Address).Sequence)
+
+	// ASM:
+	// push 0x64
+	// push 0xc
+	// push 0x0
+	// codecopy
+	// push 0x64
+	// push 0x0
+	// revert
+	// followed by the ABI encoding of Error("fail")
+	revertCode, _ := hex.DecodeString("6064600c60003960646000fd" +
+		"08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"6661696c00000000000000000000000000000000000000000000000000000000")
+	sctx = &types.SmartContractTx{
+		From: types.TxInput{
+			Address: deployer.Address,
+			Coins:   types.NewCoins(0, 0),
+		},
+		Data: revertCode,
+	}
+	_, err = estimateGas(parentBlock, sctx, view)
+	require.NotNil(err)
+	assert.True(strings.Contains(err.Error(), "fail"), err.Error())
+}
+
+func TestDecodeRevertReason(t *testing.T) {
+	assert := assert.New(t)
+
+	ret, _ := hex.DecodeString("08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"000000000000000000000000000000000000000000000000000000000000000c" +
+		"696e73756666696369656e740000000000000000000000000000000000000000")
+	reason, ok := decodeRevertReason(ret)
+	assert.True(ok)
+	assert.Equal("insufficient", reason)
+
+	_, ok = decodeRevertReason(ret[:40])
+	assert.False(ok)
+	_, ok = decodeRevertReason(common.Bytes{})
+	assert.False(ok)
+}
+
+func TestDecodeRevertReasonMalformed(t *testing.T) {
+	assert := assert.New(t)
+
+	const (
+		word32   = "0000000000000000000000000000000000000000000000000000000000000020"
+		word12   = "000000000000000000000000000000000000000000000000000000000000000c"
+		maxUint  = "000000000000000000000000000000000000000000000000ffffffffffffffff"
+		maxUintM = "000000000000000000000000000000000000000000000000ffffffffffffffe0"
+		huge     = "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+		reason   = "696e73756666696369656e740000000000000000000000000000000000000000"
+	)
+
+	cases := []struct {
+		name string
+		ret  string
+	}{
+		{"offset wrapping to a small start", maxUint + word12 + reason},
+		{"offset wrapping to zero", maxUintM + word12 + reason},
+		{"offset above uint64", huge + word12 + reason},
+		{"offset past the data", "0000000000000000000000000000000000000000000000000000000000000060" + word12 + reason},
+		{"length wrapping the end", word32 + maxUint + reason},
+		{"length above uint64", word32 + huge + reason},
+		{"length past the data", word32 + "0000000000000000000000000000000000000000000000000000000000000021" + reason},
+	}
+	for _, c := range cases {
+		ret, err := hex.DecodeString("08c379a0" + c.ret)
+		assert.Nil(err)
+		assert.NotPanics(func() {
+			_, ok := decodeRevertReason(ret)
+			assert.False(ok, c.name)
+		}, c.name)
+	}
+}
@@ -0,0 +1,818 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/hexutil"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/state"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/ledger/vm"
+	"github.com/pandotoken/pando/store"
+)
+
+// EthRPCService serves the Ethereum-compatible eth_* JSON-RPC namespace. It is registered
+// under the "eth" service name, and ethMethodMiddleware translates the Ethereum style method
+// names (e.g. "eth_getBalance") into the net/rpc style names (e.g. "eth.GetBalance").
+type EthRPCService struct {
+	service *PandoRPCService
+}
+
+// NewEthRPCService creates a new instance of EthRPCService backed by the given PandoRPCService.
+func NewEthRPCService(service *PandoRPCService) *EthRPCService {
+	return &EthRPCService{
+		service: service,
+	}
+}
+
+const (
+	ethNamespacePrefix = "eth_"
+	ethServiceName     = "eth"
+
+	ethBlockTagLatest   = "latest"
+	ethBlockTagEarliest = "earliest"
+	ethBlockTagPending  = "pending"
+)
+
+// emptyUncleHash is the keccak256 hash of the RLP encoding of an empty list, i.e. an empty uncle list
+var emptyUncleHash = common.HexToHash("0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347")
+
+// ------------------------------- eth_chainId -----------------------------------
+
+type EthChainIdArgs struct{}
+
+func (e *EthRPCService) ChainId(args *EthChainIdArgs, result *string) (err error) {
+	lfb := e.service.consensus.GetLastFinalizedBlock()
+	chainID := types.MapChainID(e.service.consensus.Chain().ChainID, lfb.Height)
+	*result = hexutil.EncodeBig(chainID)
+	return nil
+}
+
+// ------------------------------- eth_blockNumber -----------------------------------
+
+type EthBlockNumberArgs struct{}
+
+func (e *EthRPCService) BlockNumber(args *EthBlockNumberArgs, result *string) (err error) {
+	lfb := e.service.consensus.GetLastFinalizedBlock()
+	*result = hexutil.EncodeUint64(lfb.Height)
+	return nil
+}
+
+// ------------------------------- eth_getBalance -----------------------------------
+
+type EthGetBalanceArgs struct {
+	Address  common.Address
+	BlockTag EthBlockTag
+}
+
+func (a *EthGetBalanceArgs) UnmarshalJSON(data []byte) error {
+	return decodeEthParams(data, 1, &a.Address, &a.BlockTag)
+}
+
+// GetBalance returns the PTX balance of the account, which is the native currency from the
+// perspective of the EVM.
+func (e *EthRPCService) GetBalance(args *EthGetBalanceArgs, result *string) (err error) {
+	view, err := e.stateAt(args.BlockTag)
+	if err != nil {
+		return err
+	}
+	balance := view.GetBalance(args.Address)
+	*result = hexutil.EncodeBig(balance)
+	return nil
+}
+
+// ------------------------------- eth_getTransactionCount -----------------------------------
+
+type EthGetTransactionCountArgs struct {
+	Address  common.Address
+	BlockTag EthBlockTag
+}
+
+func (a *EthGetTransactionCountArgs) UnmarshalJSON(data []byte) error {
+	return decodeEthParams(data, 1, &a.Address, &a.BlockTag)
+}
+
+// GetTransactionCount returns the ETH nonce of the account. ETH tx nonce starts from 0, while
+// Pando tx sequence starts from 1, hence the next ETH nonce equals to the current account sequence.
+func (e *EthRPCService) GetTransactionCount(args *EthGetTransactionCountArgs, result *string) (err error) {
+	view, err := e.stateAt(args.BlockTag)
+	if err != nil {
+		return err
+	}
+	sequence := uint64(0)
+	account := view.GetAccount(args.Address)
+	if account != nil {
+		sequence = account.Sequence
+	}
+	*result = hexutil.EncodeUint64(sequence)
+	return nil
+}
+
+// ------------------------------- eth_call -----------------------------------
+
+type EthCallObject struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      *hexutil.Uint64 `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Data     hexutil.Bytes   `json:"data"`
+	Input    hexutil.Bytes   `json:"input"`
+}
+
+type EthCallArgs struct {
+	Call     EthCallObject
+	BlockTag EthBlockTag
+}
+
+func (a *EthCallArgs) UnmarshalJSON(data []byte) error {
+	return decodeEthParams(data, 1, &a.Call, &a.BlockTag)
+}
+
+func (e *EthRPCService) Call(args *EthCallArgs, result *string) (err error) {
+	view, err := e.stateAt(args.BlockTag)
+	if err != nil {
+		return err
+	}
+	parentBlock, err := e.blockForView(view)
+	if err != nil {
+		return err
+	}
+
+	sctx := args.Call.toSmartContractTx(view.Height() + 1)
+	vmRet, _, _, vmErr := vm.Execute(parentBlock, sctx, view)
+	if vmErr != nil {
+		return vmErr
+	}
+	*result = hexutil.Encode(vmRet)
+	return nil
+}
+
+// ------------------------------- eth_estimateGas -----------------------------------
+
+type EthEstimateGasArgs struct {
+	Call EthCallObject
+}
+
+func (a *EthEstimateGasArgs) UnmarshalJSON(data []byte) error {
+	var blockTag EthBlockTag
+	return decodeEthParams(data, 1, &a.Call, &blockTag)
+}
+
+func (e *EthRPCService) EstimateGas(args *EthEstimateGasArgs, result *string) (err error) {
+	view, err := e.service.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return err
+	}
+	parentBlock := e.service.ledger.State().ParentBlock()
+
+	sctx := args.Call.toSmartContractTx(view.Height() + 1)
+	gasEstimate, err := estimateGas(parentBlock, sctx, view)
+	if err != nil {
+		return err
+	}
+	*result = hexutil.EncodeUint64(gasEstimate)
+	return nil
+}
+
+// ------------------------------- eth_sendRawTransaction -----------------------------------
+
+type EthSendRawTransactionArgs struct {
+	TxBytes string
+}
+
+func (a *EthSendRawTransactionArgs) UnmarshalJSON(data []byte) error {
+	return decodeEthParams(data, 1, &a.TxBytes)
+}
+
+// SendRawTransaction translates the signed ETH transaction into a Pando SmartContractTx, submits it
+// to the mempool, and returns the ETH tx hash.
+func (e *EthRPCService) SendRawTransaction(args *EthSendRawTransactionArgs, result *string) (err error) {
+	broadcastResult := &BroadcastRawTransactionAsyncResult{}
+	err = e.service.BroadcastRawEthTransactionAsync(&BroadcastRawTransactionAsyncArgs{
+		TxBytes: args.TxBytes,
+	}, broadcastResult)
+	if err != nil {
+		return err
+	}
+	*result = broadcastResult.TxHash
+	return nil
+}
+
+// ------------------------------- eth_getTransactionReceipt -----------------------------------
+
+type EthGetTransactionReceiptArgs struct {
+	Hash common.Hash
+}
+
+func (a *EthGetTransactionReceiptArgs) UnmarshalJSON(data []byte) error {
+	return decodeEthParams(data, 1, &a.Hash)
+}
+
+type EthTxReceipt struct {
+	TransactionHash   common.Hash     `json:"transactionHash"`
+	TransactionIndex  hexutil.Uint64  `json:"transactionIndex"`
+	BlockHash         common.Hash     `json:"blockHash"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	From              common.Address  `json:"from"`
+	To                *common.Address `json:"to"`
+	CumulativeGasUsed hexutil.Uint64  `json:"cumulativeGasUsed"`
+	GasUsed           hexutil.Uint64  `json:"gasUsed"`
+	ContractAddress   *common.Address `json:"contractAddress"`
+	Logs              []*EthLog       `json:"logs"`
+	LogsBloom         core.Bloom      `json:"logsBloom"`
+	Status            hexutil.Uint64  `json:"status"`
+}
+
+type EthGetTransactionReceiptResult struct {
+	*EthTxReceipt
+}
+
+func (r EthGetTransactionReceiptResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.EthTxReceipt)
+}
+
+func (e *EthRPCService) GetTransactionReceipt(args *EthGetTransactionReceiptArgs, result *EthGetTransactionReceiptResult) (err error) {
+	raw, block, found := e.service.chain.FindTxByHash(args.Hash)
+	if !found || !block.Status.IsFinalized() {
+		return nil
+	}
+
+	tx, err := types.TxFromBytes(raw)
+	if err != nil {
+		return err
+	}
+	sctx, ok := tx.(*types.SmartContractTx)
+	if !ok {
+		return nil // only smart contract transactions have receipts
+	}
+
+	txHash := crypto.Keccak256Hash(raw)
+	receipt, found := e.service.chain.FindTxReceiptByHash(block.Hash(), txHash)
+	if !found {
+		return nil
+	}
+
+	txIndex := ethTxIndex(block, raw)
+	ethTxHash := ethTxHashOf(block, raw)
+	logs := ethLogsOf(block, receipt, ethTxHash, txIndex)
+
+	r := &EthTxReceipt{
+		TransactionHash:   ethTxHash,
+		TransactionIndex:  hexutil.Uint64(txIndex),
+		BlockHash:         block.Hash(),
+		BlockNumber:       hexutil.Uint64(block.Height),
+		From:              sctx.From.Address,
+		CumulativeGasUsed: hexutil.Uint64(e.cumulativeGasUsed(block, txIndex)),
+		GasUsed:           hexutil.Uint64(receipt.GasUsed),
+		Logs:              logs,
+		LogsBloom:         blockchain.CreateLogsBloom(receipt.Logs),
+	}
+	if (sctx.To.Address == common.Address{}) {
+		contractAddr := receipt.ContractAddress
+		r.ContractAddress = &contractAddr
+	} else {
+		to := sctx.To.Address
+		r.To = &to
+	}
+	if receipt.EvmErr == "" {
+		r.Status = hexutil.Uint64(1)
+	}
+
+	result.EthTxReceipt = r
+	return nil
+}
+
+// ------------------------------- eth_getBlockByNumber / eth_getBlockByHash -----------------------------------
+
+type EthGetBlockByNumberArgs struct {
+	BlockTag      EthBlockTag
+	FullTxObjects bool
+}
+
+func (a *EthGetBlockByNumberArgs) UnmarshalJSON(data []byte) error {
+	return decodeEthParams(data, 1, &a.BlockTag, &a.FullTxObjects)
+}
+
+type EthGetBlockByHashArgs struct {
+	Hash          common.Hash
+	FullTxObjects bool
+}
+
+func (a *EthGetBlockByHashArgs) UnmarshalJSON(data []byte) error {
+	return decodeEthParams(data, 1, &a.Hash, &a.FullTxObjects)
+}
+
+type EthBlock struct {
+	Number           hexutil.Uint64 `json:"number"`
+	Hash             common.Hash    `json:"hash"`
+	ParentHash       common.Hash    `json:"parentHash"`
+	Nonce            hexutil.Bytes  `json:"nonce"`
+	Sha3Uncles       common.Hash    `json:"sha3Uncles"`
+	LogsBloom        core.Bloom     `json:"logsBloom"`
+	TransactionsRoot common.Hash    `json:"transactionsRoot"`
+	StateRoot        common.Hash    `json:"stateRoot"`
+	ReceiptsRoot     common.Hash    `json:"receiptsRoot"`
+	Miner            common.Address `json:"miner"`
+	Difficulty       hexutil.Uint64 `json:"difficulty"`
+	TotalDifficulty  hexutil.Uint64 `json:"totalDifficulty"`
+	ExtraData        hexutil.Bytes  `json:"extraData"`
+	Size             hexutil.Uint64 `json:"size"`
+	GasLimit         hexutil.Uint64 `json:"gasLimit"`
+	GasUsed          hexutil.Uint64 `json:"gasUsed"`
+	Timestamp        hexutil.Uint64 `json:"timestamp"`
+	Transactions     []interface{}  `json:"transactions"`
+	Uncles           []common.Hash  `json:"uncles"`
+}
+
+type EthTx struct {
+	BlockHash        common.Hash     `json:"blockHash"`
+	BlockNumber      hexutil.Uint64  `json:"blockNumber"`
+	From             common.Address  `json:"from"`
+	Gas              hexutil.Uint64  `json:"gas"`
+	GasPrice         *hexutil.Big    `json:"gasPrice"`
+	Hash             common.Hash     `json:"hash"`
+	Input            hexutil.Bytes   `json:"input"`
+	Nonce            hexutil.Uint64  `json:"nonce"`
+	To               *common.Address `json:"to"`
+	TransactionIndex hexutil.Uint64  `json:"transactionIndex"`
+	Value            *hexutil.Big    `json:"value"`
+}
+
+type EthGetBlockResult struct {
+	*EthBlock
+}
+
+func (r EthGetBlockResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.EthBlock)
+}
+
+func (e *EthRPCService) GetBlockByNumber(args *EthGetBlockByNumberArgs, result *EthGetBlockResult) (err error) {
+	block, err := e.blockAt(args.BlockTag)
+	if err != nil || block == nil {
+		return err
+	}
+	result.EthBlock = e.toEthBlock(block, args.FullTxObjects)
+	return nil
+}
+
+func (e *EthRPCService) GetBlockByHash(args *EthGetBlockByHashArgs, result *EthGetBlockResult) (err error) {
+	block, err := e.service.chain.FindBlock(args.Hash)
+	if err == store.ErrKeyNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !block.Status.IsFinalized() {
+		return nil
+	}
+	result.EthBlock = e.toEthBlock(block, args.FullTxObjects)
+	return nil
+}
+
+// ------------------------------- eth_getLogs -----------------------------------
+
+type EthFilterObject struct {
+	FromBlock EthBlockTag       `json:"fromBlock"`
+	ToBlock   EthBlockTag       `json:"toBlock"`
+	BlockHash *common.Hash      `json:"blockHash"`
+	Address   EthAddressList    `json:"address"`
+	Topics    []EthTopicOptions `json:"topics"`
+}
+
+type EthGetLogsArgs struct {
+	Filter EthFilterObject
+}
+
+func (a *EthGetLogsArgs) UnmarshalJSON(data []byte) error {
+	return decodeEthParams(data, 1, &a.Filter)
+}
+
+type EthLog struct {
+	Address          common.Address `json:"address"`
+	Topics           []common.Hash  `json:"topics"`
+	Data             hexutil.Bytes  `json:"data"`
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	BlockHash        common.Hash    `json:"blockHash"`
+	TransactionHash  common.Hash    `json:"transactionHash"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	LogIndex         hexutil.Uint64 `json:"logIndex"`
+	Removed          bool           `json:"removed"`
+}
+
+func (e *EthRPCService) GetLogs(args *EthGetLogsArgs, result *[]*EthLog) (err error) {
+	filter := args.Filter
+	logFilter := &blockchain.LogFilter{
+		Addresses: filter.Address,
+		Topics:    [][]common.Hash{},
+	}
+	for _, options := range filter.Topics {
+		logFilter.Topics = append(logFilter.Topics, options)
+	}
+
+	var logs []*blockchain.IndexedLog
+	if filter.BlockHash != nil {
+		// The blocks which are not finalized are reported as not found, like in eth_getBlockByHash
+		block, err := e.service.chain.FindBlock(*filter.BlockHash)
+		if err != nil && err != store.ErrKeyNotFound {
+			return err
+		}
+		if err != nil || !block.Status.IsFinalized() {
+			return fmt.Errorf("block %v not found", filter.BlockHash.Hex())
+		}
+		if err := logFilter.Validate(); err != nil {
+			return err
+		}
+		logs = e.service.chain.FindLogsInBlock(block, logFilter)
+	} else {
+		fromHeight, err := e.heightOf(filter.FromBlock)
+		if err != nil {
+			return err
+		}
+		toHeight, err := e.heightOf(filter.ToBlock)
+		if err != nil {
+			return err
+		}
+		logs, err = e.service.chain.FindLogs(fromHeight, toHeight, logFilter)
+		if err != nil {
+			return err
+		}
+	}
+
+	*result = []*EthLog{}
+	blocks := make(map[common.Hash]*core.ExtendedBlock)
+	for _, log := range logs {
+		block, ok := blocks[log.BlockHash]
+		if !ok {
+			block, err = e.service.chain.FindBlock(log.BlockHash)
+			if err != nil {
+				return err
+			}
+			blocks[log.BlockHash] = block
+		}
+		*result = append(*result, &EthLog{
+			Address:          log.Address,
+			Topics:           log.Topics,
+			Data:             log.Data,
+			BlockNumber:      hexutil.Uint64(log.BlockHeight),
+			BlockHash:        log.BlockHash,
+			TransactionHash:  ethTxHashOf(block, block.Txs[log.TxIndex]),
+			TransactionIndex: hexutil.Uint64(log.TxIndex),
+			LogIndex:         hexutil.Uint64(log.LogIndex),
+		})
+	}
+
+	return nil
+}
+
+// ------------------------------- Types -----------------------------------
+
+// EthBlockTag is either a hex encoded block height, or one of "latest", "earliest" and "pending".
+type EthBlockTag string
+
+// EthAddressList accepts either a single address or an array of addresses.
+type EthAddressList []common.Address
+
+func (l *EthAddressList) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '[' {
+		var addrs []common.Address
+		if err := json.Unmarshal(data, &addrs); err != nil {
+			return err
+		}
+		*l = addrs
+		return nil
+	}
+	var addr common.Address
+	if err := json.Unmarshal(data, &addr); err != nil {
+		return err
+	}
+	*l = []common.Address{addr}
+	return nil
+}
+
+// EthTopicOptions is a list of alternative topics for one topic position. An empty list matches any topic.
+type EthTopicOptions []common.Hash
+
+func (o *EthTopicOptions) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '[' {
+		var topics []common.Hash
+		if err := json.Unmarshal(data, &topics); err != nil {
+			return err
+		}
+		*o = topics
+		return nil
+	}
+	var topic common.Hash
+	if err := json.Unmarshal(data, &topic); err != nil {
+		return err
+	}
+	*o = []common.Hash{topic}
+	return nil
+}
+
+// ------------------------------- Utils -----------------------------------
+
+// decodeEthParams decodes the positional parameters of an eth_* request into the given fields.
+// The trailing fields beyond the first numRequired ones are optional.
+func decodeEthParams(data []byte, numRequired int, fields ...interface{}) error {
+	var params []json.RawMessage
+	if err := json.Unmarshal(data, &params); err != nil {
+		return fmt.Errorf("positional parameters expected: %v", err)
+	}
+	if len(params) < numRequired {
+		return fmt.Errorf("missing value for required argument %v", len(params))
+	}
+	if len(params) > len(fields) {
+		return fmt.Errorf("too many arguments, want at most %v", len(fields))
+	}
+	for i, param := range params {
+		if bytes.Equal(param, []byte("null")) {
+			continue
+		}
+		if err := json.Unmarshal(param, fields[i]); err != nil {
+			return fmt.Errorf("invalid argument %v: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// heightOf resolves the block tag into a block height.
+func (e *EthRPCService) heightOf(tag EthBlockTag) (uint64, error) {
+	switch strings.ToLower(string(tag)) {
+	case "", ethBlockTagLatest, ethBlockTagPending:
+		return e.service.consensus.GetLastFinalizedBlock().Height, nil
+	case ethBlockTagEarliest:
+		return e.service.chain.Root().Height, nil
+	}
+	height, err := hexutil.DecodeUint64(string(tag))
+	if err != nil {
+		return 0, fmt.Errorf("invalid block tag %v: %v", tag, err)
+	}
+	return height, nil
+}
+
+// blockAt returns the finalized block for the given block tag, or nil if not found.
+func (e *EthRPCService) blockAt(tag EthBlockTag) (*core.ExtendedBlock, error) {
+	height, err := e.heightOf(tag)
+	if err != nil {
+		return nil, err
+	}
+	return e.service.findFinalizedBlockByHeight(height), nil
+}
+
+// stateAt returns the state view right after the block of the given block tag was committed.
+func (e *EthRPCService) stateAt(tag EthBlockTag) (*state.StoreView, error) {
+	switch strings.ToLower(string(tag)) {
+	case "", ethBlockTagLatest:
+		return e.service.ledger.GetFinalizedSnapshot()
+	case ethBlockTagPending:
+		return e.service.ledger.GetDeliveredSnapshot()
+	}
+
+	block, err := e.blockAt(tag)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %v not found", tag)
+	}
+	return e.service.stateAfterBlock(block)
+}
+
+// blockForView returns the block whose state the view points to.
+func (e *EthRPCService) blockForView(view *state.StoreView) (*core.Block, error) {
+	block := e.service.findFinalizedBlockByHeight(view.Height())
+	if block == nil {
+		return e.service.ledger.State().ParentBlock(), nil
+	}
+	return block.Block, nil
+}
+
+func (e *EthRPCService) toEthBlock(block *core.ExtendedBlock, fullTxObjects bool) *EthBlock {
+	eb := &EthBlock{
+		Number:           hexutil.Uint64(block.Height),
+		Hash:             block.Hash(),
+		ParentHash:       block.Parent,
+		Nonce:            make(hexutil.Bytes, 8),
+		Sha3Uncles:       emptyUncleHash,
+		TransactionsRoot: block.TxHash,
+		StateRoot:        block.StateHash,
+		ReceiptsRoot:     block.ReceiptHash,
+		Miner:            block.Proposer,
+		ExtraData:        hexutil.Bytes{},
+		GasLimit:         hexutil.Uint64(types.GetMaxGasLimit(block.Height).Uint64()),
+		Transactions:     []interface{}{},
+		Uncles:           []common.Hash{},
+	}
+	if block.Timestamp != nil {
+		eb.Timestamp = hexutil.Uint64(block.Timestamp.Uint64())
+	}
+
+	gasUsed := uint64(0)
+	for idx, raw := range block.Txs {
+		ethTxHash := ethTxHashOf(block, raw)
+		size := uint64(len(raw))
+		eb.Size += hexutil.Uint64(size)
+
+		receipt, found := e.service.chain.FindTxReceiptByHash(block.Hash(), crypto.Keccak256Hash(raw))
+		if found {
+			gasUsed += receipt.GasUsed
+		}
+
+		if !fullTxObjects {
+			eb.Transactions = append(eb.Transactions, ethTxHash)
+			continue
+		}
+
+		ethTx := &EthTx{
+			BlockHash:        block.Hash(),
+			BlockNumber:      hexutil.Uint64(block.Height),
+			Hash:             ethTxHash,
+			Input:            hexutil.Bytes{},
+			TransactionIndex: hexutil.Uint64(idx),
+			GasPrice:         (*hexutil.Big)(big.NewInt(0)),
+			Value:            (*hexutil.Big)(big.NewInt(0)),
+		}
+		tx, err := types.TxFromBytes(raw)
+		if err == nil {
+			if sctx, ok := tx.(*types.SmartContractTx); ok {
+				ethTx.From = sctx.From.Address
+				ethTx.Gas = hexutil.Uint64(sctx.GasLimit)
+				ethTx.GasPrice = (*hexutil.Big)(sctx.GasPrice)
+				ethTx.Input = hexutil.Bytes(sctx.Data)
+				if sctx.From.Sequence > 0 {
+					ethTx.Nonce = hexutil.Uint64(sctx.From.Sequence - 1)
+				}
+				if (sctx.To.Address != common.Address{}) {
+					to := sctx.To.Address
+					ethTx.To = &to
+				}
+				ethTx.Value = (*hexutil.Big)(sctx.From.Coins.NoNil().PTXWei)
+			}
+		}
+		eb.Transactions = append(eb.Transactions, ethTx)
+	}
+	eb.GasUsed = hexutil.Uint64(gasUsed)
+
+	return eb
+}
+
+func (c *EthCallObject) toSmartContractTx(blockHeight uint64) *types.SmartContractTx {
+	gasLimit := types.GetMaxGasLimit(blockHeight).Uint64()
+	if c.Gas != nil {
+		gasLimit = uint64(*c.Gas)
+	}
+	gasPrice := big.NewInt(0)
+	if c.GasPrice != nil {
+		gasPrice = c.GasPrice.ToInt()
+	}
+	value := big.NewInt(0)
+	if c.Value != nil {
+		value = c.Value.ToInt()
+	}
+	data := c.Input
+	if len(data) == 0 {
+		data = c.Data
+	}
+	to := common.Address{}
+	if c.To != nil {
+		to = *c.To
+	}
+
+	return &types.SmartContractTx{
+		From: types.TxInput{
+			Address: c.From,
+			Coins: types.Coins{
+				PandoWei: big.NewInt(0),
+				PTXWei:   value,
+			},
+		},
+		To: types.TxOutput{
+			Address: to,
+		},
+		GasLimit: gasLimit,
+		GasPrice: gasPrice,
+		Data:     common.Bytes(data),
+	}
+}
+
+// ethTxHashOf returns the ETH tx hash if the transaction was signed as an ETH transaction,
+// otherwise the native Pando tx hash.
+func ethTxHashOf(block *core.ExtendedBlock, raw common.Bytes) common.Hash {
+	ethTxHash, err := blockchain.CalcEthTxHash(block, raw)
+	if err != nil {
+		return crypto.Keccak256Hash(raw)
+	}
+	return ethTxHash
+}
+
+func ethTxIndex(block *core.ExtendedBlock, raw common.Bytes) uint64 {
+	for idx, tx := range block.Txs {
+		if bytes.Equal(tx, raw) {
+			return uint64(idx)
+		}
+	}
+	return 0
+}
+
+// cumulativeGasUsed returns the gas used by the transactions of the block up to, and including,
+// the transaction at the given index.
+func (e *EthRPCService) cumulativeGasUsed(block *core.ExtendedBlock, txIndex uint64) uint64 {
+	gasUsed := uint64(0)
+	for idx := uint64(0); idx <= txIndex && idx < uint64(len(block.Txs)); idx++ {
+		receipt, found := e.service.chain.FindTxReceiptByHash(block.Hash(), crypto.Keccak256Hash(block.Txs[idx]))
+		if found {
+			gasUsed += receipt.GasUsed
+		}
+	}
+	return gasUsed
+}
+
+func ethLogsOf(block *core.ExtendedBlock, receipt *blockchain.TxReceiptEntry, txHash common.Hash, txIndex uint64) []*EthLog {
+	logs := []*EthLog{}
+	for idx, log := range receipt.Logs {
+		logs = append(logs, &EthLog{
+			Address:          log.Address,
+			Topics:           log.Topics,
+			Data:             log.Data,
+			BlockNumber:      hexutil.Uint64(block.Height),
+			BlockHash:        block.Hash(),
+			TransactionHash:  txHash,
+			TransactionIndex: hexutil.Uint64(txIndex),
+			LogIndex:         hexutil.Uint64(idx),
+		})
+	}
+	return logs
+}
+
+// ethMethodMiddleware translates the eth_* method names into the names registered with
+// the net/rpc server, e.g. "eth_getBalance" -> "eth.GetBalance". Batch requests are supported.
+func ethMethodMiddleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if bytes.Contains(body, []byte(ethNamespacePrefix)) {
+			if rewritten, err := rewriteEthMethods(body); err == nil {
+				body = rewritten
+			}
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func rewriteEthMethods(body []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var reqs []map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &reqs); err != nil {
+			return nil, err
+		}
+		for _, req := range reqs {
+			rewriteEthMethod(req)
+		}
+		return json.Marshal(reqs)
+	}
+
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &req); err != nil {
+		return nil, err
+	}
+	rewriteEthMethod(req)
+	return json.Marshal(req)
+}
+
+func rewriteEthMethod(req map[string]json.RawMessage) {
+	var method string
+	if err := json.Unmarshal(req["method"], &method); err != nil {
+		return
+	}
+	if !strings.HasPrefix(method, ethNamespacePrefix) || len(method) == len(ethNamespacePrefix) {
+		return
+	}
+	name := strings.TrimPrefix(method, ethNamespacePrefix)
+	translated := ethServiceName + "." + strings.ToUpper(name[:1]) + name[1:]
+	if raw, err := json.Marshal(translated); err == nil {
+		req["method"] = raw
+	}
+}
@@ -0,0 +1,198 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/hexutil"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/store/database"
+	"github.com/pandotoken/pando/store/database/backend"
+	"github.com/pandotoken/pando/store/kvstore"
+)
+
+func TestRewriteEthMethods(t *testing.T) {
+	assert := assert.New(t)
+
+	body := []byte(`{"jsonrpc":"2.0","method":"eth_getBalance","params":["0x2e833968e5bb786ae419c4d13189fb081cc43bab","latest"],"id":1}`)
+	rewritten, err := rewriteEthMethods(body)
+	assert.Nil(err)
+
+	var req map[string]json.RawMessage
+	assert.Nil(json.Unmarshal(rewritten, &req))
+	assert.Equal(`"eth.GetBalance"`, string(req["method"]))
+	assert.Equal(`1`, string(req["id"]))
+
+	batch := []byte(`[{"jsonrpc":"2.0","method":"eth_chainId","id":1},{"jsonrpc":"2.0","method":"pando.GetVersion","params":[{}],"id":2}]`)
+	rewritten, err = rewriteEthMethods(batch)
+	assert.Nil(err)
+
+	var reqs []map[string]json.RawMessage
+	assert.Nil(json.Unmarshal(rewritten, &reqs))
+	assert.Equal(2, len(reqs))
+	assert.Equal(`"eth.ChainId"`, string(reqs[0]["method"]))
+	assert.Equal(`"pando.GetVersion"`, string(reqs[1]["method"]))
+}
+
+func TestDecodeEthParams(t *testing.T) {
+	assert := assert.New(t)
+
+	args := &EthGetBalanceArgs{}
+	err := json.Unmarshal([]byte(`["0x2e833968e5bb786ae419c4d13189fb081cc43bab","0x10"]`), args)
+	assert.Nil(err)
+	assert.Equal(common.HexToAddress("0x2e833968e5bb786ae419c4d13189fb081cc43bab"), args.Address)
+	assert.Equal(EthBlockTag("0x10"), args.BlockTag)
+
+	args = &EthGetBalanceArgs{}
+	err = json.Unmarshal([]byte(`["0x2e833968e5bb786ae419c4d13189fb081cc43bab"]`), args)
+	assert.Nil(err)
+	assert.Equal(EthBlockTag(""), args.BlockTag)
+
+	err = json.Unmarshal([]byte(`[]`), args)
+	assert.NotNil(err)
+
+	err = json.Unmarshal([]byte(`"0x2e833968e5bb786ae419c4d13189fb081cc43bab"`), args)
+	assert.NotNil(err)
+}
+
+func TestEthFilterObject(t *testing.T) {
+	assert := assert.New(t)
+
+	filter := &EthFilterObject{}
+	err := json.Unmarshal([]byte(`{"address":"0x0000000000000000000000000000000000000002","topics":[null,"0x00000000000000000000000000000000000000000000000000000000000000a1",["0x00000000000000000000000000000000000000000000000000000000000000a2","0x00000000000000000000000000000000000000000000000000000000000000a3"]]}`), filter)
+	assert.Nil(err)
+	assert.Equal(EthAddressList{common.HexToAddress("0x02")}, filter.Address)
+	assert.Equal(3, len(filter.Topics))
+	assert.Equal(0, len(filter.Topics[0]))
+	assert.Equal(EthTopicOptions{common.HexToHash("0xa1")}, filter.Topics[1])
+	assert.Equal(EthTopicOptions{common.HexToHash("0xa2"), common.HexToHash("0xa3")}, filter.Topics[2])
+
+	filter = &EthFilterObject{}
+	err = json.Unmarshal([]byte(`{"address":["0x0000000000000000000000000000000000000001","0x0000000000000000000000000000000000000002"]}`), filter)
+	assert.Nil(err)
+	assert.Equal(2, len(filter.Address))
+}
+
+// failingDB mocks a db whose reads fail for another reason than a missing key.
+type failingDB struct {
+	database.Database
+	failing bool
+}
+
+func (db *failingDB) Get(key []byte) ([]byte, error) {
+	if db.failing {
+		return nil, errors.New("Disk failure")
+	}
+	return db.Database.Get(key)
+}
+
+func TestEthGetBlockByHash(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	db := &failingDB{Database: backend.NewMemDatabase()}
+	block100 := newQueryTestBlock(100, common.Hash{}, common.Hash{})
+	block101 := newQueryTestBlock(101, block100.Hash(), common.Hash{})
+	chain := blockchain.NewChain("querytest", kvstore.NewKVStore(db), block100)
+	_, err := chain.AddBlock(block101)
+	require.Nil(err)
+	eth := NewEthRPCService(&PandoRPCService{chain: chain})
+
+	result := &EthGetBlockResult{}
+	require.Nil(eth.GetBlockByHash(&EthGetBlockByHashArgs{Hash: block100.Hash()}, result))
+	require.NotNil(result.EthBlock)
+	assert.Equal(block100.Hash(), result.Hash)
+
+	// The block is not finalized yet
+	result = &EthGetBlockResult{}
+	require.Nil(eth.GetBlockByHash(&EthGetBlockByHashArgs{Hash: block101.Hash()}, result))
+	assert.Nil(result.EthBlock)
+
+	result = &EthGetBlockResult{}
+	require.Nil(eth.GetBlockByHash(&EthGetBlockByHashArgs{Hash: common.BytesToHash([]byte("missing"))}, result))
+	assert.Nil(result.EthBlock)
+
+	db.failing = true
+	result = &EthGetBlockResult{}
+	assert.NotNil(eth.GetBlockByHash(&EthGetBlockByHashArgs{Hash: block100.Hash()}, result))
+	assert.Nil(result.EthBlock)
+}
+
+func TestGetLogsByBlockHash(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	db := &failingDB{Database: backend.NewMemDatabase()}
+	block100 := newQueryTestBlock(100, common.Hash{}, common.Hash{})
+	block101 := newQueryTestBlock(101, block100.Hash(), common.Hash{})
+	chain := blockchain.NewChain("querytest", kvstore.NewKVStore(db), block100)
+	_, err := chain.AddBlock(block101)
+	require.Nil(err)
+	service := &PandoRPCService{chain: chain}
+	eth := NewEthRPCService(service)
+
+	ethLogs := func(hash common.Hash) error {
+		result := []*EthLog{}
+		return eth.GetLogs(&EthGetLogsArgs{Filter: EthFilterObject{BlockHash: &hash}}, &result)
+	}
+	pandoLogs := func(hash common.Hash) error {
+		return service.GetLogs(&GetLogsArgs{BlockHash: hash}, &GetLogsResult{})
+	}
+
+	assert.Nil(ethLogs(block100.Hash()))
+	assert.Nil(pandoLogs(block100.Hash()))
+
+	// The block is not finalized yet
+	assert.NotNil(ethLogs(block101.Hash()))
+	assert.NotNil(pandoLogs(block101.Hash()))
+
+	db.failing = true
+	assert.NotNil(ethLogs(block100.Hash()))
+	assert.NotNil(pandoLogs(block100.Hash()))
+}
+
+func TestEthGetTransactionReceiptCumulativeGasUsed(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	privKey, _, err := crypto.GenerateKeyPair()
+	require.Nil(err)
+	block := newQueryTestBlock(100, common.Hash{}, common.Hash{})
+	txs := []*types.SmartContractTx{}
+	for i := 0; i < 3; i++ {
+		sctx := &types.SmartContractTx{
+			From:     types.TxInput{Address: privKey.PublicKey().Address(), Coins: types.NewCoins(0, 0), Sequence: uint64(i + 1)},
+			To:       types.TxOutput{Address: common.HexToAddress("0x9F1233798E905E173560071255140b4A8aBd3Ec6")},
+			GasLimit: 100000,
+			GasPrice: big.NewInt(0),
+		}
+		sig, err := privKey.Sign(sctx.SignBytes(block.ChainID))
+		require.Nil(err)
+		sctx.SetSignature(sctx.From.Address, sig)
+		raw, err := types.TxToBytes(sctx)
+		require.Nil(err)
+		block.Txs = append(block.Txs, raw)
+		txs = append(txs, sctx)
+	}
+	chain := blockchain.NewChain("querytest", kvstore.NewKVStore(backend.NewMemDatabase()), block)
+	for i, sctx := range txs {
+		chain.AddTxReceipt(block, sctx, nil, nil, nil, common.Address{}, uint64(100*(i+1)), nil)
+	}
+	eth := NewEthRPCService(&PandoRPCService{chain: chain})
+
+	cumulativeGasUsed := []uint64{100, 300, 600}
+	for i, raw := range block.Txs {
+		result := &EthGetTransactionReceiptResult{}
+		require.Nil(eth.GetTransactionReceipt(&EthGetTransactionReceiptArgs{Hash: crypto.Keccak256Hash(raw)}, result))
+		require.NotNil(result.EthTxReceipt)
+		assert.Equal(hexutil.Uint64(100*(i+1)), result.GasUsed)
+		assert.Equal(hexutil.Uint64(cumulativeGasUsed[i]), result.CumulativeGasUsed)
+	}
+}
@@ -0,0 +1,150 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/metrics"
+)
+
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// HealthCheckResult is the outcome of a single health check.
+type HealthCheckResult struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// HealthReport is the JSON body returned by the /healthz and /readyz endpoints.
+type HealthReport struct {
+	Status string              `json:"status"`
+	Checks []HealthCheckResult `json:"checks"`
+}
+
+type namedHealthcheck struct {
+	name  string
+	check *metrics.StandardHealthcheck
+}
+
+// healthService runs a list of health checks and reports which of them failed.
+type healthService struct {
+	mu     *sync.Mutex // the health checks store their status, so they can't run concurrently
+	checks []namedHealthcheck
+}
+
+func newHealthService() *healthService {
+	return &healthService{
+		mu: &sync.Mutex{},
+	}
+}
+
+func (hs *healthService) addCheck(name string, f func() error) {
+	check := metrics.NewStandardHealthcheck(func(h metrics.Healthcheck) {
+		if err := f(); err != nil {
+			h.Unhealthy(err)
+		} else {
+			h.Healthy()
+		}
+	})
+	hs.checks = append(hs.checks, namedHealthcheck{name: name, check: check})
+}
+
+func (hs *healthService) run() *HealthReport {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	report := &HealthReport{
+		Status: HealthStatusOK,
+		Checks: []HealthCheckResult{},
+	}
+	for _, nc := range hs.checks {
+		nc.check.Check()
+		res := HealthCheckResult{Name: nc.name, Healthy: true}
+		if err := nc.check.Error(); err != nil {
+			res.Healthy = false
+			res.Error = err.Error()
+			report.Status = HealthStatusUnavailable
+		}
+		report.Checks = append(report.Checks, res)
+	}
+	return report
+}
+
+func (hs *healthService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := hs.run()
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != HealthStatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// newLivenessService creates the service behind /healthz. The node is considered alive as long
+// as the consensus main loop keeps running, so a syncing or isolated node is not restarted by the
+// orchestrator, while a stuck one is.
+func newLivenessService(lastMainLoopTime func() time.Time) *healthService {
+	hs := newHealthService()
+	hs.addCheck("consensus_main_loop", func() error {
+		last := lastMainLoopTime()
+		if last.IsZero() {
+			return fmt.Errorf("Consensus main loop is not running")
+		}
+		maxIdle := viper.GetDuration(common.CfgRPCHealthMaxConsensusIdleSecs) * time.Second
+		idle := time.Since(last)
+		if idle > maxIdle {
+			return fmt.Errorf("Consensus main loop has been idle for %v, max idle time is %v",
+				idle.Truncate(time.Second), maxIdle)
+		}
+		return nil
+	})
+	return hs
+}
+
+// newReadinessService creates the service behind /readyz. The node is ready to serve the
+// clients once it has caught up with the network and can query the finalized state.
+func (t *PandoRPCService) newReadinessService() *healthService {
+	hs := newHealthService()
+	hs.addCheck("synced", func() error {
+		if !t.consensus.HasSynced() {
+			return fmt.Errorf("Node is still syncing")
+		}
+		return nil
+	})
+	hs.addCheck("finalized_block_age", func() error {
+		block := t.consensus.GetLastFinalizedBlock()
+		if block == nil || block.Timestamp == nil {
+			return fmt.Errorf("Last finalized block not found")
+		}
+		maxAge := viper.GetDuration(common.CfgRPCHealthMaxFinalizedBlockAgeSecs) * time.Second
+		age := time.Since(time.Unix(block.Timestamp.Int64(), 0))
+		if age > maxAge {
+			return fmt.Errorf("Last finalized block %v is %v old, max age is %v",
+				block.Height, age.Truncate(time.Second), maxAge)
+		}
+		return nil
+	})
+	hs.addCheck("peers", func() error {
+		minNumPeers := viper.GetInt(common.CfgRPCHealthMinNumPeers)
+		if numPeers := len(t.dispatcher.Peers(false)); numPeers < minNumPeers {
+			return fmt.Errorf("Connected to %v peers, at least %v required", numPeers, minNumPeers)
+		}
+		return nil
+	})
+	hs.addCheck("finalized_state", func() error {
+		if _, err := t.ledger.GetFinalizedSnapshot(); err != nil {
+			return fmt.Errorf("Failed to open the finalized state: %v", err)
+		}
+		return nil
+	})
+	return hs
+}
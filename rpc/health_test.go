@@ -0,0 +1,73 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveHealth(t *testing.T, handler http.Handler, path string) (int, *HealthReport) {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	report := &HealthReport{}
+	require.Nil(t, json.Unmarshal(rec.Body.Bytes(), report))
+	return rec.Code, report
+}
+
+func TestHealthService(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	synced := false
+	hs := newHealthService()
+	hs.addCheck("synced", func() error {
+		if !synced {
+			return errors.New("Node is still syncing")
+		}
+		return nil
+	})
+	hs.addCheck("peers", func() error { return nil })
+
+	code, report := serveHealth(t, hs, "/readyz")
+	assert.Equal(http.StatusServiceUnavailable, code)
+	assert.Equal(HealthStatusUnavailable, report.Status)
+	require.Equal(2, len(report.Checks))
+	assert.Equal(HealthCheckResult{Name: "synced", Healthy: false, Error: "Node is still syncing"}, report.Checks[0])
+	assert.Equal(HealthCheckResult{Name: "peers", Healthy: true}, report.Checks[1])
+
+	synced = true
+	code, report = serveHealth(t, hs, "/readyz")
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(HealthStatusOK, report.Status)
+	assert.True(report.Checks[0].Healthy)
+
+}
+
+func TestLivenessService(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	lastMainLoopTime := time.Time{}
+	hs := newLivenessService(func() time.Time { return lastMainLoopTime })
+
+	code, report := serveHealth(t, hs, "/healthz")
+	assert.Equal(http.StatusServiceUnavailable, code)
+	require.Equal(1, len(report.Checks))
+	assert.Equal(HealthCheckResult{Name: "consensus_main_loop", Healthy: false, Error: "Consensus main loop is not running"}, report.Checks[0])
+
+	lastMainLoopTime = time.Now().Add(-time.Hour)
+	code, report = serveHealth(t, hs, "/healthz")
+	assert.Equal(http.StatusServiceUnavailable, code)
+	assert.False(report.Checks[0].Healthy)
+
+	lastMainLoopTime = time.Now()
+	code, report = serveHealth(t, hs, "/healthz")
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(HealthStatusOK, report.Status)
+}
@@ -0,0 +1,39 @@
+package jsonrpc2
+
+import "context"
+
+const deniedMethod = "JSONRPC2.Denied"
+
+// MethodFilter decides whether a request is allowed to call the method.
+// If it returns an error, the method is not called and the error is sent
+// back to the client instead. Return an Error to choose the error code.
+type MethodFilter func(method string) error
+
+type methodFilterContextKey struct{}
+
+// WithMethodFilter returns a copy of ctx with the filter. The filter is
+// applied to every request served by the codecs created with the returned
+// context (see NewServerCodecContext), including each request of a batch.
+//
+// HTTPHandler applies the filter found in the context of the HTTP request.
+func WithMethodFilter(ctx context.Context, filter MethodFilter) context.Context {
+	return context.WithValue(ctx, methodFilterContextKey{}, filter)
+}
+
+// MethodFilterFromContext returns the filter given to WithMethodFilter, or
+// nil if ctx has no filter.
+func MethodFilterFromContext(ctx context.Context) MethodFilter {
+	filter, _ := ctx.Value(methodFilterContextKey{}).(MethodFilter)
+	return filter
+}
+
+// DeniedArg is a param for internal RPC JSONRPC2.Denied.
+type DeniedArg struct {
+	err error
+}
+
+// Denied is an internal RPC method used to reply to the requests rejected
+// by the method filter.
+func (JSONRPC2) Denied(arg DeniedArg, reply *struct{}) error {
+	return arg.err
+}
@@ -0,0 +1,64 @@
+// nolint:errcheck
+package jsonrpc2_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pandotoken/pando/rpc/lib/rpc-codec/jsonrpc2"
+)
+
+func TestMethodFilter(t *testing.T) {
+	handler := jsonrpc2.HTTPHandler(nil)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filter := func(method string) error {
+			if method == "Svc.Sum" && r.Header.Get("X-Deny") != "" {
+				return jsonrpc2.NewError(-32003, "denied")
+			}
+			return nil
+		}
+		handler.ServeHTTP(w, r.WithContext(jsonrpc2.WithMethodFilter(r.Context(), filter)))
+	}))
+	defer ts.Close()
+
+	const jSum = `{"jsonrpc":"2.0","id":0,"method":"Svc.Sum","params":[3,5]}`
+	const jBatch = `[{"jsonrpc":"2.0","id":0,"method":"Svc.Sum","params":[3,5]}]`
+
+	cases := []struct {
+		deny  bool
+		body  string
+		reply string
+	}{
+		{false, jSum, `{"jsonrpc":"2.0","id":0,"result":8}`},
+		{true, jSum, `{"jsonrpc":"2.0","id":0,"error":{"code":-32003,"message":"denied"}}`},
+		{false, jBatch, `[{"jsonrpc":"2.0","id":0,"result":8}]`},
+		{true, jBatch, `[{"jsonrpc":"2.0","id":0,"error":{"code":-32003,"message":"denied"}}]`},
+	}
+
+	for _, c := range cases {
+		req, err := http.NewRequest("POST", ts.URL, strings.NewReader(c.body))
+		if err != nil {
+			t.Fatalf("NewRequest(%s), err = %v", c.body, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.deny {
+			req.Header.Set("X-Deny", "1")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Do(%s), err = %v", c.body, err)
+		}
+		buf, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("ReadAll, err = %v", err)
+		}
+		if reply := string(bytes.TrimRight(buf, "\n")); reply != c.reply {
+			t.Errorf("deny = %v, body = %s:\nexp %s\ngot %s", c.deny, c.body, c.reply, reply)
+		}
+	}
+}
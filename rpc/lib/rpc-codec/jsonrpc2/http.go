@@ -75,6 +75,9 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 
 	ctx := context.WithValue(context.Background(), httpRequestContextKey, req)
+	if filter := MethodFilterFromContext(req.Context()); filter != nil {
+		ctx = WithMethodFilter(ctx, filter)
+	}
 	conn := &httpServerConn{req: req.Body, res: w}
 	_ = h.rpc.ServeRequest(NewServerCodecContext(ctx, conn, h.rpc))
 	if !conn.replied {
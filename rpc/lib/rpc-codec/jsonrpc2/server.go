@@ -28,7 +28,8 @@ type serverCodec struct {
 	ctx      context.Context
 
 	// temporary work space
-	req serverRequest
+	req    serverRequest
+	denied error // error of the request rejected by the method filter
 
 	// JSON-RPC clients can use arbitrary json values as request IDs.
 	// Package rpc expects uint64 request IDs.
@@ -90,8 +91,8 @@ func (r *serverRequest) reset() {
 
 func (r *serverRequest) UnmarshalJSON(raw []byte) error {
 	r.reset()
-	type req *serverRequest
-	if err := json.Unmarshal(raw, req(r)); err != nil {
+	type req serverRequest // drop the UnmarshalJSON method to avoid recursion
+	if err := json.Unmarshal(raw, (*req)(r)); err != nil {
 		return errors.New("bad request")
 	}
 
@@ -170,6 +171,13 @@ func (c *serverCodec) ReadRequestHeader(r *rpc.Request) (err error) {
 	}
 
 	r.ServiceMethod = c.req.Method
+	c.denied = nil
+	if filter := MethodFilterFromContext(c.ctx); filter != nil && c.req.Method != batchMethod {
+		if err := filter(c.req.Method); err != nil {
+			r.ServiceMethod = deniedMethod
+			c.denied = err
+		}
+	}
 
 	// JSON request id can be any JSON value;
 	// RPC package expects uint64.  Translate to
@@ -193,6 +201,10 @@ func (c *serverCodec) ReadRequestBody(x interface{}) error {
 	if x, ok := x.(WithContext); ok {
 		x.SetContext(c.ctx)
 	}
+	if arg, ok := x.(*DeniedArg); ok {
+		arg.err = c.denied
+		return nil
+	}
 	if c.req.Params == nil {
 		return nil
 	}
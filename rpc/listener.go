@@ -0,0 +1,98 @@
+package rpc
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+var errListenerClosed = errors.New("Listener closed")
+
+// limitListener limits the number of connections accepted simultaneously like
+// netutil.LimitListener, except that the limit can be changed while serving.
+type limitListener struct {
+	net.Listener
+
+	mu     *sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+	closed bool
+}
+
+func newLimitListener(l net.Listener, limit int) *limitListener {
+	mu := &sync.Mutex{}
+	return &limitListener{
+		Listener: l,
+		mu:       mu,
+		cond:     sync.NewCond(mu),
+		limit:    limit,
+	}
+}
+
+// SetLimit changes the max number of connections. The connections above a lowered limit are
+// not closed, new connections are accepted once enough of them are closed.
+func (l *limitListener) SetLimit(limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	l.cond.Broadcast()
+}
+
+// acquire blocks until a connection can be accepted, and returns false if the listener is closed.
+func (l *limitListener) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.active >= l.limit && !l.closed {
+		l.cond.Wait()
+	}
+	if l.closed {
+		return false
+	}
+	l.active++
+	return true
+}
+
+func (l *limitListener) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+	l.cond.Broadcast()
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	if !l.acquire() {
+		return nil, errListenerClosed
+	}
+	c, err := l.Listener.Accept()
+	if err != nil {
+		l.release()
+		return nil, err
+	}
+	return &limitListenerConn{Conn: c, releaseOnce: &sync.Once{}, release: l.release}, nil
+}
+
+func (l *limitListener) Close() error {
+	err := l.Listener.Close()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	l.cond.Broadcast()
+	return err
+}
+
+type limitListenerConn struct {
+	net.Conn
+	releaseOnce *sync.Once
+	release     func()
+}
+
+func (c *limitListenerConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(c.release)
+	return err
+}
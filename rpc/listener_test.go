@@ -0,0 +1,89 @@
+package rpc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/spf13/viper"
+	"github.com/pandotoken/pando/common"
+)
+
+func TestLimitListener(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(err)
+	ll := newLimitListener(l, 1)
+	defer ll.Close()
+
+	accepted := make(chan net.Conn, 3)
+	go func() {
+		for {
+			conn, err := ll.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	dial := func() {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		require.Nil(err)
+		defer conn.Close()
+	}
+	waitAccepted := func() net.Conn {
+		select {
+		case conn := <-accepted:
+			return conn
+		case <-time.After(time.Second):
+			return nil
+		}
+	}
+
+	dial()
+	first := waitAccepted()
+	require.NotNil(first)
+
+	// The second connection waits for the limit to be raised
+	dial()
+	assert.Nil(waitAccepted())
+	ll.SetLimit(2)
+	assert.NotNil(waitAccepted())
+
+	// Closing a connection makes room for another one
+	dial()
+	assert.Nil(waitAccepted())
+	first.Close()
+	first.Close()
+	assert.NotNil(waitAccepted())
+}
+
+func TestValidateConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	config := viper.New()
+	assert.Nil(ValidateConfig(config))
+
+	config.Set(common.CfgRPCNamespaces, []string{NamespacePublic, NamespaceDebug})
+	config.Set(common.CfgRPCMaxConnections, 100)
+	assert.Nil(ValidateConfig(config))
+
+	config.Set(common.CfgRPCMaxConnections, 0)
+	assert.NotNil(ValidateConfig(config))
+
+	config.Set(common.CfgRPCMaxConnections, 100)
+	config.Set(common.CfgRPCNamespaces, []string{"private"})
+	assert.NotNil(ValidateConfig(config))
+
+	config.Set(common.CfgRPCNamespaces, []string{NamespacePublic})
+	config.Set(common.CfgRPCHealthMaxConsensusIdleSecs, 30)
+	assert.Nil(ValidateConfig(config))
+
+	config.Set(common.CfgRPCHealthMaxConsensusIdleSecs, 0)
+	assert.NotNil(ValidateConfig(config))
+}
@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/pandotoken/pando/common/metrics"
+)
+
+const unknownRPCMethod = "unknown"
+
+// rpcMetrics records the latency of the RPC calls per method.
+type rpcMetrics struct {
+	methods map[string]bool // the registered methods, to bound the cardinality of the method label
+}
+
+func newRPCMetrics(services map[string]interface{}) *rpcMetrics {
+	rm := &rpcMetrics{
+		methods: make(map[string]bool),
+	}
+	for name, service := range services {
+		typ := reflect.TypeOf(service)
+		for i := 0; i < typ.NumMethod(); i++ {
+			rm.methods[name+"."+typ.Method(i).Name] = true
+		}
+	}
+	return rm
+}
+
+// middleware times the JSON-RPC requests served by the handler. A batch request is recorded
+// once for each method it calls, with the duration of the whole batch.
+func (rm *rpcMetrics) middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		start := time.Now()
+		handler.ServeHTTP(w, r)
+		elapsed := time.Since(start)
+
+		for _, method := range rm.parseMethods(body) {
+			metrics.GetOrRegisterTimer(fmt.Sprintf("rpc/duration{method=%q}", method), nil).Update(elapsed)
+		}
+	})
+}
+
+func (rm *rpcMetrics) parseMethods(body []byte) []string {
+	type request struct {
+		Method string `json:"method"`
+	}
+
+	reqs := []request{}
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &reqs); err != nil {
+			return []string{unknownRPCMethod}
+		}
+	} else {
+		req := request{}
+		if err := json.Unmarshal(trimmed, &req); err != nil {
+			return []string{unknownRPCMethod}
+		}
+		reqs = append(reqs, req)
+	}
+
+	methods := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		if rm.methods[req.Method] {
+			methods = append(methods, req.Method)
+		} else {
+			methods = append(methods, unknownRPCMethod)
+		}
+	}
+	return methods
+}
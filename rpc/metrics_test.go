@@ -0,0 +1,25 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRPCMetricsParseMethods(t *testing.T) {
+	assert := assert.New(t)
+
+	service := &PandoRPCService{}
+	rm := newRPCMetrics(map[string]interface{}{
+		"pando":        service,
+		ethServiceName: NewEthRPCService(service),
+	})
+
+	assert.Equal([]string{"pando.GetStatus"}, rm.parseMethods([]byte(`{"jsonrpc":"2.0","method":"pando.GetStatus","params":[{}],"id":1}`)))
+	assert.Equal([]string{"eth.GetBalance", "pando.GetAccount"}, rm.parseMethods(
+		[]byte(`[{"method":"eth.GetBalance","id":1},{"method":"pando.GetAccount","id":2}]`)))
+
+	// Unregistered methods are not recorded by name, to bound the number of metrics
+	assert.Equal([]string{unknownRPCMethod}, rm.parseMethods([]byte(`{"method":"pando.NoSuchMethod","id":1}`)))
+	assert.Equal([]string{unknownRPCMethod}, rm.parseMethods([]byte(`not json`)))
+}
@@ -0,0 +1,313 @@
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/rpc/lib/rpc-codec/jsonrpc2"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// NamespacePublic contains the methods to query the chain and to submit transactions.
+	NamespacePublic = "public"
+	// NamespaceAdmin contains the methods to operate the node, e.g. the backups written to the node's disk.
+	NamespaceAdmin = "admin"
+	// NamespaceDebug contains the methods which are expensive to serve, e.g. the transaction tracers.
+	NamespaceDebug = "debug"
+)
+
+// rpcMethodNamespaces maps the methods outside of the public namespace to their namespaces.
+var rpcMethodNamespaces = map[string]string{
+	"pando.BackupSnapshot":        NamespaceAdmin,
+	"pando.BackupChain":           NamespaceAdmin,
+	"pando.BackupChainCorrection": NamespaceAdmin,
+	"pando.AddPeer":               NamespaceAdmin,
+	"pando.RemovePeer":            NamespaceAdmin,
+	"pando.SetLogLevel":           NamespaceAdmin,
+	"pando.PruneState":            NamespaceAdmin,
+	"pando.FlushMempool":          NamespaceAdmin,
+	"pando.DumpConsensusState":    NamespaceAdmin,
+	"pando.ReloadConfig":          NamespaceAdmin,
+	"pando.TraceTransaction":      NamespaceDebug,
+	"pando.TraceCall":             NamespaceDebug,
+}
+
+// MethodNamespace returns the namespace of the RPC method.
+func MethodNamespace(method string) string {
+	if namespace, ok := rpcMethodNamespaces[method]; ok {
+		return namespace
+	}
+	return NamespacePublic
+}
+
+const (
+	rpcErrCodeMethodNotAvailable = -32601
+	rpcErrCodeUnauthorized       = -32003
+	rpcErrCodeRateLimited        = -32005
+)
+
+var errInvalidCredentials = errors.New("Invalid credentials")
+
+// rpcClient identifies the client of a request, for the authorization and the rate limit.
+type rpcClient struct {
+	id            string // the key or JWT subject of an authenticated client, otherwise its IP
+	authenticated bool
+}
+
+// rpcPolicy decides which methods the clients of an RPC listener can call, and how often.
+type rpcPolicy struct {
+	namespaces      map[string]bool // the namespaces served by the listener
+	authNamespaces  map[string]bool // the namespaces that require an authenticated client
+	apiKeys         [][]byte
+	jwtSecret       []byte
+	limiter         *rateLimiter // nil if the rate limit is disabled
+	allowAllOrigins bool
+	allowedOrigins  map[string]bool
+	trusted         bool // the clients are trusted without credential, e.g. on the admin socket
+}
+
+// newRPCPolicy creates the policy of a listener serving the given namespaces. The authentication,
+// rate limit and CORS settings are shared by all the listeners.
+func newRPCPolicy(namespaces []string) *rpcPolicy {
+	p := &rpcPolicy{
+		namespaces:     toSet(namespaces),
+		authNamespaces: toSet(viper.GetStringSlice(common.CfgRPCAuthNamespaces)),
+		jwtSecret:      []byte(viper.GetString(common.CfgRPCAuthJWTSecret)),
+		allowedOrigins: make(map[string]bool),
+	}
+	for _, key := range viper.GetStringSlice(common.CfgRPCAuthAPIKeys) {
+		if key != "" {
+			p.apiKeys = append(p.apiKeys, []byte(key))
+		}
+	}
+	if rate := viper.GetFloat64(common.CfgRPCRateLimitRequestsPerSecond); rate > 0 {
+		p.limiter = newRateLimiter(rate, viper.GetInt(common.CfgRPCRateLimitBurst))
+	}
+	for _, origin := range viper.GetStringSlice(common.CfgRPCCORSAllowedOrigins) {
+		if origin == "*" {
+			p.allowAllOrigins = true
+		}
+		p.allowedOrigins[origin] = true
+	}
+	return p
+}
+
+// newAdminRPCPolicy creates the policy of the admin socket. It serves all the namespaces to
+// every client without rate limit, since the access is protected by the file permissions.
+func newAdminRPCPolicy() *rpcPolicy {
+	return &rpcPolicy{
+		namespaces:     toSet([]string{NamespacePublic, NamespaceAdmin, NamespaceDebug}),
+		authNamespaces: make(map[string]bool),
+		allowedOrigins: make(map[string]bool),
+		trusted:        true,
+	}
+}
+
+// ValidateConfig checks the RPC settings of a reloaded config file.
+func ValidateConfig(config *viper.Viper) error {
+	if config.IsSet(common.CfgRPCNamespaces) {
+		for namespace := range toSet(config.GetStringSlice(common.CfgRPCNamespaces)) {
+			if namespace != NamespacePublic && namespace != NamespaceAdmin && namespace != NamespaceDebug {
+				return fmt.Errorf("Unknown RPC namespace: %v", namespace)
+			}
+		}
+	}
+	if config.IsSet(common.CfgRPCMaxConnections) && config.GetInt(common.CfgRPCMaxConnections) <= 0 {
+		return fmt.Errorf("Invalid %v: %v", common.CfgRPCMaxConnections, config.Get(common.CfgRPCMaxConnections))
+	}
+	if config.IsSet(common.CfgRPCHealthMaxConsensusIdleSecs) && config.GetInt(common.CfgRPCHealthMaxConsensusIdleSecs) <= 0 {
+		return fmt.Errorf("Invalid %v: %v", common.CfgRPCHealthMaxConsensusIdleSecs, config.Get(common.CfgRPCHealthMaxConsensusIdleSecs))
+	}
+	return nil
+}
+
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, item := range items {
+		set[strings.TrimSpace(item)] = true
+	}
+	return set
+}
+
+// authenticate identifies the client of the request. The credential, either an API key or a
+// JWT, is read from the "Authorization: Bearer" header, the "X-Api-Key" header, or the "token"
+// query parameter for the WebSocket clients which cannot set headers. Requests without
+// credential are identified by their IP.
+func (p *rpcPolicy) authenticate(r *http.Request) (rpcClient, error) {
+	if p.trusted {
+		return rpcClient{id: "admin", authenticated: true}, nil
+	}
+
+	token := r.Header.Get("X-Api-Key")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		return rpcClient{id: "ip:" + host}, nil
+	}
+
+	for _, key := range p.apiKeys {
+		if subtle.ConstantTimeCompare(key, []byte(token)) == 1 {
+			hash := sha256.Sum256(key)
+			return rpcClient{id: "key:" + hex.EncodeToString(hash[:8]), authenticated: true}, nil
+		}
+	}
+	if len(p.jwtSecret) > 0 && strings.Count(token, ".") == 2 {
+		subject, err := verifyJWT(token, p.jwtSecret, time.Now())
+		if err != nil {
+			return rpcClient{}, err
+		}
+		return rpcClient{id: "jwt:" + subject, authenticated: true}, nil
+	}
+	return rpcClient{}, errInvalidCredentials
+}
+
+// methodFilter returns the filter applied to the methods called by the client.
+func (p *rpcPolicy) methodFilter(client rpcClient) jsonrpc2.MethodFilter {
+	return func(method string) error {
+		namespace := MethodNamespace(method)
+		if !p.namespaces[namespace] {
+			return jsonrpc2.NewError(rpcErrCodeMethodNotAvailable,
+				fmt.Sprintf("Method %v of namespace %v is not available", method, namespace))
+		}
+		if p.authNamespaces[namespace] && !client.authenticated {
+			return jsonrpc2.NewError(rpcErrCodeUnauthorized,
+				fmt.Sprintf("Method %v of namespace %v requires authentication", method, namespace))
+		}
+		if p.limiter != nil && !p.limiter.allow(client.id) {
+			return jsonrpc2.NewError(rpcErrCodeRateLimited, "Rate limit exceeded")
+		}
+		return nil
+	}
+}
+
+// middleware authenticates the client, and attaches the method filter of the client to the
+// request, which is applied by the JSON-RPC codec to each method called.
+func (p *rpcPolicy) middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, err := p.authenticate(r)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":null,"error":%v}`, jsonrpc2.NewError(rpcErrCodeUnauthorized, err.Error()))
+			return
+		}
+		ctx := jsonrpc2.WithMethodFilter(r.Context(), p.methodFilter(client))
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (p *rpcPolicy) isOriginAllowed(origin string) bool {
+	return p.allowAllOrigins || p.allowedOrigins[origin]
+}
+
+// corsMiddleware allows the cross-origin requests from the configured origins.
+func (p *rpcPolicy) corsMiddleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if p.allowAllOrigins {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else if origin != "" && p.allowedOrigins[origin] {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Api-Key")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// webSocketHandshake rejects the WebSocket connections opened by the pages of the origins not
+// allowed. Clients other than browsers do not send an origin, and are accepted.
+func (p *rpcPolicy) webSocketHandshake(config *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin != "" && !p.isOriginAllowed(origin) {
+		return fmt.Errorf("Origin %v is not allowed", origin)
+	}
+	return nil
+}
+
+// verifyJWT verifies the HS256 signature and the "exp" and "nbf" claims of the token, and
+// returns its "sub" claim.
+func verifyJWT(token string, secret []byte, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errInvalidCredentials
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return "", err
+	}
+	if header.Alg != "HS256" {
+		return "", fmt.Errorf("Unsupported JWT algorithm: %v", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errInvalidCredentials
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", errInvalidCredentials
+	}
+
+	var claims struct {
+		Subject   string `json:"sub"`
+		ExpiresAt *int64 `json:"exp"`
+		NotBefore *int64 `json:"nbf"`
+	}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return "", err
+	}
+	if claims.ExpiresAt != nil && now.Unix() >= *claims.ExpiresAt {
+		return "", errors.New("JWT expired")
+	}
+	if claims.NotBefore != nil && now.Unix() < *claims.NotBefore {
+		return "", errors.New("JWT not valid yet")
+	}
+	if claims.Subject == "" {
+		hash := sha256.Sum256([]byte(token))
+		claims.Subject = hex.EncodeToString(hash[:8])
+	}
+	return claims.Subject, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return errInvalidCredentials
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return errInvalidCredentials
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/spf13/viper"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/rpc/lib/rpc-codec/jsonrpc2"
+)
+
+func newTestRPCPolicy(namespaces []string, settings map[string]interface{}) *rpcPolicy {
+	previous := make(map[string]interface{})
+	for key, value := range settings {
+		previous[key] = viper.Get(key)
+		viper.Set(key, value)
+	}
+	defer func() {
+		for key, value := range previous {
+			viper.Set(key, value)
+		}
+	}()
+	return newRPCPolicy(namespaces)
+}
+
+func signTestJWT(secret string, claims map[string]interface{}) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	rawClaims, _ := json.Marshal(claims)
+	payload := base64.RawURLEncoding.EncodeToString(rawClaims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func rpcErrorCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return err.(*jsonrpc2.Error).Code
+}
+
+func TestRPCPolicyMethodFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(NamespacePublic, MethodNamespace("pando.GetStatus"))
+	assert.Equal(NamespacePublic, MethodNamespace("eth.GetBalance"))
+	assert.Equal(NamespaceAdmin, MethodNamespace("pando.BackupChain"))
+	assert.Equal(NamespaceDebug, MethodNamespace("pando.TraceCall"))
+
+	policy := newTestRPCPolicy([]string{NamespacePublic, NamespaceAdmin}, map[string]interface{}{
+		common.CfgRPCAuthNamespaces: []string{NamespaceAdmin},
+	})
+	anonymous := policy.methodFilter(rpcClient{id: "ip:127.0.0.1"})
+	authenticated := policy.methodFilter(rpcClient{id: "key:01", authenticated: true})
+
+	assert.Nil(anonymous("pando.GetStatus"))
+	assert.Equal(rpcErrCodeUnauthorized, rpcErrorCode(anonymous("pando.BackupSnapshot")))
+	assert.Nil(authenticated("pando.BackupSnapshot"))
+
+	// The debug namespace is not served by the listener, even to the authenticated clients
+	assert.Equal(rpcErrCodeMethodNotAvailable, rpcErrorCode(anonymous("pando.TraceTransaction")))
+	assert.Equal(rpcErrCodeMethodNotAvailable, rpcErrorCode(authenticated("pando.TraceTransaction")))
+}
+
+func TestRPCPolicyDefaultNamespaces(t *testing.T) {
+	assert := assert.New(t)
+
+	// The admin and debug methods are only served on the admin socket by default
+	policy := newRPCPolicy(viper.GetStringSlice(common.CfgRPCNamespaces))
+	anonymous := policy.methodFilter(rpcClient{id: "ip:10.0.0.1"})
+	authenticated := policy.methodFilter(rpcClient{id: "key:01", authenticated: true})
+	assert.Nil(anonymous("pando.GetStatus"))
+	assert.Equal(rpcErrCodeMethodNotAvailable, rpcErrorCode(anonymous("pando.FlushMempool")))
+	assert.Equal(rpcErrCodeMethodNotAvailable, rpcErrorCode(authenticated("pando.FlushMempool")))
+	assert.Equal(rpcErrCodeMethodNotAvailable, rpcErrorCode(anonymous("pando.TraceCall")))
+
+	admin := newAdminRPCPolicy().methodFilter(rpcClient{id: "socket"})
+	assert.Nil(admin("pando.FlushMempool"))
+}
+
+func TestRPCPolicyAuthenticate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	policy := newTestRPCPolicy([]string{NamespacePublic}, map[string]interface{}{
+		common.CfgRPCAuthAPIKeys:   []string{"key1", "key2"},
+		common.CfgRPCAuthJWTSecret: "secret",
+	})
+
+	newRequest := func(header, value string) *http.Request {
+		r := httptest.NewRequest("POST", "/rpc", nil)
+		r.RemoteAddr = "10.0.0.1:12345"
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		return r
+	}
+
+	client, err := policy.authenticate(newRequest("", ""))
+	require.Nil(err)
+	assert.Equal(rpcClient{id: "ip:10.0.0.1"}, client)
+
+	client, err = policy.authenticate(newRequest("X-Api-Key", "key2"))
+	require.Nil(err)
+	assert.True(client.authenticated)
+	assert.True(strings.HasPrefix(client.id, "key:"))
+
+	client, err = policy.authenticate(newRequest("Authorization", "Bearer key1"))
+	require.Nil(err)
+	assert.True(client.authenticated)
+
+	_, err = policy.authenticate(newRequest("X-Api-Key", "key3"))
+	assert.NotNil(err)
+
+	token := signTestJWT("secret", map[string]interface{}{"sub": "explorer", "exp": time.Now().Add(time.Hour).Unix()})
+	client, err = policy.authenticate(newRequest("Authorization", "Bearer "+token))
+	require.Nil(err)
+	assert.Equal(rpcClient{id: "jwt:explorer", authenticated: true}, client)
+
+	// WebSocket clients can pass the token as a query parameter
+	r := httptest.NewRequest("GET", "/ws?token="+token, nil)
+	client, err = policy.authenticate(r)
+	require.Nil(err)
+	assert.True(client.authenticated)
+
+	expired := signTestJWT("secret", map[string]interface{}{"sub": "explorer", "exp": time.Now().Add(-time.Hour).Unix()})
+	_, err = policy.authenticate(newRequest("Authorization", "Bearer "+expired))
+	assert.NotNil(err)
+
+	forged := signTestJWT("another secret", map[string]interface{}{"sub": "explorer"})
+	_, err = policy.authenticate(newRequest("Authorization", "Bearer "+forged))
+	assert.NotNil(err)
+}
+
+func TestRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1000, 0)
+	rl := newRateLimiter(2, 3)
+	rl.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		assert.True(rl.allow("a"))
+	}
+	assert.False(rl.allow("a"))
+	assert.True(rl.allow("b")) // each client has its own bucket
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(rl.allow("a"))
+	assert.False(rl.allow("a"))
+
+	// The bucket does not refill beyond the burst
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.True(rl.allow("a"))
+	}
+	assert.False(rl.allow("a"))
+
+	rl.prune(now)
+	assert.Equal(1, len(rl.buckets))
+}
+
+func TestRPCPolicyHTTP(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	policy := newTestRPCPolicy([]string{NamespacePublic}, map[string]interface{}{
+		common.CfgRPCAuthAPIKeys:                []string{"key1"},
+		common.CfgRPCRateLimitRequestsPerSecond: 1,
+		common.CfgRPCRateLimitBurst:             2,
+		common.CfgRPCCORSAllowedOrigins:         []string{"https://explorer.example"},
+	})
+
+	s := rpc.NewServer()
+	s.RegisterName("pando", &PandoRPCService{})
+	server := httptest.NewServer(policy.corsMiddleware(policy.middleware(jsonrpc2.HTTPHandler(s))))
+	defer server.Close()
+
+	call := func(method, apiKey, origin string) (*http.Response, map[string]json.RawMessage) {
+		body := `{"jsonrpc":"2.0","method":"` + method + `","params":[{}],"id":1}`
+		req, err := http.NewRequest("POST", server.URL, strings.NewReader(body))
+		require.Nil(err)
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("X-Api-Key", apiKey)
+		}
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(err)
+		defer resp.Body.Close()
+		var res map[string]json.RawMessage
+		require.Nil(json.NewDecoder(resp.Body).Decode(&res))
+		return resp, res
+	}
+
+	resp, res := call("pando.GetVersion", "", "https://explorer.example")
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.NotNil(res["result"])
+	assert.Equal("https://explorer.example", resp.Header.Get("Access-Control-Allow-Origin"))
+
+	resp, _ = call("pando.GetVersion", "", "https://elsewhere.example")
+	assert.Equal("", resp.Header.Get("Access-Control-Allow-Origin"))
+
+	// The burst of the client IP is used up
+	_, res = call("pando.GetVersion", "", "")
+	assert.Contains(string(res["error"]), "-32005")
+
+	// Admin methods are not served
+	_, res = call("pando.BackupChain", "key1", "")
+	assert.Contains(string(res["error"]), "-32601")
+
+	resp, _ = call("pando.GetVersion", "wrong key", "")
+	assert.Equal(http.StatusUnauthorized, resp.StatusCode)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/big"
 	"math/rand"
 	"strings"
@@ -16,11 +17,16 @@ import (
 	"github.com/pandotoken/pando/crypto/bls"
 
 	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/hexutil"
 	"github.com/pandotoken/pando/core"
 	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger"
+	"github.com/pandotoken/pando/ledger/proof"
 	"github.com/pandotoken/pando/ledger/state"
 	"github.com/pandotoken/pando/ledger/types"
 	"github.com/pandotoken/pando/mempool"
+	"github.com/pandotoken/pando/rpc/lib/rpc-codec/jsonrpc2"
+	"github.com/pandotoken/pando/store"
 	"github.com/pandotoken/pando/version"
 )
 
@@ -89,18 +95,11 @@ func (t *PandoRPCService) GetAccount(args *GetAccountArgs, result *GetAccountRes
 			return nil
 		}
 
-		deliveredView, err := t.ledger.GetDeliveredSnapshot()
-		if err != nil {
-			return err
-		}
-		db := deliveredView.GetDB()
-
 		for _, b := range blocks {
 			if b.Status.IsFinalized() {
-				stateRoot := b.StateHash
-				ledgerState := state.NewStoreView(height, stateRoot, db)
-				if ledgerState == nil { // might have been pruned
-					return fmt.Errorf("the account details for height %v is not available, it might have been pruned", height)
+				ledgerState, err := t.stateAfterBlock(b)
+				if err != nil {
+					return err
 				}
 				account := ledgerState.GetAccount(address)
 				if account == nil {
@@ -513,6 +512,8 @@ type GetStatusResult struct {
 	GenesisBlockHash           common.Hash       `json:"genesis_block_hash"`
 	SnapshotBlockHeight        common.JSONUint64 `json:"snapshot_block_height"`
 	SnapshotBlockHash          common.Hash       `json:"snapshot_block_hash"`
+	OldestStateHeight          common.JSONUint64 `json:"oldest_state_height"`
+	ArchiveMode                bool              `json:"archive_mode"`
 }
 
 func (t *PandoRPCService) GetStatus(args *GetStatusArgs, result *GetStatusResult) (err error) {
@@ -561,6 +562,8 @@ func (t *PandoRPCService) GetStatus(args *GetStatusArgs, result *GetStatusResult
 	result.GenesisBlockHash = genesisHash
 	result.SnapshotBlockHeight = common.JSONUint64(t.chain.Root().Block.BlockHeader.Height)
 	result.SnapshotBlockHash = t.chain.Root().Block.BlockHeader.Hash()
+	result.OldestStateHeight = common.JSONUint64(t.ledger.OldestStateHeight())
+	result.ArchiveMode = viper.GetBool(common.CfgStorageArchiveEnabled)
 
 	return
 }
@@ -625,22 +628,15 @@ type BlockHashVcpPair struct {
 }
 
 func (t *PandoRPCService) GetVcpByHeight(args *GetVcpByHeightArgs, result *GetVcpResult) (err error) {
-	deliveredView, err := t.ledger.GetDeliveredSnapshot()
-	if err != nil {
-		return err
-	}
-
-	db := deliveredView.GetDB()
 	height := uint64(args.Height)
 
 	blockHashVcpPairs := []BlockHashVcpPair{}
 	blocks := t.chain.FindBlocksByHeight(height)
 	for _, b := range blocks {
 		blockHash := b.Hash()
-		stateRoot := b.StateHash
-		blockStoreView := state.NewStoreView(height, stateRoot, db)
-		if blockStoreView == nil { // might have been pruned
-			return fmt.Errorf("the VCP for height %v does not exists, it might have been pruned", height)
+		blockStoreView, err := t.stateAfterBlock(b)
+		if err != nil {
+			return err
 		}
 		vcp := blockStoreView.GetValidatorCandidatePool()
 		hl := blockStoreView.GetStakeTransactionHeightList()
@@ -672,22 +668,15 @@ type BlockHashGcpPair struct {
 }
 
 func (t *PandoRPCService) GetGcpByHeight(args *GetGcpByHeightArgs, result *GetGcpResult) (err error) {
-	deliveredView, err := t.ledger.GetDeliveredSnapshot()
-	if err != nil {
-		return err
-	}
-
-	db := deliveredView.GetDB()
 	height := uint64(args.Height)
 
 	blockHashGcpPairs := []BlockHashGcpPair{}
 	blocks := t.chain.FindBlocksByHeight(height)
 	for _, b := range blocks {
 		blockHash := b.Hash()
-		stateRoot := b.StateHash
-		blockStoreView := state.NewStoreView(height, stateRoot, db)
-		if blockStoreView == nil { // might have been pruned
-			return fmt.Errorf("the GCP for height %v does not exists, it might have been pruned", height)
+		blockStoreView, err := t.stateAfterBlock(b)
+		if err != nil {
+			return err
 		}
 		gcp := blockStoreView.GetGuardianCandidatePool()
 		blockHashGcpPairs = append(blockHashGcpPairs, BlockHashGcpPair{
@@ -749,22 +738,15 @@ type BlockHashEenpPair struct {
 }
 
 func (t *PandoRPCService) GetEenpByHeight(args *GetEenpByHeightArgs, result *GetEenpResult) (err error) {
-	deliveredView, err := t.ledger.GetDeliveredSnapshot()
-	if err != nil {
-		return err
-	}
-
-	db := deliveredView.GetDB()
 	height := uint64(args.Height)
 
 	blockHashEenpPairs := []BlockHashEenpPair{}
 	blocks := t.chain.FindBlocksByHeight(height)
 	for _, b := range blocks {
 		blockHash := b.Hash()
-		stateRoot := b.StateHash
-		blockStoreView := state.NewStoreView(height, stateRoot, db)
-		if blockStoreView == nil { // might have been pruned
-			return fmt.Errorf("the EENP for height %v does not exists, it might have been pruned", height)
+		blockStoreView, err := t.stateAfterBlock(b)
+		if err != nil {
+			return err
 		}
 		eenp := state.NewEliteEdgeNodePool(blockStoreView, true)
 		eens := eenp.GetAll(false)
@@ -797,12 +779,6 @@ type BlockHashStakeRewardDistributionRuleSetPair struct {
 
 func (t *PandoRPCService) GetStakeRewardDistributionByHeight(
 	args *GetStakeRewardDistributionRuleSetByHeightArgs, result *GetStakeRewardDistributionRuleSetResult) (err error) {
-	deliveredView, err := t.ledger.GetDeliveredSnapshot()
-	if err != nil {
-		return err
-	}
-
-	db := deliveredView.GetDB()
 	height := uint64(args.Height)
 	addressStr := args.Address
 
@@ -810,10 +786,9 @@ func (t *PandoRPCService) GetStakeRewardDistributionByHeight(
 	blocks := t.chain.FindBlocksByHeight(height)
 	for _, b := range blocks {
 		blockHash := b.Hash()
-		stateRoot := b.StateHash
-		blockStoreView := state.NewStoreView(height, stateRoot, db)
-		if blockStoreView == nil { // might have been pruned
-			return fmt.Errorf("the EENP for height %v does not exists, it might have been pruned", height)
+		blockStoreView, err := t.stateAfterBlock(b)
+		if err != nil {
+			return err
 		}
 		srdrs := state.NewStakeRewardDistributionRuleSet(blockStoreView)
 
@@ -940,18 +915,11 @@ func (t *PandoRPCService) GetCode(args *GetCodeArgs, result *GetCodeResult) (err
 			return nil
 		}
 
-		deliveredView, err := t.ledger.GetDeliveredSnapshot()
-		if err != nil {
-			return err
-		}
-		db := deliveredView.GetDB()
-
 		for _, b := range blocks {
 			if b.Status.IsFinalized() {
-				stateRoot := b.StateHash
-				ledgerState := state.NewStoreView(height, stateRoot, db)
-				if ledgerState == nil { // might have been pruned
-					return fmt.Errorf("the account details for height %v is not available, it might have been pruned", height)
+				ledgerState, err := t.stateAfterBlock(b)
+				if err != nil {
+					return err
 				}
 				codeBytes := ledgerState.GetCode(address)
 				result.Code = hex.EncodeToString(codeBytes)
@@ -970,6 +938,7 @@ type GetStorageAtArgs struct {
 	Address         string            `json:"address"`
 	StoragePosition string            `json:"storage_positon"`
 	Height          common.JSONUint64 `json:"height"`
+	BlockHash       string            `json:"block_hash"` // optional, query the state of this block instead of the height
 }
 
 type GetStorageAtResult struct {
@@ -984,7 +953,18 @@ func (t *PandoRPCService) GetStorageAt(args *GetStorageAtArgs, result *GetStorag
 	key := common.HexToHash(args.StoragePosition)
 	height := uint64(args.Height)
 
-	if height == 0 { // get the latest
+	if args.BlockHash != "" {
+		block, err := t.findBlockByHeightOrHash(height, args.BlockHash)
+		if err != nil {
+			return err
+		}
+		ledgerState, err := t.stateAfterBlock(block)
+		if err != nil {
+			return err
+		}
+		value := ledgerState.GetState(address, key)
+		result.Value = hex.EncodeToString(value.Bytes())
+	} else if height == 0 { // get the latest
 		var ledgerState *state.StoreView
 		ledgerState, err = t.ledger.GetFinalizedSnapshot()
 		if err != nil {
@@ -999,18 +979,11 @@ func (t *PandoRPCService) GetStorageAt(args *GetStorageAtArgs, result *GetStorag
 			return nil
 		}
 
-		deliveredView, err := t.ledger.GetDeliveredSnapshot()
-		if err != nil {
-			return err
-		}
-		db := deliveredView.GetDB()
-
 		for _, b := range blocks {
 			if b.Status.IsFinalized() {
-				stateRoot := b.StateHash
-				ledgerState := state.NewStoreView(height, stateRoot, db)
-				if ledgerState == nil { // might have been pruned
-					return fmt.Errorf("the account details for height %v is not available, it might have been pruned", height)
+				ledgerState, err := t.stateAfterBlock(b)
+				if err != nil {
+					return err
 				}
 				value := ledgerState.GetState(address, key)
 				result.Value = hex.EncodeToString(value.Bytes())
@@ -1022,8 +995,299 @@ func (t *PandoRPCService) GetStorageAt(args *GetStorageAtArgs, result *GetStorag
 	return nil
 }
 
+// ------------------------------- GetProof -----------------------------------
+
+type GetProofArgs struct {
+	Address     string            `json:"address"`
+	StorageKeys []string          `json:"storage_keys"` // optional, the storage slots to prove
+	Height      common.JSONUint64 `json:"height"`       // optional, defaults to the latest finalized block
+	BlockHash   string            `json:"block_hash"`   // optional, prove against the state of this block instead of the height
+}
+
+type GetProofResult struct {
+	BlockHash   common.Hash       `json:"block_hash"`
+	BlockHeight common.JSONUint64 `json:"block_height"`
+	StateHash   common.Hash       `json:"state_hash"`
+	*proof.AccountProof
+}
+
+// GetProof returns the merkle proof of an account against the state hash of a block, and the proofs
+// of the given storage slots against the storage root of the account. The proofs can be checked
+// with the ledger/proof package.
+func (t *PandoRPCService) GetProof(args *GetProofArgs, result *GetProofResult) (err error) {
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	address := common.HexToAddress(args.Address)
+	height := uint64(args.Height)
+
+	var block *core.ExtendedBlock
+	if height == 0 && args.BlockHash == "" {
+		block = t.consensus.GetLastFinalizedBlock()
+	} else {
+		block, err = t.findBlockByHeightOrHash(height, args.BlockHash)
+		if err != nil {
+			return err
+		}
+	}
+	ledgerState, err := t.stateAfterBlock(block)
+	if err != nil {
+		return err
+	}
+
+	accountProof := &proof.AccountProof{
+		Address:       address,
+		Account:       ledgerState.GetAccount(address),
+		Proof:         proof.ProofList{},
+		StorageProofs: []*proof.StorageProof{},
+	}
+	if err = ledgerState.ProveAccount(address, &accountProof.Proof); err != nil {
+		return fmt.Errorf("Failed to prove account %v: %v", address.Hex(), err)
+	}
+	for _, storageKey := range args.StorageKeys {
+		key := common.HexToHash(storageKey)
+		storageProof := &proof.StorageProof{
+			Key:   key,
+			Value: ledgerState.GetState(address, key),
+			Proof: proof.ProofList{},
+		}
+		if err = ledgerState.ProveState(address, key, &storageProof.Proof); err != nil {
+			return fmt.Errorf("Failed to prove key %v of account %v: %v", key.Hex(), address.Hex(), err)
+		}
+		accountProof.StorageProofs = append(accountProof.StorageProofs, storageProof)
+	}
+
+	result.BlockHash = block.Hash()
+	result.BlockHeight = common.JSONUint64(block.Height)
+	result.StateHash = block.StateHash
+	result.AccountProof = accountProof
+
+	return nil
+}
+
+// ------------------------------- GetLogs -----------------------------------
+
+type GetLogsArgs struct {
+	FromBlock common.JSONUint64 `json:"from_block"` // defaults to to_block
+	ToBlock   common.JSONUint64 `json:"to_block"`   // defaults to the latest finalized block
+	BlockHash common.Hash       `json:"block_hash"` // if specified, from_block and to_block are ignored
+	Addresses []string          `json:"addresses"`
+	Topics    [][]string        `json:"topics"` // up to four topic positions, each lists the alternatives; an empty list matches any topic
+}
+
+type LogResult struct {
+	Address     common.Address    `json:"address"`
+	Topics      []common.Hash     `json:"topics"`
+	Data        hexutil.Bytes     `json:"data"`
+	BlockHash   common.Hash       `json:"block_hash"`
+	BlockHeight common.JSONUint64 `json:"block_height"`
+	TxHash      common.Hash       `json:"tx_hash"`
+	TxIndex     common.JSONUint64 `json:"tx_index"`
+	LogIndex    common.JSONUint64 `json:"log_index"`
+}
+
+type GetLogsResult struct {
+	Logs []*LogResult `json:"logs"`
+}
+
+func (t *PandoRPCService) GetLogs(args *GetLogsArgs, result *GetLogsResult) (err error) {
+	filter := &blockchain.LogFilter{
+		Addresses: []common.Address{},
+		Topics:    [][]common.Hash{},
+	}
+	for _, addr := range args.Addresses {
+		filter.Addresses = append(filter.Addresses, common.HexToAddress(addr))
+	}
+	for _, options := range args.Topics {
+		topics := []common.Hash{}
+		for _, topic := range options {
+			topics = append(topics, common.HexToHash(topic))
+		}
+		filter.Topics = append(filter.Topics, topics)
+	}
+
+	var logs []*blockchain.IndexedLog
+	if !args.BlockHash.IsEmpty() {
+		// The logs of the blocks which are not finalized might never make it to the chain
+		block, err := t.chain.FindBlock(args.BlockHash)
+		if err != nil && err != store.ErrKeyNotFound {
+			return err
+		}
+		if err != nil || !block.Status.IsFinalized() {
+			return fmt.Errorf("Block %v is not found", args.BlockHash.Hex())
+		}
+		if err := filter.Validate(); err != nil {
+			return err
+		}
+		logs = t.chain.FindLogsInBlock(block, filter)
+	} else {
+		toHeight := uint64(args.ToBlock)
+		if toHeight == 0 {
+			toHeight = t.consensus.GetLastFinalizedBlock().Height
+		}
+		fromHeight := uint64(args.FromBlock)
+		if fromHeight == 0 {
+			fromHeight = toHeight
+		}
+		logs, err = t.chain.FindLogs(fromHeight, toHeight, filter)
+		if err != nil {
+			return err
+		}
+	}
+
+	result.Logs = []*LogResult{}
+	for _, log := range logs {
+		result.Logs = append(result.Logs, newLogResult(log))
+	}
+
+	return nil
+}
+
+func newLogResult(log *blockchain.IndexedLog) *LogResult {
+	return &LogResult{
+		Address:     log.Address,
+		Topics:      log.Topics,
+		Data:        log.Data,
+		BlockHash:   log.BlockHash,
+		BlockHeight: common.JSONUint64(log.BlockHeight),
+		TxHash:      log.TxHash,
+		TxIndex:     common.JSONUint64(log.TxIndex),
+		LogIndex:    common.JSONUint64(log.LogIndex),
+	}
+}
+
+// ------------------------------- GetAccountTransactions -----------------------------------
+
+const defaultAccountTxPageSize = uint64(20)
+
+type GetAccountTransactionsArgs struct {
+	Address string            `json:"address"`
+	Page    common.JSONUint64 `json:"page"`  // zero-based page number, most recent transactions first
+	Limit   common.JSONUint64 `json:"limit"` // page size, defaults to 20 and is capped at 100
+}
+
+type AccountTxResult struct {
+	TxHash      common.Hash       `json:"hash"`
+	BlockHash   common.Hash       `json:"block_hash"`
+	BlockHeight common.JSONUint64 `json:"block_height"`
+	Type        byte              `json:"type"`
+	Tx          types.Tx          `json:"transaction"`
+}
+
+type GetAccountTransactionsResult struct {
+	Address common.Address     `json:"address"`
+	Total   common.JSONUint64  `json:"total"`
+	Page    common.JSONUint64  `json:"page"`
+	Limit   common.JSONUint64  `json:"limit"`
+	Txs     []*AccountTxResult `json:"transactions"`
+}
+
+func (t *PandoRPCService) GetAccountTransactions(args *GetAccountTransactionsArgs, result *GetAccountTransactionsResult) (err error) {
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	if !t.chain.AccountTxIndexEnabled() {
+		return fmt.Errorf("Account transaction index is not enabled on this node, set %v to enable it", common.CfgStorageAccountTxIndexEnabled)
+	}
+	address := common.HexToAddress(args.Address)
+
+	limit := uint64(args.Limit)
+	if limit == 0 {
+		limit = defaultAccountTxPageSize
+	}
+	if limit > blockchain.MaxAccountTxQueryLimit {
+		limit = blockchain.MaxAccountTxQueryLimit
+	}
+	page := uint64(args.Page)
+	if page > math.MaxUint64/limit {
+		return fmt.Errorf("Page %v is out of range", page)
+	}
+
+	entries, total := t.chain.FindAccountTxs(address, page*limit, limit)
+
+	result.Address = address
+	result.Total = common.JSONUint64(total)
+	result.Page = common.JSONUint64(page)
+	result.Limit = common.JSONUint64(limit)
+	result.Txs = []*AccountTxResult{}
+	for _, entry := range entries {
+		raw, block, found := t.chain.FindTxByHash(entry.TxHash)
+		if !found {
+			continue
+		}
+		tx, err := types.TxFromBytes(raw)
+		if err != nil {
+			return err
+		}
+		result.Txs = append(result.Txs, &AccountTxResult{
+			TxHash:      entry.TxHash,
+			BlockHash:   block.Hash(),
+			BlockHeight: common.JSONUint64(block.Height),
+			Type:        getTxType(tx),
+			Tx:          tx,
+		})
+	}
+
+	return nil
+}
+
 // ------------------------------ Utils ------------------------------
 
+// ErrCodeStateNotAvailable is the JSON-RPC error code returned when the state of the requested
+// block has been pruned.
+const ErrCodeStateNotAvailable = -32010
+
+// StateNotAvailableErrorData is the data of the JSON-RPC error returned when the state of the
+// requested block has been pruned.
+type StateNotAvailableErrorData struct {
+	Height            common.JSONUint64 `json:"height"`
+	OldestStateHeight common.JSONUint64 `json:"oldest_state_height"`
+}
+
+// stateAfterBlock returns the state view right after the given block was committed.
+func (t *PandoRPCService) stateAfterBlock(block *core.ExtendedBlock) (*state.StoreView, error) {
+	view, err := t.ledger.GetSnapshotAfterBlock(block)
+	if notAvailable, ok := err.(*ledger.StateNotAvailableError); ok {
+		rpcErr := jsonrpc2.NewError(ErrCodeStateNotAvailable, notAvailable.Error())
+		rpcErr.Data = StateNotAvailableErrorData{
+			Height:            common.JSONUint64(notAvailable.Height),
+			OldestStateHeight: common.JSONUint64(notAvailable.OldestStateHeight),
+		}
+		return nil, rpcErr
+	}
+	return view, err
+}
+
+// findBlockByHeightOrHash returns the finalized block at the given height, or the block with the given
+// hash. At most one of them should be specified.
+func (t *PandoRPCService) findBlockByHeightOrHash(height uint64, blockHash string) (*core.ExtendedBlock, error) {
+	if height != 0 && blockHash != "" {
+		return nil, errors.New("Only one of height and block_hash can be specified")
+	}
+	if blockHash != "" {
+		block, err := t.chain.FindBlock(common.HexToHash(blockHash))
+		if err != nil {
+			return nil, fmt.Errorf("Block %v is not found", blockHash)
+		}
+		return block, nil
+	}
+	block := t.findFinalizedBlockByHeight(height)
+	if block == nil {
+		return nil, fmt.Errorf("Finalized block at height %v is not found", height)
+	}
+	return block, nil
+}
+
+// findFinalizedBlockByHeight returns the finalized block at the given height, or nil if not found.
+func (t *PandoRPCService) findFinalizedBlockByHeight(height uint64) *core.ExtendedBlock {
+	for _, b := range t.chain.FindBlocksByHeight(height) {
+		if b.Status.IsFinalized() {
+			return b
+		}
+	}
+	return nil
+}
+
 func (t *PandoRPCService) gatherTxs(block *core.ExtendedBlock, txs *[]interface{}, includeEthTxHashes bool) error {
 	// Parse and fulfill Txs.
 	//var tx types.Tx
@@ -0,0 +1,152 @@
+package rpc
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/ledger"
+	"github.com/pandotoken/pando/ledger/state"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/rpc/lib/rpc-codec/jsonrpc2"
+	"github.com/pandotoken/pando/store/database"
+	"github.com/pandotoken/pando/store/database/backend"
+	"github.com/pandotoken/pando/store/kvstore"
+)
+
+// prunedDB mocks a db whose states before oldestHeight have been dropped.
+type prunedDB struct {
+	database.Database
+	oldestHeight uint64
+}
+
+func (db *prunedDB) OldestStateHeight() uint64 {
+	return db.oldestHeight
+}
+
+func (db *prunedDB) Tag(height uint64, root common.Hash) {}
+
+func newQueryTestBlock(height uint64, parent common.Hash, stateHash common.Hash) *core.Block {
+	block := core.NewBlock()
+	block.ChainID = "querytest"
+	block.Epoch = height
+	block.Height = height
+	block.Parent = parent
+	block.HCC.BlockHash = parent
+	block.StateHash = stateHash
+	block.Timestamp = big.NewInt(int64(height))
+	return block
+}
+
+func TestStateAfterBlock(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	db := &prunedDB{Database: backend.NewMemDatabase()}
+	addr := common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab")
+	sv := state.NewStoreView(100, common.Hash{}, db)
+	sv.SetAccount(addr, types.NewAccount(addr))
+	root := sv.Save()
+
+	block100 := newQueryTestBlock(100, common.Hash{}, root)
+	block101 := newQueryTestBlock(101, block100.Hash(), root)
+	chain := blockchain.NewChain("querytest", kvstore.NewKVStore(db), block100)
+	_, err := chain.AddBlock(block101)
+	require.Nil(err)
+	eb101, err := chain.FindBlock(block101.Hash())
+	require.Nil(err)
+
+	service := &PandoRPCService{
+		chain:  chain,
+		ledger: ledger.NewLedger("querytest", db, db, chain, nil, nil, nil),
+	}
+
+	// The state of the chain root is the oldest available
+	assert.Equal(uint64(100), service.ledger.OldestStateHeight())
+	view, err := service.stateAfterBlock(chain.Root())
+	require.Nil(err)
+	assert.NotNil(view.GetAccount(addr))
+
+	// The blocks before the chain root have no state
+	_, err = service.stateAfterBlock(&core.ExtendedBlock{Block: newQueryTestBlock(99, common.Hash{}, root)})
+	rpcErr, ok := err.(*jsonrpc2.Error)
+	require.True(ok)
+	assert.Equal(ErrCodeStateNotAvailable, rpcErr.Code)
+	assert.Equal(StateNotAvailableErrorData{Height: 99, OldestStateHeight: 100}, rpcErr.Data)
+
+	// The states dropped by the db are reported as not available
+	db.oldestHeight = 101
+	assert.Equal(uint64(101), service.ledger.OldestStateHeight())
+	_, err = service.stateAfterBlock(chain.Root())
+	rpcErr, ok = err.(*jsonrpc2.Error)
+	require.True(ok)
+	assert.Equal(StateNotAvailableErrorData{Height: 100, OldestStateHeight: 101}, rpcErr.Data)
+	_, err = service.stateAfterBlock(eb101)
+	assert.Nil(err)
+
+	// So are the missing states after the oldest available state
+	_, err = service.stateAfterBlock(&core.ExtendedBlock{Block: newQueryTestBlock(102, block101.Hash(), common.HexToHash("0x1234"))})
+	rpcErr, ok = err.(*jsonrpc2.Error)
+	require.True(ok)
+	assert.Equal(ErrCodeStateNotAvailable, rpcErr.Code)
+}
+
+func TestGetProof(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	db := &prunedDB{Database: backend.NewMemDatabase()}
+	addr := common.HexToAddress("0x2E833968E5bB786Ae419c4d13189fB081Cc43bab")
+	key := common.HexToHash("0x01")
+	sv := state.NewStoreView(100, common.Hash{}, db)
+	sv.SetAccount(addr, types.NewAccount(addr))
+	sv.SetState(addr, key, common.HexToHash("0xabcd"))
+	root := sv.Save()
+
+	block100 := newQueryTestBlock(100, common.Hash{}, root)
+	chain := blockchain.NewChain("querytest", kvstore.NewKVStore(db), block100)
+	service := &PandoRPCService{
+		chain:  chain,
+		ledger: ledger.NewLedger("querytest", db, db, chain, nil, nil, nil),
+	}
+
+	result := &GetProofResult{}
+	args := &GetProofArgs{Address: addr.Hex(), StorageKeys: []string{key.Hex()}, BlockHash: block100.Hash().Hex()}
+	require.Nil(service.GetProof(args, result))
+	assert.Equal(block100.Hash(), result.BlockHash)
+	assert.Equal(root, result.StateHash)
+
+	account, err := result.AccountProof.Verify(result.StateHash)
+	require.Nil(err)
+	require.NotNil(account)
+	require.Equal(1, len(result.StorageProofs))
+	assert.Equal(common.HexToHash("0xabcd"), result.StorageProofs[0].Value)
+
+	// Either the height or the block hash can be specified
+	args.Height = 100
+	assert.NotNil(service.GetProof(args, &GetProofResult{}))
+}
+
+func TestGetAccountTransactionsPageOverflow(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := blockchain.NewChain("querytest", kvstore.NewKVStore(backend.NewMemDatabase()), newQueryTestBlock(100, common.Hash{}, common.Hash{}))
+	chain.SetAccountTxIndexEnabled(true)
+	service := &PandoRPCService{chain: chain}
+
+	addr := "0x2E833968E5bB786Ae419c4d13189fB081Cc43bab"
+	result := &GetAccountTransactionsResult{}
+	assert.Nil(service.GetAccountTransactions(&GetAccountTransactionsArgs{Address: addr, Page: 3, Limit: 10}, result))
+	assert.Equal(0, len(result.Txs))
+
+	// page * limit would overflow
+	err := service.GetAccountTransactions(&GetAccountTransactionsArgs{Address: addr, Page: common.JSONUint64(math.MaxUint64 / 10), Limit: 20}, &GetAccountTransactionsResult{})
+	assert.NotNil(err)
+	err = service.GetAccountTransactions(&GetAccountTransactionsArgs{Address: addr, Page: math.MaxUint64}, &GetAccountTransactionsResult{})
+	assert.NotNil(err)
+}
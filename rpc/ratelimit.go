@@ -0,0 +1,77 @@
+package rpc
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// maxRateLimitedClients is the number of clients tracked before the idle ones are dropped.
+const maxRateLimitedClients = 10000
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits the rate of the RPC calls of each client with a token bucket.
+type rateLimiter struct {
+	mu      *sync.Mutex
+	rate    float64 // tokens added per second
+	burst   float64 // capacity of the buckets
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+// newRateLimiter creates a rateLimiter allowing rate calls per second, and bursts of up to burst
+// calls. The burst is at least the rate rounded up, and at least one call.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		mu:      &sync.Mutex{},
+		rate:    rate,
+		burst:   math.Max(float64(burst), math.Max(math.Ceil(rate), 1)),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token from the bucket of the client, and returns false if the bucket is empty.
+func (rl *rateLimiter) allow(clientID string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	bucket, ok := rl.buckets[clientID]
+	if !ok {
+		if len(rl.buckets) >= maxRateLimitedClients {
+			rl.prune(now)
+		}
+		bucket = &tokenBucket{tokens: rl.burst, last: now}
+		rl.buckets[clientID] = bucket
+	}
+
+	bucket.tokens = rl.refill(bucket, now)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+func (rl *rateLimiter) refill(bucket *tokenBucket, now time.Time) float64 {
+	elapsed := now.Sub(bucket.last).Seconds()
+	if elapsed <= 0 {
+		return bucket.tokens
+	}
+	return math.Min(rl.burst, bucket.tokens+elapsed*rl.rate)
+}
+
+// prune drops the buckets which have refilled, since they are identical to new buckets.
+func (rl *rateLimiter) prune(now time.Time) {
+	for clientID, bucket := range rl.buckets {
+		if rl.refill(bucket, now) >= rl.burst {
+			delete(rl.buckets, clientID)
+		}
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"os"
+	"path"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"net/rpc"
@@ -19,13 +22,13 @@ import (
 	"github.com/spf13/viper"
 	"github.com/pandotoken/pando/blockchain"
 	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/metrics"
 	"github.com/pandotoken/pando/common/util"
 	"github.com/pandotoken/pando/consensus"
 	"github.com/pandotoken/pando/dispatcher"
 	"github.com/pandotoken/pando/ledger"
 	"github.com/pandotoken/pando/mempool"
 	"github.com/pandotoken/pando/rpc/lib/rpc-codec/jsonrpc2"
-	"golang.org/x/net/netutil"
 	"golang.org/x/net/websocket"
 )
 
@@ -38,6 +41,8 @@ type PandoRPCService struct {
 	chain      *blockchain.Chain
 	consensus  *consensus.ConsensusEngine
 
+	subscriptions *subscriptionHub
+
 	// Life cycle
 	wg      *sync.WaitGroup
 	ctx     context.Context
@@ -49,19 +54,27 @@ type PandoRPCService struct {
 type PandoRPCServer struct {
 	*PandoRPCService
 
-	server   *http.Server
-	handler  *rpc.Server
-	router   *mux.Router
-	listener net.Listener
+	server     *http.Server
+	handler    *rpc.Server
+	router     *mux.Router
+	policy     atomic.Value // *rpcPolicy of the RPC port, replaced when the config is reloaded
+	listenerMu *sync.Mutex
+	listener   *limitListener
+
+	adminServer     *http.Server // nil if the admin socket is disabled
+	adminSocketPath string
 }
 
-// NewPandoRPCServer creates a new instance of PandoRPCServer.
+// NewPandoRPCServer creates a new instance of PandoRPCServer. The admin socket is created in
+// the data directory unless its path is configured.
 func NewPandoRPCServer(mempool *mempool.Mempool, ledger *ledger.Ledger, dispatcher *dispatcher.Dispatcher,
-	chain *blockchain.Chain, consensus *consensus.ConsensusEngine) *PandoRPCServer {
+	chain *blockchain.Chain, consensus *consensus.ConsensusEngine, dataPath string) *PandoRPCServer {
 	t := &PandoRPCServer{
 		PandoRPCService: &PandoRPCService{
-			wg: &sync.WaitGroup{},
+			subscriptions: newSubscriptionHub(),
+			wg:            &sync.WaitGroup{},
 		},
+		listenerMu: &sync.Mutex{},
 	}
 
 	t.mempool = mempool
@@ -70,22 +83,62 @@ func NewPandoRPCServer(mempool *mempool.Mempool, ledger *ledger.Ledger, dispatch
 	t.chain = chain
 	t.consensus = consensus
 
+	services := map[string]interface{}{
+		"pando":        t.PandoRPCService,
+		ethServiceName: NewEthRPCService(t.PandoRPCService),
+	}
 	s := rpc.NewServer()
-	s.RegisterName("pando", t.PandoRPCService)
+	for name, service := range services {
+		s.RegisterName(name, service)
+	}
 
 	t.handler = s
 
+	var rpcHandler http.Handler = jsonrpc2.HTTPHandler(s)
+	if metrics.Enabled {
+		rpcHandler = newRPCMetrics(services).middleware(rpcHandler)
+	}
+
+	t.policy.Store(newRPCPolicy(viper.GetStringSlice(common.CfgRPCNamespaces)))
+
+	timeoutHandler := TimeoutHandler(ethMethodMiddleware(rpcHandler), viper.GetDuration(common.CfgRPCTimeoutSecs)*time.Second, "")
+	webSocketHandler := func(ws *websocket.Conn) {
+		t.serveWebSocket(s, ws)
+	}
+
 	t.router = mux.NewRouter()
 	t.router.Handle("/", &defaultHTTPHandler{})
-	t.router.Handle("/rpc", corsMiddleware(TimeoutHandler(jsonrpc2.HTTPHandler(s), viper.GetDuration(common.CfgRPCTimeoutSecs)*time.Second, "")))
-	t.router.Handle("/ws", websocket.Handler(func(ws *websocket.Conn) {
-		s.ServeCodec(jsonrpc2.NewServerCodec(ws, s))
+	t.router.Handle("/healthz", newLivenessService(t.consensus.LastMainLoopTime))
+	t.router.Handle("/readyz", t.newReadinessService())
+	t.router.Handle("/rpc", t.withPolicy(func(policy *rpcPolicy) http.Handler {
+		return policy.corsMiddleware(policy.middleware(timeoutHandler))
+	}))
+	t.router.Handle("/ws", t.withPolicy(func(policy *rpcPolicy) http.Handler {
+		return policy.middleware(websocket.Server{
+			Handshake: policy.webSocketHandshake,
+			Handler:   webSocketHandler,
+		})
 	}))
 
 	t.server = &http.Server{
 		Handler: t.router,
 	}
 
+	if viper.GetBool(common.CfgRPCAdminSocketEnabled) {
+		t.adminSocketPath = viper.GetString(common.CfgRPCAdminSocketPath)
+		if t.adminSocketPath == "" && dataPath != "" {
+			t.adminSocketPath = path.Join(dataPath, "admin.sock")
+		}
+	}
+	if t.adminSocketPath != "" {
+		// No timeout, the backups can take longer than the timeout of the public RPC
+		adminRouter := mux.NewRouter()
+		adminRouter.Handle("/rpc", newAdminRPCPolicy().middleware(ethMethodMiddleware(rpcHandler)))
+		t.adminServer = &http.Server{
+			Handler: adminRouter,
+		}
+	}
+
 	logger = util.GetLoggerForModule("rpc")
 
 	return t
@@ -102,16 +155,25 @@ func (t *PandoRPCServer) Start(ctx context.Context) {
 
 	t.wg.Add(1)
 	go t.txCallback()
+
+	t.wg.Add(1)
+	go t.subscriptionLoop()
 }
 
 func (t *PandoRPCServer) mainLoop() {
 	defer t.wg.Done()
 
 	go t.serve()
+	if t.adminServer != nil {
+		go t.serveAdmin()
+	}
 
 	<-t.ctx.Done()
 	t.stopped = true
 	t.server.Shutdown(t.ctx)
+	if t.adminServer != nil {
+		t.adminServer.Shutdown(t.ctx)
+	}
 }
 
 func (t *PandoRPCServer) serve() {
@@ -125,25 +187,101 @@ func (t *PandoRPCServer) serve() {
 	}
 	defer l.Close()
 
-	ll := netutil.LimitListener(l, viper.GetInt(common.CfgRPCMaxConnections))
+	ll := newLimitListener(l, viper.GetInt(common.CfgRPCMaxConnections))
+	t.listenerMu.Lock()
 	t.listener = ll
+	t.listenerMu.Unlock()
 
 	logger.Info(t.server.Serve(ll))
 }
 
-func corsMiddleware(handler http.Handler) http.Handler {
+// withPolicy serves the requests with the handler built for the current policy of the RPC port.
+func (t *PandoRPCServer) withPolicy(build func(policy *rpcPolicy) http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		//Allow CORS here By * or specific origin
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "*")
+		build(t.policy.Load().(*rpcPolicy)).ServeHTTP(w, r)
+	})
+}
+
+// ApplyConfig applies the reloaded RPC settings: the max number of connections, and the
+// namespaces, authentication, rate limit and CORS settings of the RPC port. The rate limits
+// restart from full buckets, and the open WebSocket connections keep the previous policy.
+func (t *PandoRPCServer) ApplyConfig() {
+	t.policy.Store(newRPCPolicy(viper.GetStringSlice(common.CfgRPCNamespaces)))
+
+	t.listenerMu.Lock()
+	defer t.listenerMu.Unlock()
+	if t.listener != nil {
+		t.listener.SetLimit(viper.GetInt(common.CfgRPCMaxConnections))
+	}
+	logger.Infof("Reloaded the RPC settings")
+}
+
+// serveAdmin serves the admin RPC on the Unix domain socket, which only the user running the
+// node can connect to.
+func (t *PandoRPCServer) serveAdmin() {
+	l, err := listenAdminSocket(t.adminSocketPath)
+	if err != nil {
+		logger.WithFields(log.Fields{"error": err, "path": t.adminSocketPath}).Error("Failed to create admin socket")
+		return
+	}
+	logger.WithFields(log.Fields{"path": t.adminSocketPath}).Info("Admin RPC server started")
+	defer l.Close()
+
+	logger.Info(t.adminServer.Serve(l))
+}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+// listenAdminSocket listens on the Unix domain socket at the given path, replacing the socket
+// left by a previous run. The socket is only accessible to the owner: it is created in a private
+// directory and only moved to the given path once its permissions are restricted.
+func listenAdminSocket(socketPath string) (net.Listener, error) {
+	if info, err := os.Lstat(socketPath); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%v exists and is not a socket", socketPath)
 		}
+		if err := os.Remove(socketPath); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(path.Dir(socketPath), 0700); err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := ioutil.TempDir(path.Dir(socketPath), ".admin")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpPath := path.Join(tmpDir, "admin.sock")
+	l, err := net.Listen("unix", tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, socketPath); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return &adminSocketListener{Listener: l, socketPath: socketPath}, nil
+}
+
+// adminSocketListener removes the admin socket when closed.
+type adminSocketListener struct {
+	net.Listener
+	socketPath string
+	once       sync.Once
+}
 
-		handler.ServeHTTP(w, r)
+func (l *adminSocketListener) Close() error {
+	err := l.Listener.Close()
+	l.once.Do(func() {
+		os.Remove(l.socketPath)
 	})
+	return err
 }
 
 // Stop notifies all goroutines to stop without blocking.
@@ -0,0 +1,411 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/rpc"
+	"sync"
+
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/rpc/lib/rpc-codec/jsonrpc2"
+	"golang.org/x/net/websocket"
+)
+
+// Subscription types supported by pando.Subscribe.
+const (
+	SubscriptionNewHeads            = "newHeads"
+	SubscriptionFinalizedBlocks     = "finalizedBlocks"
+	SubscriptionPendingTransactions = "pendingTransactions"
+	SubscriptionLogs                = "logs"
+)
+
+// SubscriptionNotificationMethod is the method name of the notifications pushed to the subscribers.
+const SubscriptionNotificationMethod = "pando.Subscription"
+
+// wsSendQueueSize is the number of notifications that can be queued for a WebSocket connection.
+// A client that falls further behind is disconnected.
+const wsSendQueueSize = 256
+
+// maxSubscriptionsPerConn is the maximum number of active subscriptions of a WebSocket connection.
+const maxSubscriptionsPerConn = 32
+
+type wsContextKey int
+
+var wsConnContextKey wsContextKey
+
+// wsConn is a WebSocket connection to the RPC server, together with its subscriptions.
+type wsConn struct {
+	ws        *websocket.Conn
+	send      chan []byte
+	quit      chan struct{}
+	closeOnce sync.Once
+
+	mu   sync.Mutex
+	subs map[string]*subscription
+}
+
+type subscription struct {
+	id     string
+	kind   string
+	filter *blockchain.LogFilter // only for log subscriptions
+}
+
+func newWsConn(ws *websocket.Conn) *wsConn {
+	return &wsConn{
+		ws:   ws,
+		send: make(chan []byte, wsSendQueueSize),
+		quit: make(chan struct{}),
+		subs: make(map[string]*subscription),
+	}
+}
+
+func (c *wsConn) writeLoop() {
+	for {
+		select {
+		case <-c.quit:
+			return
+		case msg := <-c.send:
+			if _, err := c.ws.Write(msg); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// enqueue queues the notification for sending without blocking. The connection is closed if
+// the client doesn't keep up with its notifications.
+func (c *wsConn) enqueue(msg []byte) {
+	select {
+	case <-c.quit:
+	case c.send <- msg:
+	default:
+		logger.Warnf("WebSocket client %v is too slow, closing connection", c.ws.Request().RemoteAddr)
+		c.close()
+	}
+}
+
+func (c *wsConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.quit)
+		c.ws.Close()
+	})
+}
+
+func wsConnFromContext(ctx context.Context) *wsConn {
+	if ctx == nil {
+		return nil
+	}
+	conn, _ := ctx.Value(wsConnContextKey).(*wsConn)
+	return conn
+}
+
+// subscriptionHub keeps track of the WebSocket connections and dispatches notifications to their subscriptions.
+type subscriptionHub struct {
+	mu     *sync.RWMutex
+	conns  map[*wsConn]bool
+	nextID uint64
+}
+
+func newSubscriptionHub() *subscriptionHub {
+	return &subscriptionHub{
+		mu:    &sync.RWMutex{},
+		conns: make(map[*wsConn]bool),
+	}
+}
+
+func (h *subscriptionHub) addConn(conn *wsConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conns[conn] = true
+}
+
+func (h *subscriptionHub) removeConn(conn *wsConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, conn)
+}
+
+func (h *subscriptionHub) subscribe(conn *wsConn, kind string, filter *blockchain.LogFilter) (string, error) {
+	h.mu.Lock()
+	h.nextID++
+	id := fmt.Sprintf("0x%x", h.nextID)
+	h.mu.Unlock()
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if len(conn.subs) >= maxSubscriptionsPerConn {
+		return "", fmt.Errorf("Can't have more than %v subscriptions per connection", maxSubscriptionsPerConn)
+	}
+	conn.subs[id] = &subscription{
+		id:     id,
+		kind:   kind,
+		filter: filter,
+	}
+	return id, nil
+}
+
+func (h *subscriptionHub) unsubscribe(conn *wsConn, id string) bool {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	_, exists := conn.subs[id]
+	delete(conn.subs, id)
+	return exists
+}
+
+// subscriptions returns the active subscriptions of the given kind, grouped by connection.
+func (h *subscriptionHub) subscriptions(kind string) map[*wsConn][]*subscription {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ret := make(map[*wsConn][]*subscription)
+	for conn := range h.conns {
+		conn.mu.Lock()
+		for _, sub := range conn.subs {
+			if sub.kind == kind {
+				ret[conn] = append(ret[conn], sub)
+			}
+		}
+		conn.mu.Unlock()
+	}
+	return ret
+}
+
+// publish sends the notifications produced by results to every subscription of the given kind.
+// results is only evaluated if there are subscribers.
+func (h *subscriptionHub) publish(kind string, results func(sub *subscription) []interface{}) {
+	for conn, subs := range h.subscriptions(kind) {
+		for _, sub := range subs {
+			for _, result := range results(sub) {
+				msg, err := encodeSubscriptionNotification(sub.id, result)
+				if err != nil {
+					logger.Errorf("Failed to encode subscription notification: %v", err)
+					continue
+				}
+				conn.enqueue(msg)
+			}
+		}
+	}
+}
+
+type subscriptionNotification struct {
+	Version string                         `json:"jsonrpc"`
+	Method  string                         `json:"method"`
+	Params  subscriptionNotificationParams `json:"params"`
+}
+
+type subscriptionNotificationParams struct {
+	Subscription string      `json:"subscription"`
+	Result       interface{} `json:"result"`
+}
+
+func encodeSubscriptionNotification(id string, result interface{}) ([]byte, error) {
+	return json.Marshal(subscriptionNotification{
+		Version: "2.0",
+		Method:  SubscriptionNotificationMethod,
+		Params: subscriptionNotificationParams{
+			Subscription: id,
+			Result:       result,
+		},
+	})
+}
+
+// serveWebSocket serves the JSON-RPC requests of a WebSocket connection, and removes
+// its subscriptions once the client disconnects.
+func (t *PandoRPCService) serveWebSocket(s *rpc.Server, ws *websocket.Conn) {
+	conn := newWsConn(ws)
+	t.subscriptions.addConn(conn)
+	defer func() {
+		t.subscriptions.removeConn(conn)
+		conn.close()
+	}()
+
+	go conn.writeLoop()
+
+	ctx := context.WithValue(context.Background(), wsConnContextKey, conn)
+	if req := ws.Request(); req != nil {
+		if filter := jsonrpc2.MethodFilterFromContext(req.Context()); filter != nil {
+			ctx = jsonrpc2.WithMethodFilter(ctx, filter)
+		}
+	}
+	s.ServeCodec(jsonrpc2.NewServerCodecContext(ctx, ws, s))
+}
+
+// subscriptionLoop publishes the new blocks and pending transactions to the subscribers.
+func (t *PandoRPCService) subscriptionLoop() {
+	defer t.wg.Done()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case block := <-t.consensus.NewBlocks():
+			t.notifyNewBlock(block)
+		case rawTx := <-t.mempool.InsertedTxs():
+			t.notifyPendingTx(rawTx)
+		}
+	}
+}
+
+func (t *PandoRPCService) notifyNewBlock(block *core.Block) {
+	t.subscriptions.publish(SubscriptionNewHeads, func(sub *subscription) []interface{} {
+		eb, err := t.chain.FindBlock(block.Hash())
+		if err != nil {
+			return nil
+		}
+		return []interface{}{t.newBlockResult(eb, false)}
+	})
+}
+
+func (t *PandoRPCService) notifyFinalizedBlock(block *core.Block) {
+	var eb *core.ExtendedBlock
+	findBlock := func() *core.ExtendedBlock {
+		if eb == nil {
+			eb, _ = t.chain.FindBlock(block.Hash())
+		}
+		return eb
+	}
+
+	t.subscriptions.publish(SubscriptionFinalizedBlocks, func(sub *subscription) []interface{} {
+		if findBlock() == nil {
+			return nil
+		}
+		return []interface{}{t.newBlockResult(eb, true)}
+	})
+
+	t.subscriptions.publish(SubscriptionLogs, func(sub *subscription) []interface{} {
+		if findBlock() == nil {
+			return nil
+		}
+		ret := []interface{}{}
+		for _, log := range t.chain.FindLogsInBlock(eb, sub.filter) {
+			ret = append(ret, newLogResult(log))
+		}
+		return ret
+	})
+}
+
+func (t *PandoRPCService) notifyPendingTx(rawTx common.Bytes) {
+	t.subscriptions.publish(SubscriptionPendingTransactions, func(sub *subscription) []interface{} {
+		tx, err := types.TxFromBytes(rawTx)
+		if err != nil {
+			return nil
+		}
+		return []interface{}{&PendingTxResult{
+			TxHash: crypto.Keccak256Hash(rawTx),
+			Type:   getTxType(tx),
+			Tx:     tx,
+		}}
+	})
+}
+
+// newBlockResult converts the block into the format returned by GetBlock.
+func (t *PandoRPCService) newBlockResult(block *core.ExtendedBlock, includeTxs bool) *GetBlockResultInner {
+	result := &GetBlockResultInner{
+		ChainID:            block.ChainID,
+		Epoch:              common.JSONUint64(block.Epoch),
+		Height:             common.JSONUint64(block.Height),
+		Parent:             block.Parent,
+		TxHash:             block.TxHash,
+		StateHash:          block.StateHash,
+		Timestamp:          (*common.JSONBig)(block.Timestamp),
+		Proposer:           block.Proposer,
+		HCC:                block.HCC,
+		GuardianVotes:      block.GuardianVotes,
+		EliteEdgeNodeVotes: block.EliteEdgeNodeVotes,
+		Children:           block.Children,
+		Status:             block.Status,
+		Hash:               block.Hash(),
+	}
+	if includeTxs {
+		t.gatherTxs(block, &result.Txs, false)
+	}
+	return result
+}
+
+// ------------------------------- Subscribe -----------------------------------
+
+type SubscribeArgs struct {
+	jsonrpc2.Ctx
+	Type      string     `json:"type"`      // newHeads, finalizedBlocks, pendingTransactions or logs
+	Addresses []string   `json:"addresses"` // for logs subscriptions only
+	Topics    [][]string `json:"topics"`    // for logs subscriptions only
+}
+
+type SubscribeResult struct {
+	SubscriptionID string `json:"subscription"`
+}
+
+type PendingTxResult struct {
+	TxHash common.Hash `json:"hash"`
+	Type   byte        `json:"type"`
+	Tx     types.Tx    `json:"transaction"`
+}
+
+// Subscribe creates a subscription for the WebSocket connection of the request. The notifications
+// are pushed to the client with the pando.Subscription method until the client unsubscribes or
+// disconnects.
+func (t *PandoRPCService) Subscribe(args *SubscribeArgs, result *SubscribeResult) (err error) {
+	conn := wsConnFromContext(args.Context())
+	if conn == nil {
+		return errors.New("Subscriptions are only available over WebSocket connections")
+	}
+
+	var filter *blockchain.LogFilter
+	switch args.Type {
+	case SubscriptionNewHeads, SubscriptionFinalizedBlocks, SubscriptionPendingTransactions:
+	case SubscriptionLogs:
+		filter = &blockchain.LogFilter{
+			Addresses: []common.Address{},
+			Topics:    [][]common.Hash{},
+		}
+		for _, addr := range args.Addresses {
+			filter.Addresses = append(filter.Addresses, common.HexToAddress(addr))
+		}
+		for _, options := range args.Topics {
+			topics := []common.Hash{}
+			for _, topic := range options {
+				topics = append(topics, common.HexToHash(topic))
+			}
+			filter.Topics = append(filter.Topics, topics)
+		}
+		if err := filter.Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Unsupported subscription type: %v", args.Type)
+	}
+
+	id, err := t.subscriptions.subscribe(conn, args.Type, filter)
+	if err != nil {
+		return err
+	}
+	result.SubscriptionID = id
+	return nil
+}
+
+// ------------------------------- Unsubscribe -----------------------------------
+
+type UnsubscribeArgs struct {
+	jsonrpc2.Ctx
+	SubscriptionID string `json:"subscription"`
+}
+
+type UnsubscribeResult struct {
+	Success bool `json:"success"`
+}
+
+func (t *PandoRPCService) Unsubscribe(args *UnsubscribeArgs, result *UnsubscribeResult) (err error) {
+	conn := wsConnFromContext(args.Context())
+	if conn == nil {
+		return errors.New("Subscriptions are only available over WebSocket connections")
+	}
+	result.Success = t.subscriptions.unsubscribe(conn, args.SubscriptionID)
+	return nil
+}
@@ -0,0 +1,81 @@
+package rpc
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/rpc"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/types"
+	"golang.org/x/net/websocket"
+)
+
+func TestSubscription(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	service := &PandoRPCService{subscriptions: newSubscriptionHub()}
+	s := rpc.NewServer()
+	s.RegisterName("pando", service)
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		service.serveWebSocket(s, ws)
+	}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	ws, err := websocket.Dial(url, "", server.URL)
+	require.Nil(err)
+
+	var res map[string]json.RawMessage
+	require.Nil(websocket.Message.Send(ws, `{"jsonrpc":"2.0","method":"pando.Subscribe","params":[{"type":"pendingTransactions"}],"id":1}`))
+	require.Nil(websocket.JSON.Receive(ws, &res))
+	subResult := &SubscribeResult{}
+	require.Nil(json.Unmarshal(res["result"], subResult))
+	assert.NotEqual("", subResult.SubscriptionID)
+
+	require.Nil(websocket.Message.Send(ws, `{"jsonrpc":"2.0","method":"pando.Subscribe","params":[{"type":"unknown"}],"id":2}`))
+	res = nil
+	require.Nil(websocket.JSON.Receive(ws, &res))
+	assert.NotNil(res["error"])
+
+	tx := &types.SendTx{
+		Fee:     types.NewCoins(0, 1000000000000),
+		Inputs:  []types.TxInput{{Address: common.HexToAddress("0xa1"), Sequence: 1}},
+		Outputs: []types.TxOutput{{Address: common.HexToAddress("0xb1")}},
+	}
+	raw, err := types.TxToBytes(tx)
+	require.Nil(err)
+	service.notifyPendingTx(raw)
+
+	notification := &struct {
+		Method string `json:"method"`
+		Params struct {
+			Subscription string          `json:"subscription"`
+			Result       json.RawMessage `json:"result"`
+		} `json:"params"`
+	}{}
+	require.Nil(websocket.JSON.Receive(ws, notification))
+	assert.Equal(SubscriptionNotificationMethod, notification.Method)
+	assert.Equal(subResult.SubscriptionID, notification.Params.Subscription)
+	pendingTx := map[string]json.RawMessage{}
+	require.Nil(json.Unmarshal(notification.Params.Result, &pendingTx))
+	assert.Equal(`"`+crypto.Keccak256Hash(raw).Hex()+`"`, string(pendingTx["hash"]))
+
+	// Subscriptions are removed once the client disconnects.
+	assert.Equal(1, len(service.subscriptions.subscriptions(SubscriptionPendingTransactions)))
+	ws.Close()
+	for i := 0; i < 100 && len(service.subscriptions.subscriptions(SubscriptionPendingTransactions)) > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(0, len(service.subscriptions.subscriptions(SubscriptionPendingTransactions)))
+
+	// Subscriptions are not available over plain HTTP.
+	err = service.Subscribe(&SubscribeArgs{Type: SubscriptionNewHeads}, &SubscribeResult{})
+	assert.NotNil(err)
+}
@@ -0,0 +1,216 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/state"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/ledger/vm"
+)
+
+// TraceConfig controls what the struct logs of a trace capture.
+type TraceConfig struct {
+	DisableMemory  bool `json:"disable_memory"`
+	DisableStack   bool `json:"disable_stack"`
+	DisableStorage bool `json:"disable_storage"`
+	Limit          int  `json:"limit"` // maximum number of struct logs, zero means unlimited
+}
+
+type StructLogResult struct {
+	Pc      uint64            `json:"pc"`
+	Op      string            `json:"op"`
+	Gas     uint64            `json:"gas"`
+	GasCost uint64            `json:"gas_cost"`
+	Depth   int               `json:"depth"`
+	Error   string            `json:"error,omitempty"`
+	Stack   []string          `json:"stack,omitempty"`
+	Memory  []string          `json:"memory,omitempty"`
+	Storage map[string]string `json:"storage,omitempty"` // storage slots changed so far by the executing contract
+}
+
+type TraceResult struct {
+	GasUsed         common.JSONUint64  `json:"gas_used"`
+	Failed          bool               `json:"failed"`
+	VmReturn        string             `json:"vm_return"`
+	VmError         string             `json:"vm_error"`
+	ContractAddress common.Address     `json:"contract_address"`
+	StructLogs      []*StructLogResult `json:"struct_logs"`
+}
+
+// ------------------------------- TraceTransaction -----------------------------------
+
+type TraceTransactionArgs struct {
+	Hash string `json:"hash"`
+	TraceConfig
+}
+
+// TraceTransaction re-executes a committed smart contract transaction on the state of its parent
+// block and returns the struct logs of the execution. The smart contract transactions that precede
+// it in the same block are replayed first, other transaction types are not.
+func (t *PandoRPCService) TraceTransaction(args *TraceTransactionArgs, result *TraceResult) (err error) {
+	if args.Hash == "" {
+		return errors.New("Transanction hash must be specified")
+	}
+	hash := common.HexToHash(args.Hash)
+
+	raw, block, found := t.chain.FindTxByHash(hash)
+	if !found {
+		return fmt.Errorf("Transaction %v is not found", args.Hash)
+	}
+	tx, err := types.TxFromBytes(raw)
+	if err != nil {
+		return err
+	}
+	sctx, ok := tx.(*types.SmartContractTx)
+	if !ok {
+		return fmt.Errorf("Transaction %v is not a smart contract transaction", args.Hash)
+	}
+
+	parent, err := t.chain.FindBlock(block.Parent)
+	if err != nil {
+		return fmt.Errorf("Parent block %v is not found", block.Parent.Hex())
+	}
+	view, err := t.stateAfterBlock(parent)
+	if err != nil {
+		return err
+	}
+
+	txHash := crypto.Keccak256Hash(raw)
+	for _, prevRaw := range block.Txs {
+		if crypto.Keccak256Hash(prevRaw) == txHash {
+			break
+		}
+		prevTx, err := types.TxFromBytes(prevRaw)
+		if err != nil {
+			return err
+		}
+		if prevSctx, ok := prevTx.(*types.SmartContractTx); ok {
+			replaySmartContractTx(parent.Block, prevSctx, view)
+		}
+	}
+
+	t.trace(parent.Block, sctx, view, &args.TraceConfig, result)
+	return nil
+}
+
+// ------------------------------- TraceCall -----------------------------------
+
+type TraceCallArgs struct {
+	SctxBytes string `json:"sctx_bytes"`
+	TraceConfig
+}
+
+// TraceCall executes the given smart contract transaction on the current state like CallSmartContract,
+// and returns the struct logs of the execution. The transaction doesn't need to be signed.
+func (t *PandoRPCService) TraceCall(args *TraceCallArgs, result *TraceResult) (err error) {
+	view, err := t.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return err
+	}
+
+	blockHeight := view.Height() + 1 // the view points to the parent of the current block
+	if blockHeight < common.HeightEnableSmartContract {
+		return fmt.Errorf("Smart contract feature not enabled until block height %v.", common.HeightEnableSmartContract)
+	}
+
+	sctx, err := decodeSmartContractTx(args.SctxBytes)
+	if err != nil {
+		return err
+	}
+
+	parentBlock := t.ledger.State().ParentBlock()
+	t.trace(parentBlock, sctx, view, &args.TraceConfig, result)
+	return nil
+}
+
+// ------------------------------ Utils ------------------------------
+
+func (t *PandoRPCService) trace(parentBlock *core.Block, sctx *types.SmartContractTx, view *state.StoreView,
+	config *TraceConfig, result *TraceResult) {
+	tracer := vm.NewStructLogger(&vm.LogConfig{
+		DisableMemory:  config.DisableMemory,
+		DisableStack:   config.DisableStack,
+		DisableStorage: config.DisableStorage,
+		Limit:          config.Limit,
+	})
+	vmConfig := vm.Config{
+		Debug:  true,
+		Tracer: tracer,
+	}
+	vmRet, contractAddr, gasUsed, vmErr := vm.ExecuteWithConfig(parentBlock, sctx, view, vmConfig)
+
+	result.GasUsed = common.JSONUint64(gasUsed)
+	result.VmReturn = hex.EncodeToString(vmRet)
+	result.ContractAddress = contractAddr
+	if vmErr != nil {
+		result.Failed = true
+		result.VmError = vmErr.Error()
+	}
+	result.StructLogs = formatStructLogs(tracer.StructLogs())
+}
+
+// replaySmartContractTx applies the smart contract transaction to the view, including the gas fee
+// and the sequence update performed by the SmartContractTxExecutor.
+func replaySmartContractTx(parentBlock *core.Block, sctx *types.SmartContractTx, view *state.StoreView) {
+	_, _, gasUsed, _ := vm.Execute(parentBlock, sctx, view)
+
+	account := view.GetAccount(sctx.From.Address)
+	if account == nil {
+		return
+	}
+	fee := types.Coins{
+		PandoWei: big.NewInt(0),
+		PTXWei:   new(big.Int).Mul(sctx.GasPrice, new(big.Int).SetUint64(gasUsed)),
+	}
+	account.Balance = account.Balance.Minus(fee)
+	if (sctx.To.Address != common.Address{}) { // vm.Create() increments the sequence of the from account
+		account.Sequence++
+	}
+	view.SetAccount(sctx.From.Address, account)
+}
+
+func formatStructLogs(logs []vm.StructLog) []*StructLogResult {
+	ret := make([]*StructLogResult, 0, len(logs))
+	for _, log := range logs {
+		formatted := &StructLogResult{
+			Pc:      log.Pc,
+			Op:      log.Op.String(),
+			Gas:     log.Gas,
+			GasCost: log.GasCost,
+			Depth:   log.Depth,
+		}
+		if log.Err != nil {
+			formatted.Error = log.Err.Error()
+		}
+		if log.Stack != nil {
+			formatted.Stack = make([]string, len(log.Stack))
+			for i, value := range log.Stack {
+				formatted.Stack[i] = fmt.Sprintf("%064x", value)
+			}
+		}
+		if log.Memory != nil {
+			formatted.Memory = make([]string, 0, (len(log.Memory)+31)/32)
+			for i := 0; i < len(log.Memory); i += 32 {
+				end := i + 32
+				if end > len(log.Memory) {
+					end = len(log.Memory)
+				}
+				formatted.Memory = append(formatted.Memory, fmt.Sprintf("%x", log.Memory[i:end]))
+			}
+		}
+		if log.Storage != nil {
+			formatted.Storage = make(map[string]string, len(log.Storage))
+			for key, value := range log.Storage {
+				formatted.Storage[fmt.Sprintf("%x", key)] = fmt.Sprintf("%x", value)
+			}
+		}
+		ret = append(ret, formatted)
+	}
+	return ret
+}
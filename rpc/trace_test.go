@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/ledger/state"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/store/database/backend"
+)
+
+func TestTrace(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	view := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	deployer := types.MakeAccWithInitBalance("deployer", types.NewCoins(0, 50000000000))
+	view.SetAccount(deployer.Address, &deployer.Account)
+	view.IncrementHeight()
+	view.Save()
+
+	// ASM:
+	// push 0x1
+	// push 0x0
+	// sstore
+	// stop
+	deployCode, _ := hex.DecodeString("600160005500")
+	sctx := &types.SmartContractTx{
+		From: types.TxInput{
+			Address: deployer.Address,
+			Coins:   types.NewCoins(0, 0),
+		},
+		GasLimit: 100000,
+		GasPrice: big.NewInt(1),
+		Data:     deployCode,
+	}
+	parentBlock := core.NewBlock()
+	parentBlock.ChainID = "testchain"
+	parentBlock.Height = view.Height()
+
+	service := &PandoRPCService{}
+	result := &TraceResult{}
+	service.trace(parentBlock, sctx, view, &TraceConfig{}, result)
+	assert.False(result.Failed)
+	assert.True(result.GasUsed > 0)
+
+	require.Equal(4, len(result.StructLogs))
+	assert.Equal("PUSH1", result.StructLogs[0].Op)
+	assert.Equal("SSTORE", result.StructLogs[2].Op)
+	assert.Equal("STOP", result.StructLogs[3].Op)
+	assert.Equal([]string{
+		"0000000000000000000000000000000000000000000000000000000000000001",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+	}, result.StructLogs[2].Stack)
+	assert.Equal(map[string]string{
+		"0000000000000000000000000000000000000000000000000000000000000000": "0000000000000000000000000000000000000000000000000000000000000001",
+	}, result.StructLogs[3].Storage)
+
+	result = &TraceResult{}
+	service.trace(parentBlock, sctx, view, &TraceConfig{DisableStack: true, DisableStorage: true, Limit: 2}, result)
+	require.Equal(2, len(result.StructLogs))
+	assert.Nil(result.StructLogs[1].Stack)
+	assert.Nil(result.StructLogs[1].Storage)
+}
@@ -112,6 +112,8 @@ func (t *PandoRPCService) txCallback() {
 				}
 			}
 
+			t.notifyFinalizedBlock(block)
+
 			logger.Infof("Done processing finalized block, height=%v", block.Height)
 		case <-timer.C:
 			logger.Debugf("txCallbackManager.Trim()")
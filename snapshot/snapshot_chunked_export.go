@@ -0,0 +1,317 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	cns "github.com/pandotoken/pando/consensus"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto/sha3"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/store/database"
+	"github.com/pandotoken/pando/store/treestore"
+	"github.com/pandotoken/pando/store/trie"
+)
+
+const (
+	// ChunkedSnapshotVersion is the version of the chunked snapshot format
+	ChunkedSnapshotVersion = 5
+
+	// DefaultSnapshotChunkSize is the default maximal size of a snapshot chunk in bytes
+	DefaultSnapshotChunkSize = uint64(64 * 1024 * 1024)
+
+	// SnapshotManifestFile is the name of the manifest file in a chunked snapshot directory
+	SnapshotManifestFile = "manifest"
+)
+
+// SnapshotChunkFile returns the name of the file of the chunk with the given index.
+func SnapshotChunkFile(index int) string {
+	return fmt.Sprintf("chunk-%06d", index)
+}
+
+// ExportChunkedSnapshot exports the state at the given height into a chunked snapshot directory,
+// and returns the name of the directory. If baseHeight is not 0, only the trie nodes not present
+// in the state at baseHeight are exported, i.e. the snapshot is a delta to be applied on top of
+// the snapshot at baseHeight.
+func ExportChunkedSnapshot(db database.Database, consensus *cns.ConsensusEngine, chain *blockchain.Chain, snapshotDir string,
+	height uint64, baseHeight uint64, chunkSize uint64) (string, error) {
+	if chunkSize == 0 {
+		chunkSize = DefaultSnapshotChunkSize
+	}
+
+	lastFinalizedBlock, err := findSnapshotBlock(consensus, chain, height)
+	if err != nil {
+		return "", err
+	}
+
+	var baseBlock *core.ExtendedBlock
+	if baseHeight != 0 {
+		if baseHeight >= lastFinalizedBlock.Height {
+			return "", fmt.Errorf("Base height %v must be lower than the snapshot height %v", baseHeight, lastFinalizedBlock.Height)
+		}
+		baseBlock, err = findSnapshotBlock(consensus, chain, baseHeight)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	lastCheckpoint, lastCheckpointBlock, err := buildLastCheckpoint(chain, lastFinalizedBlock)
+	if err != nil {
+		return "", err
+	}
+	metadata, parentBlock, err := buildSnapshotMetadataV4(chain, db, lastFinalizedBlock)
+	if err != nil {
+		return "", err
+	}
+
+	currentTime := time.Now().UTC()
+	dirname := "pando_snapshot-" + strconv.FormatUint(lastFinalizedBlock.Height, 10) + "-" + lastFinalizedBlock.StateHash.String()
+	if baseBlock != nil {
+		dirname += "-delta-" + strconv.FormatUint(baseBlock.Height, 10)
+	}
+	dirname += "-" + currentTime.Format("2006-01-02")
+	dirPath := path.Join(snapshotDir, dirname)
+	if err := os.MkdirAll(dirPath, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	manifest := &core.SnapshotManifest{
+		Height:    lastFinalizedBlock.Height,
+		StateHash: lastFinalizedBlock.StateHash,
+	}
+	if baseBlock != nil {
+		manifest.BaseHeight = baseBlock.Height
+		manifest.BaseStateHash = baseBlock.StateHash
+	}
+
+	// -------------- Export the Chunks -------------- //
+
+	cw := newChunkWriter(dirPath, chunkSize, manifest)
+	baseStateHash := manifest.BaseStateHash
+
+	// Same tries as ExportSnapshotV4: the last checkpoint and parent states, and the full
+	// state of the snapshot block, including the account storages.
+	if lastFinalizedBlock.Height != lastCheckpointBlock.Height {
+		if err := cw.writeTrie(lastCheckpointBlock.StateHash, baseStateHash, db); err != nil {
+			return "", err
+		}
+	}
+	if err := cw.writeTrie(parentBlock.StateHash, baseStateHash, db); err != nil {
+		return "", err
+	}
+	if err := cw.writeTrie(lastFinalizedBlock.StateHash, parentBlock.StateHash, db); err != nil {
+		return "", err
+	}
+	if err := cw.writeAccountStorages(lastFinalizedBlock.StateHash, baseStateHash, db); err != nil {
+		return "", err
+	}
+	if err := cw.close(); err != nil {
+		return "", err
+	}
+
+	// -------------- Export the Manifest -------------- //
+
+	file, err := os.Create(path.Join(dirPath, SnapshotManifestFile))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+
+	snapshotHeader := &core.SnapshotHeader{
+		Magic:   core.SnapshotHeaderMagic,
+		Version: ChunkedSnapshotVersion,
+	}
+	if err = core.WriteSnapshotHeader(writer, snapshotHeader); err != nil {
+		return "", err
+	}
+	if err = core.WriteLastCheckpoint(writer, lastCheckpoint); err != nil {
+		return "", err
+	}
+	if err = core.WriteMetadata(writer, metadata); err != nil {
+		return "", err
+	}
+	if err = core.WriteManifest(writer, manifest); err != nil {
+		return "", err
+	}
+
+	logger.Infof("Exported chunked snapshot %v, height: %v, base height: %v, chunks: %v",
+		dirname, manifest.Height, manifest.BaseHeight, len(manifest.Chunks))
+
+	return dirname, nil
+}
+
+// chunkWriter splits the exported trie nodes into chunk files of bounded size.
+type chunkWriter struct {
+	dir       string
+	chunkSize uint64
+	manifest  *core.SnapshotManifest
+
+	file    *os.File
+	hasher  hash.Hash
+	counter *byteCounter
+	writer  *bufio.Writer
+	chunk   *core.SnapshotChunkInfo
+}
+
+// byteCounter counts the bytes written through it.
+type byteCounter struct {
+	n uint64
+}
+
+func (bc *byteCounter) Write(p []byte) (int, error) {
+	bc.n += uint64(len(p))
+	return len(p), nil
+}
+
+func newChunkWriter(dir string, chunkSize uint64, manifest *core.SnapshotManifest) *chunkWriter {
+	return &chunkWriter{
+		dir:       dir,
+		chunkSize: chunkSize,
+		manifest:  manifest,
+	}
+}
+
+// writeTrie writes the nodes of the trie with the given root which are not in the trie with
+// the base root. All the nodes are written if the base root is empty.
+func (cw *chunkWriter) writeTrie(root common.Hash, base common.Hash, db database.Database) error {
+	if root == base {
+		return nil
+	}
+	tr, err := trie.New(root, trie.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+	var it trie.NodeIterator
+	if !base.IsEmpty() {
+		baseTr, err := trie.New(base, trie.NewDatabase(db))
+		if err != nil {
+			return err
+		}
+		it, _ = trie.NewDifferenceIterator(baseTr.NodeIterator(nil), tr.NodeIterator(nil))
+	} else {
+		it = tr.NodeIterator(nil)
+	}
+
+	trieIndex := uint64(len(cw.manifest.Tries))
+	cw.manifest.Tries = append(cw.manifest.Tries, root)
+
+	for it.Next(true) {
+		if it.Hash() == (common.Hash{}) {
+			continue
+		}
+		hash := it.Hash()
+		val, err := db.Get(hash.Bytes())
+		if err != nil {
+			return err
+		}
+		pos := core.SnapshotTriePosition{Trie: trieIndex, Path: common.CopyBytes(it.Path())}
+		if err := cw.writeRecord(pos, hash.Bytes(), val); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+// writeAccountStorages writes the storage tries of the accounts in the state with the given root.
+// The storage tries which are unchanged from the base state are skipped.
+func (cw *chunkWriter) writeAccountStorages(root common.Hash, base common.Hash, db database.Database) error {
+	var baseStore *treestore.TreeStore
+	if !base.IsEmpty() {
+		baseStore = treestore.NewTreeStore(base, db)
+	}
+
+	var err error
+	treestore.NewTreeStore(root, db).Traverse(nil, func(k, v common.Bytes) bool {
+		if err != nil || !bytes.HasPrefix(k, []byte("ls/a")) {
+			return true
+		}
+		account := &types.Account{}
+		if err = types.FromBytes([]byte(v), account); err != nil {
+			return false
+		}
+		if account.Root == (common.Hash{}) {
+			return true
+		}
+
+		baseRoot := common.Hash{}
+		if baseStore != nil {
+			if raw := baseStore.Get(k); raw != nil {
+				baseAccount := &types.Account{}
+				if types.FromBytes(raw, baseAccount) == nil {
+					baseRoot = baseAccount.Root
+				}
+			}
+		}
+		err = cw.writeTrie(account.Root, baseRoot, db)
+		return err == nil
+	})
+	return err
+}
+
+func (cw *chunkWriter) writeRecord(pos core.SnapshotTriePosition, k, v common.Bytes) error {
+	if cw.chunk != nil && cw.chunk.Size >= cw.chunkSize {
+		if err := cw.close(); err != nil {
+			return err
+		}
+	}
+	if cw.chunk == nil {
+		if err := cw.open(pos); err != nil {
+			return err
+		}
+	}
+
+	// core.WriteRecord flushes the writer, so the counter is up to date
+	if err := core.WriteRecord(cw.writer, k, v); err != nil {
+		return err
+	}
+	cw.chunk.Size = cw.counter.n
+	cw.chunk.NumRecords++
+	cw.chunk.End = pos
+	return nil
+}
+
+func (cw *chunkWriter) open(start core.SnapshotTriePosition) error {
+	index := len(cw.manifest.Chunks)
+	file, err := os.Create(path.Join(cw.dir, SnapshotChunkFile(index)))
+	if err != nil {
+		return err
+	}
+	cw.file = file
+	cw.hasher = sha3.NewKeccak256()
+	cw.counter = &byteCounter{}
+	cw.writer = bufio.NewWriter(io.MultiWriter(file, cw.hasher, cw.counter))
+	cw.chunk = &core.SnapshotChunkInfo{Start: start, End: start}
+	return nil
+}
+
+// close finalizes the current chunk, if any, and adds it to the manifest.
+func (cw *chunkWriter) close() error {
+	if cw.chunk == nil {
+		return nil
+	}
+	defer func() {
+		cw.file.Close()
+		cw.file = nil
+		cw.hasher = nil
+		cw.counter = nil
+		cw.writer = nil
+		cw.chunk = nil
+	}()
+
+	if err := cw.writer.Flush(); err != nil {
+		return err
+	}
+	cw.chunk.Hash = common.BytesToHash(cw.hasher.Sum(nil))
+	cw.manifest.Chunks = append(cw.manifest.Chunks, *cw.chunk)
+	return nil
+}
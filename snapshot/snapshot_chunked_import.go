@@ -0,0 +1,266 @@
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/crypto/sha3"
+	"github.com/pandotoken/pando/store/database"
+	"github.com/pandotoken/pando/store/trie"
+)
+
+// DBSnapshotChunkPrefix is the prefix of the keys recording the chunks already imported, so that
+// an interrupted import can be resumed.
+const DBSnapshotChunkPrefix = "/snapshot_chunk/"
+
+// chunkedSnapshot holds the content of the manifest file of a chunked snapshot.
+type chunkedSnapshot struct {
+	dir            string
+	lastCheckpoint core.LastCheckpoint
+	metadata       core.SnapshotMetadata
+	manifest       core.SnapshotManifest
+}
+
+// IsChunkedSnapshot returns whether the given path is a chunked snapshot directory.
+func IsChunkedSnapshot(snapshotPath string) bool {
+	info, err := os.Stat(path.Join(snapshotPath, SnapshotManifestFile))
+	return err == nil && !info.IsDir()
+}
+
+// LoadSnapshotManifest reads the manifest of the chunked snapshot in the given directory.
+func LoadSnapshotManifest(dir string) (*core.SnapshotManifest, error) {
+	cs, err := readChunkedSnapshot(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &cs.manifest, nil
+}
+
+func readChunkedSnapshot(dir string) (*chunkedSnapshot, error) {
+	file, err := os.Open(path.Join(dir, SnapshotManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	snapshotHeader := &core.SnapshotHeader{}
+	if _, err = core.ReadRecord(file, snapshotHeader); err != nil {
+		return nil, fmt.Errorf("Failed to read snapshot header, %v", err)
+	}
+	if snapshotHeader.Magic != core.SnapshotHeaderMagic || snapshotHeader.Version != ChunkedSnapshotVersion {
+		return nil, fmt.Errorf("Not a chunked snapshot, magic: %v, version: %v", snapshotHeader.Magic, snapshotHeader.Version)
+	}
+
+	cs := &chunkedSnapshot{dir: dir}
+	if _, err = core.ReadRecord(file, &cs.lastCheckpoint); err != nil {
+		return nil, fmt.Errorf("Failed to read snapshot last checkpoint, %v", err)
+	}
+	if _, err = core.ReadRecord(file, &cs.metadata); err != nil {
+		return nil, fmt.Errorf("Failed to read snapshot metadata, %v", err)
+	}
+	if _, err = core.ReadRecord(file, &cs.manifest); err != nil {
+		return nil, fmt.Errorf("Failed to read snapshot manifest, %v", err)
+	}
+
+	// The manifest is committed to by the state hash of the snapshot block
+	snapshotBlockHeader := cs.metadata.TailTrio.Second.Header
+	if snapshotBlockHeader == nil {
+		return nil, fmt.Errorf("Snapshot block header is missing")
+	}
+	if cs.manifest.Height != snapshotBlockHeader.Height || cs.manifest.StateHash != snapshotBlockHeader.StateHash {
+		return nil, fmt.Errorf("Manifest does not match the snapshot block, height: %v vs %v, state hash: %v vs %v",
+			cs.manifest.Height, snapshotBlockHeader.Height, cs.manifest.StateHash.Hex(), snapshotBlockHeader.StateHash.Hex())
+	}
+	for idx, chunk := range cs.manifest.Chunks {
+		if chunk.Start.Trie >= uint64(len(cs.manifest.Tries)) || chunk.End.Trie >= uint64(len(cs.manifest.Tries)) {
+			return nil, fmt.Errorf("Chunk %v refers to an unknown trie", idx)
+		}
+	}
+	return cs, nil
+}
+
+// VerifySnapshotChunk checks the chunk file matches its description in the manifest, and that
+// each of its records is a trie node keyed by its hash.
+func VerifySnapshotChunk(filePath string, info *core.SnapshotChunkInfo) error {
+	return readSnapshotChunk(filePath, info, nil)
+}
+
+// readSnapshotChunk verifies the chunk file and passes its records to the callback, if any.
+// The records are only passed once the whole chunk is verified.
+func readSnapshotChunk(filePath string, info *core.SnapshotChunkInfo, cb func(record *core.SnapshotTrieRecord) error) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if uint64(fileInfo.Size()) != info.Size {
+		return fmt.Errorf("Chunk size mismatch: %v vs %v", fileInfo.Size(), info.Size)
+	}
+	hasher := sha3.NewKeccak256()
+	if _, err = io.Copy(hasher, file); err != nil {
+		return err
+	}
+	if hash := common.BytesToHash(hasher.Sum(nil)); hash != info.Hash {
+		return fmt.Errorf("Chunk hash mismatch: %v vs %v", hash.Hex(), info.Hash.Hex())
+	}
+
+	if _, err = file.Seek(0, 0); err != nil {
+		return err
+	}
+	records := []*core.SnapshotTrieRecord{}
+	for {
+		record := &core.SnapshotTrieRecord{}
+		if _, err := core.ReadRecord(file, record); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("Failed to read chunk record, %v", err)
+		}
+		if !bytes.Equal(crypto.Keccak256Hash(record.V).Bytes(), record.K) {
+			return fmt.Errorf("Chunk record is not a trie node, key: %v", common.Bytes2Hex(record.K))
+		}
+		records = append(records, record)
+	}
+	if uint64(len(records)) != info.NumRecords {
+		return fmt.Errorf("Chunk record count mismatch: %v vs %v", len(records), info.NumRecords)
+	}
+
+	if cb != nil {
+		for _, record := range records {
+			if err := cb(record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateChunkedSnapshot checks the manifest and the chunk files of a chunked snapshot. The
+// state is checked against the snapshot block when the snapshot is imported.
+func validateChunkedSnapshot(dir string) (*core.BlockHeader, error) {
+	cs, err := readChunkedSnapshot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	blockHash := cs.metadata.TailTrio.Second.Header.Hash()
+	if err := VerifyStateCheckpoint(blockHash, &cs.lastCheckpoint, &cs.metadata); err != nil {
+		return nil, err
+	}
+
+	for idx := range cs.manifest.Chunks {
+		if err := VerifySnapshotChunk(path.Join(dir, SnapshotChunkFile(idx)), &cs.manifest.Chunks[idx]); err != nil {
+			return nil, fmt.Errorf("Invalid snapshot chunk %v: %v", idx, err)
+		}
+	}
+	return cs.metadata.TailTrio.Second.Header, nil
+}
+
+func snapshotChunkKey(manifestHash common.Hash, index int) []byte {
+	return []byte(DBSnapshotChunkPrefix + manifestHash.Hex() + "/" + strconv.Itoa(index))
+}
+
+// loadChunkedSnapshot imports the chunked snapshot chunk by chunk. The chunks already imported
+// are skipped, so an interrupted import resumes where it stopped.
+func loadChunkedSnapshot(dir string, db database.Database, logStr string) (*core.BlockHeader, *core.SnapshotMetadata, error) {
+	cs, err := readChunkedSnapshot(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest := &cs.manifest
+	metadata := &cs.metadata
+
+	if manifest.IsDelta() {
+		if has, err := db.Has(manifest.BaseStateHash.Bytes()); err != nil || !has {
+			return nil, nil, fmt.Errorf("Base state %v at height %v of the delta snapshot not found",
+				manifest.BaseStateHash.Hex(), manifest.BaseHeight)
+		}
+	}
+
+	// ------------------------------ Load Chunks ------------------------------ //
+
+	manifestHash := manifest.Hash()
+	numChunks := len(manifest.Chunks)
+	for idx := range manifest.Chunks {
+		chunkKey := snapshotChunkKey(manifestHash, idx)
+		if done, _ := db.Has(chunkKey); done {
+			logger.Debugf("%s, chunk %v already imported", logStr, idx)
+			continue
+		}
+
+		batch := db.NewBatch()
+		err := readSnapshotChunk(path.Join(dir, SnapshotChunkFile(idx)), &manifest.Chunks[idx], func(record *core.SnapshotTrieRecord) error {
+			if err := batch.Put(record.K, record.V); err != nil {
+				return fmt.Errorf("Failed to write snapshot record, %v", err)
+			}
+			// Set the ref count to 3 to be conservative as we have 3 state tries in the snapshot
+			for i := 0; i < 3; i++ {
+				if err := batch.Reference(record.K); err != nil {
+					return fmt.Errorf("Failed to create reference of snapshot record, %v", err)
+				}
+			}
+			if batch.ValueSize() > database.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					return err
+				}
+				batch.Reset()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to import snapshot chunk %v: %v", idx, err)
+		}
+
+		// Only mark the chunk as imported once all of its records are written
+		if err := batch.Put(chunkKey, []byte{1}); err != nil {
+			return nil, nil, err
+		}
+		if err := batch.Write(); err != nil {
+			return nil, nil, err
+		}
+		logger.Infof("%s, chunk %v/%v done.", logStr, idx+1, numChunks)
+	}
+
+	// ------------------- Validity Checks and Tail Blocks  ------------------- //
+
+	for _, root := range manifest.Tries {
+		if err := checkTrieComplete(root, db); err != nil {
+			return nil, nil, fmt.Errorf("Snapshot state is incomplete: %v", err)
+		}
+	}
+
+	secondBlockHeader, err := ImportStateCheckpoint(&cs.lastCheckpoint, metadata, db)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Snapshot validation failed: %v", err)
+	}
+
+	for idx := range manifest.Chunks {
+		db.Delete(snapshotChunkKey(manifestHash, idx))
+	}
+
+	return secondBlockHeader, metadata, nil
+}
+
+// checkTrieComplete checks all the nodes of the trie with the given root are in the db.
+func checkTrieComplete(root common.Hash, db database.Database) error {
+	tr, err := trie.New(root, trie.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+	it := tr.NodeIterator(nil)
+	for it.Next(true) {
+	}
+	return it.Error()
+}
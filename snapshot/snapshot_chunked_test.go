@@ -0,0 +1,123 @@
+package snapshot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/store/database"
+	"github.com/pandotoken/pando/store/database/backend"
+	"github.com/pandotoken/pando/store/treestore"
+)
+
+func buildTestTrie(t *testing.T, db database.Database, root common.Hash, from, to int) common.Hash {
+	tree := treestore.NewTreeStore(root, db)
+	for i := from; i < to; i++ {
+		tree.Set(common.Bytes(fmt.Sprintf("key-%04d", i)), common.Bytes(fmt.Sprintf("value-%04d", i)))
+	}
+	hash, err := tree.Commit()
+	require.Nil(t, err)
+	return hash
+}
+
+func writeTestChunks(t *testing.T, db database.Database, root, base common.Hash) (string, *core.SnapshotManifest) {
+	dir, err := ioutil.TempDir("", "snapshot_chunks")
+	require.Nil(t, err)
+
+	manifest := &core.SnapshotManifest{}
+	cw := newChunkWriter(dir, 1024, manifest)
+	require.Nil(t, cw.writeTrie(root, base, db))
+	require.Nil(t, cw.close())
+	return dir, manifest
+}
+
+func importTestChunks(t *testing.T, dir string, manifest *core.SnapshotManifest, db database.Database) {
+	for idx := range manifest.Chunks {
+		err := readSnapshotChunk(path.Join(dir, SnapshotChunkFile(idx)), &manifest.Chunks[idx], func(record *core.SnapshotTrieRecord) error {
+			return db.Put(record.K, record.V)
+		})
+		require.Nil(t, err)
+	}
+}
+
+func TestChunkedSnapshotFull(t *testing.T) {
+	assert := assert.New(t)
+
+	db := backend.NewMemDatabase()
+	root := buildTestTrie(t, db, common.Hash{}, 0, 500)
+
+	dir, manifest := writeTestChunks(t, db, root, common.Hash{})
+	defer os.RemoveAll(dir)
+
+	assert.True(len(manifest.Chunks) > 1)
+	assert.Equal([]common.Hash{root}, manifest.Tries)
+	for idx := range manifest.Chunks {
+		assert.Nil(VerifySnapshotChunk(path.Join(dir, SnapshotChunkFile(idx)), &manifest.Chunks[idx]))
+	}
+
+	newdb := backend.NewMemDatabase()
+	assert.NotNil(checkTrieComplete(root, newdb))
+	importTestChunks(t, dir, manifest, newdb)
+	assert.Nil(checkTrieComplete(root, newdb))
+}
+
+func TestChunkedSnapshotDelta(t *testing.T) {
+	assert := assert.New(t)
+
+	db := backend.NewMemDatabase()
+	base := buildTestTrie(t, db, common.Hash{}, 0, 500)
+	root := buildTestTrie(t, db, base, 500, 520)
+
+	fullDir, fullManifest := writeTestChunks(t, db, root, common.Hash{})
+	defer os.RemoveAll(fullDir)
+	deltaDir, deltaManifest := writeTestChunks(t, db, root, base)
+	defer os.RemoveAll(deltaDir)
+
+	numRecords := func(manifest *core.SnapshotManifest) (n uint64) {
+		for _, chunk := range manifest.Chunks {
+			n += chunk.NumRecords
+		}
+		return
+	}
+	assert.True(numRecords(deltaManifest) < numRecords(fullManifest))
+
+	// The delta only completes the state on top of the base state
+	newdb := backend.NewMemDatabase()
+	importTestChunks(t, deltaDir, deltaManifest, newdb)
+	assert.NotNil(checkTrieComplete(root, newdb))
+
+	newdb = backend.NewMemDatabase()
+	buildTestTrie(t, newdb, common.Hash{}, 0, 500)
+	importTestChunks(t, deltaDir, deltaManifest, newdb)
+	assert.Nil(checkTrieComplete(root, newdb))
+}
+
+func TestVerifySnapshotChunk(t *testing.T) {
+	assert := assert.New(t)
+
+	db := backend.NewMemDatabase()
+	root := buildTestTrie(t, db, common.Hash{}, 0, 100)
+	dir, manifest := writeTestChunks(t, db, root, common.Hash{})
+	defer os.RemoveAll(dir)
+
+	chunkPath := path.Join(dir, SnapshotChunkFile(0))
+	chunk := manifest.Chunks[0]
+	assert.Nil(VerifySnapshotChunk(chunkPath, &chunk))
+
+	// Tampered content
+	raw, err := ioutil.ReadFile(chunkPath)
+	require.Nil(t, err)
+	raw[len(raw)-1] ^= 0xff
+	require.Nil(t, ioutil.WriteFile(chunkPath, raw, 0644))
+	assert.NotNil(VerifySnapshotChunk(chunkPath, &chunk))
+
+	// Truncated content
+	require.Nil(t, ioutil.WriteFile(chunkPath, raw[:len(raw)/2], 0644))
+	assert.NotNil(VerifySnapshotChunk(chunkPath, &chunk))
+}
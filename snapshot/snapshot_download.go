@@ -0,0 +1,103 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// DownloadChunkedSnapshot downloads the chunked snapshot served at baseURL into the given directory.
+// The chunks already downloaded and verified are skipped, and the partially downloaded chunks are
+// resumed, so the download can be restarted after an interruption.
+func DownloadChunkedSnapshot(baseURL string, dir string) error {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	manifestPath := path.Join(dir, SnapshotManifestFile)
+	if err := downloadFile(baseURL+"/"+SnapshotManifestFile, manifestPath+".tmp", false); err != nil {
+		return fmt.Errorf("Failed to download snapshot manifest: %v", err)
+	}
+	if err := os.Rename(manifestPath+".tmp", manifestPath); err != nil {
+		return err
+	}
+	manifest, err := LoadSnapshotManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	numChunks := len(manifest.Chunks)
+	for idx := range manifest.Chunks {
+		chunk := &manifest.Chunks[idx]
+		chunkFile := SnapshotChunkFile(idx)
+		chunkPath := path.Join(dir, chunkFile)
+		if VerifySnapshotChunk(chunkPath, chunk) == nil {
+			logger.Debugf("Snapshot chunk %v already downloaded", idx)
+			continue
+		}
+
+		// Resume the partial chunk file, unless it is already complete or longer than expected
+		resume := false
+		if info, err := os.Stat(chunkPath); err == nil && uint64(info.Size()) < chunk.Size {
+			resume = true
+		}
+		if err := downloadFile(baseURL+"/"+chunkFile, chunkPath, resume); err != nil {
+			return fmt.Errorf("Failed to download snapshot chunk %v: %v", idx, err)
+		}
+		if err := VerifySnapshotChunk(chunkPath, chunk); err != nil {
+			os.Remove(chunkPath)
+			return fmt.Errorf("Invalid snapshot chunk %v: %v", idx, err)
+		}
+		logger.Infof("Downloaded snapshot chunk %v/%v", idx+1, numChunks)
+	}
+	return nil
+}
+
+// downloadFile downloads the file at the URL. If resume is true, only the bytes after the end of
+// the local file are requested.
+func downloadFile(url string, filePath string, resume bool) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	if resume {
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return err
+		}
+		offset = info.Size()
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resume && resp.StatusCode == http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusOK:
+		// The server does not support range requests, download the whole file again
+		flags |= os.O_TRUNC
+	default:
+		return fmt.Errorf("Unexpected HTTP status: %v", resp.Status)
+	}
+
+	file, err := os.OpenFile(filePath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, resp.Body)
+	return err
+}
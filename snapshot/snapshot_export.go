@@ -455,26 +455,9 @@ func ExportSnapshotV3(db database.Database, consensus *cns.ConsensusEngine, chai
 }
 
 func ExportSnapshotV4(db database.Database, consensus *cns.ConsensusEngine, chain *blockchain.Chain, snapshotDir string, height uint64) (string, error) {
-	var lastFinalizedBlock *core.ExtendedBlock
-	if height != 0 {
-		blocks := chain.FindBlocksByHeight(height)
-		for _, block := range blocks {
-			if block.Status.IsDirectlyFinalized() {
-				lastFinalizedBlock = block
-				break
-			}
-		}
-		if lastFinalizedBlock == nil {
-			return "", fmt.Errorf("Can't find finalized block at height %v", height)
-		}
-	} else {
-		stub := consensus.GetSummary()
-		var err error
-		lastFinalizedBlock, err = chain.FindBlock(stub.LastFinalizedBlock)
-		if err != nil {
-			logger.Errorf("Failed to get block %v, %v", stub.LastFinalizedBlock, err)
-			return "", err
-		}
+	lastFinalizedBlock, err := findSnapshotBlock(consensus, chain, height)
+	if err != nil {
+		return "", err
 	}
 	sv := state.NewStoreView(lastFinalizedBlock.Height, lastFinalizedBlock.BlockHeader.StateHash, db)
 
@@ -501,84 +484,127 @@ func ExportSnapshotV4(db database.Database, consensus *cns.ConsensusEngine, chai
 
 	// ------------ Export the Last Checkpoint Section ------------- //
 
-	lastFinalizedBlockHeight := lastFinalizedBlock.Height
-	lastCheckpointHeight := common.LastCheckPointHeight(lastFinalizedBlockHeight)
+	lastCheckpoint, lastCheckpointBlock, err := buildLastCheckpoint(chain, lastFinalizedBlock)
+	if err != nil {
+		return "", err
+	}
+
+	err = core.WriteLastCheckpoint(writer, lastCheckpoint)
+	if err != nil {
+		return "", err
+	}
+
+	// -------------- Export the Metadata Section -------------- //
+
+	metadata, parentBlock, err := buildSnapshotMetadataV4(chain, db, lastFinalizedBlock)
+	if err != nil {
+		return "", err
+	}
+
+	err = core.WriteMetadata(writer, metadata)
+	if err != nil {
+		return "", err
+	}
+
+	// -------------- Export the StoreView Section -------------- //
+	// Last checkpoint storeview
+	if lastFinalizedBlock.Height != lastCheckpointBlock.Height {
+		lastCheckpointSV := state.NewStoreView(lastCheckpointBlock.Height, lastCheckpointBlock.StateHash, db)
+		writeStoreViewV3(lastCheckpointSV, false, writer, db, common.Hash{})
+	}
+
+	// Parent block storeview
+	parentSV := state.NewStoreView(parentBlock.Height, parentBlock.StateHash, db)
+	writeStoreViewV3(parentSV, false, writer, db, common.Hash{})
+
+	writeStoreViewV3(sv, true, writer, db, parentSV.Hash())
+
+	return filename, nil
+}
+
+// findSnapshotBlock returns the directly finalized block at the given height, or the last
+// finalized block if the height is 0.
+func findSnapshotBlock(consensus *cns.ConsensusEngine, chain *blockchain.Chain, height uint64) (*core.ExtendedBlock, error) {
+	if height != 0 {
+		blocks := chain.FindBlocksByHeight(height)
+		for _, block := range blocks {
+			if block.Status.IsDirectlyFinalized() {
+				return block, nil
+			}
+		}
+		return nil, fmt.Errorf("Can't find finalized block at height %v", height)
+	}
+
+	stub := consensus.GetSummary()
+	lastFinalizedBlock, err := chain.FindBlock(stub.LastFinalizedBlock)
+	if err != nil {
+		logger.Errorf("Failed to get block %v, %v", stub.LastFinalizedBlock, err)
+		return nil, err
+	}
+	return lastFinalizedBlock, nil
+}
+
+// buildLastCheckpoint collects the headers from the last checkpoint up to the snapshot block.
+// It also returns the last checkpoint block.
+func buildLastCheckpoint(chain *blockchain.Chain, lastFinalizedBlock *core.ExtendedBlock) (*core.LastCheckpoint, *core.ExtendedBlock, error) {
+	var err error
+	lastCheckpointHeight := common.LastCheckPointHeight(lastFinalizedBlock.Height)
 	lastCheckpoint := &core.LastCheckpoint{}
 
-	currHeight := lastFinalizedBlockHeight
+	currHeight := lastFinalizedBlock.Height
 	currBlock := lastFinalizedBlock
 	for currHeight > lastCheckpointHeight {
 		parentHash := currBlock.Parent
 		currBlock, err = chain.FindBlock(parentHash)
 		if err != nil {
 			logger.Errorf("Failed to get intermediate block %v, %v", parentHash.Hex(), err)
-			return "", err
+			return nil, nil, err
 		}
 		lastCheckpoint.IntermediateHeaders = append(lastCheckpoint.IntermediateHeaders, currBlock.Block.BlockHeader)
 		currHeight = currBlock.Height
 	}
 
-	lastCheckpointBlock := currBlock
-
-	lastCheckpoint.CheckpointHeader = lastCheckpointBlock.BlockHeader
-
-	err = core.WriteLastCheckpoint(writer, lastCheckpoint)
-	if err != nil {
-		return "", err
-	}
-
-	// -------------- Export the Metadata Section -------------- //
+	lastCheckpoint.CheckpointHeader = currBlock.BlockHeader
+	return lastCheckpoint, currBlock, nil
+}
 
+// buildSnapshotMetadataV4 builds the tail trio proving the snapshot block is finalized. It also
+// returns the parent of the snapshot block.
+func buildSnapshotMetadataV4(chain *blockchain.Chain, db database.Database, lastFinalizedBlock *core.ExtendedBlock) (*core.SnapshotMetadata, *core.ExtendedBlock, error) {
 	metadata := &core.SnapshotMetadata{}
 
 	parentBlock, err := chain.FindBlock(lastFinalizedBlock.Parent)
 	if err != nil {
-		return "", fmt.Errorf("Failed to find last finalized block's parent, %v", err)
+		return nil, nil, fmt.Errorf("Failed to find last finalized block's parent, %v", err)
 	}
 	childBlock, err := getAtLeastCommittedChild(lastFinalizedBlock, chain)
 	if err != nil {
-		return "", fmt.Errorf("Failed to find last finalized block's committed child, %v", err)
+		return nil, nil, fmt.Errorf("Failed to find last finalized block's committed child, %v", err)
+	}
+	if childBlock == nil {
+		return nil, nil, fmt.Errorf("Last finalized block %v has no committed child", lastFinalizedBlock.Hash().Hex())
 	}
 
 	if lastFinalizedBlock.HCC.BlockHash != parentBlock.Hash() {
-		return "", fmt.Errorf("Parent block hash mismatch: %v vs %v", lastFinalizedBlock.HCC.BlockHash, parentBlock.Hash())
+		return nil, nil, fmt.Errorf("Parent block hash mismatch: %v vs %v", lastFinalizedBlock.HCC.BlockHash, parentBlock.Hash())
 	}
 
 	if childBlock.HCC.BlockHash != lastFinalizedBlock.Hash() {
-		return "", fmt.Errorf("Finalized block hash mismatch: %v vs %v", childBlock.HCC.BlockHash, lastFinalizedBlock.Hash())
+		return nil, nil, fmt.Errorf("Finalized block hash mismatch: %v vs %v", childBlock.HCC.BlockHash, lastFinalizedBlock.Hash())
 	}
 
 	childVoteSet := chain.FindVotesByHash(childBlock.Hash())
 
 	vcpProof, err := proveVCP(parentBlock, db)
 	if err != nil {
-		return "", fmt.Errorf("Failed to get VCP Proof")
+		return nil, nil, fmt.Errorf("Failed to get VCP Proof")
 	}
 	metadata.TailTrio = core.SnapshotBlockTrio{
 		First:  core.SnapshotFirstBlock{Header: parentBlock.BlockHeader, Proof: *vcpProof},
 		Second: core.SnapshotSecondBlock{Header: lastFinalizedBlock.BlockHeader},
 		Third:  core.SnapshotThirdBlock{Header: childBlock.BlockHeader, VoteSet: childVoteSet},
 	}
-
-	err = core.WriteMetadata(writer, metadata)
-	if err != nil {
-		return "", err
-	}
-
-	// -------------- Export the StoreView Section -------------- //
-	// Last checkpoint storeview
-	if lastFinalizedBlock.Height != lastCheckpointHeight {
-		lastCheckpointSV := state.NewStoreView(lastCheckpointBlock.Height, lastCheckpointBlock.StateHash, db)
-		writeStoreViewV3(lastCheckpointSV, false, writer, db, common.Hash{})
-	}
-
-	// Parent block storeview
-	parentSV := state.NewStoreView(parentBlock.Height, parentBlock.StateHash, db)
-	writeStoreViewV3(parentSV, false, writer, db, common.Hash{})
-
-	writeStoreViewV3(sv, true, writer, db, parentSV.Hash())
-
-	return filename, nil
+	return metadata, parentBlock, nil
 }
 
 func proveVCP(block *core.ExtendedBlock, db database.Database) (*core.VCPProof, error) {
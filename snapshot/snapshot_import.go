@@ -95,6 +95,13 @@ func ImportSnapshot(snapshotFilePath, chainImportDirPath, chainCorrectionPath st
 func ValidateSnapshot(snapshotFilePath, chainImportDirPath, chainCorrectionPath string) (*core.BlockHeader, error) {
 	logger.Infof("Verifying snapshot: %v", snapshotFilePath)
 
+	// A chunked snapshot could be a delta of a snapshot which is not in the temporary database, and
+	// its chunks are verified one by one while importing. The chain import and chain correction, if
+	// any, are also validated while importing.
+	if IsChunkedSnapshot(snapshotFilePath) {
+		return validateChunkedSnapshot(snapshotFilePath)
+	}
+
 	tmpdbRoot, err := ioutil.TempDir("", "tmpdb")
 	if err != nil {
 		log.Panicf("Failed to create temporary db for snapshot verification: %v", err)
@@ -143,6 +150,11 @@ func ValidateSnapshot(snapshotFilePath, chainImportDirPath, chainCorrectionPath
 func LoadSnapshotCheckpointHeader(snapshotFilePath string) *core.BlockHeader {
 	var err error
 
+	// The manifest of a chunked snapshot starts with the same sections as a snapshot file
+	if IsChunkedSnapshot(snapshotFilePath) {
+		snapshotFilePath = path.Join(snapshotFilePath, SnapshotManifestFile)
+	}
+
 	snapshotFile, err := os.Open(snapshotFilePath)
 	if err != nil {
 		return nil
@@ -173,6 +185,10 @@ func LoadSnapshotCheckpointHeader(snapshotFilePath string) *core.BlockHeader {
 func loadSnapshot(snapshotFilePath string, db database.Database, logStr string) (*core.BlockHeader, *core.SnapshotMetadata, error) {
 	var err error
 
+	if IsChunkedSnapshot(snapshotFilePath) {
+		return loadChunkedSnapshot(snapshotFilePath, db, logStr)
+	}
+
 	snapshotFile, err := os.Open(snapshotFilePath)
 	if err != nil {
 		return nil, nil, err
@@ -200,39 +216,7 @@ func loadSnapshot(snapshotFilePath string, db database.Database, logStr string)
 		if err != nil {
 			return nil, nil, fmt.Errorf("Failed to load snapshot last checkpoint, %v", err)
 		}
-
-		ckb := core.Block{
-			BlockHeader: lastCheckpoint.CheckpointHeader,
-		}
-		eckb := core.ExtendedBlock{
-			Block:  &ckb,
-			Status: core.BlockStatusTrusted, // HCC links between all three blocks
-		}
-		ckbHash := ckb.BlockHeader.Hash()
-
-		existingCkbExt := core.ExtendedBlock{}
-		if kvstore.Get(ckbHash[:], &existingCkbExt) != nil {
-			logger.Infof("Saving the last checkpoint block: %v", ckbHash.Hex())
-			err = kvstore.Put(ckbHash[:], &eckb)
-			if err != nil {
-				logger.Panicf("Failed to save the last checkpoint: %v, err: %v", ckbHash.Hex(), err)
-			}
-		}
-
-		for _, intermediateHeader := range lastCheckpoint.IntermediateHeaders {
-			ibHash := intermediateHeader.Hash()
-			eib := core.ExtendedBlock{
-				Block: &core.Block{BlockHeader: intermediateHeader},
-			}
-			existingEib := core.ExtendedBlock{}
-			if kvstore.Get(ibHash[:], &existingEib) != nil {
-				logger.Debugf("Saving intermediate blocks: %v", ibHash.Hex())
-				err = kvstore.Put(ibHash[:], &eib)
-				if err != nil {
-					logger.Panicf("Failed to save ntermediate block: %v, err: %v", ibHash.Hex(), err)
-				}
-			}
-		}
+		saveLastCheckpointBlocks(&lastCheckpoint, kvstore)
 	}
 
 	metadata := core.SnapshotMetadata{}
@@ -298,6 +282,42 @@ func loadSnapshot(snapshotFilePath string, db database.Database, logStr string)
 	return secondBlockHeader, &metadata, nil
 }
 
+// saveLastCheckpointBlocks saves the last checkpoint block and the intermediate blocks up to the snapshot block.
+func saveLastCheckpointBlocks(lastCheckpoint *core.LastCheckpoint, kvstore store.Store) {
+	ckb := core.Block{
+		BlockHeader: lastCheckpoint.CheckpointHeader,
+	}
+	eckb := core.ExtendedBlock{
+		Block:  &ckb,
+		Status: core.BlockStatusTrusted, // HCC links between all three blocks
+	}
+	ckbHash := ckb.BlockHeader.Hash()
+
+	existingCkbExt := core.ExtendedBlock{}
+	if kvstore.Get(ckbHash[:], &existingCkbExt) != nil {
+		logger.Infof("Saving the last checkpoint block: %v", ckbHash.Hex())
+		err := kvstore.Put(ckbHash[:], &eckb)
+		if err != nil {
+			logger.Panicf("Failed to save the last checkpoint: %v, err: %v", ckbHash.Hex(), err)
+		}
+	}
+
+	for _, intermediateHeader := range lastCheckpoint.IntermediateHeaders {
+		ibHash := intermediateHeader.Hash()
+		eib := core.ExtendedBlock{
+			Block: &core.Block{BlockHeader: intermediateHeader},
+		}
+		existingEib := core.ExtendedBlock{}
+		if kvstore.Get(ibHash[:], &existingEib) != nil {
+			logger.Debugf("Saving intermediate blocks: %v", ibHash.Hex())
+			err := kvstore.Put(ibHash[:], &eib)
+			if err != nil {
+				logger.Panicf("Failed to save ntermediate block: %v, err: %v", ibHash.Hex(), err)
+			}
+		}
+	}
+}
+
 func LoadChainCorrection(chainImportDirPath string, snapshotBlockHeader *core.BlockHeader, metadata *core.SnapshotMetadata, chain *blockchain.Chain, db database.Database, ledger *ledger.Ledger) (headBlock, tailBlock *core.ExtendedBlock, err error) {
 	chainFile, err := os.Open(chainImportDirPath)
 	if err != nil {
@@ -513,7 +533,7 @@ func loadChainSegment(filePath string, start, end uint64, prevBlock *core.Extend
 				if proofTrio.First.Header.Height == core.GenesisBlockHeight {
 					provenValSet, err = checkGenesisBlock(proofTrio.Second.Header, db)
 				} else {
-					provenValSet, err = getValidatorSetFromVCPProof(proofTrio.First.Header.StateHash, &proofTrio.First.Proof)
+					provenValSet, err = GetValidatorSetFromVCPProof(proofTrio.First.Header.StateHash, &proofTrio.First.Proof)
 				}
 				if err != nil {
 					return nil, fmt.Errorf("Failed to retrieve validator set from VCP proof: %v", err)
@@ -810,7 +830,7 @@ func checkSnapshotV4(sv *state.StoreView, metadata *core.SnapshotMetadata, db da
 	var err error
 
 	first := tailTrio.First
-	valSet, err = getValidatorSetFromVCPProof(first.Header.StateHash, &first.Proof)
+	valSet, err = GetValidatorSetFromVCPProof(first.Header.StateHash, &first.Proof)
 	if err != nil {
 		return fmt.Errorf("Failed to retrieve validator set from VCP proof: %v", err)
 	}
@@ -833,7 +853,6 @@ func checkProofTrios(proofTrios []core.SnapshotBlockTrio, db database.Database)
 	for idx, blockTrio := range proofTrios {
 		first := blockTrio.First
 		second := blockTrio.Second
-		third := blockTrio.Third
 		if idx == 0 {
 			// special handling for the genesis block
 			provenValSet, err = checkGenesisBlock(second.Header, db)
@@ -841,22 +860,9 @@ func checkProofTrios(proofTrios []core.SnapshotBlockTrio, db database.Database)
 				return nil, fmt.Errorf("Invalid genesis block: %v", err)
 			}
 		} else {
-			if second.Header.Parent != first.Header.Hash() || third.Header.Parent != second.Header.Hash() {
-				return nil, fmt.Errorf("block trio has invalid Parent link")
-			}
-
-			if second.Header.HCC.BlockHash != first.Header.Hash() || third.Header.HCC.BlockHash != second.Header.Hash() {
-				return nil, fmt.Errorf("block trio has invalid HCC link: %v, %v; %v, %v", first.Header.Hash(), second.Header.HCC.BlockHash,
-					second.Header.Hash(), third.Header.HCC.BlockHash)
-			}
-
-			// third.Header.HCC.Votes contains the votes for the second block in the trio
-			if err := validateVotes(provenValSet, second.Header, third.Header.HCC.Votes); err != nil {
-				return nil, fmt.Errorf("Failed to validate voteSet, %v", err)
-			}
-			provenValSet, err = getValidatorSetFromVCPProof(first.Header.StateHash, &first.Proof)
+			provenValSet, err = CheckBlockTrio(provenValSet, &proofTrios[idx])
 			if err != nil {
-				return nil, fmt.Errorf("Failed to retrieve validator set from VCP proof: %v", err)
+				return nil, err
 			}
 		}
 
@@ -866,6 +872,38 @@ func checkProofTrios(proofTrios []core.SnapshotBlockTrio, db database.Database)
 	return provenValSet, nil
 }
 
+// CheckBlockTrio checks the first block of the trio is finalized: the second block is linked
+// to it by both its Parent and its HCC, and is committed by the votes of the given validator set
+// carried in the HCC of the third block. It returns the validator set proven by the VCP proof
+// in the state of the first block, which is in effect from two blocks after the first block.
+func CheckBlockTrio(provenValSet *core.ValidatorSet, blockTrio *core.SnapshotBlockTrio) (*core.ValidatorSet, error) {
+	first := blockTrio.First
+	second := blockTrio.Second
+	third := blockTrio.Third
+	if first.Header == nil || second.Header == nil || third.Header == nil {
+		return nil, fmt.Errorf("block trio is incomplete")
+	}
+
+	if second.Header.Parent != first.Header.Hash() || third.Header.Parent != second.Header.Hash() {
+		return nil, fmt.Errorf("block trio has invalid Parent link")
+	}
+
+	if second.Header.HCC.BlockHash != first.Header.Hash() || third.Header.HCC.BlockHash != second.Header.Hash() {
+		return nil, fmt.Errorf("block trio has invalid HCC link: %v, %v; %v, %v", first.Header.Hash(), second.Header.HCC.BlockHash,
+			second.Header.Hash(), third.Header.HCC.BlockHash)
+	}
+
+	// third.Header.HCC.Votes contains the votes for the second block in the trio
+	if err := validateVotes(provenValSet, second.Header, third.Header.HCC.Votes); err != nil {
+		return nil, fmt.Errorf("Failed to validate voteSet, %v", err)
+	}
+	valSet, err := GetValidatorSetFromVCPProof(first.Header.StateHash, &first.Proof)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve validator set from VCP proof: %v", err)
+	}
+	return valSet, nil
+}
+
 func checkTailTrio(sv *state.StoreView, provenValSet *core.ValidatorSet, tailTrio *core.SnapshotBlockTrio) error {
 	second := &tailTrio.Second
 	third := &tailTrio.Third
@@ -916,7 +954,9 @@ func checkGenesisBlock(block *core.BlockHeader, db database.Database) (*core.Val
 	return genesisValidatorSet, nil
 }
 
-func getValidatorSetFromVCPProof(stateHash common.Hash, recoverredVp *core.VCPProof) (*core.ValidatorSet, error) {
+// GetValidatorSetFromVCPProof verifies the proof of the validator candidate pool against the
+// state hash, and returns the validator set selected from the proven pool.
+func GetValidatorSetFromVCPProof(stateHash common.Hash, recoverredVp *core.VCPProof) (*core.ValidatorSet, error) {
 	serializedVCP, _, err := trie.VerifyProof(stateHash, state.ValidatorCandidatePoolKey(), recoverredVp)
 	if err != nil {
 		return nil, err
@@ -936,6 +976,9 @@ func getValidatorSetFromSV(sv *state.StoreView) *core.ValidatorSet {
 }
 
 func validateVotes(validatorSet *core.ValidatorSet, block *core.BlockHeader, voteSet *core.VoteSet) error {
+	if voteSet == nil {
+		return fmt.Errorf("block has no votes")
+	}
 	if !validatorSet.HasMajority(voteSet) {
 		return fmt.Errorf("block doesn't have majority votes")
 	}
@@ -0,0 +1,135 @@
+package snapshot
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/ledger/state"
+	"github.com/pandotoken/pando/store/database"
+	"github.com/pandotoken/pando/store/kvstore"
+)
+
+//
+// A state checkpoint is a snapshot without the state tries: the last checkpoint headers and
+// the tail trio proving the checkpoint block is finalized. The state tries are downloaded
+// separately, e.g. from the peers by the state sync, and are validated against the state
+// checkpoint once they are all in the db.
+//
+
+// ExportStateCheckpoint builds the state checkpoint of the given finalized block.
+func ExportStateCheckpoint(chain *blockchain.Chain, db database.Database, block *core.ExtendedBlock) (*core.LastCheckpoint, *core.SnapshotMetadata, error) {
+	lastCheckpoint, _, err := buildLastCheckpoint(chain, block)
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata, _, err := buildSnapshotMetadataV4(chain, db, block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return lastCheckpoint, metadata, nil
+}
+
+// StateCheckpointTries returns the roots of the state tries needed to start a node from the
+// state checkpoint, i.e. the state tries of ExportSnapshotV4. The account storage tries of the
+// checkpoint block state are needed as well.
+func StateCheckpointTries(lastCheckpoint *core.LastCheckpoint, metadata *core.SnapshotMetadata) []common.Hash {
+	roots := []common.Hash{metadata.TailTrio.Second.Header.StateHash}
+	for _, root := range []common.Hash{metadata.TailTrio.First.Header.StateHash, lastCheckpoint.CheckpointHeader.StateHash} {
+		duplicate := false
+		for _, r := range roots {
+			duplicate = duplicate || r == root
+		}
+		if !duplicate {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+// VerifyStateCheckpoint checks the state checkpoint is for the given block, and that its tail
+// trio is well formed. The validator set proven by the VCP proof is checked against the state
+// by ImportStateCheckpoint, once the state is downloaded.
+func VerifyStateCheckpoint(blockHash common.Hash, lastCheckpoint *core.LastCheckpoint, metadata *core.SnapshotMetadata) error {
+	tailTrio := &metadata.TailTrio
+	if tailTrio.First.Header == nil || tailTrio.Second.Header == nil || tailTrio.Third.Header == nil {
+		return fmt.Errorf("Tail trio is incomplete")
+	}
+	if lastCheckpoint.CheckpointHeader == nil {
+		return fmt.Errorf("The last checkpoint header is nil")
+	}
+	if tailTrio.Second.Header.Hash() != blockHash {
+		return fmt.Errorf("State checkpoint is for block %v instead of %v", tailTrio.Second.Header.Hash().Hex(), blockHash.Hex())
+	}
+	if err := checkTailTrioLinks(tailTrio); err != nil {
+		return err
+	}
+
+	// The intermediate headers link the block back to the last checkpoint
+	hash := tailTrio.Second.Header.Parent
+	for _, header := range lastCheckpoint.IntermediateHeaders {
+		if header.Hash() != hash {
+			return fmt.Errorf("Intermediate header %v is not linked to the block", header.Hash().Hex())
+		}
+		hash = header.Parent
+	}
+	lastHash := blockHash
+	if num := len(lastCheckpoint.IntermediateHeaders); num > 0 {
+		lastHash = lastCheckpoint.IntermediateHeaders[num-1].Hash()
+	}
+	if lastCheckpoint.CheckpointHeader.Hash() != lastHash {
+		return fmt.Errorf("The last checkpoint %v is not linked to the block", lastCheckpoint.CheckpointHeader.Hash().Hex())
+	}
+
+	if _, err := GetValidatorSetFromVCPProof(tailTrio.First.Header.StateHash, &tailTrio.First.Proof); err != nil {
+		return fmt.Errorf("Failed to retrieve validator set from VCP proof: %v", err)
+	}
+	return nil
+}
+
+// ImportStateCheckpoint validates the state tries in the db against the state checkpoint, and
+// saves the checkpoint blocks. It returns the header of the checkpoint block.
+func ImportStateCheckpoint(lastCheckpoint *core.LastCheckpoint, metadata *core.SnapshotMetadata, db database.Database) (*core.BlockHeader, error) {
+	for _, root := range StateCheckpointTries(lastCheckpoint, metadata) {
+		if err := checkTrieComplete(root, db); err != nil {
+			return nil, fmt.Errorf("State is incomplete: %v", err)
+		}
+	}
+
+	kvstore := kvstore.NewKVStore(db)
+	saveLastCheckpointBlocks(lastCheckpoint, kvstore)
+
+	lfb := metadata.TailTrio.Second
+	sv := state.NewStoreView(lfb.Header.Height, lfb.Header.StateHash, db)
+	if err := checkSnapshotV4(sv, metadata, db); err != nil {
+		return nil, fmt.Errorf("State validation failed: %v", err)
+	}
+
+	for _, blockTrio := range metadata.ProofTrios {
+		blockTrioKey := []byte(core.BlockTrioStoreKeyPrefix + strconv.FormatUint(blockTrio.First.Header.Height, 10))
+		err := kvstore.Put(blockTrioKey, blockTrio)
+		if err != nil {
+			logger.Panicf("Failed to save ProofTrios: err: %v", err)
+		}
+	}
+
+	secondBlockHeader := saveTailBlocks(metadata, sv, kvstore)
+
+	if err := checkLastCheckpoint(sv, secondBlockHeader, lastCheckpoint, db); err != nil {
+		return nil, fmt.Errorf("Last checkpoint validation failed: %v", err)
+	}
+	return secondBlockHeader, nil
+}
+
+func checkTailTrioLinks(tailTrio *core.SnapshotBlockTrio) error {
+	first, second, third := tailTrio.First.Header, tailTrio.Second.Header, tailTrio.Third.Header
+	if second.Parent != first.Hash() || third.Parent != second.Hash() {
+		return fmt.Errorf("Tail trio has invalid Parent link")
+	}
+	if second.HCC.BlockHash != first.Hash() || third.HCC.BlockHash != second.Hash() {
+		return fmt.Errorf("Tail trio has invalid HCC link")
+	}
+	return nil
+}
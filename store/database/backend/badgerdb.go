@@ -20,7 +20,7 @@ func NewBadgerDatabase(dirname string) (*BadgerDatabase, error) {
 	opts.ValueDir = dirname
 	db, err := badger.Open(opts)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	return &BadgerDatabase{
@@ -164,6 +164,29 @@ func (db *BadgerDatabase) CountReference(key []byte) (int, error) {
 	return document.Reference, nil
 }
 
+// ForEach implements the Iteratee interface.
+func (db *BadgerDatabase) ForEach(cb func(key, value []byte, ref int) error) error {
+	return db.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			var document Document
+			err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &document)
+			})
+			if err != nil {
+				return err
+			}
+			if err := cb(item.KeyCopy(nil), document.Value, document.Reference); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (db *BadgerDatabase) Close() {
 	db.db.Close()
 }
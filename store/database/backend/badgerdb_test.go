@@ -34,3 +34,15 @@ func TestBadgerDB_PutGet(t *testing.T) {
 	defer close()
 	testPutGet(db, batch, t)
 }
+
+func TestMigrateDatabaseToBadgerDB(t *testing.T) {
+	dst, _, close := newTestBDB()
+	defer close()
+	testMigrateDatabase(t, NewMemDatabase(), dst)
+}
+
+func TestMigrateDatabaseFromBadgerDB(t *testing.T) {
+	src, _, close := newTestBDB()
+	defer close()
+	testMigrateDatabase(t, src, NewMemDatabase())
+}
@@ -0,0 +1,112 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pandotoken/pando/store/database"
+)
+
+// Names of the supported storage backends
+const (
+	LevelDBBackend   = "leveldb"
+	BadgerDBBackend  = "badger"
+	MemDBBackend     = "memdb"
+	MongoDBBackend   = "mongodb"
+	AerospikeBackend = "aerospike"
+)
+
+// NewDatabase opens the database of the given storage backend under the db directory. The
+// backends keep their files in separate sub-directories, so that the databases of different
+// backends can live side by side, e.g. while migrating from one to the other. The reference
+// counts are kept in a separate "ref" LevelDB for LevelDB, and alongside the values for the
+// other backends.
+func NewDatabase(backendName string, dbDir string, cache int, handles int) (database.Database, error) {
+	switch strings.ToLower(backendName) {
+	case "", LevelDBBackend:
+		db, err := NewLDBDatabase(path.Join(dbDir, "main"), path.Join(dbDir, "ref"), cache, handles)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	case BadgerDBBackend:
+		badgerDir := path.Join(dbDir, "badger")
+		if err := os.MkdirAll(badgerDir, 0700); err != nil {
+			return nil, err
+		}
+		db, err := NewBadgerDatabase(badgerDir)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	case MemDBBackend:
+		return NewMemDatabase(), nil
+	case MongoDBBackend:
+		db, err := NewMongoDatabase()
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	case AerospikeBackend:
+		db, err := NewAerospikeDatabase()
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	default:
+		return nil, fmt.Errorf("Unsupported storage backend: %v", backendName)
+	}
+}
+
+// Iteratee is implemented by the databases whose content can be enumerated.
+type Iteratee interface {
+	// ForEach calls cb with each key / value pair and its reference count. The iteration stops
+	// at the first error returned by cb.
+	ForEach(cb func(key, value []byte, ref int) error) error
+}
+
+// MigrateDatabase copies all the key / value pairs of src, along with their reference counts,
+// into dst. The progress callback, if any, is called periodically with the number of keys
+// copied so far. It returns the total number of keys copied.
+func MigrateDatabase(src database.Database, dst database.Database, progress func(count uint64)) (uint64, error) {
+	iteratee, ok := src.(Iteratee)
+	if !ok {
+		return 0, fmt.Errorf("The content of %T cannot be enumerated", src)
+	}
+
+	count := uint64(0)
+	batch := dst.NewBatch()
+	err := iteratee.ForEach(func(key, value []byte, ref int) error {
+		if err := batch.Put(key, value); err != nil {
+			return err
+		}
+		for i := 0; i < ref; i++ {
+			if err := batch.Reference(key); err != nil {
+				return err
+			}
+		}
+		count++
+		if batch.ValueSize() > database.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+			if progress != nil {
+				progress(count)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	if err := batch.Write(); err != nil {
+		return count, err
+	}
+	if progress != nil {
+		progress(count)
+	}
+	return count, nil
+}
@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/store/database"
+)
+
+func TestNewDatabase(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir(os.TempDir(), "factory_test_")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(LevelDBBackend, dir, 16, 16)
+	require.Nil(t, err)
+	_, ok := db.(*LDBDatabase)
+	assert.True(ok)
+	db.Close()
+
+	db, err = NewDatabase(MemDBBackend, dir, 16, 16)
+	require.Nil(t, err)
+	_, ok = db.(*MemDatabase)
+	assert.True(ok)
+
+	_, err = NewDatabase("rocksdb", dir, 16, 16)
+	assert.NotNil(err)
+}
+
+func testMigrateDatabase(t *testing.T, src database.Database, dst database.Database) {
+	assert := assert.New(t)
+
+	for i := 0; i < 1000; i++ {
+		key := []byte{byte(i >> 8), byte(i)}
+		require.Nil(t, src.Put(key, append([]byte("value"), key...)))
+		for j := 0; j < i%4; j++ {
+			require.Nil(t, src.Reference(key))
+		}
+	}
+
+	count, err := MigrateDatabase(src, dst, nil)
+	require.Nil(t, err)
+	assert.Equal(uint64(1000), count)
+
+	for i := 0; i < 1000; i++ {
+		key := []byte{byte(i >> 8), byte(i)}
+		value, err := dst.Get(key)
+		assert.Nil(err)
+		assert.Equal(append([]byte("value"), key...), value)
+		ref, _ := dst.CountReference(key)
+		assert.Equal(i%4, ref)
+	}
+}
+
+func TestMigrateDatabaseFromLDB(t *testing.T) {
+	src, remove := newTestLDB()
+	defer remove()
+	testMigrateDatabase(t, src, NewMemDatabase())
+}
+
+func TestMigrateDatabaseToLDB(t *testing.T) {
+	dst, remove := newTestLDB()
+	defer remove()
+	testMigrateDatabase(t, NewMemDatabase(), dst)
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/pandotoken/pando/common"
 	"github.com/pandotoken/pando/common/metrics"
 	"github.com/pandotoken/pando/store"
 	"github.com/pandotoken/pando/store/database"
@@ -245,6 +246,26 @@ func (db *LDBDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
 	return db.db.NewIterator(util.BytesPrefix(prefix), nil)
 }
 
+// ForEach implements the Iteratee interface.
+func (db *LDBDatabase) ForEach(cb func(key, value []byte, ref int) error) error {
+	it := db.db.NewIterator(nil, nil)
+	defer it.Release()
+
+	for it.Next() {
+		// The iterator reuses its buffers
+		key := common.CopyBytes(it.Key())
+		value := common.CopyBytes(it.Value())
+		ref, err := db.CountReference(key)
+		if err != nil && err != store.ErrKeyNotFound {
+			return err
+		}
+		if err := cb(key, value, ref); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
 func (db *LDBDatabase) Close() {
 	// Stop the metrics collection to avoid internal database races
 	db.quitLock.Lock()
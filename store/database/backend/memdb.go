@@ -85,6 +85,21 @@ func (db *MemDatabase) Keys() [][]byte {
 	return keys
 }
 
+// ForEach implements the Iteratee interface.
+func (db *MemDatabase) ForEach(cb func(key, value []byte, ref int) error) error {
+	for _, key := range db.Keys() {
+		value, err := db.Get(key)
+		if err != nil {
+			continue // deleted in the meantime
+		}
+		ref, _ := db.CountReference(key)
+		if err := cb(key, value, ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (db *MemDatabase) Delete(key []byte) error {
 	db.lock.Lock()
 	defer db.lock.Unlock()
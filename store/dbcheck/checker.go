@@ -0,0 +1,341 @@
+package dbcheck
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/util"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/store"
+	"github.com/pandotoken/pando/store/database"
+	"github.com/pandotoken/pando/store/trie"
+)
+
+var logger = util.GetLoggerForModule("dbcheck")
+
+// accountKeyPrefix is the prefix of the account keys in the state trie.
+var accountKeyPrefix = []byte("ls/a")
+
+// IssueKind is the kind of an inconsistency found in the db.
+type IssueKind string
+
+const (
+	IssueMissingBlock       IssueKind = "missing block"
+	IssueMissingHeightIndex IssueKind = "missing height index"
+	IssueDeadChildLink      IssueKind = "dead child link"
+	IssueMissingTxIndex     IssueKind = "missing tx index"
+	IssueWrongTxIndex       IssueKind = "wrong tx index"
+	IssueMissingTxReceipt   IssueKind = "missing tx receipt"
+	IssueMissingTrieNode    IssueKind = "missing trie node"
+	IssueWrongRefCount      IssueKind = "wrong reference count"
+)
+
+// Issue is an inconsistency found in the db.
+type Issue struct {
+	Kind     IssueKind
+	Height   uint64
+	Block    common.Hash
+	Detail   string
+	Repaired bool
+}
+
+func (issue *Issue) String() string {
+	status := "not repaired"
+	if issue.Repaired {
+		status = "repaired"
+	}
+	return fmt.Sprintf("%v at height %v, block %v: %v (%v)", issue.Kind, issue.Height, issue.Block.Hex(), issue.Detail, status)
+}
+
+// Report is the result of a db check.
+type Report struct {
+	NumBlocks uint64   // number of blocks checked
+	NumNodes  uint64   // number of distinct trie nodes checked
+	Issues    []*Issue // inconsistencies found
+}
+
+// NumUnrepaired returns the number of issues which were not repaired.
+func (r *Report) NumUnrepaired() int {
+	count := 0
+	for _, issue := range r.Issues {
+		if !issue.Repaired {
+			count++
+		}
+	}
+	return count
+}
+
+// Options configures a db check.
+type Options struct {
+	NumBlocks uint64 // number of finalized blocks to check, starting from the last finalized block
+	CheckRefs bool   // whether to check the reference counts of the trie nodes in the ref db
+	Repair    bool   // whether to repair the inconsistencies which can be repaired
+}
+
+// Checker verifies that the finalized blocks retained by the node, their indices and their
+// state tries are fully present in the db. It must only be used while the node is stopped.
+type Checker struct {
+	chain *blockchain.Chain
+	db    database.Database // the db holding the state tries, i.e. the rolling db
+	refdb database.Database // the db holding the reference counts of the trie nodes
+	opts  Options
+
+	visited map[common.Hash]bool // trie nodes already checked
+	missing map[common.Hash]bool // trie nodes already reported missing
+	parents map[common.Hash]int  // number of distinct checked parents of each trie node
+	roots   map[common.Hash]bool // roots of the checked tries
+
+	report *Report
+}
+
+// NewChecker creates a new Checker. The reference counts are only meaningful when all the trie
+// nodes are in refdb, i.e. when the rolling db is disabled.
+func NewChecker(chain *blockchain.Chain, db database.Database, refdb database.Database, opts Options) *Checker {
+	return &Checker{
+		chain:   chain,
+		db:      db,
+		refdb:   refdb,
+		opts:    opts,
+		visited: make(map[common.Hash]bool),
+		missing: make(map[common.Hash]bool),
+		parents: make(map[common.Hash]int),
+		roots:   make(map[common.Hash]bool),
+		report:  &Report{Issues: []*Issue{}},
+	}
+}
+
+// Check walks the chain backwards from the given last finalized block, and checks each block
+// down to the chain root or up to the configured number of blocks.
+func (c *Checker) Check(lastFinalizedBlock common.Hash) *Report {
+	root := c.chain.Root()
+	hash := lastFinalizedBlock
+	for c.report.NumBlocks < c.opts.NumBlocks && !hash.IsEmpty() {
+		block, err := c.chain.FindBlock(hash)
+		if err != nil {
+			// The walk cannot continue without the parent link
+			c.addIssue(IssueMissingBlock, 0, hash, fmt.Sprintf("%v", err), false)
+			break
+		}
+
+		c.checkBlock(block)
+		c.report.NumBlocks++
+
+		if block.Height <= root.Height {
+			break
+		}
+		hash = block.Parent
+	}
+
+	if c.opts.CheckRefs {
+		c.checkRefs()
+	}
+	return c.report
+}
+
+func (c *Checker) addIssue(kind IssueKind, height uint64, block common.Hash, detail string, repaired bool) {
+	issue := &Issue{
+		Kind:     kind,
+		Height:   height,
+		Block:    block,
+		Detail:   detail,
+		Repaired: repaired,
+	}
+	logger.Warnf("Found %v", issue)
+	c.report.Issues = append(c.report.Issues, issue)
+}
+
+func (c *Checker) checkBlock(block *core.ExtendedBlock) {
+	blockHash := block.Hash()
+	logger.Debugf("Checking block %v at height %v", blockHash.Hex(), block.Height)
+
+	c.checkBlockIndex(block)
+	c.checkChildren(block)
+	c.checkTxs(block)
+	c.checkState(block.Height, blockHash, block.StateHash)
+}
+
+// checkBlockIndex checks the block is in the by height index.
+func (c *Checker) checkBlockIndex(block *core.ExtendedBlock) {
+	blockHash := block.Hash()
+	for _, b := range c.chain.FindBlocksByHeight(block.Height) {
+		if b.Hash() == blockHash {
+			return
+		}
+	}
+	if c.opts.Repair {
+		c.chain.FixBlockIndex(block)
+	}
+	c.addIssue(IssueMissingHeightIndex, block.Height, blockHash, "block not found by height", c.opts.Repair)
+}
+
+// checkChildren checks the children of the block are all in the db.
+func (c *Checker) checkChildren(block *core.ExtendedBlock) {
+	blockHash := block.Hash()
+	dead := []common.Hash{}
+	for _, child := range block.Children {
+		if _, err := c.chain.FindBlock(child); err != nil {
+			dead = append(dead, child)
+		}
+	}
+	if len(dead) == 0 {
+		return
+	}
+	if c.opts.Repair {
+		c.chain.FixMissingChildren(block)
+	}
+	for _, child := range dead {
+		c.addIssue(IssueDeadChildLink, block.Height, blockHash, fmt.Sprintf("child %v not found", child.Hex()), c.opts.Repair)
+	}
+}
+
+// checkTxs checks the txs of the block are indexed with the block, and that the smart contract
+// txs have a receipt. The receipts cannot be rebuilt without re-executing the block.
+func (c *Checker) checkTxs(block *core.ExtendedBlock) {
+	blockHash := block.Hash()
+	for idx, rawTx := range block.Txs {
+		txHash := crypto.Keccak256Hash(rawTx)
+
+		_, txBlock, found := c.chain.FindTxByHash(txHash)
+		if !found {
+			if c.opts.Repair {
+				c.chain.FixBlockIndex(block)
+			}
+			c.addIssue(IssueMissingTxIndex, block.Height, blockHash, fmt.Sprintf("tx %v not found", txHash.Hex()), c.opts.Repair)
+		} else if txBlock.Hash() != blockHash {
+			// The finalized block takes precedence over the forks including the same tx
+			if c.opts.Repair {
+				c.chain.AddTxsToIndex(block, true)
+			}
+			c.addIssue(IssueWrongTxIndex, block.Height, blockHash,
+				fmt.Sprintf("tx %v indexed with block %v", txHash.Hex(), txBlock.Hash().Hex()), c.opts.Repair)
+		}
+
+		// The chain root is not executed by the node
+		if block.Height <= c.chain.Root().Height {
+			continue
+		}
+		tx, err := types.TxFromBytes(rawTx)
+		if err != nil {
+			logger.Warnf("Failed to decode tx %v of block %v: %v", idx, blockHash.Hex(), err)
+			continue
+		}
+		if _, ok := tx.(*types.SmartContractTx); !ok {
+			continue
+		}
+		if _, found := c.chain.FindTxReceiptByHash(blockHash, txHash); !found {
+			c.addIssue(IssueMissingTxReceipt, block.Height, blockHash, fmt.Sprintf("receipt of tx %v not found", txHash.Hex()), false)
+		}
+	}
+}
+
+// checkState checks the state trie with the given root, and the storage tries of its accounts,
+// are fully present in the db. The missing trie nodes cannot be rebuilt from the db.
+func (c *Checker) checkState(height uint64, blockHash common.Hash, stateRoot common.Hash) {
+	storageRoots := c.checkTrie(height, blockHash, stateRoot, true)
+	for _, root := range storageRoots {
+		c.checkTrie(height, blockHash, root, false)
+	}
+}
+
+// checkTrie walks the trie nodes not yet checked, and returns the storage roots of the accounts
+// found in these nodes if isState is set.
+func (c *Checker) checkTrie(height uint64, blockHash common.Hash, root common.Hash, isState bool) []common.Hash {
+	storageRoots := []common.Hash{}
+	if root == (common.Hash{}) {
+		return storageRoots
+	}
+	c.roots[root] = true
+	if c.visited[root] {
+		return storageRoots
+	}
+
+	tr, err := trie.New(root, trie.NewDatabase(c.db))
+	if err != nil {
+		c.addMissingNode(height, blockHash, root, err)
+		return storageRoots
+	}
+
+	it := tr.NodeIterator(nil)
+	descend := true
+	for it.Next(descend) {
+		descend = true
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			if parent := it.Parent(); parent != (common.Hash{}) {
+				c.parents[hash]++
+			}
+			// The children of a checked node have been checked already
+			if c.visited[hash] {
+				descend = false
+				continue
+			}
+			c.visited[hash] = true
+			c.report.NumNodes++
+		}
+
+		if isState && it.Leaf() && bytes.HasPrefix(it.LeafKey(), accountKeyPrefix) {
+			account := &types.Account{}
+			if err := types.FromBytes(it.LeafBlob(), account); err != nil {
+				logger.Warnf("Failed to decode account %v: %v", common.Bytes2Hex(it.LeafKey()), err)
+				continue
+			}
+			if account.Root != (common.Hash{}) {
+				storageRoots = append(storageRoots, account.Root)
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		if missingErr, ok := err.(*trie.MissingNodeError); ok {
+			c.addMissingNode(height, blockHash, missingErr.NodeHash, err)
+		} else {
+			c.addMissingNode(height, blockHash, root, err)
+		}
+	}
+	return storageRoots
+}
+
+func (c *Checker) addMissingNode(height uint64, blockHash common.Hash, hash common.Hash, err error) {
+	if c.missing[hash] {
+		return
+	}
+	c.missing[hash] = true
+	c.addIssue(IssueMissingTrieNode, height, blockHash, fmt.Sprintf("%v", err), false)
+}
+
+// checkRefs checks the reference count of each checked trie node covers its checked parents,
+// plus one if it is the root of a trie. A lower count means the node would be deleted while
+// still in use by the pruning.
+func (c *Checker) checkRefs() {
+	for hash := range c.visited {
+		expected := c.parents[hash]
+		if c.roots[hash] {
+			expected++
+		}
+
+		ref, err := c.refdb.CountReference(hash[:])
+		if err != nil && err != store.ErrKeyNotFound {
+			logger.Warnf("Failed to count the references of trie node %v: %v", hash.Hex(), err)
+			continue
+		}
+		if ref >= expected {
+			continue
+		}
+
+		repaired := false
+		if c.opts.Repair {
+			repaired = true
+			for i := ref; i < expected; i++ {
+				if err := c.refdb.Reference(hash[:]); err != nil {
+					logger.Warnf("Failed to reference trie node %v: %v", hash.Hex(), err)
+					repaired = false
+					break
+				}
+			}
+		}
+		c.addIssue(IssueWrongRefCount, 0, common.Hash{},
+			fmt.Sprintf("trie node %v has %v references, expected at least %v", hash.Hex(), ref, expected), repaired)
+	}
+}
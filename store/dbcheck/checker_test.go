@@ -0,0 +1,264 @@
+package dbcheck
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/pandotoken/pando/blockchain"
+	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/core"
+	"github.com/pandotoken/pando/crypto"
+	"github.com/pandotoken/pando/ledger/state"
+	"github.com/pandotoken/pando/ledger/types"
+	"github.com/pandotoken/pando/store/database"
+	"github.com/pandotoken/pando/store/database/backend"
+	"github.com/pandotoken/pando/store/kvstore"
+	"github.com/pandotoken/pando/store/trie"
+)
+
+const checkerTestChainID = "dbchecktest"
+
+type checkerTestEnv struct {
+	db         database.Database
+	chain      *blockchain.Chain
+	lfb        *core.ExtendedBlock
+	scTx       common.Bytes
+	stateRoots []common.Hash
+}
+
+func newCheckerTestBlock(height uint64, parent common.Hash, stateHash common.Hash, txs []common.Bytes) *core.Block {
+	block := core.NewBlock()
+	block.ChainID = checkerTestChainID
+	block.Epoch = height
+	block.Height = height
+	block.Parent = parent
+	block.HCC.BlockHash = parent
+	block.StateHash = stateHash
+	block.Timestamp = big.NewInt(int64(height))
+	block.AddTxs(txs)
+	return block
+}
+
+func newCheckerTestSCTx(t *testing.T, contract common.Address) (*types.SmartContractTx, common.Bytes) {
+	privKey, _, err := crypto.GenerateKeyPair()
+	require.Nil(t, err)
+	tx := &types.SmartContractTx{
+		From:     types.TxInput{Address: privKey.PublicKey().Address(), Coins: types.NewCoins(0, 0), Sequence: 1},
+		To:       types.TxOutput{Address: contract},
+		GasLimit: 100000,
+		GasPrice: big.NewInt(1),
+		Data:     common.Bytes("call"),
+	}
+	sig, err := privKey.Sign(tx.SignBytes(checkerTestChainID))
+	require.Nil(t, err)
+	tx.From.Signature = sig
+	raw, err := types.TxToBytes(tx)
+	require.Nil(t, err)
+	return tx, raw
+}
+
+func newCheckerTestEnv(t *testing.T) *checkerTestEnv {
+	require := require.New(t)
+	db := backend.NewMemDatabase()
+
+	sv := state.NewStoreView(100, common.Hash{}, db)
+	for i := 0; i < 100; i++ {
+		addr := common.BytesToAddress([]byte(fmt.Sprintf("account-%04d", i)))
+		acc := types.NewAccount(addr)
+		acc.Balance = types.NewCoins(int64(i), int64(i))
+		sv.SetAccount(addr, acc)
+	}
+	rootState := sv.Save()
+
+	sv = state.NewStoreView(101, rootState, db)
+	contract := common.BytesToAddress([]byte("contract"))
+	for i := 0; i < 20; i++ {
+		sv.SetState(contract, common.BytesToHash([]byte(fmt.Sprintf("key-%04d", i))), common.BytesToHash([]byte(fmt.Sprintf("value-%04d", i))))
+	}
+	state101 := sv.Save()
+
+	sv = state.NewStoreView(102, state101, db)
+	for i := 0; i < 10; i++ {
+		addr := common.BytesToAddress([]byte(fmt.Sprintf("account-%04d", i)))
+		acc := sv.GetAccount(addr)
+		acc.Balance = types.NewCoins(int64(i+1000), int64(i))
+		sv.SetAccount(addr, acc)
+	}
+	state102 := sv.Save()
+
+	sendTx, err := types.TxToBytes(&types.SendTx{
+		Fee:     types.NewCoins(0, 1),
+		Inputs:  []types.TxInput{{Address: common.BytesToAddress([]byte("account-0001")), Coins: types.NewCoins(0, 2)}},
+		Outputs: []types.TxOutput{{Address: common.BytesToAddress([]byte("account-0002")), Coins: types.NewCoins(0, 1)}},
+	})
+	require.Nil(err)
+	scTx, rawSCTx := newCheckerTestSCTx(t, contract)
+
+	root := newCheckerTestBlock(100, common.Hash{}, rootState, []common.Bytes{})
+	block101 := newCheckerTestBlock(101, root.Hash(), state101, []common.Bytes{sendTx})
+	block102 := newCheckerTestBlock(102, block101.Hash(), state102, []common.Bytes{rawSCTx})
+
+	chain := blockchain.NewChain(checkerTestChainID, kvstore.NewKVStore(db), root)
+	_, err = chain.AddBlock(block101)
+	require.Nil(err)
+	_, err = chain.AddBlock(block102)
+	require.Nil(err)
+	require.Nil(chain.FinalizePreviousBlocks(block102.Hash()))
+	chain.AddTxReceipt(block102, scTx, nil, nil, nil, common.Address{}, 0, nil)
+
+	lfb, err := chain.FindBlock(block102.Hash())
+	require.Nil(err)
+
+	return &checkerTestEnv{
+		db:         db,
+		chain:      chain,
+		lfb:        lfb,
+		scTx:       rawSCTx,
+		stateRoots: []common.Hash{state102, state101, rootState},
+	}
+}
+
+func (env *checkerTestEnv) check(repair bool) *Report {
+	opts := Options{NumBlocks: 2048, CheckRefs: true, Repair: repair}
+	return NewChecker(env.chain, env.db, env.db, opts).Check(env.lfb.Hash())
+}
+
+func issueKinds(report *Report) []IssueKind {
+	kinds := []IssueKind{}
+	for _, issue := range report.Issues {
+		kinds = append(kinds, issue.Kind)
+	}
+	return kinds
+}
+
+func TestCheckConsistentDB(t *testing.T) {
+	assert := assert.New(t)
+
+	env := newCheckerTestEnv(t)
+	report := env.check(false)
+	assert.Equal(uint64(3), report.NumBlocks)
+	assert.True(report.NumNodes > 0)
+	assert.Empty(report.Issues)
+
+	// Only the given number of blocks are checked
+	report = NewChecker(env.chain, env.db, env.db, Options{NumBlocks: 1}).Check(env.lfb.Hash())
+	assert.Equal(uint64(1), report.NumBlocks)
+	assert.Empty(report.Issues)
+}
+
+func TestCheckRepairIndices(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	env := newCheckerTestEnv(t)
+	store := kvstore.NewKVStore(env.db)
+
+	// Drop the height index and the tx index of the last finalized block
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, env.lfb.Height)
+	require.Nil(store.Delete(append(common.Bytes("bh/"), buf[:n]...)))
+	txHash := crypto.Keccak256Hash(env.scTx)
+	require.Nil(store.Delete(append(common.Bytes("tx/"), txHash[:]...)))
+
+	// Add a dead child link
+	env.lfb.Children = append(env.lfb.Children, common.HexToHash("0x1234"))
+	require.Nil(env.chain.SaveBlock(env.lfb))
+
+	report := env.check(false)
+	assert.Equal([]IssueKind{IssueMissingHeightIndex, IssueDeadChildLink, IssueMissingTxIndex}, issueKinds(report))
+	assert.Equal(3, report.NumUnrepaired())
+
+	// Fixing the block index also fixes the tx index
+	report = env.check(true)
+	assert.Equal([]IssueKind{IssueMissingHeightIndex, IssueDeadChildLink}, issueKinds(report))
+	assert.Equal(0, report.NumUnrepaired())
+
+	report = env.check(false)
+	assert.Empty(report.Issues)
+	_, block, found := env.chain.FindTxByHash(txHash)
+	require.True(found)
+	assert.Equal(env.lfb.Hash(), block.Hash())
+}
+
+func TestCheckMissingReceipt(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	env := newCheckerTestEnv(t)
+
+	// The smart contract tx of the new block is not executed
+	scTx, rawSCTx := newCheckerTestSCTx(t, common.BytesToAddress([]byte("contract")))
+	block := newCheckerTestBlock(103, env.lfb.Hash(), env.lfb.StateHash, []common.Bytes{rawSCTx})
+	_, err := env.chain.AddBlock(block)
+	require.Nil(err)
+	require.Nil(env.chain.FinalizePreviousBlocks(block.Hash()))
+	lfb, err := env.chain.FindBlock(block.Hash())
+	require.Nil(err)
+	env.lfb = lfb
+
+	report := env.check(true)
+	require.Equal([]IssueKind{IssueMissingTxReceipt}, issueKinds(report))
+	assert.Equal(block.Hash(), report.Issues[0].Block)
+	assert.Equal(1, report.NumUnrepaired())
+
+	env.chain.AddTxReceipt(block, scTx, nil, nil, nil, common.Address{}, 0, nil)
+	report = env.check(false)
+	assert.Empty(report.Issues)
+}
+
+func TestCheckMissingTrieNode(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	env := newCheckerTestEnv(t)
+
+	// Delete an inner node of the state trie of the last finalized block
+	tr, err := trie.New(env.stateRoots[0], trie.NewDatabase(env.db))
+	require.Nil(err)
+	var missing common.Hash
+	it := tr.NodeIterator(nil)
+	for it.Next(true) {
+		if it.Hash() != (common.Hash{}) && it.Hash() != env.stateRoots[0] {
+			missing = it.Hash()
+			break
+		}
+	}
+	require.NotEqual(common.Hash{}, missing)
+	require.Nil(env.db.Delete(missing[:]))
+
+	report := env.check(true)
+	require.Equal([]IssueKind{IssueMissingTrieNode}, issueKinds(report))
+	assert.Equal(env.lfb.Hash(), report.Issues[0].Block)
+	assert.Contains(report.Issues[0].Detail, fmt.Sprintf("%x", missing))
+	assert.Equal(1, report.NumUnrepaired())
+}
+
+func TestCheckRepairRefCounts(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	env := newCheckerTestEnv(t)
+
+	// The state root of the last finalized block is still referenced by the block
+	root := env.stateRoots[0]
+	ref, err := env.db.CountReference(root[:])
+	require.Nil(err)
+	for i := 0; i < ref; i++ {
+		require.Nil(env.db.Dereference(root[:]))
+	}
+
+	report := env.check(false)
+	assert.Equal([]IssueKind{IssueWrongRefCount}, issueKinds(report))
+	assert.Equal(1, report.NumUnrepaired())
+
+	report = env.check(true)
+	assert.Equal([]IssueKind{IssueWrongRefCount}, issueKinds(report))
+	assert.Equal(0, report.NumUnrepaired())
+
+	report = env.check(false)
+	assert.Empty(report.Issues)
+}
@@ -1,6 +1,7 @@
 package rollingdb
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path"
@@ -12,12 +13,17 @@ import (
 	"github.com/spf13/viper"
 	"github.com/pandotoken/pando/blockchain"
 	"github.com/pandotoken/pando/common"
+	"github.com/pandotoken/pando/common/metrics"
 	"github.com/pandotoken/pando/common/util"
+	"github.com/pandotoken/pando/rlp"
 	"github.com/pandotoken/pando/store/database"
 )
 
 var logger = util.GetLoggerForModule("rollingdb")
 
+// oldestStateHeightKey is the key of the height of the oldest state kept by the compactions, saved in the root db.
+var oldestStateHeightKey = []byte("/rolling/oldest_state_height")
+
 type RollingDB struct {
 	mu sync.RWMutex
 
@@ -31,6 +37,8 @@ type RollingDB struct {
 	activeLayer *DBLayer
 
 	compactC chan struct{}
+
+	compactionTimer metrics.Timer // Timer for measuring the duration of the compactions, nil if not metered
 }
 
 func NewRollingDB(parentPath string, root database.Database) *RollingDB {
@@ -62,6 +70,21 @@ func (rdb *RollingDB) SetChain(chain *blockchain.Chain) {
 	rdb.chain = chain
 }
 
+// Meter configures the rolling database metrics collectors.
+func (rdb *RollingDB) Meter(prefix string) {
+	metrics.NewRegisteredFunctionalGauge(prefix+"layers", nil, func() int64 {
+		rdb.mu.RLock()
+		defer rdb.mu.RUnlock()
+		return int64(len(rdb.layers))
+	})
+	metrics.NewRegisteredFunctionalGauge(prefix+"active_layer", nil, func() int64 {
+		rdb.mu.RLock()
+		defer rdb.mu.RUnlock()
+		return int64(rdb.activeLayer.name)
+	})
+	rdb.compactionTimer = metrics.NewRegisteredTimer(prefix+"compaction/time", nil)
+}
+
 func (rdb *RollingDB) loadLayers(rollingPath string) (*DBLayer, []*DBLayer) {
 	files, err := ioutil.ReadDir(rollingPath)
 	if err != nil {
@@ -126,92 +149,148 @@ func (rdb *RollingDB) addLayer() {
 }
 
 func (rdb *RollingDB) compact(height uint64) {
-	if !viper.GetBool(common.CfgStorageStatePruningEnabled) {
+	if !viper.GetBool(common.CfgStorageStatePruningEnabled) || viper.GetBool(common.CfgStorageArchiveEnabled) {
 		return
 	}
 
-	select {
-	case rdb.compactC <- struct{}{}: // Make sure there is only one active compaction task
-		logger.Infof("Starting compaction")
+	if !rdb.tryCompact(height) {
+		logger.Debugf("Only one active compaction task allowed")
+	}
+}
 
-		start := time.Now()
-		defer func() {
-			logger.Infof("Compaction finished in %v", time.Since(start))
-		}()
+// Compact runs a compaction right away instead of waiting for the next compaction height. The layers
+// whose states are older than the retained blocks before the given height are dropped. It blocks
+// until the compaction is done.
+func (rdb *RollingDB) Compact(height uint64) error {
+	if !viper.GetBool(common.CfgStorageRollingEnabled) {
+		return errors.New("Rolling db is disabled")
+	}
+	if !viper.GetBool(common.CfgStorageStatePruningEnabled) || viper.GetBool(common.CfgStorageArchiveEnabled) {
+		return errors.New("State pruning is disabled")
+	}
+	if !rdb.tryCompact(height) {
+		return errors.New("Another compaction is running")
+	}
+	return nil
+}
 
+// tryCompact runs the compaction unless another one is running, in which case it returns false.
+func (rdb *RollingDB) tryCompact(height uint64) bool {
+	select {
+	case rdb.compactC <- struct{}{}: // Make sure there is only one active compaction task
 		defer func() {
 			<-rdb.compactC
 		}()
+		rdb.runCompaction(height)
+		return true
+	default:
+		return false
+	}
+}
 
-		logger.Debugf("Number of layers: %v", len(rdb.layers))
-		if len(rdb.layers) == 0 {
-			logger.Infof("No rolling DB layer found, skip compaction")
-			return
-		}
+func (rdb *RollingDB) runCompaction(height uint64) {
+	logger.Infof("Starting compaction")
 
-		// Copying state from source to target
-		targetLayer := rdb.activeLayer
-		var sourceLayer *DBLayer
-
-		// Look for layers to cut off
-		minimumNumBlocksToRetain := uint64(viper.GetInt(common.CfgStorageStatePruningRetainedBlocks))
-		found := false
-		for i := len(rdb.layers) - 1; i >= 0; i-- {
-			if height-rdb.layers[i].tag.Height > minimumNumBlocksToRetain+10 {
-				found = true
-				sourceLayer = rdb.layers[i]
-				break
-			}
-		}
-		if !found {
-			logger.Info("No layer old enough to cut off")
-			return
+	start := time.Now()
+	defer func() {
+		logger.Infof("Compaction finished in %v", time.Since(start))
+		if rdb.compactionTimer != nil {
+			rdb.compactionTimer.UpdateSince(start)
 		}
+	}()
 
-		if !isRollingHeight(sourceLayer.tag.Height) {
-			// potentially db was not cut off cleanly, keep the layer until one cleancut is made
-			logger.Infof("Compaction canceled: sourceLayer.name=%v, lastLayer.Height=%v", sourceLayer.name, sourceLayer.tag.Height)
-			return
+	logger.Debugf("Number of layers: %v", len(rdb.layers))
+	if len(rdb.layers) == 0 {
+		logger.Infof("No rolling DB layer found, skip compaction")
+		return
+	}
+
+	// Copying state from source to target
+	targetLayer := rdb.activeLayer
+	var sourceLayer *DBLayer
+
+	// Look for layers to cut off
+	minimumNumBlocksToRetain := uint64(viper.GetInt(common.CfgStorageStatePruningRetainedBlocks))
+	found := false
+	for i := len(rdb.layers) - 1; i >= 0; i-- {
+		if height > rdb.layers[i].tag.Height && height-rdb.layers[i].tag.Height > minimumNumBlocksToRetain+10 {
+			found = true
+			sourceLayer = rdb.layers[i]
+			break
 		}
+	}
+	if !found {
+		logger.Info("No layer old enough to cut off")
+		return
+	}
+
+	if !isRollingHeight(sourceLayer.tag.Height) {
+		// potentially db was not cut off cleanly, keep the layer until one cleancut is made
+		logger.Infof("Compaction canceled: sourceLayer.name=%v, lastLayer.Height=%v", sourceLayer.name, sourceLayer.tag.Height)
+		return
+	}
 
-		blocks := rdb.chain.FindBlocksByHeight(sourceLayer.tag.Height)
-		logger.Debugf("Found %v blocks for height %v", len(blocks), sourceLayer.tag.Height)
+	blocks := rdb.chain.FindBlocksByHeight(sourceLayer.tag.Height)
+	logger.Debugf("Found %v blocks for height %v", len(blocks), sourceLayer.tag.Height)
 
-		for _, block := range blocks {
-			if block.Status.IsFinalized() {
-				logger.Debugf("Found finalized block: %v", block.Hash().Hex())
+	for _, block := range blocks {
+		if block.Status.IsFinalized() {
+			logger.Debugf("Found finalized block: %v", block.Hash().Hex())
 
-				for _, stateRoot := range sourceLayer.tag.StateRoots {
-					logger.Debugf("State root check, stateRoot: %v, block.StateHash: %v", stateRoot.Hex(), block.StateHash.Hex())
+			for _, stateRoot := range sourceLayer.tag.StateRoots {
+				logger.Debugf("State root check, stateRoot: %v, block.StateHash: %v", stateRoot.Hex(), block.StateHash.Hex())
 
-					if stateRoot == block.StateHash {
-						logger.Infof("Moving finalized state hash=%v, source=%v, target=%v", stateRoot.Hex(), sourceLayer.name, targetLayer.name)
-						copyState(rdb, targetLayer.db.NewBatch(), stateRoot)
+				if stateRoot == block.StateHash {
+					logger.Infof("Moving finalized state hash=%v, source=%v, target=%v", stateRoot.Hex(), sourceLayer.name, targetLayer.name)
+					copyState(rdb, targetLayer.db.NewBatch(), stateRoot)
 
-						rdb.mu.Lock()
-						defer rdb.mu.Unlock()
+					rdb.mu.Lock()
+					defer rdb.mu.Unlock()
 
-						remainingLayers := []*DBLayer{}
-						for _, layer := range rdb.layers {
-							// New layers might have been added after `targetLayer`
-							if layer.name <= sourceLayer.name {
-								layer.destroy()
-							} else {
-								remainingLayers = append(remainingLayers, layer)
-							}
+					remainingLayers := []*DBLayer{}
+					for _, layer := range rdb.layers {
+						// New layers might have been added after `targetLayer`
+						if layer.name <= sourceLayer.name {
+							layer.destroy()
+						} else {
+							remainingLayers = append(remainingLayers, layer)
 						}
-						rdb.layers = remainingLayers
-						break
 					}
+					rdb.layers = remainingLayers
+
+					// The states before the copied state are gone with the destroyed layers
+					rdb.setOldestStateHeight(sourceLayer.tag.Height)
+					break
 				}
-				break
 			}
+			break
 		}
-	default:
-		logger.Debugf("Only one active compaction task allowed")
-		return
 	}
+}
+
+// OldestStateHeight returns the height of the oldest state kept by the compactions, the states of the
+// finalized blocks from this height on are all available. It returns 0 if no state has been dropped.
+func (rdb *RollingDB) OldestStateHeight() uint64 {
+	raw, err := rdb.root.Get(oldestStateHeightKey)
+	if err != nil {
+		return 0
+	}
+	var height uint64
+	if err := rlp.DecodeBytes(raw, &height); err != nil {
+		logger.Errorf("Failed to decode the oldest state height: %v", err)
+		return 0
+	}
+	return height
+}
 
+func (rdb *RollingDB) setOldestStateHeight(height uint64) {
+	raw, err := rlp.EncodeToBytes(height)
+	if err != nil {
+		logger.Panicf("Failed to encode the oldest state height: %v", err)
+	}
+	if err := rdb.root.Put(oldestStateHeightKey, raw); err != nil {
+		logger.Errorf("Failed to save the oldest state height: %v", err)
+	}
 }
 
 func isRollingHeight(height uint64) bool {